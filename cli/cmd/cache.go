@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/misty-step/mercury/cli/internal/cache"
+	"github.com/misty-step/mercury/cli/internal/config"
+)
+
+var cachePruneMaxMB int
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the local message cache",
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show cache size and contents",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printer, err := newPrinter()
+		if err != nil {
+			return err
+		}
+
+		store, err := requireCache()
+		if err != nil {
+			return err
+		}
+
+		stats := store.Stats()
+		if !printer.Human() {
+			return printer.Print(stats)
+		}
+
+		printHeader("Message Cache")
+		fmt.Printf("Directory: %s\n", stats.Dir)
+		fmt.Printf("Emails:    %d\n", stats.Messages)
+		fmt.Printf("Bodies:    %d\n", stats.Bodies)
+		fmt.Printf("Size:      %s of %s\n", formatBytes(stats.Size), formatBytes(stats.MaxSize))
+		return nil
+	},
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Evict least recently read message bodies over the size limit",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := requireCache()
+		if err != nil {
+			return err
+		}
+
+		removed, freed, err := store.Prune(int64(cachePruneMaxMB) << 20)
+		if err != nil {
+			return err
+		}
+		printSuccess("Pruned %d messages (%s)", removed, formatBytes(freed))
+		return nil
+	},
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all cached messages for the profile",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := requireCache()
+		if err != nil {
+			return err
+		}
+
+		if err := store.Clear(); err != nil {
+			return err
+		}
+		printSuccess("Cache cleared.")
+		return nil
+	},
+}
+
+func init() {
+	cachePruneCmd.Flags().IntVar(&cachePruneMaxMB, "max-size", 0, "Target size in MB (default: configured limit)")
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
+
+// openCache opens the active profile's message cache. It returns nil
+// without error when the cache is disabled in config.
+func openCache() (*cache.Store, error) {
+	return openProfileCache(profileName)
+}
+
+// openProfileCache opens the named profile's message cache, resolving an
+// empty name as activeProfileName does.
+func openProfileCache(name string) (*cache.Store, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	if cfg.Cache.Disabled {
+		return nil, nil
+	}
+
+	dir, err := cache.Dir(cfg.Cache.Dir, resolveProfile(cfg, name))
+	if err != nil {
+		return nil, err
+	}
+	store, err := cache.Open(dir, int64(cfg.Cache.MaxSizeMB)<<20)
+	if err != nil {
+		return nil, err
+	}
+	openStoresMu.Lock()
+	openStores = append(openStores, store)
+	openStoresMu.Unlock()
+	return store, nil
+}
+
+// openStores are the caches opened by this process. The TUI opens them
+// from background commands when switching profile, hence the lock.
+var (
+	openStoresMu sync.Mutex
+	openStores   []*cache.Store
+)
+
+// flushCaches writes the batched index changes of every opened cache. A
+// failure only loses cached metadata, so it is not reported.
+func flushCaches() {
+	openStoresMu.Lock()
+	defer openStoresMu.Unlock()
+	for _, store := range openStores {
+		_ = store.Flush()
+	}
+}
+
+func requireCache() (*cache.Store, error) {
+	store, err := openCache()
+	if err != nil {
+		return nil, err
+	}
+	if store == nil {
+		return nil, fmt.Errorf("message cache is disabled in config")
+	}
+	return store, nil
+}
+
+// formatBytes renders a byte count with a binary unit suffix.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -1,8 +1,24 @@
 package cmd
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/cache"
+	"github.com/misty-step/mercury/cli/internal/calendar"
+	"github.com/misty-step/mercury/cli/internal/config"
+	"github.com/misty-step/mercury/cli/internal/tui"
 )
 
 func TestNormalizeReplySubject(t *testing.T) {
@@ -67,3 +83,269 @@ func TestGetDefaultFrom(t *testing.T) {
 		t.Errorf("expected test@example.com, got %q", got)
 	}
 }
+
+func TestProfileRecordsSortedByName(t *testing.T) {
+	cfg := &config.Config{
+		Default: "work",
+		Profiles: map[string]config.Profile{
+			"work":     {Email: "me@work.com"},
+			"personal": {Email: "me@home.com"},
+		},
+	}
+
+	records := profileRecords(cfg)
+	if len(records) != 2 {
+		t.Fatalf("len(records) = %d, want 2", len(records))
+	}
+	if records[0].Name != "personal" || records[1].Name != "work" {
+		t.Errorf("records not sorted: %+v", records)
+	}
+	if records[0].Default || !records[1].Default {
+		t.Errorf("default flag wrong: %+v", records)
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"2026-10-18 09:30:15", "2026-10-18 09:30"},
+		{"2026-10-18T09:30:15Z", "2026-10-18 09:30"},
+		{"yesterday", "yesterday"},
+	}
+
+	for _, tt := range tests {
+		if got := formatDate(tt.input); got != tt.want {
+			t.Errorf("formatDate(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestTUITheme(t *testing.T) {
+	cfg := config.TUIConfig{
+		Theme: "solar",
+		Themes: map[string]map[string]string{
+			"solar": {"base": "light", "accent": "#268bd2"},
+			"bad":   {"accent": "blue"},
+		},
+	}
+	theme, err := tuiTheme(cfg)
+	if err != nil {
+		t.Fatalf("tuiTheme() error = %v", err)
+	}
+	light, _ := tui.LookupTheme("light")
+	if theme.Accent != "#268bd2" || theme.Muted != light.Muted {
+		t.Errorf("theme = %+v, want light with a custom accent", theme)
+	}
+
+	cfg.Theme = "bad"
+	if _, err := tuiTheme(cfg); err == nil || !strings.Contains(err.Error(), "tui.themes.bad") {
+		t.Errorf("invalid color error = %v", err)
+	}
+	cfg.Theme = "neon"
+	if _, err := tuiTheme(cfg); err == nil {
+		t.Error("unknown theme should fail")
+	}
+}
+
+func TestRsvpRequest(t *testing.T) {
+	invite := "BEGIN:VCALENDAR\r\nMETHOD:REQUEST\r\nBEGIN:VEVENT\r\nUID:u1\r\n" +
+		"DTSTART:20261102T140000Z\r\nSUMMARY:Standup\r\n" +
+		"ORGANIZER;CN=Bob:mailto:bob@example.com\r\n" +
+		"ATTENDEE;PARTSTAT=NEEDS-ACTION:mailto:alice@example.com\r\n" +
+		"END:VEVENT\r\nEND:VCALENDAR\r\n"
+	email := &api.Email{ID: 3, Recipient: "Alice <alice@example.com>", Subject: "Invitation: Standup",
+		RawEmail: "Content-Type: text/calendar; method=REQUEST\r\n\r\n" + invite}
+
+	req, err := rsvpRequest(email, calendar.Tentative, "", "Might be late")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.From != "alice@example.com" || req.To != "bob@example.com" || req.Subject != "Tentatively accepted: Standup" {
+		t.Errorf("request = %+v", req)
+	}
+	if !strings.Contains(req.Text, "Might be late") || len(req.Attachments) != 1 {
+		t.Fatalf("request = %+v", req)
+	}
+	ics, err := base64.StdEncoding.DecodeString(req.Attachments[0].Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(ics), "ATTENDEE;PARTSTAT=TENTATIVE:mailto:alice@example.com") ||
+		req.Attachments[0].ContentType != "text/calendar; method=REPLY; charset=UTF-8" {
+		t.Errorf("attachment %q:\n%s", req.Attachments[0].ContentType, ics)
+	}
+
+	cancelled := *email
+	cancelled.RawEmail = strings.Replace(email.RawEmail, "METHOD:REQUEST", "METHOD:CANCEL", 1)
+	if _, err := rsvpRequest(&cancelled, calendar.Accept, "", ""); err == nil {
+		t.Error("a cancellation should not be answered")
+	}
+	if _, err := rsvpRequest(&api.Email{ID: 4, RawEmail: "Subject: hi\r\n\r\nhello"}, calendar.Accept, "", ""); err == nil {
+		t.Error("an email without an invitation should fail")
+	}
+}
+
+func TestAfterRead(t *testing.T) {
+	dir := t.TempDir()
+	original := config.ConfigPath
+	config.ConfigPath = func() string { return filepath.Join(dir, "config.toml") }
+	defer func() { config.ConfigPath = original }()
+	t.Setenv("XDG_DATA_HOME", dir)
+
+	var updates int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&updates, 1)
+		_, _ = w.Write([]byte(`{"success":true}`))
+	}))
+	defer srv.Close()
+	client := api.NewClientNoAuth(srv.URL)
+
+	// Read elsewhere: nothing to mark, but the sender is still collected.
+	afterRead(client, &api.Email{ID: 1, Sender: "Alice <alice@example.com>", IsRead: 1, ReceivedAt: "2026-10-01 09:00:00"})
+	afterRead(client, &api.Email{ID: 2, Sender: "bob@example.com", ReceivedAt: "2026-10-01 09:00:00"})
+	if updates != 1 {
+		t.Errorf("mark-as-read requests = %d, want 1 for the unread email", updates)
+	}
+	book, err := openContacts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, addr := range []string{"alice@example.com", "bob@example.com"} {
+		if found := book.Find(addr); len(found) != 1 {
+			t.Errorf("%s not collected: %+v", addr, found)
+		}
+	}
+}
+
+func TestExpandRecipients(t *testing.T) {
+	dir := t.TempDir()
+	original := config.ConfigPath
+	config.ConfigPath = func() string { return filepath.Join(dir, "config.toml") }
+	defer func() { config.ConfigPath = original }()
+	t.Setenv("XDG_DATA_HOME", dir)
+	t.Setenv("MERCURY_FROM", "")
+
+	book, err := openContacts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	book.Add("Alice Smith", "alice@example.com")
+	if _, err := book.AddToGroup("board", "alice", "bob@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := book.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	people, grouped, err := expandRecipients("@board, carol@example.com, alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := addressList(people); !grouped || got != "alice@example.com, bob@example.com, carol@example.com" {
+		t.Errorf("expandRecipients = %q, grouped %v", got, grouped)
+	}
+	if _, grouped, err := expandRecipients("Dave <dave@example.com>"); err != nil || grouped {
+		t.Errorf("plain address: grouped %v, err %v", grouped, err)
+	}
+	if _, _, err := expandRecipients("@nobody"); err == nil {
+		t.Error("unknown group should fail")
+	}
+}
+
+func TestThreadCandidates(t *testing.T) {
+	raw := func(headers string) string { return headers + "\r\n\r\nbody" }
+	emails := []api.Email{
+		{ID: 1, Folder: "inbox", Subject: "Re: Budget", MessageID: "<c@x>",
+			RawEmail: raw("Message-ID: <c@x>\r\nIn-Reply-To: <b@x>\r\nReferences: <a@x> <b@x>")},
+		// A retitled reply, archived: only its Message-ID links it.
+		{ID: 2, Folder: "archive", Subject: "Numbers for Q3", MessageID: "<b@x>",
+			RawEmail: raw("Message-ID: <b@x>\r\nReferences: <a@x>")},
+		{ID: 3, Folder: "archive", Subject: "Budget", MessageID: "<a@x>", RawEmail: raw("Message-ID: <a@x>")},
+		// A retitled reply to the target, known from the cache.
+		{ID: 4, Folder: "inbox", Subject: "Side question", MessageID: "<d@x>",
+			RawEmail: raw("Message-ID: <d@x>\r\nIn-Reply-To: <c@x>")},
+		{ID: 5, Folder: "inbox", Subject: "Lunch?", MessageID: "<e@x>", RawEmail: raw("Message-ID: <e@x>")},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/emails/")); err == nil {
+			_ = json.NewEncoder(w).Encode(api.EmailResponse{Email: emails[id-1]})
+			return
+		}
+		resp := api.EmailListResponse{Emails: []api.Email{}}
+		for _, e := range emails {
+			if e.Folder == r.URL.Query().Get("folder") {
+				e.RawEmail = ""
+				resp.Emails = append(resp.Emails, e)
+			}
+		}
+		resp.Total = len(resp.Emails)
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	store, err := cache.Open(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.StoreEmail(&emails[3])
+	client := api.NewClientNoAuth(srv.URL)
+	client.Cache = store
+
+	candidates, err := threadCandidates(client, &emails[0], 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[int]bool)
+	for _, c := range candidates {
+		got[c.ID] = true
+	}
+	for id, want := range map[int]bool{1: true, 2: true, 3: true, 4: true, 5: false} {
+		if got[id] != want {
+			t.Errorf("email %d gathered = %v, want %v", id, got[id], want)
+		}
+	}
+}
+
+func TestFailedDMARC(t *testing.T) {
+	var inFlight, peak int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		id, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/emails/"))
+		result := "pass"
+		if id%3 == 0 {
+			result = "fail"
+		}
+		raw := "Authentication-Results: mx.cloudflare.net; dmarc=" + result + "\r\n\r\nbody"
+		_ = json.NewEncoder(w).Encode(api.EmailResponse{Email: api.Email{ID: id, RawEmail: raw}})
+	}))
+	defer srv.Close()
+
+	var emails []api.Email
+	for id := 1; id <= 12; id++ {
+		emails = append(emails, api.Email{ID: id})
+	}
+	failed, err := failedDMARC(api.NewClientNoAuth(srv.URL), emails)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, e := range failed {
+		ids = append(ids, e.ID)
+	}
+	if fmt.Sprint(ids) != "[3 6 9 12]" {
+		t.Errorf("failed = %v, want [3 6 9 12]", ids)
+	}
+	if peak > api.ConcurrentRequests {
+		t.Errorf("%d requests at once, want at most %d", peak, api.ConcurrentRequests)
+	}
+}
@@ -0,0 +1,450 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/misty-step/mercury/cli/internal/config"
+	"github.com/misty-step/mercury/cli/internal/contacts"
+	"github.com/misty-step/mercury/cli/internal/table"
+)
+
+var contactsCmd = &cobra.Command{
+	Use:   "contacts",
+	Short: "Manage the address book",
+	Long: `Manage the active profile's address book.
+
+Besides the contacts you add or import, mercury collects the senders of mail
+you read and the recipients of mail you send, ranking everyone by how often
+and how recently they appear. Wherever a recipient is expected, such as
+"send --to alice", a name that matches one contact stands for its address,
+and a group such as "@board" for its members (see "mercury contacts group").`,
+}
+
+var contactsListCmd = &cobra.Command{
+	Use:   "list [query]",
+	Short: "List contacts, best first",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printer, err := newPrinter()
+		if err != nil {
+			return err
+		}
+		book, err := openContacts()
+		if err != nil {
+			return err
+		}
+		query := ""
+		if len(args) == 1 {
+			query = args[0]
+		}
+		found := book.Find(query)
+		records := make([]contactRecord, len(found))
+		for i, c := range found {
+			records[i] = newContactRecord(c)
+		}
+		if !printer.Human() {
+			return printer.Print(records)
+		}
+
+		if len(records) == 0 {
+			fmt.Println("No contacts.")
+			return nil
+		}
+		printHeader(fmt.Sprintf("Contacts (%d)", len(records)))
+		t := table.New(terminalWidth(),
+			table.Column{Min: 8, Max: 30, Flex: true},
+			table.Column{Min: 10, Flex: true},
+			table.Column{Min: 4, Max: 6, Align: table.AlignRight},
+			table.Column{Min: 10, Max: 10},
+		)
+		for _, c := range found {
+			last := ""
+			if !c.Last.IsZero() {
+				last = c.Last.Local().Format("2006-01-02")
+			}
+			t.Add(nil, c.Name, c.Email, strconv.Itoa(c.Count), last)
+		}
+		return t.Render(os.Stdout)
+	},
+}
+
+var contactsAddCmd = &cobra.Command{
+	Use:   "add <address> [name]",
+	Short: "Add a contact, or rename one",
+	Example: `  mercury contacts add alice@example.com "Alice Smith"
+  mercury contacts add "Alice Smith <alice@example.com>"`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		book, err := openContacts()
+		if err != nil {
+			return err
+		}
+		name := ""
+		if len(args) == 2 {
+			name = strings.TrimSpace(args[1])
+		}
+		c, err := book.Add(name, args[0])
+		if err != nil {
+			return err
+		}
+		if err := book.Save(); err != nil {
+			return err
+		}
+		printSuccess("Saved %s", c)
+		return nil
+	},
+}
+
+var contactsRmCmd = &cobra.Command{
+	Use:     "rm <name|address>",
+	Aliases: []string{"remove"},
+	Short:   "Remove a contact",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		book, err := openContacts()
+		if err != nil {
+			return err
+		}
+		c, err := book.Remove(args[0])
+		if err != nil {
+			return err
+		}
+		if err := book.Save(); err != nil {
+			return err
+		}
+		printSuccess("Removed %s", c)
+		return nil
+	},
+}
+
+var contactsImportCmd = &cobra.Command{
+	Use:   "import <file.vcf>",
+	Short: "Import contacts from a vCard file",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		book, err := openContacts()
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		read, added, err := book.Import(f)
+		if err != nil {
+			return err
+		}
+		if err := book.Save(); err != nil {
+			return err
+		}
+		printSuccess("Imported %d contacts (%d new)", read, added)
+		return nil
+	},
+}
+
+var contactsExportCmd = &cobra.Command{
+	Use:   "export [file.vcf]",
+	Short: "Export contacts as vCards, to stdout by default",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		book, err := openContacts()
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			return contacts.WriteVCard(os.Stdout, book.List())
+		}
+		f, err := os.Create(args[0])
+		if err != nil {
+			return err
+		}
+		if err := contacts.WriteVCard(f, book.List()); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		printSuccess("Exported %d contacts to %s", len(book.List()), args[0])
+		return nil
+	},
+}
+
+// contactRecord is the stable schema for `contacts list`.
+type contactRecord struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Count    int    `json:"count"`
+	LastSeen string `json:"last_seen,omitempty"`
+	Saved    bool   `json:"saved"`
+}
+
+func newContactRecord(c contacts.Contact) contactRecord {
+	r := contactRecord{Name: c.Name, Email: c.Email, Count: c.Count, Saved: c.Saved}
+	if !c.Last.IsZero() {
+		r.LastSeen = c.Last.UTC().Format(time.RFC3339)
+	}
+	return r
+}
+
+// openContacts opens the active profile's address book. The profile's own
+// addresses are never collected into it.
+func openContacts() (*contacts.Book, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	return profileContacts(cfg, activeProfileName(cfg))
+}
+
+// profileContacts opens the named profile's address book.
+func profileContacts(cfg *config.Config, name string) (*contacts.Book, error) {
+	dir, err := config.DataDir(name)
+	if err != nil {
+		return nil, err
+	}
+	book, err := contacts.Open(filepath.Join(dir, contacts.FileName))
+	if err != nil {
+		return nil, err
+	}
+	if p, ok := cfg.Profiles[name]; ok && p.Email != "" {
+		book.Self = append(book.Self, p.Email)
+	}
+	if from := getDefaultFrom(); from != "" {
+		book.Self = append(book.Self, extractEmailAddress(from))
+	}
+	return book, nil
+}
+
+// rememberContacts records addresses in the address book. It is best
+// effort: the address book never fails the command that feeds it.
+func rememberContacts(observe func(*contacts.Book) int) {
+	book, err := openContacts()
+	if err != nil {
+		return
+	}
+	if observe(book) > 0 {
+		_ = book.Save()
+	}
+}
+
+// tuiContacts opens the address book of the profile the TUI is showing,
+// keeping each one open for the rest of the session. It returns nil when
+// the book cannot be read.
+func tuiContacts(cfg *config.Config) func(profile string) *contacts.Book {
+	books := make(map[string]*contacts.Book)
+	return func(profile string) *contacts.Book {
+		name := resolveProfile(cfg, profile)
+		if book, ok := books[name]; ok {
+			return book
+		}
+		book, err := profileContacts(cfg, name)
+		if err != nil {
+			return nil
+		}
+		books[name] = book
+		return book
+	}
+}
+
+// expandRecipients resolves a recipient list of addresses, contact names
+// and @groups to the people it names, reporting whether a group was
+// expanded.
+func expandRecipients(list string) ([]contacts.Contact, bool, error) {
+	grouped := false
+	names := false
+	for _, item := range contacts.SplitList(list) {
+		grouped = grouped || contacts.IsGroup(item)
+		names = names || !strings.Contains(item, "@")
+	}
+	if !grouped && !names {
+		// Plain addresses need no address book.
+		var people []contacts.Contact
+		for _, item := range contacts.SplitList(list) {
+			people = append(people, contacts.Contact{Email: extractEmailAddress(item)})
+		}
+		return people, false, nil
+	}
+	book, err := openContacts()
+	if err != nil {
+		return nil, false, err
+	}
+	people, err := book.Expand(list)
+	if err != nil {
+		return nil, false, err
+	}
+	return people, grouped, nil
+}
+
+// addressList joins the addresses of people for a send request.
+func addressList(people []contacts.Contact) string {
+	addrs := make([]string, len(people))
+	for i, c := range people {
+		addrs[i] = c.Email
+	}
+	return strings.Join(addrs, ", ")
+}
+
+var contactsGroupCmd = &cobra.Command{
+	Use:   "group",
+	Short: "Manage contact groups",
+	Long: `Manage named groups of recipients, such as @board.
+
+A group's members are addresses, contacts (stored by address) and other
+groups. Wherever recipients are expected, "@board" stands for everyone in the
+group, nested groups included.`,
+	Example: `  mercury contacts group add board alice bob@example.com
+  mercury contacts group add everyone @board @oncall
+  echo "Minutes attached" | mercury send --to @board me@example.com "Minutes"`,
+}
+
+var contactsGroupListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List groups and their members",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printer, err := newPrinter()
+		if err != nil {
+			return err
+		}
+		book, err := openContacts()
+		if err != nil {
+			return err
+		}
+		groups := book.Groups()
+		records := make([]groupRecord, len(groups))
+		for i, g := range groups {
+			records[i] = groupRecord{Name: g.Name, Members: g.Members}
+		}
+		if !printer.Human() {
+			return printer.Print(records)
+		}
+
+		if len(groups) == 0 {
+			fmt.Println("No groups.")
+			return nil
+		}
+		printHeader(fmt.Sprintf("Groups (%d)", len(groups)))
+		t := table.New(terminalWidth(),
+			table.Column{Min: 6, Max: 20},
+			table.Column{Min: 10, Flex: true},
+		)
+		for _, g := range groups {
+			t.Add(nil, contacts.GroupPrefix+g.Name, strings.Join(g.Members, ", "))
+		}
+		return t.Render(os.Stdout)
+	},
+}
+
+var contactsGroupShowCmd = &cobra.Command{
+	Use:   "show <group>",
+	Short: "Show everyone a group expands to",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printer, err := newPrinter()
+		if err != nil {
+			return err
+		}
+		book, err := openContacts()
+		if err != nil {
+			return err
+		}
+		name := args[0]
+		if !contacts.IsGroup(name) {
+			name = contacts.GroupPrefix + name
+		}
+		people, err := book.Expand(name)
+		if err != nil {
+			return err
+		}
+		records := make([]contactRecord, len(people))
+		for i, c := range people {
+			records[i] = newContactRecord(c)
+		}
+		if !printer.Human() {
+			return printer.Print(records)
+		}
+
+		printHeader(fmt.Sprintf("%s (%d)", name, len(people)))
+		for _, c := range people {
+			fmt.Println(c)
+		}
+		return nil
+	},
+}
+
+var contactsGroupAddCmd = &cobra.Command{
+	Use:   "add <group> <member>...",
+	Short: "Add members to a group, creating it if needed",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		book, err := openContacts()
+		if err != nil {
+			return err
+		}
+		g, err := book.AddToGroup(args[0], args[1:]...)
+		if err != nil {
+			return err
+		}
+		if err := book.Save(); err != nil {
+			return err
+		}
+		printSuccess("%s%s: %s", contacts.GroupPrefix, g.Name, strings.Join(g.Members, ", "))
+		return nil
+	},
+}
+
+var contactsGroupRmCmd = &cobra.Command{
+	Use:     "rm <group> [member...]",
+	Aliases: []string{"remove"},
+	Short:   "Remove members from a group, or the whole group",
+	Args:    cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		book, err := openContacts()
+		if err != nil {
+			return err
+		}
+		g, err := book.RemoveFromGroup(args[0], args[1:]...)
+		if err != nil {
+			return err
+		}
+		if err := book.Save(); err != nil {
+			return err
+		}
+		if len(args) == 1 {
+			printSuccess("Removed %s%s", contacts.GroupPrefix, g.Name)
+		} else {
+			printSuccess("%s%s: %s", contacts.GroupPrefix, g.Name, strings.Join(g.Members, ", "))
+		}
+		return nil
+	},
+}
+
+// groupRecord is the stable schema for `contacts group list`.
+type groupRecord struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
+
+func init() {
+	contactsCmd.AddCommand(contactsListCmd)
+	contactsCmd.AddCommand(contactsAddCmd)
+	contactsCmd.AddCommand(contactsRmCmd)
+	contactsCmd.AddCommand(contactsImportCmd)
+	contactsCmd.AddCommand(contactsExportCmd)
+	contactsGroupCmd.AddCommand(contactsGroupListCmd)
+	contactsGroupCmd.AddCommand(contactsGroupShowCmd)
+	contactsGroupCmd.AddCommand(contactsGroupAddCmd)
+	contactsGroupCmd.AddCommand(contactsGroupRmCmd)
+	contactsCmd.AddCommand(contactsGroupCmd)
+	rootCmd.AddCommand(contactsCmd)
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/mbox"
+)
+
+var (
+	exportFolder string
+	exportLimit  int
+)
+
+var exportSpec = sharedCommand("export")
+
+var exportCmd = &cobra.Command{
+	Use:   exportSpec.Usage(),
+	Short: "Export emails to a file",
+	Long: `Write a folder's most recent emails, with their full content, to a file.
+Use - as the path to write to stdout.`,
+	Example: `  mercury export mbox ~/inbox.mbox
+  mercury export mbox archive.mbox --folder archive --limit 500`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		_, err := exportSpec.Check(args)
+		return err
+	},
+	ValidArgsFunction: completeShared(exportSpec),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := args[1]
+		if !api.ValidFolder(exportFolder) {
+			return fmt.Errorf("unknown folder %q (valid: %s)", exportFolder, strings.Join(api.Folders, ", "))
+		}
+		if exportLimit <= 0 {
+			return fmt.Errorf("limit must be positive")
+		}
+
+		client, err := authedClient()
+		if err != nil {
+			return err
+		}
+
+		emails, err := exportEmails(client, exportFolder, exportLimit)
+		if err != nil {
+			return err
+		}
+		if path == "-" {
+			return mbox.Write(os.Stdout, emails)
+		}
+		if err := mbox.WriteFile(path, emails); err != nil {
+			return err
+		}
+		printSuccess("Exported %d emails to %s", len(emails), path)
+		return nil
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&exportFolder, "folder", "inbox", "Folder to export")
+	exportCmd.Flags().IntVar(&exportLimit, "limit", 100, "Maximum number of emails, newest first")
+	rootCmd.AddCommand(exportCmd)
+}
+
+// exportEmails lists up to n of the newest emails in folder and fetches
+// each in full.
+func exportEmails(client *api.Client, folder string, n int) ([]api.Email, error) {
+	const pageSize = 100
+	var emails []api.Email
+	for offset := 0; offset < n; offset += pageSize {
+		limit := pageSize
+		if n-offset < limit {
+			limit = n - offset
+		}
+		resp, err := client.ListEmails(limit, offset, folder)
+		if err != nil {
+			return nil, err
+		}
+		for _, summary := range resp.Emails {
+			full, err := client.GetEmail(summary.ID)
+			if err != nil {
+				return nil, fmt.Errorf("fetch email #%d: %w", summary.ID, err)
+			}
+			emails = append(emails, *full)
+		}
+		if len(resp.Emails) < limit {
+			break
+		}
+	}
+	return emails, nil
+}
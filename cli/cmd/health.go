@@ -12,9 +12,21 @@ var healthCmd = &cobra.Command{
 	Short:   "Check server health",
 	Aliases: []string{"ping"},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		printDim("Checking %s...", apiURL)
+		printer, err := newPrinter()
+		if err != nil {
+			return err
+		}
+
 		client := unauthedClient()
+		if !printer.Human() {
+			resp, err := client.Health()
+			if err != nil {
+				return err
+			}
+			return printer.Print(resp)
+		}
 
+		printDim("Checking %s...", apiURL)
 		resp, err := client.Health()
 		if err != nil {
 			return err
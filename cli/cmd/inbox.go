@@ -2,10 +2,17 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"sync"
 
 	"github.com/spf13/cobra"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/table"
 )
 
+var inboxDMARCFail bool
+
 var inboxCmd = &cobra.Command{
 	Use:     "inbox [limit] [offset]",
 	Short:   "List emails in inbox",
@@ -35,6 +42,11 @@ var inboxCmd = &cobra.Command{
 			return fmt.Errorf("offset must be zero or positive")
 		}
 
+		printer, err := newPrinter()
+		if err != nil {
+			return err
+		}
+
 		client, err := authedClient()
 		if err != nil {
 			return err
@@ -44,28 +56,107 @@ var inboxCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		listed := len(resp.Emails)
+		if inboxDMARCFail {
+			if resp.Emails, err = failedDMARC(client, resp.Emails); err != nil {
+				return err
+			}
+		}
 
-		printHeader("Mercury Inbox")
+		if !printer.Human() {
+			records := make([]emailRecord, len(resp.Emails))
+			for i := range resp.Emails {
+				records[i] = newEmailRecord(&resp.Emails[i])
+			}
+			return printer.Print(records)
+		}
+
+		title := "Mercury Inbox"
+		if inboxDMARCFail {
+			title += " — failed DMARC"
+		}
+		if client.Offline {
+			title += " (offline)"
+		}
+		printHeader(title)
 
 		if len(resp.Emails) == 0 {
 			fmt.Println("  (empty)")
-		} else {
-			for _, email := range resp.Emails {
-				marker := " "
-				if email.IsRead == 0 {
-					marker = "*"
-				}
-				sender := truncate(normalizeSender(email.Sender), 25)
-				subject := truncate(email.Subject, 45)
-				fmt.Printf("%s [%3d] %-25s %s\n", marker, email.ID, sender, subject)
-			}
+		} else if err := emailTable(resp.Emails).Render(os.Stdout); err != nil {
+			return err
 		}
 
-		fmt.Printf("\nTotal: %d emails\n", resp.Total)
+		if inboxDMARCFail {
+			fmt.Printf("\n%d of %d listed emails failed DMARC\n", len(resp.Emails), listed)
+		} else {
+			fmt.Printf("\nTotal: %d emails\n", resp.Total)
+		}
 		return nil
 	},
 }
 
+// emailTable lays out emails as marker, id, sender, subject and date columns.
+func emailTable(emails []api.Email) *table.Table {
+	t := table.New(terminalWidth(),
+		table.Column{Min: 1, Max: 1},
+		table.Column{Min: 5, Align: table.AlignRight},
+		table.Column{Min: 8, Max: 25, Flex: true},
+		table.Column{Min: 10, Flex: true},
+		table.Column{Min: 10, Max: 16},
+	)
+	for i := range emails {
+		email := &emails[i]
+		marker := " "
+		if !email.Read() {
+			marker = "*"
+		}
+		t.Add(emailRowStyle(email),
+			marker,
+			fmt.Sprintf("[%d]", email.ID),
+			normalizeSender(email.Sender),
+			email.Subject,
+			formatDate(email.ReceivedAt),
+		)
+	}
+	return t
+}
+
+// failedDMARC fetches each email's headers, a few at a time, and keeps
+// those whose Authentication-Results report a DMARC failure, in order.
+func failedDMARC(client *api.Client, emails []api.Email) ([]api.Email, error) {
+	fails := make([]bool, len(emails))
+	errs := make([]error, len(emails))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, api.ConcurrentRequests)
+	for i := range emails {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			email, err := client.GetEmail(emails[i].ID)
+			if err != nil {
+				errs[i] = fmt.Errorf("fetch email #%d: %w", emails[i].ID, err)
+				return
+			}
+			fails[i] = email.Authentication().DMARC.Result == api.AuthFail
+		}(i)
+	}
+	wg.Wait()
+
+	var failed []api.Email
+	for i, summary := range emails {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		if fails[i] {
+			failed = append(failed, summary)
+		}
+	}
+	return failed, nil
+}
+
 func init() {
+	inboxCmd.Flags().BoolVar(&inboxDMARCFail, "dmarc-fail", false, "Only show listed emails that failed DMARC (fetches their headers)")
 	rootCmd.AddCommand(inboxCmd)
 }
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+)
+
+var linksCmd = &cobra.Command{
+	Use:   "links <id>",
+	Short: "List the links in an email",
+	Long: `List the URLs in an email's plain text and HTML parts, numbered in the
+order they first appear. HTML links whose text shows a different domain than
+the one they lead to are flagged as lookalikes.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseIDArg(args[0])
+		if err != nil {
+			return err
+		}
+
+		printer, err := newPrinter()
+		if err != nil {
+			return err
+		}
+
+		client, err := authedClient()
+		if err != nil {
+			return err
+		}
+
+		email, err := client.GetEmail(id)
+		if err != nil {
+			return err
+		}
+		links := email.Links()
+
+		if !printer.Human() {
+			records := make([]linkRecord, len(links))
+			for i, link := range links {
+				records[i] = newLinkRecord(i+1, link)
+			}
+			return printer.Print(records)
+		}
+
+		if len(links) == 0 {
+			printDim("No links in email #%d", id)
+			return nil
+		}
+		printHeader(fmt.Sprintf("Links in email #%d (%d)", id, len(links)))
+		for i, link := range links {
+			fmt.Printf("%3d  %s\n", i+1, link.URL)
+			if link.Lookalike {
+				warnStyle.Printf("     ⚠ lookalike: shows %q but leads to %s\n", link.Text, link.Host())
+			} else if link.Text != "" && link.Text != link.URL {
+				printDim("     %s", link.Text)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(linksCmd)
+}
+
+// linkRecord is the stable schema for `links`.
+type linkRecord struct {
+	Index     int    `json:"index"`
+	URL       string `json:"url"`
+	Text      string `json:"text"`
+	Lookalike bool   `json:"lookalike"`
+}
+
+func newLinkRecord(index int, link api.Link) linkRecord {
+	return linkRecord{Index: index, URL: link.URL, Text: link.Text, Lookalike: link.Lookalike}
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var moveSpec = sharedCommand("move")
+
+var moveCmd = &cobra.Command{
+	Use:     moveSpec.Usage() + " <id>...",
+	Short:   "Move emails to a folder",
+	Aliases: []string{"mv"},
+	Example: `  mercury move archive 12 13 14`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 2 {
+			return fmt.Errorf("usage: mercury %s", cmd.Use)
+		}
+		_, err := moveSpec.Check(args[:1])
+		return err
+	},
+	ValidArgsFunction: completeShared(moveSpec),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		folder := args[0]
+		ids := make([]int, len(args)-1)
+		for i, arg := range args[1:] {
+			id, err := parseIDArg(arg)
+			if err != nil {
+				return err
+			}
+			ids[i] = id
+		}
+
+		client, err := authedClient()
+		if err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			if err := client.MoveEmail(id, folder); err != nil {
+				return fmt.Errorf("move email #%d: %w", id, err)
+			}
+			printSuccess("Moved email #%d to %s", id, folder)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(moveCmd)
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/output"
+	"github.com/misty-step/mercury/cli/internal/pgp"
+)
+
+var (
+	outputFormat   string
+	outputTemplate string
+)
+
+func init() {
+	names := make([]string, len(output.Formats))
+	for i, f := range output.Formats {
+		names[i] = string(f)
+	}
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", string(output.FormatTable), "Output format: "+strings.Join(names, "|"))
+	rootCmd.PersistentFlags().StringVar(&outputTemplate, "template", "", "Go text/template applied to each record, e.g. '{{.ID}} {{.Subject}}'")
+}
+
+// newPrinter builds a printer for stdout from the global output flags.
+func newPrinter() (*output.Printer, error) {
+	return output.New(os.Stdout, outputFormat, outputTemplate)
+}
+
+// emailRecord is the stable schema for emails in structured output.
+type emailRecord struct {
+	ID         int    `json:"id"`
+	MessageID  string `json:"message_id"`
+	Sender     string `json:"sender"`
+	Recipient  string `json:"recipient"`
+	Subject    string `json:"subject"`
+	ReceivedAt string `json:"received_at"`
+	Read       bool   `json:"read"`
+	Starred    bool   `json:"starred"`
+	Folder     string `json:"folder"`
+}
+
+func newEmailRecord(e *api.Email) emailRecord {
+	return emailRecord{
+		ID:         e.ID,
+		MessageID:  e.MessageID,
+		Sender:     e.Sender,
+		Recipient:  e.Recipient,
+		Subject:    e.Subject,
+		ReceivedAt: e.ReceivedAt,
+		Read:       e.Read(),
+		Starred:    e.Starred(),
+		Folder:     e.Folder,
+	}
+}
+
+// messageRecord extends emailRecord with the decoded body and the
+// Authentication-Results verdicts for `read`. Unreported checks are empty.
+type messageRecord struct {
+	emailRecord
+	Body  string `json:"body"`
+	SPF   string `json:"spf"`
+	DKIM  string `json:"dkim"`
+	DMARC string `json:"dmarc"`
+	// Set by read for OpenPGP and S/MIME mail, whose body is the decrypted
+	// or signed text.
+	Encrypted bool   `json:"encrypted,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Signer    string `json:"signer,omitempty"`
+}
+
+func newMessageRecord(e *api.Email) messageRecord {
+	auth := e.Authentication()
+	return messageRecord{
+		emailRecord: newEmailRecord(e),
+		Body:        e.Body(),
+		SPF:         string(auth.SPF.Result),
+		DKIM:        string(auth.DKIM.Result),
+		DMARC:       string(auth.DMARC.Result),
+	}
+}
+
+// withPGP replaces the body with the opened OpenPGP content and records
+// how it was protected.
+func (r messageRecord) withPGP(res *pgp.Result) messageRecord {
+	r.Body = res.Body
+	r.Encrypted = res.Encrypted
+	if res.Signature != nil {
+		r.Signature = res.Signature.Status.String()
+		r.Signer = res.Signature.Signer
+	}
+	return r
+}
+
+// withSMIME replaces the body with the signed S/MIME content and records
+// the signature.
+func (r messageRecord) withSMIME(res *api.SMIMEResult) messageRecord {
+	r.Body = res.Content.Body()
+	r.Signature = res.Verification.Status.String()
+	r.Signer = res.Verification.SignerName()
+	return r
+}
+
+// profileRecord is the stable schema for `profile list`.
+type profileRecord struct {
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Default bool   `json:"default"`
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/config"
+	"github.com/misty-step/mercury/cli/internal/pgp"
+)
+
+// profileKeyring returns the OpenPGP keys configured for the named
+// profile, or gpg's defaults when it configures none.
+func profileKeyring(cfg *config.Config, name string) *pgp.Keyring {
+	k := &pgp.Keyring{}
+	p, ok := cfg.Profiles[name]
+	if !ok {
+		return k
+	}
+	k.Home = config.ExpandHome(p.PGP.Keyring)
+	for _, file := range p.PGP.KeyFiles {
+		k.KeyFiles = append(k.KeyFiles, config.ExpandHome(file))
+	}
+	k.SignKey = p.PGP.SignKey
+	return k
+}
+
+// activeKeyring returns the OpenPGP keys of the active profile. Close it
+// when done.
+func activeKeyring() (*pgp.Keyring, error) {
+	return namedKeyring(profileName)
+}
+
+// namedKeyring returns the OpenPGP keys of the named profile, resolving an
+// empty name as activeProfileName does. Close it when done.
+func namedKeyring(name string) (*pgp.Keyring, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	return profileKeyring(cfg, resolveProfile(cfg, name)), nil
+}
+
+// openPGP decrypts and verifies an OpenPGP email with the named profile's
+// keys. It returns nil for mail that is neither signed nor encrypted.
+func openPGP(profile string, email *api.Email) (*pgp.Result, error) {
+	if pgp.Detect(email) == pgp.None {
+		return nil, nil
+	}
+	keyring, err := namedKeyring(profile)
+	if err != nil {
+		return nil, err
+	}
+	defer keyring.Close()
+	return keyring.Open(email)
+}
+
+// pgpBadge colors a verification: green when trusted, red when the
+// signature is bad or its key revoked, yellow otherwise.
+func pgpBadge(res *pgp.Result) string {
+	switch {
+	case res.Signature != nil && res.Signature.Trusted():
+		return successStyle.Sprint(res.Badge())
+	case res.Signature != nil && (res.Signature.Status == pgp.Bad || res.Signature.Status == pgp.RevokedKey):
+		return errorStyle.Sprint(res.Badge())
+	default:
+		return warnStyle.Sprint(res.Badge())
+	}
+}
@@ -2,10 +2,14 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"sort"
 
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 
 	"github.com/misty-step/mercury/cli/internal/config"
+	"github.com/misty-step/mercury/cli/internal/table"
 )
 
 var profileCmd = &cobra.Command{
@@ -17,26 +21,43 @@ var profileListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all profiles",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		printer, err := newPrinter()
+		if err != nil {
+			return err
+		}
+
 		cfg, err := config.Load()
 		if err != nil {
 			return err
 		}
 
-		if len(cfg.Profiles) == 0 {
+		records := profileRecords(cfg)
+		if !printer.Human() {
+			return printer.Print(records)
+		}
+
+		if len(records) == 0 {
 			fmt.Println("No profiles configured.")
 			fmt.Println("Create ~/.config/mercury/config.toml to add profiles.")
 			return nil
 		}
 
 		printHeader("Profiles")
-		for name, p := range cfg.Profiles {
-			marker := "  "
-			if name == cfg.Default {
-				marker = "* "
+		t := table.New(terminalWidth(),
+			table.Column{Min: 1, Max: 1},
+			table.Column{Min: 8, Max: 24, Flex: true},
+			table.Column{Min: 10, Flex: true},
+		)
+		for _, p := range records {
+			marker := " "
+			var style *color.Color
+			if p.Default {
+				marker = "*"
+				style = headerStyle
 			}
-			fmt.Printf("%s%s <%s>\n", marker, name, p.Email)
+			t.Add(style, marker, p.Name, "<"+p.Email+">")
 		}
-		return nil
+		return t.Render(os.Stdout)
 	},
 }
 
@@ -66,6 +87,25 @@ var profileUseCmd = &cobra.Command{
 	},
 }
 
+// profileRecords returns the configured profiles sorted by name.
+func profileRecords(cfg *config.Config) []profileRecord {
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	records := make([]profileRecord, len(names))
+	for i, name := range names {
+		records[i] = profileRecord{
+			Name:    name,
+			Email:   cfg.Profiles[name].Email,
+			Default: name == cfg.Default,
+		}
+	}
+	return records
+}
+
 func init() {
 	profileCmd.AddCommand(profileListCmd)
 	profileCmd.AddCommand(profileUseCmd)
@@ -2,22 +2,45 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/calendar"
+	"github.com/misty-step/mercury/cli/internal/contacts"
+	"github.com/misty-step/mercury/cli/internal/output"
+)
+
+var (
+	readHeaders   bool
+	readRaw       bool
+	readStructure bool
 )
 
 var readCmd = &cobra.Command{
 	Use:     "read <id>",
 	Short:   "Read an email",
 	Aliases: []string{"show", "view"},
-	Args:    cobra.ExactArgs(1),
+	Example: `  mercury read 12
+  mercury read 12 --headers
+  mercury read 12 --raw | grep -i '^received:'
+  mercury read 12 --structure`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		id, err := parseIDArg(args[0])
 		if err != nil {
 			return err
 		}
 
+		printer, err := newPrinter()
+		if err != nil {
+			return err
+		}
+
 		client, err := authedClient()
 		if err != nil {
 			return err
@@ -28,30 +51,195 @@ var readCmd = &cobra.Command{
 			return err
 		}
 
+		switch {
+		case readRaw:
+			return printRaw(email)
+		case readHeaders:
+			return printHeaderFields(printer, email)
+		case readStructure:
+			return printStructure(printer, email)
+		}
+
+		secure, pgpErr := openPGP(profileName, email)
+		signed, smimeErr := verifySMIME(profileName, email)
+		if !printer.Human() {
+			record := newMessageRecord(email)
+			if signed != nil {
+				record = record.withSMIME(signed)
+			}
+			if secure != nil {
+				record = record.withPGP(secure)
+			}
+			for _, err := range []error{pgpErr, smimeErr} {
+				if err != nil {
+					warnStyle.Fprintf(color.Error, "Warning: %v\n", err)
+				}
+			}
+			if err := printer.Print(record); err != nil {
+				return err
+			}
+			afterRead(client, email)
+			return nil
+		}
+
 		printHeader(fmt.Sprintf("Email #%d", id))
 		fmt.Printf("From:    %s\n", email.Sender)
 		fmt.Printf("To:      %s\n", email.Recipient)
 		fmt.Printf("Subject: %s\n", email.Subject)
 		fmt.Printf("Date:    %s\n", email.ReceivedAt)
+		if auth := email.Authentication(); auth.Known() {
+			fmt.Printf("Auth:    %s\n", authBadges(auth))
+		}
+		switch {
+		case pgpErr != nil:
+			fmt.Printf("PGP:     %s\n", errorStyle.Sprint(pgpErr))
+		case secure != nil:
+			fmt.Printf("PGP:     %s\n", pgpBadge(secure))
+		}
+		switch {
+		case smimeErr != nil:
+			fmt.Printf("S/MIME:  %s\n", errorStyle.Sprint(smimeErr))
+		case signed != nil:
+			fmt.Printf("S/MIME:  %s\n", smimeBadge(signed))
+		}
 		fmt.Println("")
-		fmt.Println(strings.Repeat("-", 78))
+		fmt.Println(strings.Repeat("-", terminalWidth()))
 		fmt.Println("")
 
+		hasEvents := printEventCards(email)
 		body := email.Body()
-		if body == "" {
+		if signed != nil {
+			body = signed.Content.Body()
+		}
+		if secure != nil {
+			body = secure.Body
+		}
+		if body == "" && !hasEvents {
 			body = "(no body)"
 		}
 		fmt.Println(body)
 
-		// Mark as read (best effort - don't fail the read if this fails)
-		if !email.Read() {
-			_ = client.MarkAsRead(id)
-		}
-
+		afterRead(client, email)
 		return nil
 	},
 }
 
+// afterRead marks an unread email read and remembers its sender, whether
+// or not it was read before (say in another client). Both are best effort
+// and never fail the read.
+func afterRead(client *api.Client, email *api.Email) {
+	if !email.Read() {
+		_ = client.MarkAsRead(email.ID)
+	}
+	rememberContacts(func(b *contacts.Book) int { return b.ObserveEmail(email) })
+}
+
 func init() {
+	readCmd.Flags().BoolVar(&readHeaders, "headers", false, "Show every header instead of the body")
+	readCmd.Flags().BoolVar(&readRaw, "raw", false, "Write the exact RFC 822 source to stdout")
+	readCmd.Flags().BoolVar(&readStructure, "structure", false, "Show the MIME tree with content types, encodings and sizes")
+	readCmd.MarkFlagsMutuallyExclusive("headers", "raw", "structure")
 	rootCmd.AddCommand(readCmd)
 }
+
+// authBadges renders SPF, DKIM and DMARC results, failures in red. The
+// DMARC domain is shown since it is the From domain that was checked.
+func authBadges(v api.AuthVerdict) string {
+	badge := func(name string, check api.AuthCheck) string {
+		result := string(check.Result)
+		if result == "" {
+			result = "none"
+		}
+		text := name + " " + result
+		if name == "DMARC" && check.Domain != "" {
+			text += " (" + check.Domain + ")"
+		}
+		switch {
+		case check.Result == api.AuthPass:
+			return successStyle.Sprint(text)
+		case check.Result.Failed():
+			return errorStyle.Sprint(text)
+		default:
+			return dimStyle.Sprint(text)
+		}
+	}
+	return strings.Join([]string{badge("SPF", v.SPF), badge("DKIM", v.DKIM), badge("DMARC", v.DMARC)}, "  ")
+}
+
+// printEventCards shows each event of the email's calendar above the body.
+func printEventCards(email *api.Email) bool {
+	cal := calendar.FromEmail(email)
+	if cal == nil {
+		return false
+	}
+	for _, event := range cal.Events {
+		for _, f := range event.Card(cal.Method, time.Local) {
+			headerStyle.Printf("%-10s", f.Label+":")
+			fmt.Printf(" %s\n", f.Value)
+		}
+		fmt.Println("")
+	}
+	return true
+}
+
+// printRaw writes the message source unchanged, for piping to other tools.
+func printRaw(email *api.Email) error {
+	if email.RawEmail == "" {
+		return fmt.Errorf("email #%d has no raw source", email.ID)
+	}
+	_, err := os.Stdout.WriteString(email.RawEmail)
+	return err
+}
+
+func printHeaderFields(printer *output.Printer, email *api.Email) error {
+	fields := email.HeaderFields()
+	if !printer.Human() {
+		return printer.Print(fields)
+	}
+	for _, f := range fields {
+		headerStyle.Printf("%s:", f.Name)
+		fmt.Printf(" %s\n", f.Value)
+	}
+	return nil
+}
+
+// structureRecord is the stable schema for `read --structure`: one MIME
+// part per record in document order.
+type structureRecord struct {
+	Depth       int    `json:"depth"`
+	ContentType string `json:"content_type"`
+	Charset     string `json:"charset"`
+	Encoding    string `json:"encoding"`
+	Size        int    `json:"size"`
+	Filename    string `json:"filename"`
+}
+
+func printStructure(printer *output.Printer, email *api.Email) error {
+	root := email.Structure()
+	if root == nil {
+		return fmt.Errorf("email #%d has no raw source", email.ID)
+	}
+	if !printer.Human() {
+		var records []structureRecord
+		var walk func(p *api.Part, depth int)
+		walk = func(p *api.Part, depth int) {
+			records = append(records, structureRecord{
+				Depth:       depth,
+				ContentType: p.MediaType,
+				Charset:     p.Params["charset"],
+				Encoding:    p.Encoding(),
+				Size:        p.Size,
+				Filename:    p.Filename(),
+			})
+			for _, child := range p.Children {
+				walk(child, depth+1)
+			}
+		}
+		walk(root, 0)
+		return printer.Print(records)
+	}
+	for _, line := range root.Tree() {
+		fmt.Println(line)
+	}
+	return nil
+}
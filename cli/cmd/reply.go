@@ -7,8 +7,10 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/contacts"
 	"github.com/spf13/cobra"
 )
 
@@ -92,6 +94,7 @@ var replyCmd = &cobra.Command{
 			return err
 		}
 		if resp.Success {
+			rememberContacts(func(b *contacts.Book) int { return b.Observe(req.To, time.Now()) })
 			printSuccess("Reply sent.")
 			return nil
 		}
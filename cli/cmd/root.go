@@ -16,12 +16,14 @@ import (
 	"github.com/misty-step/mercury/cli/internal/api"
 	"github.com/misty-step/mercury/cli/internal/auth"
 	"github.com/misty-step/mercury/cli/internal/config"
+	"github.com/misty-step/mercury/cli/internal/table"
 )
 
 var (
 	version     = "dev"
 	apiURL      string
 	profileName string
+	offline     bool
 
 	rootCmd = &cobra.Command{
 		Use:           "mercury",
@@ -35,13 +37,19 @@ var (
 	errorStyle   = color.New(color.FgRed)
 	dimStyle     = color.New(color.Faint)
 	warnStyle    = color.New(color.FgYellow)
+
+	unreadRowStyle        = color.New(color.Bold)
+	starredRowStyle       = color.New(color.FgYellow)
+	unreadStarredRowStyle = color.New(color.FgYellow, color.Bold)
 )
 
 // ErrUserCancelled indicates the user pressed Ctrl+D to cancel.
 var ErrUserCancelled = errors.New("cancelled")
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	flushCaches()
+	if err != nil {
 		printError(err)
 		os.Exit(1)
 	}
@@ -53,7 +61,18 @@ func init() {
 	rootCmd.SetVersionTemplate("mercury v{{.Version}}\n")
 	rootCmd.PersistentFlags().StringVar(&apiURL, "api-url", apiURL, "Server URL")
 	rootCmd.PersistentFlags().StringVarP(&profileName, "profile", "p", "", "Profile to use (from ~/.config/mercury/config.toml)")
-	color.NoColor = !isTTY(os.Stdout)
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "Serve reads from the local cache without contacting the server")
+	cobra.OnInitialize(trustAuthServers)
+	// color already honors NO_COLOR; also disable it when piped.
+	color.NoColor = color.NoColor || !isTTY(os.Stdout)
+}
+
+// trustAuthServers applies the configured authserv-ids. A config that
+// fails to load is reported by the command that needs it.
+func trustAuthServers() {
+	if cfg, err := config.Load(); err == nil && len(cfg.AuthServers) > 0 {
+		api.TrustedAuthServers = cfg.AuthServers
+	}
 }
 
 func getDefaultFrom() string {
@@ -64,15 +83,35 @@ func getDefaultFrom() string {
 }
 
 func authedClient() (*api.Client, error) {
+	return profileClient(profileName)
+}
+
+// profileClient opens a client as the named profile, or as the one
+// auth.GetSecret picks when name is empty.
+func profileClient(name string) (*api.Client, error) {
+	if offline {
+		client := api.NewClientNoAuth(apiURL)
+		client.Offline = true
+		store, err := openProfileCache(name)
+		if err != nil {
+			return nil, err
+		}
+		if store == nil {
+			return nil, fmt.Errorf("--offline requires the message cache, which is disabled in config")
+		}
+		client.Cache = store
+		return client, nil
+	}
+
 	var secret string
 	var err error
 
-	if profileName != "" {
+	if name != "" {
 		cfg, err := config.Load()
 		if err != nil {
 			return nil, fmt.Errorf("load config: %w", err)
 		}
-		profile, err := cfg.GetProfile(profileName)
+		profile, err := cfg.GetProfile(name)
 		if err != nil {
 			return nil, err
 		}
@@ -87,7 +126,33 @@ func authedClient() (*api.Client, error) {
 		}
 	}
 
-	return api.NewClientWithSecret(apiURL, secret), nil
+	client := api.NewClientWithSecret(apiURL, secret)
+	// The cache only speeds things up; a broken cache must not block requests.
+	if store, err := openProfileCache(name); err == nil && store != nil {
+		client.Cache = store
+	}
+	return client, nil
+}
+
+// activeProfileName names the profile requests are made as, following the
+// same precedence as auth.GetSecret. It returns "" when no profile applies.
+func activeProfileName(cfg *config.Config) string {
+	return resolveProfile(cfg, profileName)
+}
+
+// resolveProfile returns name, or when it is empty the profile
+// auth.GetSecret falls back to.
+func resolveProfile(cfg *config.Config, name string) string {
+	if name != "" {
+		return name
+	}
+	if strings.TrimSpace(os.Getenv("MERCURY_API_SECRET")) != "" {
+		return ""
+	}
+	if name := strings.TrimSpace(os.Getenv("MERCURY_PROFILE")); name != "" {
+		return name
+	}
+	return cfg.Default
 }
 
 func unauthedClient() *api.Client {
@@ -96,7 +161,26 @@ func unauthedClient() *api.Client {
 
 func printHeader(title string) {
 	headerStyle.Println(title)
-	fmt.Println(strings.Repeat("-", 78))
+	fmt.Println(strings.Repeat("-", terminalWidth()))
+}
+
+// terminalWidth is the display width available on stdout.
+func terminalWidth() int {
+	return table.TerminalWidth(os.Stdout)
+}
+
+// emailRowStyle highlights unread and starred emails in list output.
+func emailRowStyle(e *api.Email) *color.Color {
+	switch {
+	case !e.Read() && e.Starred():
+		return unreadStarredRowStyle
+	case !e.Read():
+		return unreadRowStyle
+	case e.Starred():
+		return starredRowStyle
+	default:
+		return nil
+	}
 }
 
 func printSuccess(format string, args ...interface{}) {
@@ -138,16 +222,6 @@ func parseOptionalInt(arg string, fallback int) (int, error) {
 	return value, nil
 }
 
-func truncate(s string, max int) string {
-	if max <= 0 {
-		return ""
-	}
-	if len(s) <= max {
-		return s
-	}
-	return s[:max]
-}
-
 // normalizeReplySubject ensures subject has exactly one "Re: " prefix.
 func normalizeReplySubject(subject string) string {
 	cleaned := subject
@@ -164,6 +238,15 @@ func normalizeReplySubject(subject string) string {
 	return "Re: " + cleaned
 }
 
+// formatDate shortens a server timestamp to minute precision for list views.
+func formatDate(value string) string {
+	t, err := api.ParseTimestamp(value)
+	if err != nil {
+		return value
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
 func normalizeSender(sender string) string {
 	sender = strings.TrimSpace(sender)
 	if sender == "" {
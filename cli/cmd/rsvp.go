@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/calendar"
+)
+
+var (
+	rsvpAs      string
+	rsvpComment string
+)
+
+var rsvpCmd = &cobra.Command{
+	Use:   "rsvp <id> accept|decline|tentative",
+	Short: "Answer a meeting invitation",
+	Long: `Reply to the calendar invitation in an email. The organizer receives an
+iCalendar METHOD:REPLY that their calendar applies to the event.
+
+The reply is sent from the address the invitation was delivered to; use --as
+when you were invited under another address.`,
+	Example: `  mercury rsvp 42 accept
+  mercury rsvp 42 decline --comment "Travelling that week"`,
+	Args:      cobra.ExactArgs(2),
+	ValidArgs: []string{"accept", "decline", "tentative"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseIDArg(args[0])
+		if err != nil {
+			return err
+		}
+		response, err := calendar.ParseResponse(args[1])
+		if err != nil {
+			return err
+		}
+
+		client, err := authedClient()
+		if err != nil {
+			return err
+		}
+
+		email, err := client.GetEmail(id)
+		if err != nil {
+			return err
+		}
+		req, err := rsvpRequest(email, response, rsvpAs, rsvpComment)
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.SendEmail(req)
+		if err != nil {
+			return err
+		}
+		if !resp.Success {
+			return fmt.Errorf("send failed: %s", resp.Error)
+		}
+		printSuccess("%s: sent to %s", req.Subject, req.To)
+		return nil
+	},
+}
+
+func init() {
+	rsvpCmd.Flags().StringVar(&rsvpAs, "as", "", "Attendee address to answer as (default: the invitation's recipient)")
+	rsvpCmd.Flags().StringVar(&rsvpComment, "comment", "", "Note for the organizer")
+	rootCmd.AddCommand(rsvpCmd)
+}
+
+// rsvpRequest builds the reply to the first event of the email's
+// invitation, with the REPLY calendar attached as text/calendar.
+func rsvpRequest(email *api.Email, response calendar.Response, as, comment string) (*api.SendRequest, error) {
+	cal := calendar.FromEmail(email)
+	if cal == nil {
+		return nil, fmt.Errorf("email #%d has no calendar invitation", email.ID)
+	}
+	if cal.Method != "" && cal.Method != "REQUEST" {
+		return nil, fmt.Errorf("email #%d is a calendar %s, not an invitation", email.ID, strings.ToLower(cal.Method))
+	}
+	event := cal.Events[0]
+	if event.Organizer.Email == "" {
+		return nil, fmt.Errorf("email #%d: invitation has no organizer to reply to", email.ID)
+	}
+
+	attendee := as
+	if attendee == "" {
+		attendee = email.Recipient
+	}
+	if addr, err := mail.ParseAddress(attendee); err == nil {
+		attendee = addr.Address
+	}
+
+	summary := event.Summary
+	if summary == "" {
+		summary = email.Subject
+	}
+	text := fmt.Sprintf("%s has %s the invitation to %s.\n", attendee, strings.ToLower(response.Verb()), summary)
+	if comment != "" {
+		text += "\n" + comment + "\n"
+	}
+	reply := calendar.Reply(event, attendee, response, comment)
+	return &api.SendRequest{
+		From:    attendee,
+		To:      event.Organizer.Email,
+		Subject: response.Verb() + ": " + summary,
+		Text:    text,
+		Attachments: []api.Attachment{{
+			Filename:    "invite.ics",
+			Content:     base64.StdEncoding.EncodeToString([]byte(reply)),
+			ContentType: "text/calendar; method=REPLY; charset=UTF-8",
+		}},
+	}, nil
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/search"
+)
+
+var (
+	searchLimit int
+	searchSync  int
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search cached mail",
+	Long: `Search headers and bodies of locally cached mail.
+
+Clauses are combined with AND:
+  invoice                 word in subject, sender, recipient or body
+  "exact phrase"          consecutive words anywhere
+  from:stripe             word or phrase in the sender (also to:, subject:, body:)
+  is:unread               also is:read, is:starred, is:unstarred
+  has:attachment
+  in:archive              folder
+  dmarc:fail              DMARC verdict (also pass, none, temperror, permerror)
+  after:2026-09-01        received on or after the date (UTC)
+  before:2026-10-01       received before the date (UTC)
+  -clause                 negate any clause
+
+Mail is indexed as it is listed and read. Use --sync to download recent
+messages first so their bodies are searchable.`,
+	Example: `  mercury search 'from:stripe subject:invoice is:unread after:2026-09-01'
+  mercury search --sync 200 'has:attachment "quarterly report"'`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		query, err := search.Parse(strings.Join(args, " "))
+		if err != nil {
+			return err
+		}
+
+		printer, err := newPrinter()
+		if err != nil {
+			return err
+		}
+
+		// Check the cache first, so a disabled cache fails before any
+		// download it could not keep.
+		store, err := requireCache()
+		if err != nil {
+			return err
+		}
+		if searchSync > 0 {
+			client, err := authedClient()
+			if err != nil {
+				return err
+			}
+			client.Cache = store
+			if printer.Human() {
+				printDim("Syncing %d recent emails...", searchSync)
+			}
+			if err := syncRecent(client, searchSync); err != nil {
+				return err
+			}
+		}
+
+		results := store.Search(query, searchLimit)
+		if !printer.Human() {
+			records := make([]emailRecord, len(results))
+			for i := range results {
+				records[i] = newEmailRecord(&results[i])
+			}
+			return printer.Print(records)
+		}
+
+		printHeader("Search: " + query.String())
+		if len(results) == 0 {
+			fmt.Println("  (no matches)")
+		} else if err := emailTable(results).Render(os.Stdout); err != nil {
+			return err
+		}
+		fmt.Printf("\n%d matches\n", len(results))
+		return nil
+	},
+}
+
+func init() {
+	searchCmd.Flags().IntVarP(&searchLimit, "limit", "n", 50, "Maximum results (0 for all)")
+	searchCmd.Flags().IntVar(&searchSync, "sync", 0, "Download the N most recent inbox emails before searching")
+	rootCmd.AddCommand(searchCmd)
+}
+
+// syncRecent lists the n newest inbox emails and fetches any bodies missing
+// from the cache so they are indexed.
+func syncRecent(client *api.Client, n int) error {
+	const pageSize = 100
+	for offset := 0; offset < n; offset += pageSize {
+		limit := pageSize
+		if n-offset < limit {
+			limit = n - offset
+		}
+		resp, err := client.ListEmails(limit, offset, "inbox")
+		if err != nil {
+			return err
+		}
+		for _, email := range resp.Emails {
+			if _, err := client.GetEmail(email.ID); err != nil {
+				return err
+			}
+		}
+		if len(resp.Emails) < limit {
+			break
+		}
+	}
+	return nil
+}
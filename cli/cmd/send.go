@@ -7,19 +7,48 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/contacts"
 	"github.com/spf13/cobra"
 )
 
+var (
+	sendSign    bool
+	sendEncrypt bool
+	sendSMIME   bool
+	sendDryRun  bool
+	sendTo      []string
+	sendCc      []string
+	sendBcc     []string
+)
+
 var sendCmd = &cobra.Command{
 	Use:   "send [from] [to] [subject]",
 	Short: "Send an email",
-	Args:  cobra.MaximumNArgs(3),
+	Long: `Send an email, reading the body from stdin.
+
+Recipients are comma-separated lists of addresses, contact names and groups
+such as @board (see "mercury contacts"). With --to, the arguments are [from]
+[subject]. When a name or group is expanded, the resulting recipients are
+shown first; interactive mode asks before sending to a group, and --dry-run
+shows them without sending.`,
+	Example: `  echo "Lunch?" | mercury send me@example.com alice@example.com "Lunch"
+  echo "Lunch?" | mercury send --to alice me@example.com "Lunch"
+  echo "Minutes" | mercury send --to @board --cc carol me@example.com "Minutes"`,
+	Args: cobra.MaximumNArgs(3),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if len(args) != 0 && len(args) != 3 {
+		if len(sendTo) > 0 {
+			if len(args) != 0 && len(args) != 2 {
+				return fmt.Errorf("with --to, provide [from] [subject] or no args for interactive mode")
+			}
+		} else if len(args) != 0 && len(args) != 3 {
 			return fmt.Errorf("provide [from] [to] [subject] or no args for interactive mode")
 		}
+		if sendSMIME && (sendSign || sendEncrypt) {
+			return fmt.Errorf("--smime-sign cannot be combined with OpenPGP --sign or --encrypt")
+		}
 
 		reader := bufio.NewReader(os.Stdin)
 		from := ""
@@ -43,13 +72,16 @@ var sendCmd = &cobra.Command{
 				return err
 			}
 			from = line
-			to, err = promptLine(reader, "To: ", "")
-			if errors.Is(err, ErrUserCancelled) {
-				fmt.Println("Cancelled.")
-				return nil
-			}
-			if err != nil {
-				return err
+			to = strings.Join(sendTo, ", ")
+			if to == "" {
+				to, err = promptLine(reader, "To: ", "")
+				if errors.Is(err, ErrUserCancelled) {
+					fmt.Println("Cancelled.")
+					return nil
+				}
+				if err != nil {
+					return err
+				}
 			}
 			subject, err = promptLine(reader, "Subject: ", "")
 			if errors.Is(err, ErrUserCancelled) {
@@ -67,8 +99,11 @@ var sendCmd = &cobra.Command{
 			body = string(bodyBytes)
 		} else {
 			from = strings.TrimSpace(args[0])
-			to = strings.TrimSpace(args[1])
-			subject = strings.TrimSpace(args[2])
+			if len(sendTo) > 0 {
+				to, subject = strings.Join(sendTo, ", "), strings.TrimSpace(args[1])
+			} else {
+				to, subject = strings.TrimSpace(args[1]), strings.TrimSpace(args[2])
+			}
 			bodyBytes, err := io.ReadAll(os.Stdin)
 			if err != nil {
 				return err
@@ -82,8 +117,28 @@ var sendCmd = &cobra.Command{
 		if strings.TrimSpace(to) == "" {
 			return fmt.Errorf("recipient required")
 		}
-		if !validEmail(to) {
-			return fmt.Errorf("invalid recipient email")
+		// Each person gets one copy, in the first of To, Cc and Bcc naming them.
+		var recipients [3][]contacts.Contact
+		expanded := false
+		seen := make(map[string]bool)
+		for i, list := range []string{to, strings.Join(sendCc, ", "), strings.Join(sendBcc, ", ")} {
+			people, grouped, err := expandRecipients(list)
+			if err != nil {
+				return err
+			}
+			for _, c := range people {
+				if !validEmail(c.Email) {
+					return fmt.Errorf("invalid recipient email: %s", c.Email)
+				}
+				if key := strings.ToLower(c.Email); !seen[key] {
+					seen[key] = true
+					recipients[i] = append(recipients[i], c)
+				}
+			}
+			expanded = expanded || grouped
+		}
+		if len(recipients[0]) == 0 {
+			return fmt.Errorf("recipient required")
 		}
 		if strings.TrimSpace(from) != "" && !validEmail(from) {
 			return fmt.Errorf("invalid sender email")
@@ -95,22 +150,67 @@ var sendCmd = &cobra.Command{
 			return fmt.Errorf("body required")
 		}
 
+		if expanded || sendDryRun {
+			printRecipients(recipients)
+		}
+		if sendDryRun {
+			printDim("Dry run: not sent.")
+			return nil
+		}
+		if expanded && len(args) == 0 {
+			total := len(recipients[0]) + len(recipients[1]) + len(recipients[2])
+			ok, err := confirmPrompt(fmt.Sprintf("Send to %d recipients? [y/N] ", total))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Println("Cancelled.")
+				return nil
+			}
+		}
+
 		client, err := authedClient()
 		if err != nil {
 			return err
 		}
 
-		printDim("Sending...")
-		resp, err := client.SendEmail(&api.SendRequest{
+		req := &api.SendRequest{
 			From:    from,
-			To:      to,
+			To:      addressList(recipients[0]),
+			Cc:      addressList(recipients[1]),
+			Bcc:     addressList(recipients[2]),
 			Subject: subject,
 			Text:    body,
-		})
+		}
+		if sendSMIME {
+			signer, err := smimeSigner()
+			if err != nil {
+				return err
+			}
+			if err := req.SignSMIME(signer); err != nil {
+				return err
+			}
+		}
+		if sendSign || sendEncrypt {
+			keyring, err := activeKeyring()
+			if err != nil {
+				return err
+			}
+			defer keyring.Close()
+			if err := keyring.Protect(req, sendSign, sendEncrypt); err != nil {
+				return err
+			}
+		}
+
+		printDim("Sending...")
+		resp, err := client.SendEmail(req)
 		if err != nil {
 			return err
 		}
 		if resp.Success {
+			rememberContacts(func(b *contacts.Book) int {
+				return b.Observe(req.To, time.Now()) + b.Observe(req.Cc, time.Now()) + b.Observe(req.Bcc, time.Now())
+			})
 			if resp.MessageID != "" {
 				printSuccess("Sent. Message ID: %s", resp.MessageID)
 			} else {
@@ -126,5 +226,27 @@ var sendCmd = &cobra.Command{
 }
 
 func init() {
+	sendCmd.Flags().BoolVar(&sendSign, "sign", false, "Sign with the profile's OpenPGP key")
+	sendCmd.Flags().BoolVar(&sendEncrypt, "encrypt", false, "Encrypt to the recipient's OpenPGP key (PGP/MIME)")
+	sendCmd.Flags().StringArrayVar(&sendTo, "to", nil, "Recipients: addresses, contact names or @groups (repeatable)")
+	sendCmd.Flags().StringArrayVar(&sendCc, "cc", nil, "Cc recipients, as for --to (repeatable)")
+	sendCmd.Flags().StringArrayVar(&sendBcc, "bcc", nil, "Bcc recipients, as for --to (repeatable)")
+	sendCmd.Flags().BoolVar(&sendDryRun, "dry-run", false, "Show the expanded recipients without sending")
+	sendCmd.Flags().BoolVar(&sendSMIME, "smime-sign", false, "Sign with the profile's S/MIME certificate")
 	rootCmd.AddCommand(sendCmd)
 }
+
+// printRecipients shows who a message goes to once names and groups are
+// expanded.
+func printRecipients(recipients [3][]contacts.Contact) {
+	for i, label := range []string{"To:  ", "Cc:  ", "Bcc: "} {
+		if len(recipients[i]) == 0 {
+			continue
+		}
+		names := make([]string, len(recipients[i]))
+		for j, c := range recipients[i] {
+			names[j] = c.String()
+		}
+		fmt.Printf("%s %s\n", label, strings.Join(names, ", "))
+	}
+}
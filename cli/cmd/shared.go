@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/misty-step/mercury/cli/internal/commands"
+)
+
+// sharedCommands are the commands the CLI has in common with the TUI's
+// ":" command line, so both validate and complete arguments alike.
+var sharedCommands = commands.Builtin(commands.Sources{})
+
+// sharedCommand returns the spec of a shared command.
+func sharedCommand(name string) commands.Command {
+	spec, err := sharedCommands.Lookup(name)
+	if err != nil {
+		panic(err)
+	}
+	return spec
+}
+
+// completeShared completes positional arguments from a shared spec,
+// falling back to file names for free-text arguments.
+func completeShared(spec commands.Command) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) < len(spec.Args) && spec.Args[len(args)].Values != nil {
+			return spec.CompleteArg(len(args), toComplete), cobra.ShellCompDirectiveNoFileComp
+		}
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+}
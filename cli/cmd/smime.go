@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/config"
+	"github.com/misty-step/mercury/cli/internal/smime"
+)
+
+// profileSMIME returns the S/MIME settings of the named profile and its
+// resolved name, resolving an empty name as activeProfileName does.
+func profileSMIME(name string) (config.SMIMEConfig, string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return config.SMIMEConfig{}, "", err
+	}
+	name = resolveProfile(cfg, name)
+	return cfg.Profiles[name].SMIME, name, nil
+}
+
+// smimeSigner loads the active profile's signing certificate and key.
+func smimeSigner() (*smime.Signer, error) {
+	settings, name, err := profileSMIME(profileName)
+	if err != nil {
+		return nil, err
+	}
+	if settings.Cert == "" || settings.Key == "" {
+		return nil, fmt.Errorf("profile %q has no S/MIME certificate; set cert and key under [profiles.%s.smime] in config.toml", name, name)
+	}
+	return smime.LoadSigner(config.ExpandHome(settings.Cert), config.ExpandHome(settings.Key))
+}
+
+// verifySMIME checks an S/MIME signed email against the named profile's
+// trust store. It returns nil for mail that is not S/MIME signed.
+func verifySMIME(profile string, email *api.Email) (*api.SMIMEResult, error) {
+	if !email.IsSMIME() {
+		return nil, nil
+	}
+	settings, _, err := profileSMIME(profile)
+	if err != nil {
+		return nil, err
+	}
+	roots, err := smime.LoadTrustStore(config.ExpandHome(settings.TrustStore))
+	if err != nil {
+		return nil, err
+	}
+	res, err := email.VerifySMIME(roots)
+	if errors.Is(err, api.ErrNotSMIME) {
+		return nil, nil
+	}
+	return res, err
+}
+
+// smimeBadge colors a verification: green when valid, red when invalid,
+// yellow when the signer is not trusted.
+func smimeBadge(res *api.SMIMEResult) string {
+	switch res.Verification.Status {
+	case smime.Valid:
+		return successStyle.Sprint(res.Verification.Summary())
+	case smime.Invalid:
+		return errorStyle.Sprint(res.Verification.Summary())
+	default:
+		return warnStyle.Sprint(res.Verification.Summary())
+	}
+}
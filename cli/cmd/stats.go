@@ -10,6 +10,11 @@ var statsCmd = &cobra.Command{
 	Use:   "stats",
 	Short: "Show mailbox statistics",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		printer, err := newPrinter()
+		if err != nil {
+			return err
+		}
+
 		client, err := authedClient()
 		if err != nil {
 			return err
@@ -20,6 +25,10 @@ var statsCmd = &cobra.Command{
 			return err
 		}
 
+		if !printer.Human() {
+			return printer.Print(stats)
+		}
+
 		printHeader("Mailbox Statistics")
 		fmt.Printf("Total:   %d\n", stats.Total)
 		fmt.Printf("Unread:  %d\n", stats.Unread)
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/thread"
+)
+
+var threadScan int
+
+var threadCmd = &cobra.Command{
+	Use:   "thread <id>",
+	Short: "Show the conversation an email belongs to",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseIDArg(args[0])
+		if err != nil {
+			return err
+		}
+
+		printer, err := newPrinter()
+		if err != nil {
+			return err
+		}
+
+		client, err := authedClient()
+		if err != nil {
+			return err
+		}
+
+		target, err := client.GetEmail(id)
+		if err != nil {
+			return err
+		}
+
+		candidates, err := threadCandidates(client, target, threadScan)
+		if err != nil {
+			return err
+		}
+		entries := thread.Flatten(thread.Find(thread.Build(candidates), id))
+
+		if !printer.Human() {
+			records := make([]threadRecord, len(entries))
+			for i, entry := range entries {
+				records[i] = threadRecord{
+					messageRecord: newMessageRecord(entry.Email),
+					Depth:         entry.Depth,
+				}
+			}
+			return printer.Print(records)
+		}
+
+		printHeader(fmt.Sprintf("Thread: %s (%d messages)", target.Subject, len(entries)))
+		for i, entry := range entries {
+			if i > 0 {
+				fmt.Println()
+			}
+			printThreadEntry(entry)
+		}
+		return nil
+	},
+}
+
+func init() {
+	threadCmd.Flags().IntVar(&threadScan, "scan", 200, "Number of recent emails per folder (inbox, archive, sent) to search for related messages")
+	rootCmd.AddCommand(threadCmd)
+}
+
+// threadRecord is the stable schema for `thread`: a read record plus depth.
+type threadRecord struct {
+	messageRecord
+	Depth int `json:"depth"`
+}
+
+func printThreadEntry(entry thread.Entry) {
+	indent := strings.Repeat("  ", entry.Depth)
+	email := entry.Email
+	style := dimStyle
+	if !email.Read() {
+		style = unreadRowStyle
+	}
+	style.Printf("%s[%d] %s — %s\n", indent, email.ID, email.Sender, formatDate(email.ReceivedAt))
+	fmt.Printf("%s%s\n", indent, email.Subject)
+	fmt.Printf("%s%s\n", indent, strings.Repeat("-", 40))
+
+	body := email.Body()
+	if body == "" {
+		body = "(no body)"
+	}
+	for _, line := range strings.Split(body, "\n") {
+		fmt.Printf("%s%s\n", indent, line)
+	}
+}
+
+// threadFolders are the folders searched for a thread's messages.
+var threadFolders = []string{"inbox", "archive"}
+
+// threadCandidates gathers the target plus recent inbox and archive emails
+// in its conversation, fetched in full so their threading headers
+// are available. An email belongs when it shares the base subject or is
+// linked by Message-ID, References or In-Reply-To to one already gathered.
+// Listings carry only Message-IDs, so references are read from emails the
+// cache already holds in full. Fetches go through the cache, so repeat
+// lookups are cheap.
+func threadCandidates(client *api.Client, target *api.Email, scan int) ([]api.Email, error) {
+	var summaries []api.Email
+	listed := map[int]bool{target.ID: true}
+	for _, folder := range threadFolders {
+		const pageSize = 100
+		for offset := 0; offset < scan; offset += pageSize {
+			limit := pageSize
+			if scan-offset < limit {
+				limit = scan - offset
+			}
+			resp, err := client.ListEmails(limit, offset, folder)
+			if err != nil {
+				return nil, err
+			}
+			for _, summary := range resp.Emails {
+				if !listed[summary.ID] {
+					listed[summary.ID] = true
+					summaries = append(summaries, summary)
+				}
+			}
+			if len(resp.Emails) < limit {
+				break
+			}
+		}
+	}
+
+	base := thread.BaseSubject(target.Subject)
+	candidates := []api.Email{*target}
+	linked := make(map[string]bool)
+	link := func(email *api.Email) {
+		for _, id := range thread.LinkIDs(email) {
+			linked[id] = true
+		}
+	}
+	isLinked := func(email *api.Email) bool {
+		for _, id := range thread.LinkIDs(email) {
+			if linked[id] {
+				return true
+			}
+		}
+		return false
+	}
+	link(target)
+
+	// Each email that joins may link further ones, so repeat until none do.
+	seen := make(map[int]bool)
+	for joined := true; joined; {
+		joined = false
+		for i := range summaries {
+			summary := &summaries[i]
+			if seen[summary.ID] {
+				continue
+			}
+			related := thread.BaseSubject(summary.Subject) == base || isLinked(summary)
+			var full *api.Email
+			if !related && client.Cache != nil {
+				if cached, ok := client.Cache.Email(summary.ID, 0); ok && isLinked(cached) {
+					full, related = cached, true
+				}
+			}
+			if !related {
+				continue
+			}
+			seen[summary.ID], joined = true, true
+			if full == nil {
+				var err error
+				if full, err = client.GetEmail(summary.ID); err != nil {
+					// Keep the summary; subject fallback can still place it.
+					full = summary
+				}
+			}
+			candidates = append(candidates, *full)
+			link(full)
+		}
+	}
+	return candidates, nil
+}
@@ -1,9 +1,19 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fatih/color"
+	"github.com/muesli/termenv"
 	"github.com/spf13/cobra"
 
+	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/config"
 	"github.com/misty-step/mercury/cli/internal/tui"
 )
 
@@ -11,18 +21,119 @@ var tuiCmd = &cobra.Command{
 	Use:   "tui",
 	Short: "Interactive email client",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
 		client, err := authedClient()
 		if err != nil {
 			return err
 		}
 
-		model := tui.NewModel(client)
+		opts, err := tuiOptions(cfg)
+		if err != nil {
+			return err
+		}
+		state, err := tui.LoadState(config.StatePath())
+		if err != nil {
+			// Only view settings are lost; start with the defaults.
+			warnStyle.Fprintf(color.Error, "Warning: %v\n", err)
+		}
+		opts.State = state
+		if os.Getenv("NO_COLOR") != "" {
+			// Drops colors, bold and underline alike; rows keep their
+			// text markers and the cursor row its border.
+			lipgloss.SetColorProfile(termenv.Ascii)
+		}
+		model := tui.NewModel(client, opts)
 		program := tea.NewProgram(model, tea.WithAltScreen())
 		_, err = program.Run()
 		return err
 	},
 }
 
+// tuiOptions builds TUI options from the [tui] config section
+func tuiOptions(cfg *config.Config) (tui.Options, error) {
+	refresh, err := cfg.TUI.Refresh()
+	if err != nil {
+		return tui.Options{}, fmt.Errorf("tui.refresh_interval: %w", err)
+	}
+	overrides := make(map[string][]string, len(cfg.TUI.Keys))
+	for action, keys := range cfg.TUI.Keys {
+		overrides[action] = keys
+	}
+	keys, err := tui.NewKeyMap(overrides)
+	if err != nil {
+		return tui.Options{}, fmt.Errorf("tui.keys: %w", err)
+	}
+	theme, err := tuiTheme(cfg.TUI)
+	if err != nil {
+		return tui.Options{}, err
+	}
+	profiles := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		profiles = append(profiles, name)
+	}
+	sort.Strings(profiles)
+	return tui.Options{
+		RefreshInterval: refresh,
+		Notify:          cfg.TUI.Notify,
+		Keys:            &keys,
+		Theme:           &theme,
+		Profiles:        profiles,
+		ReaderWidth:     cfg.TUI.ReaderWrap(),
+		Opener:          strings.Fields(cfg.TUI.Opener),
+		Profile:         activeProfileName(cfg),
+		OpenPGP:         openPGP,
+		VerifySMIME:     verifySMIME,
+		SwitchProfile:   switchProfile,
+		Contacts:        tuiContacts(cfg),
+	}, nil
+}
+
+// switchProfile opens a client as another profile, for the TUI's :profile.
+// It runs off the UI thread, so it leaves the --profile flag alone; the
+// TUI passes the new name to its other callbacks.
+func switchProfile(name string) (*api.Client, error) {
+	return profileClient(name)
+}
+
+// tuiTheme resolves the configured theme, built-in or user-defined
+func tuiTheme(cfg config.TUIConfig) (tui.Theme, error) {
+	name := cfg.Theme
+	if name == "" {
+		name = tui.DefaultTheme
+	}
+	custom, ok := cfg.Themes[name]
+	if !ok {
+		theme, err := tui.LookupTheme(name)
+		if err != nil {
+			return tui.Theme{}, fmt.Errorf("tui.theme: %w", err)
+		}
+		return theme, nil
+	}
+
+	base := custom["base"]
+	if base == "" {
+		base = tui.DefaultTheme
+	}
+	theme, err := tui.LookupTheme(base)
+	if err != nil {
+		return tui.Theme{}, fmt.Errorf("tui.themes.%s.base: %w", name, err)
+	}
+	colors := make(map[string]string, len(custom))
+	for role, value := range custom {
+		if role != "base" {
+			colors[role] = value
+		}
+	}
+	theme, err = theme.With(colors)
+	if err != nil {
+		return tui.Theme{}, fmt.Errorf("tui.themes.%s: %w", name, err)
+	}
+	return theme, nil
+}
+
 func init() {
 	rootCmd.AddCommand(tuiCmd)
 }
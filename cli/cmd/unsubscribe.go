@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/unsubscribe"
+)
+
+var (
+	unsubscribeYes     bool
+	unsubscribeArchive bool
+	unsubscribeScan    int
+)
+
+var unsubscribeCmd = &cobra.Command{
+	Use:   "unsubscribe <id>",
+	Short: "Leave the mailing list an email came from",
+	Long: `Unsubscribe using the email's List-Unsubscribe header. One-click
+unsubscribe (RFC 8058) is used when the list supports it; otherwise the
+unsubscribe email is sent from the address the list mails. Lists that only
+offer a web page are reported with its address.
+
+With --archive, earlier inbox mail from the same sender is archived too.`,
+	Example: `  mercury unsubscribe 42
+  mercury unsubscribe 42 --archive --yes`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseIDArg(args[0])
+		if err != nil {
+			return err
+		}
+
+		client, err := authedClient()
+		if err != nil {
+			return err
+		}
+
+		email, err := client.GetEmail(id)
+		if err != nil {
+			return err
+		}
+		action, err := unsubscribe.Plan(email)
+		if err != nil {
+			return fmt.Errorf("email #%d: %w", id, err)
+		}
+		if action.Method == unsubscribe.Web {
+			return fmt.Errorf("%s only offers a web page to unsubscribe: %s", email.Sender, action.URL)
+		}
+
+		if !unsubscribeYes {
+			prompt := fmt.Sprintf("Unsubscribe from %s by %s? [y/N] ", email.Sender, action)
+			confirm, err := confirmPrompt(prompt)
+			if err != nil {
+				return err
+			}
+			if !confirm {
+				fmt.Println("Cancelled.")
+				return nil
+			}
+		}
+
+		if err := unsubscribe.Do(context.Background(), client, email, action); err != nil {
+			return err
+		}
+		printSuccess("Unsubscribed from %s", email.Sender)
+
+		if !unsubscribeArchive {
+			return nil
+		}
+		emails, err := listMail(client, email, unsubscribeScan)
+		if err != nil {
+			return err
+		}
+		for _, e := range emails {
+			if err := client.ArchiveEmail(e.ID); err != nil {
+				return fmt.Errorf("archive email #%d: %w", e.ID, err)
+			}
+		}
+		printSuccess("Archived %d emails from %s", len(emails), email.Sender)
+		return nil
+	},
+}
+
+func init() {
+	unsubscribeCmd.Flags().BoolVarP(&unsubscribeYes, "yes", "y", false, "Skip confirmation")
+	unsubscribeCmd.Flags().BoolVar(&unsubscribeArchive, "archive", false, "Archive the sender's mail in the inbox")
+	unsubscribeCmd.Flags().IntVar(&unsubscribeScan, "scan", 500, "Number of recent inbox emails to search with --archive")
+	rootCmd.AddCommand(unsubscribeCmd)
+}
+
+// listMail returns the recent inbox emails from the same list as email,
+// the email itself included when it is in the inbox.
+func listMail(client *api.Client, email *api.Email, scan int) ([]api.Email, error) {
+	var matches []api.Email
+	const pageSize = 100
+	for offset := 0; offset < scan; offset += pageSize {
+		limit := pageSize
+		if scan-offset < limit {
+			limit = scan - offset
+		}
+		resp, err := client.ListEmails(limit, offset, "inbox")
+		if err != nil {
+			return nil, err
+		}
+		for i := range resp.Emails {
+			if unsubscribe.SameList(email, &resp.Emails[i]) {
+				matches = append(matches, resp.Emails[i])
+			}
+		}
+		if len(resp.Emails) < limit {
+			break
+		}
+	}
+	return matches, nil
+}
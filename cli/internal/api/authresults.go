@@ -0,0 +1,166 @@
+package api
+
+import (
+	"strings"
+)
+
+// AuthResult is an RFC 8601 result such as "pass", "fail" or "none". The
+// zero value means the check was not reported.
+type AuthResult string
+
+const (
+	AuthPass      AuthResult = "pass"
+	AuthFail      AuthResult = "fail"
+	AuthSoftFail  AuthResult = "softfail"
+	AuthNeutral   AuthResult = "neutral"
+	AuthNone      AuthResult = "none"
+	AuthTempError AuthResult = "temperror"
+	AuthPermError AuthResult = "permerror"
+)
+
+// Failed reports whether the check ran and rejected the message.
+func (r AuthResult) Failed() bool {
+	return r == AuthFail || r == AuthSoftFail || r == AuthPermError
+}
+
+// AuthCheck is one method's result and the domain it was evaluated for.
+type AuthCheck struct {
+	Result AuthResult `json:"result,omitempty"`
+	Domain string     `json:"domain,omitempty"`
+}
+
+// AuthVerdict is the SPF, DKIM and DMARC outcome recorded by the receiving
+// server in an Authentication-Results header.
+type AuthVerdict struct {
+	// Server is the authserv-id of the header, empty when there was none.
+	Server string    `json:"server,omitempty"`
+	SPF    AuthCheck `json:"spf"`
+	DKIM   AuthCheck `json:"dkim"`
+	DMARC  AuthCheck `json:"dmarc"`
+}
+
+// Known reports whether the email carried an Authentication-Results header.
+func (v AuthVerdict) Known() bool {
+	return v.Server != ""
+}
+
+// DefaultAuthServers are the authserv-ids of the relay that receives
+// Mercury mail, Cloudflare Email Routing.
+var DefaultAuthServers = []string{"mx.cloudflare.net"}
+
+// TrustedAuthServers are the authserv-ids whose Authentication-Results
+// headers are believed. Any other header may have been written by the
+// sender, so its verdict is ignored.
+var TrustedAuthServers = DefaultAuthServers
+
+// TrustsAuthServer reports whether id is one of TrustedAuthServers.
+func TrustsAuthServer(id string) bool {
+	for _, trusted := range TrustedAuthServers {
+		if strings.EqualFold(id, trusted) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authentication returns the verdict of the topmost Authentication-Results
+// header from a trusted server, the one added when the relay received the
+// message. Headers from other servers were added by earlier hops, or
+// forged by the sender, and leave the verdict unknown.
+func (e *Email) Authentication() AuthVerdict {
+	for _, f := range e.HeaderFields() {
+		if !strings.EqualFold(f.Name, "Authentication-Results") {
+			continue
+		}
+		if v := ParseAuthenticationResults(f.Value); TrustsAuthServer(v.Server) {
+			return v
+		}
+	}
+	return AuthVerdict{}
+}
+
+// ParseAuthenticationResults parses an RFC 8601 Authentication-Results
+// value. Of several DKIM signatures, a passing one wins, then a failing one.
+func ParseAuthenticationResults(value string) AuthVerdict {
+	segments := strings.Split(stripComments(value), ";")
+	server := strings.Fields(segments[0])
+	if len(server) == 0 {
+		return AuthVerdict{}
+	}
+	v := AuthVerdict{Server: server[0]}
+	for _, segment := range segments[1:] {
+		fields := strings.Fields(segment)
+		if len(fields) == 0 {
+			continue
+		}
+		method, result, ok := strings.Cut(fields[0], "=")
+		if !ok {
+			continue
+		}
+		method, _, _ = strings.Cut(strings.ToLower(method), "/")
+		props := make(map[string]string)
+		for _, prop := range fields[1:] {
+			if k, val, ok := strings.Cut(prop, "="); ok {
+				props[strings.ToLower(k)] = strings.Trim(val, `"`)
+			}
+		}
+		check := AuthCheck{Result: AuthResult(strings.ToLower(result))}
+		switch method {
+		case "spf":
+			check.Domain = props["smtp.mailfrom"]
+			if _, domain, ok := strings.Cut(check.Domain, "@"); ok {
+				check.Domain = domain
+			}
+			if check.Domain == "" {
+				check.Domain = props["smtp.helo"]
+			}
+			v.SPF = check
+		case "dkim":
+			check.Domain = props["header.d"]
+			if check.Domain == "" {
+				_, check.Domain, _ = strings.Cut(props["header.i"], "@")
+			}
+			switch {
+			case v.DKIM.Result == "", check.Result == AuthPass && v.DKIM.Result != AuthPass:
+				v.DKIM = check
+			case check.Result.Failed() && v.DKIM.Result != AuthPass && !v.DKIM.Result.Failed():
+				v.DKIM = check
+			}
+		case "dmarc":
+			check.Domain = props["header.from"]
+			v.DMARC = check
+		}
+	}
+	return v
+}
+
+// stripComments removes parenthesized, possibly nested, comments outside
+// quoted strings.
+func stripComments(s string) string {
+	var sb strings.Builder
+	depth, quoted := 0, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && (quoted || depth > 0):
+			if depth == 0 {
+				sb.WriteByte(s[i+1])
+			}
+			i++
+			continue
+		case c == '"' && depth == 0:
+			quoted = !quoted
+		case c == '(' && !quoted:
+			depth++
+			continue
+		case c == ')' && !quoted && depth > 0:
+			depth--
+			sb.WriteByte(' ')
+			continue
+		}
+		if depth == 0 {
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
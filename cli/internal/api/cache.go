@@ -0,0 +1,36 @@
+package api
+
+import (
+	"errors"
+	"time"
+)
+
+// CachedFlagsMaxAge is how long flags a cached email was stored with are
+// trusted online. An older entry is fetched again, so a message read or
+// starred elsewhere is not shown with its old flags; offline, any age is
+// served.
+const CachedFlagsMaxAge = 10 * time.Minute
+
+// ErrOffline is returned for requests that need the network while the
+// client is in offline mode.
+var ErrOffline = errors.New("offline: this action requires a connection")
+
+// Cache persists fetched emails so reads can skip the network. Message
+// bodies never change once stored; flags are refreshed from list results
+// and successful updates. Implementations are best effort: a failed write
+// must never fail the request that triggered it.
+type Cache interface {
+	// StoreList records one page of a folder listing.
+	StoreList(folder string, limit, offset int, resp *EmailListResponse)
+	// StoreEmail records a fully fetched email, including its raw body.
+	StoreEmail(email *Email)
+	// Email returns a cached email with its raw body, if present. With
+	// maxAge > 0 it is missing when its flags are older than that.
+	Email(id int, maxAge time.Duration) (*Email, bool)
+	// List returns a page of cached emails in a folder, newest first.
+	List(folder string, limit, offset int) *EmailListResponse
+	// Update applies a metadata change the server accepted.
+	Update(id int, updates EmailUpdate)
+	// Remove drops an email the server deleted.
+	Remove(id int)
+}
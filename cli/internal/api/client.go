@@ -19,10 +19,19 @@ import (
 const defaultBaseURL = "https://mail-api.mistystep.io"
 const maxErrorBodySize = 1 << 20 // 1MB
 
+// ConcurrentRequests bounds the requests a client makes at once for work
+// on many emails, such as bulk actions. A Client is safe for concurrent use.
+const ConcurrentRequests = 4
+
 type Client struct {
 	BaseURL string
 	Secret  string
 	HTTP    *http.Client
+	// Cache, when set, is populated by list and get calls and serves
+	// message bodies without refetching them.
+	Cache Cache
+	// Offline serves reads from Cache and rejects everything else.
+	Offline bool
 }
 
 func BaseURLFromEnv() string {
@@ -74,6 +83,9 @@ func (c *Client) Do(method, path string, body interface{}) (*http.Response, erro
 }
 
 func (c *Client) DoContext(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	if c.Offline {
+		return nil, ErrOffline
+	}
 	if !strings.HasPrefix(path, "/") {
 		path = "/" + path
 	}
@@ -116,6 +128,10 @@ func (c *Client) DoContext(ctx context.Context, method, path string, body interf
 }
 
 func (c *Client) ListEmails(limit, offset int, folder string) (*EmailListResponse, error) {
+	if c.Offline {
+		return c.CachedEmails(limit, offset, folder), nil
+	}
+
 	values := url.Values{}
 	values.Set("limit", strconv.Itoa(limit))
 	values.Set("offset", strconv.Itoa(offset))
@@ -128,15 +144,67 @@ func (c *Client) ListEmails(limit, offset int, folder string) (*EmailListRespons
 	if err := c.getJSON(path, &resp); err != nil {
 		return nil, err
 	}
+	if c.Cache != nil {
+		c.Cache.StoreList(folder, limit, offset, &resp)
+	}
 	return &resp, nil
 }
 
+// ListEmailsSince lists a page of emails received at or after since, a
+// received_at value as returned by the server. Mail from that same second
+// is included, so callers de-duplicate by ID. Results bypass the cache: a
+// partial listing would look like deletions to it.
+func (c *Client) ListEmailsSince(since string, limit, offset int, folder string) (*EmailListResponse, error) {
+	if c.Offline {
+		return nil, ErrOffline
+	}
+
+	values := url.Values{}
+	values.Set("limit", strconv.Itoa(limit))
+	values.Set("offset", strconv.Itoa(offset))
+	values.Set("since", since)
+	if strings.TrimSpace(folder) != "" {
+		values.Set("folder", folder)
+	}
+
+	var resp EmailListResponse
+	if err := c.getJSON("/emails?"+values.Encode(), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CachedEmails returns a page of emails from the local cache without
+// touching the network. It returns an empty page when no cache is set.
+func (c *Client) CachedEmails(limit, offset int, folder string) *EmailListResponse {
+	if c.Cache == nil {
+		return &EmailListResponse{Emails: []Email{}, Limit: limit, Offset: offset}
+	}
+	return c.Cache.List(folder, limit, offset)
+}
+
 func (c *Client) GetEmail(id int) (*Email, error) {
+	if c.Cache != nil {
+		maxAge := CachedFlagsMaxAge
+		if c.Offline {
+			maxAge = 0
+		}
+		if email, ok := c.Cache.Email(id, maxAge); ok {
+			return email, nil
+		}
+	}
+	if c.Offline {
+		return nil, fmt.Errorf("email #%d is not cached: %w", id, ErrOffline)
+	}
+
 	path := fmt.Sprintf("/emails/%d", id)
 	var resp EmailResponse
 	if err := c.getJSON(path, &resp); err != nil {
 		return nil, err
 	}
+	if c.Cache != nil {
+		c.Cache.StoreEmail(&resp.Email)
+	}
 	return &resp.Email, nil
 }
 
@@ -170,6 +238,9 @@ func (c *Client) DeleteEmail(id int, permanent bool) error {
 	}
 	defer resp.Body.Close()
 	_, _ = io.Copy(io.Discard, resp.Body)
+	if c.Cache != nil {
+		c.Cache.Remove(id)
+	}
 	return nil
 }
 
@@ -182,6 +253,9 @@ func (c *Client) UpdateEmail(id int, updates EmailUpdate) error {
 	}
 	defer resp.Body.Close()
 	_, _ = io.Copy(io.Discard, resp.Body)
+	if c.Cache != nil {
+		c.Cache.Update(id, updates)
+	}
 	return nil
 }
 
@@ -191,6 +265,21 @@ func (c *Client) MarkAsRead(id int) error {
 	return c.UpdateEmail(id, EmailUpdate{IsRead: &read})
 }
 
+// StarEmail stars or unstars an email.
+func (c *Client) StarEmail(id int, starred bool) error {
+	return c.UpdateEmail(id, EmailUpdate{IsStarred: &starred})
+}
+
+// MoveEmail moves an email to another folder.
+func (c *Client) MoveEmail(id int, folder string) error {
+	return c.UpdateEmail(id, EmailUpdate{Folder: &folder})
+}
+
+// ArchiveEmail moves an email to the archive folder.
+func (c *Client) ArchiveEmail(id int) error {
+	return c.MoveEmail(id, "archive")
+}
+
 func (c *Client) GetStats() (*Stats, error) {
 	var resp StatsResponse
 	if err := c.getJSON("/stats", &resp); err != nil {
@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -156,3 +157,104 @@ func TestClientMarkAsRead(t *testing.T) {
 		t.Error("server was not called")
 	}
 }
+
+// memCache is a minimal in-memory Cache for client tests.
+type memCache struct {
+	emails map[int]Email
+	listed []Email
+	maxAge time.Duration // of the last Email lookup
+}
+
+func (c *memCache) StoreList(folder string, limit, offset int, resp *EmailListResponse) {
+	c.listed = resp.Emails
+}
+func (c *memCache) StoreEmail(email *Email) { c.emails[email.ID] = *email }
+func (c *memCache) Email(id int, maxAge time.Duration) (*Email, bool) {
+	c.maxAge = maxAge
+	e, ok := c.emails[id]
+	return &e, ok
+}
+func (c *memCache) List(folder string, limit, offset int) *EmailListResponse {
+	return &EmailListResponse{Emails: c.listed, Total: len(c.listed)}
+}
+func (c *memCache) Update(id int, updates EmailUpdate) {}
+func (c *memCache) Remove(id int)                      { delete(c.emails, id) }
+
+func TestClientGetEmailUsesCache(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_ = json.NewEncoder(w).Encode(EmailResponse{Email: Email{ID: 9, RawEmail: "raw"}})
+	}))
+	defer server.Close()
+
+	client := NewClientNoAuth(server.URL)
+	cache := &memCache{emails: map[int]Email{}}
+	client.Cache = cache
+
+	for i := 0; i < 2; i++ {
+		email, err := client.GetEmail(9)
+		if err != nil {
+			t.Fatalf("GetEmail() error = %v", err)
+		}
+		if email.RawEmail != "raw" {
+			t.Errorf("RawEmail = %q, want raw", email.RawEmail)
+		}
+	}
+	if cache.maxAge != CachedFlagsMaxAge {
+		t.Errorf("online lookups accept flags %v old, want %v", cache.maxAge, CachedFlagsMaxAge)
+	}
+	if calls != 1 {
+		t.Errorf("server called %d times, want 1", calls)
+	}
+}
+
+func TestClientOffline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("offline client contacted server: %s", r.URL.Path)
+	}))
+	defer server.Close()
+
+	client := NewClientNoAuth(server.URL)
+	client.Offline = true
+	cache := &memCache{
+		emails: map[int]Email{1: {ID: 1, RawEmail: "cached"}},
+		listed: []Email{{ID: 1}},
+	}
+	client.Cache = cache
+
+	resp, err := client.ListEmails(10, 0, "inbox")
+	if err != nil || len(resp.Emails) != 1 {
+		t.Fatalf("ListEmails() = %+v, %v", resp, err)
+	}
+	if _, err := client.GetEmail(1); err != nil || cache.maxAge != 0 {
+		t.Errorf("GetEmail(cached) error = %v, max age %v", err, cache.maxAge)
+	}
+	if _, err := client.GetEmail(2); !errors.Is(err, ErrOffline) {
+		t.Errorf("GetEmail(uncached) error = %v, want ErrOffline", err)
+	}
+	if err := client.MarkAsRead(1); !errors.Is(err, ErrOffline) {
+		t.Errorf("MarkAsRead() error = %v, want ErrOffline", err)
+	}
+}
+
+func TestClientListEmailsSince(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query(); got.Get("since") != "2026-10-01 12:00:00" || got.Get("offset") != "100" {
+			t.Errorf("query = %v", got)
+		}
+		_ = json.NewEncoder(w).Encode(EmailListResponse{Emails: []Email{{ID: 7}}, Total: 1})
+	}))
+	defer server.Close()
+
+	client := NewClientNoAuth(server.URL)
+	cache := &memCache{}
+	client.Cache = cache
+	resp, err := client.ListEmailsSince("2026-10-01 12:00:00", 50, 100, "inbox")
+	if err != nil || len(resp.Emails) != 1 {
+		t.Fatalf("ListEmailsSince() = %+v, %v", resp, err)
+	}
+	if cache.listed != nil {
+		t.Error("partial listing should not be stored in the cache")
+	}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Link is a URL found in an email body.
+type Link struct {
+	URL string
+	// Text is the anchor text of an HTML link, empty for plain text URLs.
+	Text string
+	// Lookalike is set when Text names a different domain than URL, as in
+	// <a href="https://evil.example">https://bank.example</a>.
+	Lookalike bool
+}
+
+var (
+	plainURLPattern = regexp.MustCompile(`https?://[^\s<>"']+`)
+	anchorPattern   = regexp.MustCompile(`(?is)<a\s[^>]*?href\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))[^>]*>(.*?)</a\s*>`)
+	tagPattern      = regexp.MustCompile(`(?s)<[^>]*>`)
+	domainPattern   = regexp.MustCompile(`(?i)^(?:https?://)?((?:[a-z0-9-]+\.)+[a-z]{2,})(?:[/:?#]\S*)?$`)
+)
+
+// Links returns the URLs in the plain text and HTML parts, in order of
+// first appearance, each listed once.
+func (e *Email) Links() []Link {
+	var links []Link
+	index := make(map[string]int)
+	add := func(link Link) {
+		if i, ok := index[link.URL]; ok {
+			if links[i].Text == "" || link.Lookalike {
+				links[i].Text, links[i].Lookalike = link.Text, link.Lookalike
+			}
+			return
+		}
+		index[link.URL] = len(links)
+		links = append(links, link)
+	}
+
+	for _, part := range e.Parts() {
+		switch part.MediaType {
+		case "text/plain":
+			for _, u := range plainURLPattern.FindAllString(string(part.Body), -1) {
+				add(Link{URL: trimURL(u)})
+			}
+		case "text/html":
+			for _, link := range htmlLinks(string(part.Body)) {
+				add(link)
+			}
+		}
+	}
+	return links
+}
+
+// htmlLinks extracts the http, https and mailto anchors of an HTML body.
+func htmlLinks(body string) []Link {
+	var links []Link
+	for _, m := range anchorPattern.FindAllStringSubmatch(body, -1) {
+		href := strings.TrimSpace(html.UnescapeString(m[1] + m[2] + m[3]))
+		lower := strings.ToLower(href)
+		if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") && !strings.HasPrefix(lower, "mailto:") {
+			continue
+		}
+		text := strings.Join(strings.Fields(html.UnescapeString(tagPattern.ReplaceAllString(m[4], " "))), " ")
+		links = append(links, Link{URL: href, Text: text, Lookalike: isLookalike(href, text)})
+	}
+	return links
+}
+
+// isLookalike reports whether text reads as a domain or URL that href
+// does not lead to. Subdomains of the shown domain are fine.
+func isLookalike(href, text string) bool {
+	m := domainPattern.FindStringSubmatch(strings.TrimSpace(text))
+	if m == nil {
+		return false
+	}
+	shown := strings.TrimPrefix(strings.ToLower(m[1]), "www.")
+	u, err := url.Parse(href)
+	if err != nil || u.Hostname() == "" {
+		return true
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	return host != shown && !strings.HasSuffix(host, "."+shown)
+}
+
+// trimURL drops punctuation that ends a sentence rather than the URL.
+func trimURL(u string) string {
+	for len(u) > 0 {
+		last := u[len(u)-1]
+		if strings.IndexByte(".,;:!?", last) >= 0 || (last == ')' && strings.Count(u, "(") < strings.Count(u, ")")) {
+			u = u[:len(u)-1]
+			continue
+		}
+		return u
+	}
+	return u
+}
+
+// Host returns the host the link leads to, or the address of a mailto link.
+func (l Link) Host() string {
+	u, err := url.Parse(l.URL)
+	if err != nil {
+		return ""
+	}
+	if u.Scheme == "mailto" {
+		return u.Opaque
+	}
+	return u.Hostname()
+}
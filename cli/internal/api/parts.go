@@ -0,0 +1,264 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"sort"
+	"strings"
+)
+
+// Part is a MIME part of an email. Multipart parts hold their children;
+// the others hold their body with the transfer encoding removed.
+type Part struct {
+	MediaType string
+	Params    map[string]string
+	Header    textproto.MIMEHeader
+	Body      []byte
+	// Size is the length of the part's body as sent, before decoding.
+	Size     int
+	Children []*Part
+}
+
+// Encoding returns the part's Content-Transfer-Encoding, "7bit" if unset.
+func (p *Part) Encoding() string {
+	if enc := strings.ToLower(strings.TrimSpace(p.Header.Get("Content-Transfer-Encoding"))); enc != "" {
+		return enc
+	}
+	return "7bit"
+}
+
+// Filename returns the attachment filename, if the part names one.
+func (p *Part) Filename() string {
+	if _, params, err := mime.ParseMediaType(p.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		return params["filename"]
+	}
+	return p.Params["name"]
+}
+
+// Tree renders the part and its descendants one per line, drawn as a tree:
+// media type, charset, transfer encoding, size and filename.
+func (p *Part) Tree() []string {
+	var lines []string
+	var walk func(p *Part, prefix, branch string)
+	walk = func(p *Part, prefix, branch string) {
+		desc := p.MediaType
+		if cs := p.Params["charset"]; cs != "" {
+			desc += "; charset=" + cs
+		}
+		if len(p.Children) == 0 {
+			desc += fmt.Sprintf("  %s  %d B", p.Encoding(), p.Size)
+		}
+		if name := p.Filename(); name != "" {
+			desc += fmt.Sprintf("  %q", name)
+		}
+		lines = append(lines, prefix+branch+desc)
+		switch branch {
+		case "├── ":
+			prefix += "│   "
+		case "└── ":
+			prefix += "    "
+		}
+		for i, child := range p.Children {
+			next := "├── "
+			if i == len(p.Children)-1 {
+				next = "└── "
+			}
+			walk(child, prefix, next)
+		}
+	}
+	walk(p, "", "")
+	return lines
+}
+
+// Structure parses the raw email into its MIME tree, or returns nil when
+// the raw email is unavailable or malformed.
+func (e *Email) Structure() *Part {
+	if strings.TrimSpace(e.RawEmail) == "" {
+		return nil
+	}
+	r := textproto.NewReader(bufio.NewReader(strings.NewReader(e.RawEmail)))
+	header, err := r.ReadMIMEHeader()
+	if err != nil && len(header) == 0 {
+		return nil
+	}
+	return parsePart(header, r.R)
+}
+
+// Parts returns the leaf parts of the raw email in document order. A
+// message that is not multipart is a single part.
+func (e *Email) Parts() []Part {
+	var parts []Part
+	var walk func(p *Part)
+	walk = func(p *Part) {
+		if len(p.Children) == 0 && !strings.HasPrefix(p.MediaType, "multipart/") {
+			parts = append(parts, *p)
+		}
+		for _, child := range p.Children {
+			walk(child)
+		}
+	}
+	if root := e.Structure(); root != nil {
+		walk(root)
+	}
+	return parts
+}
+
+func parsePart(header textproto.MIMEHeader, body io.Reader) *Part {
+	raw, _ := io.ReadAll(body)
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	p := &Part{MediaType: mediaType, Params: params, Header: header, Size: len(raw)}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		p.Body, _ = io.ReadAll(decodeTransfer(header.Get("Content-Transfer-Encoding"), bytes.NewReader(raw)))
+		return p
+	}
+	if params["boundary"] == "" {
+		return p
+	}
+	mr := multipart.NewReader(bytes.NewReader(raw), params["boundary"])
+	for {
+		part, err := mr.NextRawPart()
+		if err != nil {
+			return p
+		}
+		p.Children = append(p.Children, parsePart(part.Header, part))
+		part.Close()
+	}
+}
+
+// decodeTransfer undoes a Content-Transfer-Encoding.
+func decodeTransfer(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		data, _ := io.ReadAll(r)
+		clean := bytes.Map(func(r rune) rune {
+			if r == '\r' || r == '\n' || r == ' ' || r == '\t' {
+				return -1
+			}
+			return r
+		}, data)
+		return base64.NewDecoder(base64.StdEncoding, bytes.NewReader(clean))
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	default:
+		return r
+	}
+}
+
+// HeaderField is one header line of an email.
+type HeaderField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HeaderFields returns every header in the order it appears in the raw
+// email, unfolded but not decoded. Without a raw email it falls back to
+// headers_json, sorted by name.
+func (e *Email) HeaderFields() []HeaderField {
+	if strings.TrimSpace(e.RawEmail) != "" {
+		block := e.RawEmail
+		if i := strings.Index(block, "\r\n\r\n"); i >= 0 {
+			block = block[:i]
+		} else if i := strings.Index(block, "\n\n"); i >= 0 {
+			block = block[:i]
+		}
+		var fields []HeaderField
+		for _, line := range strings.Split(strings.ReplaceAll(block, "\r\n", "\n"), "\n") {
+			if line == "" {
+				continue
+			}
+			if (line[0] == ' ' || line[0] == '\t') && len(fields) > 0 {
+				fields[len(fields)-1].Value += " " + strings.TrimSpace(line)
+				continue
+			}
+			name, value, ok := strings.Cut(line, ":")
+			if !ok {
+				continue
+			}
+			fields = append(fields, HeaderField{Name: name, Value: strings.TrimSpace(value)})
+		}
+		return fields
+	}
+
+	var headers map[string]string
+	if err := json.Unmarshal([]byte(e.HeadersJSON), &headers); err != nil {
+		return nil
+	}
+	fields := make([]HeaderField, 0, len(headers))
+	for name, value := range headers {
+		fields = append(fields, HeaderField{Name: name, Value: value})
+	}
+	sort.Slice(fields, func(i, j int) bool { return strings.ToLower(fields[i].Name) < strings.ToLower(fields[j].Name) })
+	return fields
+}
+
+// Canonical converts line endings to CRLF, the form signatures cover.
+func Canonical(s string) []byte {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return []byte(strings.ReplaceAll(s, "\n", "\r\n"))
+}
+
+// SplitMultipart returns each part of a canonical multipart body exactly as
+// sent, headers included. The CRLF before a delimiter belongs to the
+// delimiter, not to the part.
+func SplitMultipart(body []byte, boundary string) [][]byte {
+	if boundary == "" {
+		return nil
+	}
+	delim := []byte("--" + boundary)
+	var parts [][]byte
+	var start = -1
+	pos := 0
+	for pos <= len(body) {
+		i := bytes.Index(body[pos:], delim)
+		if i < 0 {
+			break
+		}
+		i += pos
+		// Delimiters start a line.
+		if i > 0 && (i < 2 || string(body[i-2:i]) != "\r\n") {
+			pos = i + len(delim)
+			continue
+		}
+		if start >= 0 {
+			end := i
+			if end >= 2 {
+				end -= 2
+			}
+			parts = append(parts, body[start:end])
+		}
+		after := i + len(delim)
+		if bytes.HasPrefix(body[after:], []byte("--")) {
+			return parts
+		}
+		eol := bytes.Index(body[after:], []byte("\r\n"))
+		if eol < 0 {
+			return parts
+		}
+		start = after + eol + 2
+		pos = start
+	}
+	return parts
+}
+
+// TextEntity wraps text in a quoted-printable text/plain MIME entity, for
+// signing or encrypting.
+func TextEntity(text string) []byte {
+	var b bytes.Buffer
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	w := quotedprintable.NewWriter(&b)
+	_, _ = w.Write(Canonical(text))
+	_ = w.Close()
+	return b.Bytes()
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"mime"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entity renders the request's text and attachments as one MIME entity:
+// the text alone, or multipart/mixed with the attachments after it.
+func (r *SendRequest) Entity() []byte {
+	text := TextEntity(r.Text)
+	if len(r.Attachments) == 0 {
+		return text
+	}
+	boundary := NewBoundary()
+	var b bytes.Buffer
+	b.WriteString("Content-Type: multipart/mixed; boundary=\"" + boundary + "\"\r\n\r\n")
+	b.WriteString("--" + boundary + "\r\n")
+	b.Write(text)
+	for _, a := range r.Attachments {
+		contentType := a.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		b.WriteString("\r\n--" + boundary + "\r\n")
+		b.WriteString("Content-Type: " + contentType + "\r\n")
+		b.WriteString("Content-Disposition: attachment; filename=\"" + a.Filename + "\"\r\n")
+		b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+		b.WriteString(wrapBase64(a.Content))
+	}
+	b.WriteString("\r\n--" + boundary + "--\r\n")
+	return b.Bytes()
+}
+
+// ComposeRaw sets Raw to a complete message whose body is entity, a MIME
+// entity starting with its own Content-Type header, and clears the fields
+// Raw replaces. Bcc recipients are left out of the headers.
+func (r *SendRequest) ComposeRaw(entity []byte) {
+	var b bytes.Buffer
+	header := func(name, value string) {
+		if value != "" {
+			b.WriteString(name + ": " + value + "\r\n")
+		}
+	}
+	header("From", r.From)
+	header("To", r.To)
+	header("Cc", r.Cc)
+	header("Subject", mime.QEncoding.Encode("utf-8", r.Subject))
+	header("Date", now().Format(time.RFC1123Z))
+	header("Message-ID", newMessageID(r.From))
+	header("MIME-Version", "1.0")
+	names := make([]string, 0, len(r.Headers))
+	for name := range r.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		header(name, r.Headers[name])
+	}
+	b.Write(entity)
+	r.Raw = b.String()
+	r.Text, r.HTML, r.Attachments = "", "", nil
+}
+
+// now is the clock, replaced in tests.
+var now = time.Now
+
+// NewBoundary returns a random multipart boundary.
+func NewBoundary() string {
+	return "=_mercury_" + randomHex(12)
+}
+
+// newMessageID returns a unique Message-ID in the sender's domain.
+func newMessageID(from string) string {
+	domain := "mercury.local"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if _, d, ok := strings.Cut(addr.Address, "@"); ok {
+			domain = d
+		}
+	}
+	return "<" + randomHex(16) + "@" + domain + ">"
+}
+
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// wrapBase64 breaks base64 content into 76 column lines.
+func wrapBase64(content string) string {
+	content = strings.Join(strings.Fields(content), "")
+	var b strings.Builder
+	for len(content) > 76 {
+		b.WriteString(content[:76] + "\r\n")
+		content = content[76:]
+	}
+	b.WriteString(content)
+	return b.String()
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/misty-step/mercury/cli/internal/smime"
+)
+
+// ErrNotSMIME is returned by VerifySMIME for mail without an S/MIME
+// signature.
+var ErrNotSMIME = errors.New("email is not S/MIME signed")
+
+// SMIMEResult is a verified S/MIME signed email.
+type SMIMEResult struct {
+	Verification smime.Verification
+	// Content is the signed MIME entity.
+	Content *Email
+}
+
+// isPKCS7Mime reports an opaque S/MIME part. smime-type is often omitted,
+// so anything but enveloped data is tried as signed data.
+func isPKCS7Mime(mediaType string) bool {
+	return mediaType == "application/pkcs7-mime" || mediaType == "application/x-pkcs7-mime"
+}
+
+func isPKCS7Signature(protocol string) bool {
+	protocol = strings.ToLower(protocol)
+	return protocol == "application/pkcs7-signature" || protocol == "application/x-pkcs7-signature"
+}
+
+// IsSMIME reports whether the email carries an S/MIME signature: detached
+// in multipart/signed, opaque in application/pkcs7-mime, or an opaque part
+// beside a plain text copy, as mercury sends it.
+func (e *Email) IsSMIME() bool {
+	root := e.Structure()
+	if root == nil {
+		return false
+	}
+	if root.MediaType == "multipart/signed" && isPKCS7Signature(root.Params["protocol"]) {
+		return true
+	}
+	for _, p := range e.Parts() {
+		if isPKCS7Mime(p.MediaType) && !strings.EqualFold(p.Params["smime-type"], "enveloped-data") {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifySMIME checks the email's S/MIME signature against roots, or the
+// system trust store when roots is nil, and extracts the signed content.
+// A signature that fails to verify, or whose certificate was not issued
+// for the From address, is reported in the result, not as an error.
+func (e *Email) VerifySMIME(roots *x509.CertPool) (*SMIMEResult, error) {
+	root := e.Structure()
+	if root == nil {
+		return nil, ErrNotSMIME
+	}
+	if root.MediaType == "multipart/signed" && isPKCS7Signature(root.Params["protocol"]) {
+		raw := Canonical(e.RawEmail)
+		i := bytes.Index(raw, []byte("\r\n\r\n"))
+		if i < 0 {
+			return nil, fmt.Errorf("multipart/signed has no body")
+		}
+		parts := SplitMultipart(raw[i+4:], root.Params["boundary"])
+		if len(parts) != 2 || len(root.Children) != 2 {
+			return nil, fmt.Errorf("multipart/signed has %d parts, want 2", len(parts))
+		}
+		sd, err := smime.Parse(root.Children[1].Body)
+		if err != nil {
+			return nil, err
+		}
+		return &SMIMEResult{
+			Verification: sd.Verify(parts[0], roots).CheckSender(e.FromAddress()),
+			Content:      &Email{RawEmail: string(parts[0])},
+		}, nil
+	}
+	for _, p := range e.Parts() {
+		if !isPKCS7Mime(p.MediaType) {
+			continue
+		}
+		sd, err := smime.Parse(p.Body)
+		if err != nil {
+			return nil, err
+		}
+		return &SMIMEResult{
+			Verification: sd.Verify(nil, roots).CheckSender(e.FromAddress()),
+			Content:      &Email{RawEmail: string(sd.Content())},
+		}, nil
+	}
+	return nil, ErrNotSMIME
+}
+
+// opaqueBody returns the text of the content signed inside an
+// application/pkcs7-mime body, without verifying the signature.
+func opaqueBody(der []byte) string {
+	sd, err := smime.Parse(der)
+	if err != nil {
+		return ""
+	}
+	return (&Email{RawEmail: string(sd.Content())}).Body()
+}
+
+// SignSMIME adds an opaque S/MIME signature of the text to req. The relay
+// only carries a text body and attachments, so the signed copy travels as
+// an smime.p7m attachment beside the readable text.
+func (r *SendRequest) SignSMIME(signer *smime.Signer) error {
+	if r.HTML != "" {
+		return fmt.Errorf("S/MIME messages must be plain text")
+	}
+	der, err := signer.Sign(TextEntity(r.Text), false)
+	if err != nil {
+		return fmt.Errorf("S/MIME sign: %w", err)
+	}
+	r.Attachments = append(r.Attachments, Attachment{
+		Filename:    "smime.p7m",
+		ContentType: "application/pkcs7-mime; smime-type=signed-data; name=smime.p7m",
+		Content:     base64.StdEncoding.EncodeToString(der),
+	})
+	return nil
+}
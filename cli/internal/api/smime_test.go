@@ -0,0 +1,162 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/misty-step/mercury/cli/internal/smime"
+)
+
+// testSigner issues alice@example.com a certificate from a fresh CA.
+func testSigner(t *testing.T) (*smime.Signer, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+	leafTemplate := &x509.Certificate{
+		SerialNumber:   big.NewInt(2),
+		EmailAddresses: []string{"alice@example.com"},
+		NotBefore:      time.Now().Add(-time.Hour),
+		NotAfter:       time.Now().Add(time.Hour),
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, ca, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _ := x509.ParseCertificate(leafDER)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	return &smime.Signer{Certificate: leaf, Key: key}, roots
+}
+
+// wrap base64 encodes data in 76 character lines.
+func wrap(data []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	return b.String() + encoded + "\r\n"
+}
+
+func TestEmailSMIME_Opaque(t *testing.T) {
+	signer, roots := testSigner(t)
+	der, err := signer.Sign(TextEntity("Quarterly numbers attached.\nCall me."), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	email := &Email{RawEmail: "From: alice@example.com\r\n" +
+		"Content-Type: application/pkcs7-mime; smime-type=signed-data; name=smime.p7m\r\n" +
+		"Content-Transfer-Encoding: base64\r\n\r\n" + wrap(der)}
+
+	if got := email.Body(); got != "Quarterly numbers attached.\r\nCall me." {
+		t.Errorf("Body() = %q", got)
+	}
+	if !email.IsSMIME() {
+		t.Fatal("opaque signature not detected")
+	}
+	res, err := email.VerifySMIME(roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Verification.Status != smime.Valid || res.Verification.SignerName() != "alice@example.com" {
+		t.Errorf("verification = %+v", res.Verification)
+	}
+	if res, _ := email.VerifySMIME(x509.NewCertPool()); res.Verification.Status != smime.Untrusted {
+		t.Errorf("empty trust store: %+v", res.Verification)
+	}
+
+	// Alice's certificate does not vouch for mail from someone else.
+	email.RawEmail = strings.Replace(email.RawEmail, "alice@example.com", "ceo@example.com", 1)
+	if res, _ := email.VerifySMIME(roots); res.Verification.Status != smime.Untrusted ||
+		!strings.Contains(res.Verification.Summary(), "not for ceo@example.com") {
+		t.Errorf("mismatched From: %+v", res.Verification)
+	}
+}
+
+func TestEmailSMIME_Detached(t *testing.T) {
+	signer, roots := testSigner(t)
+	content := "Content-Type: text/plain; charset=utf-8\r\n\r\nPay invoice 42.\r\n"
+	der, err := signer.Sign([]byte(content), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := func(content string) *Email {
+		// LF line endings, as stored by some servers; signatures cover CRLF.
+		raw := "From: alice@example.com\r\n" +
+			"Content-Type: multipart/signed; protocol=\"application/pkcs7-signature\"; micalg=sha-256; boundary=\"sig\"\r\n\r\n" +
+			"--sig\r\n" + content + "\r\n--sig\r\n" +
+			"Content-Type: application/pkcs7-signature; name=smime.p7s\r\n" +
+			"Content-Transfer-Encoding: base64\r\n\r\n" + wrap(der) + "--sig--\r\n"
+		return &Email{RawEmail: strings.ReplaceAll(raw, "\r\n", "\n")}
+	}
+
+	res, err := message(content).VerifySMIME(roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Verification.Status != smime.Valid || res.Content.Body() != "Pay invoice 42." {
+		t.Errorf("verification = %+v, body %q", res.Verification, res.Content.Body())
+	}
+	res, err = message(strings.Replace(content, "42", "43", 1)).VerifySMIME(roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Verification.Status != smime.Invalid {
+		t.Errorf("tampered content verified: %+v", res.Verification)
+	}
+
+	if _, err := (&Email{RawEmail: "Content-Type: text/plain\r\n\r\nhi"}).VerifySMIME(roots); err != ErrNotSMIME {
+		t.Errorf("plain text: %v", err)
+	}
+}
+
+func TestSendRequest_SignSMIME(t *testing.T) {
+	signer, roots := testSigner(t)
+	req := &SendRequest{To: "bob@example.com", Subject: "Hi", Text: "Signed hello"}
+	if err := req.SignSMIME(signer); err != nil {
+		t.Fatal(err)
+	}
+	if req.Text != "Signed hello" || len(req.Attachments) != 1 || req.Attachments[0].Filename != "smime.p7m" {
+		t.Fatalf("request = %+v", req)
+	}
+
+	// As the relay delivers it: the text, then the attachment.
+	email := &Email{RawEmail: "From: Alice <Alice@Example.com>\r\nContent-Type: multipart/mixed; boundary=relay\r\n\r\n" +
+		"--relay\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n" + req.Text + "\r\n" +
+		"--relay\r\nContent-Type: " + req.Attachments[0].ContentType + "\r\n" +
+		"Content-Disposition: attachment; filename=smime.p7m\r\n" +
+		"Content-Transfer-Encoding: base64\r\n\r\n" + req.Attachments[0].Content + "\r\n--relay--\r\n"}
+	res, err := email.VerifySMIME(roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Verification.Status != smime.Valid || res.Content.Body() != "Signed hello" {
+		t.Errorf("verification = %+v, body %q", res.Verification, res.Content.Body())
+	}
+}
@@ -1,11 +1,14 @@
 package api
 
 import (
+	"encoding/json"
+	"fmt"
 	"io"
 	"mime"
 	"mime/multipart"
 	"net/mail"
 	"strings"
+	"time"
 )
 
 type Email struct {
@@ -22,6 +25,19 @@ type Email struct {
 	HeadersJSON string `json:"headers_json,omitempty"`
 }
 
+// Folders lists the folders the server accepts in an EmailUpdate.
+var Folders = []string{"inbox", "archive", "sent", "drafts", "trash"}
+
+// ValidFolder reports whether folder is one of Folders.
+func ValidFolder(folder string) bool {
+	for _, f := range Folders {
+		if f == folder {
+			return true
+		}
+	}
+	return false
+}
+
 // EmailUpdate represents fields that can be updated on an email.
 type EmailUpdate struct {
 	IsRead     *bool   `json:"is_read,omitempty"`
@@ -49,7 +65,7 @@ func (e *Email) Body() string {
 	}
 
 	if strings.HasPrefix(mediaType, "text/plain") {
-		body, _ := io.ReadAll(msg.Body)
+		body, _ := io.ReadAll(decodeTransfer(msg.Header.Get("Content-Transfer-Encoding"), msg.Body))
 		return decodeBody(body, params["charset"])
 	}
 
@@ -59,6 +75,11 @@ func (e *Email) Body() string {
 		}
 	}
 
+	if isPKCS7Mime(mediaType) {
+		body, _ := io.ReadAll(decodeTransfer(msg.Header.Get("Content-Transfer-Encoding"), msg.Body))
+		return opaqueBody(body)
+	}
+
 	body, _ := io.ReadAll(msg.Body)
 	return strings.TrimSpace(string(body))
 }
@@ -73,6 +94,116 @@ func (e *Email) Starred() bool {
 	return e.IsStarred == 1
 }
 
+// Headers returns the message headers keyed by lowercase name. It prefers
+// the server's headers_json and falls back to parsing the raw email; repeated
+// headers are joined with ", ".
+func (e *Email) Headers() map[string]string {
+	headers := make(map[string]string)
+	if strings.TrimSpace(e.HeadersJSON) != "" {
+		if err := json.Unmarshal([]byte(e.HeadersJSON), &headers); err == nil {
+			lowered := make(map[string]string, len(headers))
+			for k, v := range headers {
+				lowered[strings.ToLower(k)] = v
+			}
+			return lowered
+		}
+	}
+	if strings.TrimSpace(e.RawEmail) == "" {
+		return headers
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(e.RawEmail))
+	if err != nil {
+		return headers
+	}
+	for k, values := range msg.Header {
+		headers[strings.ToLower(k)] = strings.Join(values, ", ")
+	}
+	return headers
+}
+
+// Header returns a header value by case-insensitive name.
+func (e *Email) Header(name string) string {
+	return e.Headers()[strings.ToLower(name)]
+}
+
+// FromAddress returns the bare address in the From header, falling back
+// to the sender the server recorded. It is empty when neither parses.
+func (e *Email) FromAddress() string {
+	for _, from := range []string{e.Header("From"), e.Sender} {
+		if addr, err := mail.ParseAddress(from); err == nil {
+			return addr.Address
+		}
+	}
+	return ""
+}
+
+// HasAttachments reports whether the raw email contains a part marked as an
+// attachment or carrying a filename.
+func (e *Email) HasAttachments() bool {
+	if strings.TrimSpace(e.RawEmail) == "" {
+		return false
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(e.RawEmail))
+	if err != nil {
+		return false
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		return false
+	}
+	return multipartHasAttachment(msg.Body, params["boundary"])
+}
+
+func multipartHasAttachment(r io.Reader, boundary string) bool {
+	if boundary == "" {
+		return false
+	}
+	mr := multipart.NewReader(r, boundary)
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			return false
+		}
+		disposition, dparams, _ := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+		mediaType, params, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if disposition == "attachment" || dparams["filename"] != "" || params["name"] != "" {
+			part.Close()
+			return true
+		}
+		if strings.HasPrefix(mediaType, "multipart/") && multipartHasAttachment(part, params["boundary"]) {
+			part.Close()
+			return true
+		}
+		part.Close()
+	}
+}
+
+// timestampLayouts are the formats the server uses for received_at.
+var timestampLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05"}
+
+// ParseTimestamp parses a server timestamp. Timestamps without a zone are UTC.
+func ParseTimestamp(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp: %q", value)
+}
+
+// DecodeHeader decodes RFC 2047 encoded words, returning the input unchanged
+// when it cannot be decoded.
+func DecodeHeader(value string) string {
+	decoded, err := headerDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+var headerDecoder = &mime.WordDecoder{}
+
 func extractTextFromMultipart(r io.Reader, boundary string) string {
 	if boundary == "" {
 		return ""
@@ -103,6 +234,11 @@ func extractTextFromMultipart(r io.Reader, boundary string) string {
 				return extractTextFromMultipart(part, params["boundary"])
 			}
 
+			if isPKCS7Mime(mediaType) {
+				body, _ := io.ReadAll(decodeTransfer(part.Header.Get("Content-Transfer-Encoding"), part))
+				return opaqueBody(body)
+			}
+
 			return ""
 		}()
 
@@ -131,12 +267,26 @@ type EmailResponse struct {
 }
 
 type SendRequest struct {
-	From    string            `json:"from,omitempty"`
-	To      string            `json:"to"`
-	Subject string            `json:"subject"`
-	Text    string            `json:"text,omitempty"`
-	HTML    string            `json:"html,omitempty"`
-	Headers map[string]string `json:"headers,omitempty"`
+	From        string            `json:"from,omitempty"`
+	To          string            `json:"to"` // one address, or several separated by commas
+	Cc          string            `json:"cc,omitempty"`
+	Bcc         string            `json:"bcc,omitempty"`
+	Subject     string            `json:"subject"`
+	Text        string            `json:"text,omitempty"`
+	HTML        string            `json:"html,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Attachments []Attachment      `json:"attachments,omitempty"`
+	// Raw is a complete RFC 5322 message, sent as is in place of the text,
+	// HTML and attachments; see ComposeRaw.
+	Raw string `json:"raw,omitempty"`
+}
+
+// Attachment is a file sent with an email.
+type Attachment struct {
+	Filename string `json:"filename"`
+	// Content is the file's bytes, base64 encoded.
+	Content     string `json:"content"`
+	ContentType string `json:"content_type,omitempty"`
 }
 
 type SendResponse struct {
@@ -1,6 +1,9 @@
 package api
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestEmailBody(t *testing.T) {
 	tests := []struct {
@@ -51,3 +54,228 @@ func TestEmailReadStarred(t *testing.T) {
 		t.Error("expected Read() = true after setting IsRead=1")
 	}
 }
+
+func TestEmailHeaders(t *testing.T) {
+	e := &Email{HeadersJSON: `{"Message-ID":"<a@x>","references":"<b@x>"}`}
+	if got := e.Header("message-id"); got != "<a@x>" {
+		t.Errorf("Header(message-id) = %q", got)
+	}
+
+	raw := &Email{RawEmail: "In-Reply-To: <c@x>\r\nReceived: one\r\nReceived: two\r\n\r\nbody"}
+	if got := raw.Header("In-Reply-To"); got != "<c@x>" {
+		t.Errorf("Header(In-Reply-To) = %q", got)
+	}
+	if got := raw.Header("received"); got != "one, two" {
+		t.Errorf("Header(received) = %q, want joined values", got)
+	}
+	if got := (&Email{}).Headers(); len(got) != 0 {
+		t.Errorf("Headers() on empty email = %v", got)
+	}
+}
+
+const multipartEmail = "From: news@shop.example\r\n" +
+	"Subject: Deals\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"b1\"\r\n" +
+	"\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"See https://shop.example/deals. Or (https://shop.example/faq).\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	// <p><a href="https://shop.example/deals">Deals</a> <a href='https://evil.example/login'>https://bank.example</a> <a href="https://click.shop.example/x?a=1&amp;b=2">shop.example</a> <a href="mailto:help@shop.example">Help</a> <a href="#top">Top</a></p>
+	"PHA+PGEgaHJlZj0iaHR0cHM6Ly9zaG9wLmV4YW1wbGUvZGVhbHMiPkRlYWxzPC9hPiA8YSBocmVm\r\n" +
+	"PSdodHRwczovL2V2aWwuZXhhbXBsZS9sb2dpbic+aHR0cHM6Ly9iYW5rLmV4YW1wbGU8L2E+IDxh\r\n" +
+	"IGhyZWY9Imh0dHBzOi8vY2xpY2suc2hvcC5leGFtcGxlL3g/YT0xJmFtcDtiPTIiPnNob3AuZXhh\r\n" +
+	"bXBsZTwvYT4gPGEgaHJlZj0ibWFpbHRvOmhlbHBAc2hvcC5leGFtcGxlIj5IZWxwPC9hPiA8YSBo\r\n" +
+	"cmVmPSIjdG9wIj5Ub3A8L2E+PC9wPg==\r\n" +
+	"--b1--\r\n"
+
+func TestEmailParts(t *testing.T) {
+	parts := (&Email{RawEmail: multipartEmail}).Parts()
+	if len(parts) != 2 {
+		t.Fatalf("got %d parts, want 2", len(parts))
+	}
+	if parts[0].MediaType != "text/plain" || parts[1].MediaType != "text/html" {
+		t.Errorf("media types = %s, %s", parts[0].MediaType, parts[1].MediaType)
+	}
+	if got := string(parts[1].Body); got[:3] != "<p>" {
+		t.Errorf("html part not decoded: %q", got)
+	}
+
+	single := (&Email{RawEmail: "Subject: x\r\n\r\nhello"}).Parts()
+	if len(single) != 1 || single[0].MediaType != "text/plain" || string(single[0].Body) != "hello" {
+		t.Errorf("single part = %+v", single)
+	}
+}
+
+func TestEmailLinks(t *testing.T) {
+	links := (&Email{RawEmail: multipartEmail}).Links()
+	want := []Link{
+		{URL: "https://shop.example/deals", Text: "Deals"},
+		{URL: "https://shop.example/faq"},
+		{URL: "https://evil.example/login", Text: "https://bank.example", Lookalike: true},
+		{URL: "https://click.shop.example/x?a=1&b=2", Text: "shop.example"},
+		{URL: "mailto:help@shop.example", Text: "Help"},
+	}
+	if len(links) != len(want) {
+		t.Fatalf("Links() = %+v", links)
+	}
+	for i := range want {
+		if links[i] != want[i] {
+			t.Errorf("link %d = %+v, want %+v", i, links[i], want[i])
+		}
+	}
+	if got := links[4].Host(); got != "help@shop.example" {
+		t.Errorf("mailto Host() = %q", got)
+	}
+}
+
+func TestEmailStructure(t *testing.T) {
+	root := (&Email{RawEmail: multipartEmail}).Structure()
+	if root == nil {
+		t.Fatal("Structure() = nil")
+	}
+	got := strings.Join(root.Tree(), "\n")
+	want := "multipart/alternative\n" +
+		"├── text/plain; charset=utf-8  quoted-printable  62 B\n" +
+		"└── text/html; charset=utf-8  base64  344 B"
+	if got != want {
+		t.Errorf("Tree() =\n%s\nwant\n%s", got, want)
+	}
+	if (&Email{}).Structure() != nil {
+		t.Error("Structure() without a raw email should be nil")
+	}
+}
+
+func TestEmailHeaderFields(t *testing.T) {
+	e := &Email{RawEmail: "Received: from a\r\n\tby b\r\nFrom: x@example.com\r\nReceived: from c\r\n\r\nbody: not a header\r\n"}
+	want := []HeaderField{
+		{Name: "Received", Value: "from a by b"},
+		{Name: "From", Value: "x@example.com"},
+		{Name: "Received", Value: "from c"},
+	}
+	got := e.HeaderFields()
+	if len(got) != len(want) {
+		t.Fatalf("HeaderFields() = %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("field %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	fallback := (&Email{HeadersJSON: `{"To":"b","From":"a"}`}).HeaderFields()
+	if len(fallback) != 2 || fallback[0].Name != "From" {
+		t.Errorf("headers_json fallback = %+v", fallback)
+	}
+}
+
+func TestParseAuthenticationResults(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  AuthVerdict
+	}{
+		{
+			name: "cloudflare",
+			value: "mx.cloudflare.net; dkim=pass header.d=example.com header.s=s1 header.b=abc;\r\n" +
+				" dmarc=pass header.from=example.com policy.dmarc=none; spf=pass (mx.cloudflare.net: domain of\r\n" +
+				" a@example.com designates 1.2.3.4 as permitted sender) smtp.mailfrom=a@example.com; arc=none",
+			want: AuthVerdict{
+				Server: "mx.cloudflare.net",
+				SPF:    AuthCheck{Result: AuthPass, Domain: "example.com"},
+				DKIM:   AuthCheck{Result: AuthPass, Domain: "example.com"},
+				DMARC:  AuthCheck{Result: AuthPass, Domain: "example.com"},
+			},
+		},
+		{
+			name:  "spoofed",
+			value: "mx.example.net 1; spf=softfail smtp.mailfrom=evil.example; dkim=none; dkim=fail (bad sig) header.i=@evil.example; DMARC=FAIL (p=reject) header.from=ceo.example",
+			want: AuthVerdict{
+				Server: "mx.example.net",
+				SPF:    AuthCheck{Result: AuthSoftFail, Domain: "evil.example"},
+				DKIM:   AuthCheck{Result: AuthFail, Domain: "evil.example"},
+				DMARC:  AuthCheck{Result: AuthFail, Domain: "ceo.example"},
+			},
+		},
+		{
+			name:  "no results",
+			value: "mx.example.net; none",
+			want:  AuthVerdict{Server: "mx.example.net"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseAuthenticationResults(tt.value); got != tt.want {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+
+	// The topmost header is the receiving server's.
+	e := &Email{RawEmail: "Authentication-Results: mx.cloudflare.net; dmarc=fail header.from=ceo.example\r\n" +
+		"Authentication-Results: mx.cloudflare.net; dmarc=pass\r\n\r\nbody"}
+	if v := e.Authentication(); v.Server != "mx.cloudflare.net" || !v.DMARC.Result.Failed() {
+		t.Errorf("Authentication() = %+v", v)
+	}
+	if (&Email{}).Authentication().Known() {
+		t.Error("an email without headers has no verdict")
+	}
+}
+
+func TestEmail_AuthenticationIgnoresUntrustedServers(t *testing.T) {
+	// A sender forges a passing verdict above the relay's own header.
+	forged := "Authentication-Results: mx.cloudflare.net.evil.example; spf=pass; dkim=pass; dmarc=pass header.from=bank.example\r\n"
+	relay := "Authentication-Results: mx.cloudflare.net; dmarc=fail header.from=bank.example\r\n"
+
+	e := &Email{RawEmail: forged + relay + "\r\nbody"}
+	if v := e.Authentication(); v.Server != "mx.cloudflare.net" || v.DMARC.Result != AuthFail {
+		t.Errorf("Authentication() = %+v, want the relay's failing verdict", v)
+	}
+
+	e = &Email{RawEmail: forged + "\r\nbody"}
+	if v := e.Authentication(); v.Known() {
+		t.Errorf("Authentication() of a forged header alone = %+v, want unknown", v)
+	}
+
+	defer func(ids []string) { TrustedAuthServers = ids }(TrustedAuthServers)
+	TrustedAuthServers = []string{"MX.Example.Net"}
+	e = &Email{RawEmail: "Authentication-Results: mx.example.net; dmarc=pass\r\n" + relay + "\r\nbody"}
+	if v := e.Authentication(); v.Server != "mx.example.net" || v.DMARC.Result != AuthPass {
+		t.Errorf("Authentication() with a configured server = %+v", v)
+	}
+}
+
+func TestSplitMultipart(t *testing.T) {
+	body := []byte("preamble\r\n--b\r\nA: 1\r\n\r\none\r\n--b\r\n\r\ntwo --b inline\r\n--b--\r\nepilogue")
+	parts := SplitMultipart(body, "b")
+	if len(parts) != 2 || string(parts[0]) != "A: 1\r\n\r\none" || string(parts[1]) != "\r\ntwo --b inline" {
+		t.Errorf("parts = %q", parts)
+	}
+}
+
+func TestSendRequest_ComposeRaw(t *testing.T) {
+	req := &SendRequest{
+		From: "me@example.com", To: "a@example.com", Cc: "b@example.com", Bcc: "secret@example.com",
+		Subject: "Grüße", Text: "hello", Headers: map[string]string{"In-Reply-To": "<x@example.com>"},
+		Attachments: []Attachment{{Filename: "a.txt", ContentType: "text/plain", Content: "YXR0YWNoZWQ="}},
+	}
+	req.ComposeRaw(req.Entity())
+	if req.Text != "" || req.Attachments != nil || strings.Contains(req.Raw, "secret@example.com") {
+		t.Fatalf("request = %+v", req)
+	}
+	email := &Email{RawEmail: req.Raw}
+	if got := email.Body(); got != "hello" {
+		t.Errorf("body = %q", got)
+	}
+	for _, want := range []string{"To: a@example.com\r\n", "Cc: b@example.com\r\n", "Subject: =?utf-8?q?Gr=C3=BC=C3=9Fe?=\r\n",
+		"In-Reply-To: <x@example.com>\r\n", "Message-ID: <", "@example.com>\r\n", "filename=\"a.txt\"", "YXR0YWNoZWQ="} {
+		if !strings.Contains(req.Raw, want) {
+			t.Errorf("raw message lacks %q:\n%s", want, req.Raw)
+		}
+	}
+}
@@ -0,0 +1,556 @@
+// Package cache stores fetched emails on disk for instant startup and
+// offline reading. Each profile gets its own directory holding an index of
+// list metadata, one immutable file per downloaded message body, and a
+// full-text search index kept in step with both.
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/search"
+)
+
+// DefaultMaxSize bounds the bytes of message bodies kept per profile.
+const DefaultMaxSize int64 = 200 << 20 // 200MB
+
+// saveInterval batches index writes: storing emails or a cache hit writes
+// the index only when it has not been written for this long. Flush writes
+// the rest.
+const saveInterval = time.Minute
+
+const (
+	indexFile   = "index.json"
+	searchFile  = "search.gob"
+	messagesDir = "messages"
+)
+
+// Dir returns the cache directory for a profile under root. An empty root
+// uses the user cache directory.
+func Dir(root, profile string) (string, error) {
+	if strings.TrimSpace(root) == "" {
+		base, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("locate cache dir: %w", err)
+		}
+		root = filepath.Join(base, "mercury")
+	}
+	if strings.TrimSpace(profile) == "" {
+		profile = "default"
+	}
+	if strings.ContainsAny(profile, `/\`) || profile == "." || profile == ".." {
+		return "", fmt.Errorf("invalid profile name for cache: %q", profile)
+	}
+	return filepath.Join(root, profile), nil
+}
+
+// entry is one indexed email. The embedded Email never carries the raw body;
+// Size is non-zero when the body is on disk.
+type entry struct {
+	api.Email
+	Size     int64     `json:"size,omitempty"`
+	Accessed time.Time `json:"accessed,omitempty"`
+	// Synced is when the server last reported the email's flags.
+	Synced time.Time `json:"synced,omitempty"`
+}
+
+type indexData struct {
+	Emails []*entry `json:"emails"`
+}
+
+// Store is a file-backed api.Cache. It is safe for concurrent use within a
+// process; concurrent processes may lose each other's index updates but
+// never corrupt files. Stored lists and emails reach the index in batches;
+// call Flush before the process exits.
+type Store struct {
+	dir     string
+	maxSize int64
+
+	mu      sync.Mutex
+	entries map[int]*entry
+	search  *search.Index
+	// dirty and searchDirty are set when the index or the search index
+	// has unsaved changes.
+	dirty       bool
+	searchDirty bool
+	// saved is when the index was last written.
+	saved time.Time
+}
+
+var _ api.Cache = (*Store)(nil)
+
+// Open loads or creates the cache in dir. maxSize <= 0 uses DefaultMaxSize.
+func Open(dir string, maxSize int64) (*Store, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if err := os.MkdirAll(filepath.Join(dir, messagesDir), 0700); err != nil {
+		return nil, fmt.Errorf("create cache dir: %w", err)
+	}
+
+	s := &Store{dir: dir, maxSize: maxSize, entries: make(map[int]*entry), saved: time.Now()}
+	data, err := os.ReadFile(filepath.Join(dir, indexFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read cache index: %w", err)
+	}
+
+	var idx indexData
+	// A corrupt index only loses metadata; start over rather than fail.
+	if err == nil && json.Unmarshal(data, &idx) == nil {
+		for _, e := range idx.Emails {
+			if e != nil && e.ID > 0 {
+				s.entries[e.ID] = e
+			}
+		}
+	}
+
+	s.loadSearch()
+	return s, nil
+}
+
+// loadSearch reads the search index, rebuilding it from cached metadata and
+// bodies when it is missing or unreadable.
+func (s *Store) loadSearch() {
+	if data, err := os.ReadFile(filepath.Join(s.dir, searchFile)); err == nil {
+		idx := search.NewIndex()
+		if gob.NewDecoder(bytes.NewReader(data)).Decode(idx) == nil {
+			s.search = idx
+			return
+		}
+	}
+
+	s.search = search.NewIndex()
+	for _, e := range s.entries {
+		email := e.Email
+		if e.Size > 0 {
+			if body, ok := s.readBody(e.ID); ok {
+				email.RawEmail = body.RawEmail
+			}
+		}
+		s.search.Add(&email)
+	}
+	s.searchDirty = len(s.entries) > 0
+	_ = s.saveSearch()
+}
+
+// StoreList records list metadata, refreshing flags for known emails. A
+// first page also drops cached emails the server no longer lists in that
+// span of the folder.
+func (s *Store) StoreList(folder string, limit, offset int, resp *api.EmailListResponse) {
+	if resp == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[int]bool, len(resp.Emails))
+	for i := range resp.Emails {
+		seen[resp.Emails[i].ID] = true
+		s.upsert(resp.Emails[i])
+		s.search.Add(&resp.Emails[i])
+	}
+	s.searchDirty = s.searchDirty || len(resp.Emails) > 0
+
+	if offset == 0 && folder != "" {
+		complete := listComplete(limit, offset, resp)
+		oldest := ""
+		if n := len(resp.Emails); n > 0 {
+			oldest = resp.Emails[n-1].ReceivedAt
+		}
+		for id, e := range s.entries {
+			if seen[id] || e.Folder != folder {
+				continue
+			}
+			if complete || e.ReceivedAt >= oldest {
+				s.drop(id)
+			}
+		}
+	}
+	s.saveLater()
+}
+
+// listComplete reports whether resp holds the rest of the folder. The
+// server caps the page size, so a page shorter than the requested limit
+// may still be partial; its total and echoed limit decide instead.
+func listComplete(limit, offset int, resp *api.EmailListResponse) bool {
+	if resp.Total > 0 {
+		return offset+len(resp.Emails) >= resp.Total
+	}
+	if resp.Limit > 0 && resp.Limit < limit {
+		limit = resp.Limit
+	}
+	return len(resp.Emails) < limit
+}
+
+// StoreEmail records a fully fetched email. The body is written once and
+// never rewritten.
+func (s *Store) StoreEmail(email *api.Email) {
+	if email == nil || email.ID <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.upsert(*email)
+	e.Accessed = time.Now().UTC()
+	if e.Size == 0 && email.RawEmail != "" {
+		if size, err := s.writeBody(email); err == nil {
+			e.Size = size
+		}
+	}
+	s.search.Add(email)
+	s.searchDirty = true
+	if s.totalSize() > s.maxSize {
+		s.prune(s.maxSize)
+	}
+	s.saveLater()
+}
+
+// Email returns a cached email with its body and the latest known flags.
+// With maxAge > 0, an email whose flags the server last reported longer
+// ago is reported missing, so it is fetched again. The access time used
+// for eviction is written with the next batch.
+func (s *Store) Email(id int, maxAge time.Duration) (*api.Email, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[id]
+	if !ok || e.Size == 0 {
+		return nil, false
+	}
+	if maxAge > 0 && time.Since(e.Synced) > maxAge {
+		return nil, false
+	}
+	body, ok := s.readBody(id)
+	if !ok {
+		e.Size = 0
+		return nil, false
+	}
+
+	email := e.Email
+	email.RawEmail = body.RawEmail
+	email.HeadersJSON = body.HeadersJSON
+	e.Accessed = time.Now().UTC()
+	s.saveLater()
+	return &email, true
+}
+
+// List returns a page of cached emails in folder, newest first.
+func (s *Store) List(folder string, limit, offset int) *api.EmailListResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var matches []api.Email
+	for _, e := range s.entries {
+		if folder == "" || e.Folder == folder {
+			matches = append(matches, e.Email)
+		}
+	}
+	sortNewestFirst(matches)
+
+	resp := &api.EmailListResponse{Emails: []api.Email{}, Total: len(matches), Limit: limit, Offset: offset}
+	if offset < 0 || offset >= len(matches) {
+		return resp
+	}
+	end := len(matches)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	resp.Emails = matches[offset:end]
+	return resp
+}
+
+// Update applies a metadata change the server accepted.
+func (s *Store) Update(id int, updates api.EmailUpdate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[id]
+	if !ok {
+		return
+	}
+	if updates.IsRead != nil {
+		e.IsRead = boolInt(*updates.IsRead)
+	}
+	if updates.IsStarred != nil {
+		e.IsStarred = boolInt(*updates.IsStarred)
+	}
+	if updates.Folder != nil {
+		e.Folder = *updates.Folder
+	}
+	s.search.SetFlags(id, updates.IsRead, updates.IsStarred, updates.Folder)
+	s.searchDirty = true
+	s.save()
+}
+
+// Remove drops an email and its body.
+func (s *Store) Remove(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[id]; !ok {
+		return
+	}
+	s.drop(id)
+	s.save()
+}
+
+// Stats describes the cache contents.
+type Stats struct {
+	Dir      string `json:"dir"`
+	Messages int    `json:"messages"`
+	Bodies   int    `json:"bodies"`
+	Size     int64  `json:"size_bytes"`
+	MaxSize  int64  `json:"max_size_bytes"`
+}
+
+// Stats reports how many emails and bodies are cached and their size.
+func (s *Store) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := Stats{Dir: s.dir, Messages: len(s.entries), MaxSize: s.maxSize}
+	for _, e := range s.entries {
+		if e.Size > 0 {
+			st.Bodies++
+			st.Size += e.Size
+		}
+	}
+	return st
+}
+
+// Prune evicts least recently used bodies until the cache holds at most
+// maxSize bytes. maxSize <= 0 uses the store's limit. It returns the number
+// of bodies removed and the bytes freed.
+func (s *Store) Prune(maxSize int64) (int, int64, error) {
+	if maxSize <= 0 {
+		maxSize = s.maxSize
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed, freed := s.prune(maxSize)
+	return removed, freed, s.save()
+}
+
+// Clear removes every cached email and body.
+func (s *Store) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = make(map[int]*entry)
+	s.search = search.NewIndex()
+	s.searchDirty = true
+	if err := os.RemoveAll(filepath.Join(s.dir, messagesDir)); err != nil {
+		return fmt.Errorf("clear cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(s.dir, messagesDir), 0700); err != nil {
+		return fmt.Errorf("clear cache: %w", err)
+	}
+	return s.save()
+}
+
+func (s *Store) upsert(email api.Email) *entry {
+	email.RawEmail = ""
+	email.HeadersJSON = ""
+	e, ok := s.entries[email.ID]
+	if !ok {
+		e = &entry{}
+		s.entries[email.ID] = e
+	}
+	e.Email = email
+	e.Synced = time.Now().UTC()
+	return e
+}
+
+// Search returns cached emails matching q, newest first. limit <= 0 returns
+// every match.
+func (s *Store) Search(q *search.Query, limit int) []api.Email {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	docs := s.search.Search(q)
+	results := make([]api.Email, 0, len(docs))
+	for _, doc := range docs {
+		if limit > 0 && len(results) >= limit {
+			break
+		}
+		if e, ok := s.entries[doc.ID]; ok {
+			results = append(results, e.Email)
+		}
+	}
+	return results
+}
+
+func (s *Store) drop(id int) {
+	if e, ok := s.entries[id]; ok && e.Size > 0 {
+		_ = os.Remove(s.bodyPath(id))
+	}
+	delete(s.entries, id)
+	s.search.Remove(id)
+	s.searchDirty = true
+}
+
+func (s *Store) readBody(id int) (*api.Email, bool) {
+	data, err := os.ReadFile(s.bodyPath(id))
+	if err != nil {
+		return nil, false
+	}
+	var body api.Email
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, false
+	}
+	return &body, true
+}
+
+func (s *Store) prune(maxSize int64) (int, int64) {
+	var cached []*entry
+	var total int64
+	for _, e := range s.entries {
+		if e.Size > 0 {
+			cached = append(cached, e)
+			total += e.Size
+		}
+	}
+	sort.Slice(cached, func(i, j int) bool {
+		return cached[i].Accessed.Before(cached[j].Accessed)
+	})
+
+	removed := 0
+	var freed int64
+	for _, e := range cached {
+		if total <= maxSize {
+			break
+		}
+		_ = os.Remove(s.bodyPath(e.ID))
+		total -= e.Size
+		freed += e.Size
+		e.Size = 0
+		removed++
+	}
+	return removed, freed
+}
+
+func (s *Store) totalSize() int64 {
+	var total int64
+	for _, e := range s.entries {
+		total += e.Size
+	}
+	return total
+}
+
+func (s *Store) bodyPath(id int) string {
+	return filepath.Join(s.dir, messagesDir, strconv.Itoa(id)+".json")
+}
+
+func (s *Store) writeBody(email *api.Email) (int64, error) {
+	data, err := json.Marshal(api.Email{ID: email.ID, RawEmail: email.RawEmail, HeadersJSON: email.HeadersJSON})
+	if err != nil {
+		return 0, err
+	}
+	if err := writeFileAtomic(s.bodyPath(email.ID), data); err != nil {
+		return 0, err
+	}
+	return int64(len(data)), nil
+}
+
+// Flush writes index changes not yet saved.
+func (s *Store) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.dirty && !s.searchDirty {
+		return nil
+	}
+	return s.save()
+}
+
+// saveLater marks the index changed and writes it once saveInterval has
+// passed since the last write.
+func (s *Store) saveLater() {
+	s.dirty = true
+	if time.Since(s.saved) > saveInterval {
+		_ = s.save()
+	}
+}
+
+func (s *Store) save() error {
+	idx := indexData{Emails: make([]*entry, 0, len(s.entries))}
+	for _, e := range s.entries {
+		idx.Emails = append(idx.Emails, e)
+	}
+	sort.Slice(idx.Emails, func(i, j int) bool { return idx.Emails[i].ID < idx.Emails[j].ID })
+
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return fmt.Errorf("encode cache index: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(s.dir, indexFile), data); err != nil {
+		return fmt.Errorf("write cache index: %w", err)
+	}
+	s.saved = time.Now()
+	s.dirty = false
+	return s.saveSearch()
+}
+
+func (s *Store) saveSearch() error {
+	if !s.searchDirty {
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(s.search); err != nil {
+		return fmt.Errorf("encode search index: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(s.dir, searchFile), buf.Bytes()); err != nil {
+		return fmt.Errorf("write search index: %w", err)
+	}
+	s.searchDirty = false
+	return nil
+}
+
+// writeFileAtomic replaces path so readers never observe a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+func sortNewestFirst(emails []api.Email) {
+	sort.SliceStable(emails, func(i, j int) bool {
+		if emails[i].ReceivedAt != emails[j].ReceivedAt {
+			return emails[i].ReceivedAt > emails[j].ReceivedAt
+		}
+		return emails[i].ID > emails[j].ID
+	})
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
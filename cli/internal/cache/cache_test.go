@@ -0,0 +1,304 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/search"
+)
+
+func openTestStore(t *testing.T, maxSize int64) *Store {
+	t.Helper()
+	s, err := Open(t.TempDir(), maxSize)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	return s
+}
+
+func listEmails() []api.Email {
+	return []api.Email{
+		{ID: 3, Subject: "Newest", ReceivedAt: "2026-10-03 09:00:00", Folder: "inbox"},
+		{ID: 2, Subject: "Middle", ReceivedAt: "2026-10-02 09:00:00", Folder: "inbox", IsRead: 1},
+		{ID: 1, Subject: "Oldest", ReceivedAt: "2026-10-01 09:00:00", Folder: "inbox"},
+	}
+}
+
+func TestDir(t *testing.T) {
+	dir, err := Dir("/tmp/root", "work")
+	if err != nil || dir != filepath.Join("/tmp/root", "work") {
+		t.Errorf("Dir() = %q, %v", dir, err)
+	}
+	dir, err = Dir("/tmp/root", "")
+	if err != nil || dir != filepath.Join("/tmp/root", "default") {
+		t.Errorf("Dir() with empty profile = %q, %v", dir, err)
+	}
+	if _, err := Dir("/tmp/root", "../escape"); err == nil {
+		t.Error("Dir() should reject path separators in profile")
+	}
+}
+
+func TestStore_ListRoundTrip(t *testing.T) {
+	s := openTestStore(t, 0)
+	s.StoreList("inbox", 50, 0, &api.EmailListResponse{Emails: listEmails(), Total: 3})
+
+	resp := s.List("inbox", 2, 0)
+	if resp.Total != 3 || len(resp.Emails) != 2 {
+		t.Fatalf("List() total=%d len=%d, want 3 and 2", resp.Total, len(resp.Emails))
+	}
+	if resp.Emails[0].ID != 3 || resp.Emails[1].ID != 2 {
+		t.Errorf("List() order = %d,%d, want 3,2", resp.Emails[0].ID, resp.Emails[1].ID)
+	}
+
+	page := s.List("inbox", 2, 2)
+	if len(page.Emails) != 1 || page.Emails[0].ID != 1 {
+		t.Errorf("List() second page = %+v", page.Emails)
+	}
+	if empty := s.List("archive", 10, 0); empty.Total != 0 || empty.Emails == nil {
+		t.Errorf("List() other folder = %+v, want empty non-nil page", empty)
+	}
+}
+
+func TestStore_PersistsAcrossOpen(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := Open(dir, 0)
+	s.StoreList("inbox", 50, 0, &api.EmailListResponse{Emails: listEmails()})
+	s.StoreEmail(&api.Email{ID: 1, Subject: "Oldest", Folder: "inbox", RawEmail: "Subject: Oldest\r\n\r\nhi"})
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := Open(dir, 0)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	email, ok := reopened.Email(1, 0)
+	if !ok {
+		t.Fatal("Email(1) not cached after reopen")
+	}
+	if email.RawEmail == "" || email.Body() != "hi" {
+		t.Errorf("cached body = %q", email.Body())
+	}
+	if len(reopened.List("inbox", 10, 0).Emails) != 3 {
+		t.Error("index not persisted")
+	}
+}
+
+func TestStore_BodyImmutableFlagsRefreshed(t *testing.T) {
+	s := openTestStore(t, 0)
+	s.StoreEmail(&api.Email{ID: 7, Folder: "inbox", RawEmail: "first"})
+	s.StoreEmail(&api.Email{ID: 7, Folder: "inbox", RawEmail: "second"})
+	s.StoreList("inbox", 50, 0, &api.EmailListResponse{Emails: []api.Email{{ID: 7, Folder: "inbox", IsRead: 1, IsStarred: 1}}})
+
+	email, ok := s.Email(7, 0)
+	if !ok {
+		t.Fatal("Email(7) not cached")
+	}
+	if email.RawEmail != "first" {
+		t.Errorf("RawEmail = %q, want body to stay immutable", email.RawEmail)
+	}
+	if !email.Read() || !email.Starred() {
+		t.Errorf("flags not refreshed from list: %+v", email)
+	}
+
+	unread := false
+	s.Update(7, api.EmailUpdate{IsRead: &unread})
+	email, _ = s.Email(7, 0)
+	if email.Read() {
+		t.Error("Update() did not clear read flag")
+	}
+}
+
+func TestStore_FirstPageDropsVanishedEmails(t *testing.T) {
+	s := openTestStore(t, 0)
+	s.StoreList("inbox", 50, 0, &api.EmailListResponse{Emails: listEmails()})
+
+	// Email 2 was deleted elsewhere; the full first page no longer lists it.
+	remaining := []api.Email{listEmails()[0], listEmails()[2]}
+	s.StoreList("inbox", 50, 0, &api.EmailListResponse{Emails: remaining})
+
+	resp := s.List("inbox", 10, 0)
+	if resp.Total != 2 {
+		t.Fatalf("Total = %d, want 2", resp.Total)
+	}
+	for _, e := range resp.Emails {
+		if e.ID == 2 {
+			t.Error("vanished email 2 still listed")
+		}
+	}
+}
+
+func TestStore_CappedFirstPageKeepsOlderEmails(t *testing.T) {
+	s := openTestStore(t, 0)
+	s.StoreList("inbox", 50, 0, &api.EmailListResponse{Emails: listEmails(), Total: 3})
+
+	// The server caps the page at two of 500 emails; the older cached
+	// email lies beyond the page and must survive.
+	page := listEmails()[:2]
+	s.StoreList("inbox", 200, 0, &api.EmailListResponse{Emails: page, Total: 500, Limit: 2})
+
+	if resp := s.List("inbox", 10, 0); resp.Total != 3 {
+		t.Fatalf("Total = %d, want 3", resp.Total)
+	}
+
+	// Without a total, a page as long as the echoed limit is partial too.
+	s.StoreList("inbox", 200, 0, &api.EmailListResponse{Emails: page, Limit: 2})
+	if resp := s.List("inbox", 10, 0); resp.Total != 3 {
+		t.Fatalf("Total without server total = %d, want 3", resp.Total)
+	}
+}
+
+func TestStore_RemoveDeletesBody(t *testing.T) {
+	s := openTestStore(t, 0)
+	s.StoreEmail(&api.Email{ID: 5, Folder: "inbox", RawEmail: "body"})
+	s.Remove(5)
+
+	if _, ok := s.Email(5, 0); ok {
+		t.Error("Email(5) still cached after Remove")
+	}
+	if _, err := os.Stat(s.bodyPath(5)); !os.IsNotExist(err) {
+		t.Errorf("body file still present: %v", err)
+	}
+}
+
+func TestStore_EmailFlagAge(t *testing.T) {
+	s := openTestStore(t, 0)
+	s.StoreEmail(&api.Email{ID: 7, Folder: "inbox", RawEmail: "body"})
+	if _, ok := s.Email(7, time.Minute); !ok {
+		t.Fatal("freshly stored email reported stale")
+	}
+
+	// Flags last reported an hour ago may have changed on the server.
+	s.entries[7].Synced = time.Now().Add(-time.Hour)
+	if _, ok := s.Email(7, time.Minute); ok {
+		t.Error("stale flags served online")
+	}
+	if _, ok := s.Email(7, 0); !ok {
+		t.Error("stale email should still be served without a max age")
+	}
+	s.StoreList("inbox", 50, 0, &api.EmailListResponse{Emails: []api.Email{{ID: 7, Folder: "inbox", IsRead: 1}}})
+	if email, ok := s.Email(7, time.Minute); !ok || !email.Read() {
+		t.Errorf("listing did not refresh flags: %+v, %v", email, ok)
+	}
+}
+
+func TestStore_BatchesIndexWrites(t *testing.T) {
+	s := openTestStore(t, 0)
+	index := filepath.Join(s.dir, indexFile)
+	s.StoreList("inbox", 50, 0, &api.EmailListResponse{Emails: listEmails()})
+	for id := 1; id <= 3; id++ {
+		s.StoreEmail(&api.Email{ID: id, Folder: "inbox", RawEmail: "body"})
+		s.Email(id, 0)
+	}
+	if _, err := os.Stat(index); !os.IsNotExist(err) {
+		t.Fatalf("index written before the interval passed: %v", err)
+	}
+
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := Open(s.dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reopened.Email(3, 0); !ok || len(reopened.List("inbox", 10, 0).Emails) != 3 {
+		t.Error("Flush() did not write the batched changes")
+	}
+
+	// Once the interval has passed, the next change writes the index.
+	stored := s.entries[1].Accessed
+	s.saved = time.Now().Add(-2 * saveInterval)
+	s.Email(1, 0)
+	reopened, err = Open(s.dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reopened.entries[1].Accessed.After(stored) {
+		t.Error("access time not saved after the interval")
+	}
+}
+
+func TestStore_PruneEvictsLeastRecentlyRead(t *testing.T) {
+	s := openTestStore(t, 0)
+	for id := 1; id <= 3; id++ {
+		s.StoreEmail(&api.Email{ID: id, Folder: "inbox", RawEmail: "0123456789"})
+	}
+	// Touch 1 so 2 becomes the least recently read.
+	s.Email(1, 0)
+
+	size := s.Stats().Size
+	removed, freed, err := s.Prune(size - 1)
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if removed != 1 || freed == 0 {
+		t.Errorf("Prune() removed=%d freed=%d, want 1 body", removed, freed)
+	}
+	if _, ok := s.Email(2, 0); ok {
+		t.Error("least recently read body 2 should be evicted")
+	}
+	if _, ok := s.Email(1, 0); !ok {
+		t.Error("recently read body 1 should remain")
+	}
+}
+
+func TestStore_AutoPruneOnSizeLimit(t *testing.T) {
+	s := openTestStore(t, 100)
+	for id := 1; id <= 5; id++ {
+		s.StoreEmail(&api.Email{ID: id, Folder: "inbox", RawEmail: "012345678901234567890123456789"})
+	}
+	if st := s.Stats(); st.Size > 100 {
+		t.Errorf("Size = %d, want <= 100", st.Size)
+	}
+	if st := s.Stats(); st.Messages != 5 {
+		t.Errorf("Messages = %d, want metadata for all 5 kept", st.Messages)
+	}
+}
+
+func TestStore_Clear(t *testing.T) {
+	s := openTestStore(t, 0)
+	s.StoreEmail(&api.Email{ID: 1, Folder: "inbox", RawEmail: "body"})
+	if err := s.Clear(); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if st := s.Stats(); st.Messages != 0 || st.Size != 0 {
+		t.Errorf("Stats() after Clear = %+v", st)
+	}
+}
+
+func TestStore_SearchIndexesListsAndBodies(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := Open(dir, 0)
+	s.StoreList("inbox", 50, 0, &api.EmailListResponse{Emails: listEmails()})
+	s.StoreEmail(&api.Email{ID: 1, Subject: "Oldest", ReceivedAt: "2026-10-01 09:00:00", Folder: "inbox", RawEmail: "Subject: Oldest\r\n\r\nquarterly numbers"})
+
+	q, _ := search.Parse("quarterly")
+	if got := s.Search(q, 0); len(got) != 1 || got[0].ID != 1 {
+		t.Errorf("Search(body word) = %+v", got)
+	}
+	q, _ = search.Parse("is:unread")
+	if got := s.Search(q, 1); len(got) != 1 || got[0].ID != 3 {
+		t.Errorf("Search(is:unread, limit 1) = %+v, want newest unread", got)
+	}
+
+	// Deleting the search index rebuilds it from cached metadata and bodies.
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, searchFile)); err != nil {
+		t.Fatalf("remove search index: %v", err)
+	}
+	reopened, _ := Open(dir, 0)
+	q, _ = search.Parse("quarterly")
+	if got := reopened.Search(q, 0); len(got) != 1 {
+		t.Errorf("rebuilt index Search() = %+v", got)
+	}
+
+	reopened.Remove(1)
+	if got := reopened.Search(q, 0); len(got) != 0 {
+		t.Errorf("removed email still searchable: %+v", got)
+	}
+}
@@ -0,0 +1,333 @@
+// Package calendar reads iCalendar (RFC 5545) invitations and writes the
+// REPLY an attendee sends back to the organizer (RFC 5546).
+package calendar
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+)
+
+// Calendar is a parsed VCALENDAR object.
+type Calendar struct {
+	// Method is the iTIP method, such as REQUEST, REPLY or CANCEL.
+	Method string
+	Events []Event
+}
+
+// Person is an organizer or attendee.
+type Person struct {
+	Name  string
+	Email string
+	// Status is the attendee's PARTSTAT, such as ACCEPTED or NEEDS-ACTION.
+	Status string
+}
+
+// String formats the person as a mail address.
+func (p Person) String() string {
+	if p.Name == "" || strings.EqualFold(p.Name, p.Email) {
+		return p.Email
+	}
+	return fmt.Sprintf("%s <%s>", p.Name, p.Email)
+}
+
+// Event is a VEVENT.
+type Event struct {
+	UID         string
+	Summary     string
+	Location    string
+	Description string
+	Start       time.Time
+	End         time.Time
+	// AllDay is set for events given as dates rather than times.
+	AllDay bool
+	// TZID is the named time zone of Start, empty for UTC or floating times.
+	TZID         string
+	Organizer    Person
+	Attendees    []Person
+	Sequence     int
+	Status       string
+	RecurrenceID string
+}
+
+// FromEmail returns the first calendar attached to the email that has
+// events, or nil when there is none.
+func FromEmail(e *api.Email) *Calendar {
+	for _, part := range e.Parts() {
+		if part.MediaType != "text/calendar" && part.MediaType != "application/ics" {
+			continue
+		}
+		cal, err := Parse(string(part.Body))
+		if err != nil || len(cal.Events) == 0 {
+			continue
+		}
+		if cal.Method == "" {
+			cal.Method = strings.ToUpper(part.Params["method"])
+		}
+		return cal
+	}
+	return nil
+}
+
+// property is one content line: NAME;PARAM=value:VALUE.
+type property struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// Parse reads the first VCALENDAR in data.
+func Parse(data string) (*Calendar, error) {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\n ", "")
+	data = strings.ReplaceAll(data, "\n\t", "")
+
+	var cal *Calendar
+	var stack []string
+	var event *Event
+	for _, line := range strings.Split(data, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		prop, ok := parseLine(line)
+		if !ok {
+			continue
+		}
+		switch prop.Name {
+		case "BEGIN":
+			component := strings.ToUpper(prop.Value)
+			stack = append(stack, component)
+			switch {
+			case component == "VCALENDAR" && cal == nil:
+				cal = &Calendar{}
+			case component == "VEVENT" && cal != nil && len(stack) == 2:
+				event = &Event{}
+			}
+			continue
+		case "END":
+			if len(stack) == 0 {
+				continue
+			}
+			component := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if component == "VEVENT" && event != nil && len(stack) == 1 {
+				cal.Events = append(cal.Events, *event)
+				event = nil
+			}
+			if component == "VCALENDAR" && len(stack) == 0 && cal != nil {
+				return cal, nil
+			}
+			continue
+		}
+		switch {
+		case cal != nil && len(stack) == 1 && prop.Name == "METHOD":
+			cal.Method = strings.ToUpper(prop.Value)
+		case event != nil && len(stack) == 2:
+			if err := event.set(prop); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if cal == nil {
+		return nil, fmt.Errorf("no VCALENDAR object")
+	}
+	return cal, nil
+}
+
+// set applies a VEVENT property.
+func (ev *Event) set(p property) error {
+	switch p.Name {
+	case "UID":
+		ev.UID = p.Value
+	case "SUMMARY":
+		ev.Summary = unescape(p.Value)
+	case "LOCATION":
+		ev.Location = unescape(p.Value)
+	case "DESCRIPTION":
+		ev.Description = unescape(p.Value)
+	case "STATUS":
+		ev.Status = strings.ToUpper(p.Value)
+	case "SEQUENCE":
+		ev.Sequence, _ = strconv.Atoi(p.Value)
+	case "RECURRENCE-ID":
+		ev.RecurrenceID = p.Value
+	case "ORGANIZER":
+		ev.Organizer = person(p)
+	case "ATTENDEE":
+		ev.Attendees = append(ev.Attendees, person(p))
+	case "DTSTART":
+		t, allDay, err := parseTime(p)
+		if err != nil {
+			return fmt.Errorf("DTSTART: %w", err)
+		}
+		ev.Start, ev.AllDay, ev.TZID = t, allDay, p.Params["TZID"]
+		if ev.End.IsZero() && allDay {
+			ev.End = t.AddDate(0, 0, 1)
+		}
+	case "DTEND":
+		t, _, err := parseTime(p)
+		if err != nil {
+			return fmt.Errorf("DTEND: %w", err)
+		}
+		ev.End = t
+	case "DURATION":
+		d, err := parseDuration(p.Value)
+		if err != nil {
+			return fmt.Errorf("DURATION: %w", err)
+		}
+		ev.End = ev.Start.Add(d)
+	}
+	return nil
+}
+
+// parseLine splits a content line into its name, parameters and value.
+// Parameter values may be quoted and contain ":" or ";".
+func parseLine(line string) (property, bool) {
+	var fields []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				fields = append(fields, line[start:i])
+				start = i + 1
+			}
+		case ':':
+			if !quoted {
+				fields = append(fields, line[start:i])
+				p := property{Name: strings.ToUpper(fields[0]), Params: make(map[string]string), Value: line[i+1:]}
+				for _, param := range fields[1:] {
+					name, value, _ := strings.Cut(param, "=")
+					p.Params[strings.ToUpper(name)] = strings.Trim(value, `"`)
+				}
+				return p, true
+			}
+		}
+	}
+	return property{}, false
+}
+
+func person(p property) Person {
+	address := p.Value
+	if len(address) >= 7 && strings.EqualFold(address[:7], "mailto:") {
+		address = address[7:]
+	}
+	return Person{Name: p.Params["CN"], Email: address, Status: strings.ToUpper(p.Params["PARTSTAT"])}
+}
+
+// unescape decodes an iCalendar TEXT value.
+func unescape(s string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
+
+// escape encodes s as an iCalendar TEXT value.
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// parseTime reads a DATE or DATE-TIME in UTC, a named zone or floating
+// (local) time, reporting whether it was a date.
+func parseTime(p property) (time.Time, bool, error) {
+	value := p.Value
+	if strings.EqualFold(p.Params["VALUE"], "DATE") || len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, time.Local)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+	loc := time.Local
+	if tzid := p.Params["TZID"]; tzid != "" {
+		loc = zone(tzid)
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// windowsZones maps the Windows zone names Outlook and Exchange put in
+// TZID to IANA names.
+var windowsZones = map[string]string{
+	"Pacific Standard Time":        "America/Los_Angeles",
+	"Mountain Standard Time":       "America/Denver",
+	"Central Standard Time":        "America/Chicago",
+	"Eastern Standard Time":        "America/New_York",
+	"GMT Standard Time":            "Europe/London",
+	"W. Europe Standard Time":      "Europe/Berlin",
+	"Romance Standard Time":        "Europe/Paris",
+	"Central Europe Standard Time": "Europe/Budapest",
+	"India Standard Time":          "Asia/Kolkata",
+	"China Standard Time":          "Asia/Shanghai",
+	"Tokyo Standard Time":          "Asia/Tokyo",
+	"AUS Eastern Standard Time":    "Australia/Sydney",
+	"UTC":                          "UTC",
+}
+
+// zone resolves a TZID, falling back to local time for unknown zones since
+// their VTIMEZONE rules are not interpreted.
+func zone(tzid string) *time.Location {
+	tzid = strings.TrimPrefix(tzid, "/")
+	if name, ok := windowsZones[tzid]; ok {
+		tzid = name
+	}
+	if loc, err := time.LoadLocation(tzid); err == nil {
+		return loc
+	}
+	return time.Local
+}
+
+// parseDuration reads a DURATION value such as PT1H30M or P1D.
+func parseDuration(value string) (time.Duration, error) {
+	s := strings.TrimPrefix(value, "+")
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	if !strings.HasPrefix(s, "P") {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	s = s[1:]
+	var d time.Duration
+	inTime := false
+	number := ""
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			number += string(r)
+			continue
+		case r == 'T':
+			inTime = true
+			continue
+		}
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		number = ""
+		switch {
+		case r == 'W':
+			d += time.Duration(n) * 7 * 24 * time.Hour
+		case r == 'D':
+			d += time.Duration(n) * 24 * time.Hour
+		case r == 'H' && inTime:
+			d += time.Duration(n) * time.Hour
+		case r == 'M' && inTime:
+			d += time.Duration(n) * time.Minute
+		case r == 'S' && inTime:
+			d += time.Duration(n) * time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+	}
+	if number != "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	if negative {
+		d = -d
+	}
+	return d, nil
+}
@@ -0,0 +1,209 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+)
+
+const invite = "BEGIN:VCALENDAR\r\n" +
+	"PRODID:-//Google Inc//Google Calendar 70.9054//EN\r\n" +
+	"VERSION:2.0\r\n" +
+	"METHOD:REQUEST\r\n" +
+	"BEGIN:VTIMEZONE\r\n" +
+	"TZID:America/New_York\r\n" +
+	"BEGIN:STANDARD\r\n" +
+	"DTSTART:19701101T020000\r\n" +
+	"END:STANDARD\r\n" +
+	"END:VTIMEZONE\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;TZID=America/New_York:20261102T090000\r\n" +
+	"DTEND;TZID=America/New_York:20261102T093000\r\n" +
+	"UID:abc123@google.com\r\n" +
+	"SEQUENCE:2\r\n" +
+	"ORGANIZER;CN=Bob Smith:mailto:bob@example.com\r\n" +
+	"ATTENDEE;CUTYPE=INDIVIDUAL;ROLE=REQ-PARTICIPANT;PARTSTAT=ACCEPTED;CN=\"Smith,\r\n" +
+	"  Bob\":mailto:bob@example.com\r\n" +
+	"ATTENDEE;PARTSTAT=NEEDS-ACTION;CN=alice@example.com:mailto:alice@example.com\r\n" +
+	"SUMMARY:Quarterly review\\, Q4\r\n" +
+	"LOCATION:Room 4\\; 2nd floor\r\n" +
+	"DESCRIPTION:Agenda:\\n1. Numbers\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"DESCRIPTION:Reminder\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParse(t *testing.T) {
+	cal, err := Parse(invite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cal.Method != "REQUEST" || len(cal.Events) != 1 {
+		t.Fatalf("calendar = %+v", cal)
+	}
+	ev := cal.Events[0]
+	if ev.Summary != "Quarterly review, Q4" || ev.Location != "Room 4; 2nd floor" || ev.Description != "Agenda:\n1. Numbers" {
+		t.Errorf("text = %q %q %q", ev.Summary, ev.Location, ev.Description)
+	}
+	if ev.UID != "abc123@google.com" || ev.Sequence != 2 || ev.TZID != "America/New_York" {
+		t.Errorf("event = %+v", ev)
+	}
+	want := time.Date(2026, 11, 2, 14, 0, 0, 0, time.UTC)
+	if !ev.Start.Equal(want) || ev.End.Sub(ev.Start) != 30*time.Minute {
+		t.Errorf("start %v end %v", ev.Start, ev.End)
+	}
+	if ev.Organizer != (Person{Name: "Bob Smith", Email: "bob@example.com"}) {
+		t.Errorf("organizer = %+v", ev.Organizer)
+	}
+	if len(ev.Attendees) != 2 || ev.Attendees[0].Name != "Smith, Bob" || ev.Attendees[0].Status != "ACCEPTED" || ev.Attendees[1].Status != "NEEDS-ACTION" {
+		t.Errorf("attendees = %+v", ev.Attendees)
+	}
+}
+
+func TestParse_TimesAndDurations(t *testing.T) {
+	cal, err := Parse("BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20261102T140000Z\nDURATION:PT1H30M\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nDTSTART;VALUE=DATE:20261224\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nDTSTART;TZID=W. Europe Standard Time:20261102T090000\nEND:VEVENT\nEND:VCALENDAR\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cal.Events) != 3 {
+		t.Fatalf("events = %d", len(cal.Events))
+	}
+	if got := cal.Events[0].End.Sub(cal.Events[0].Start); got != 90*time.Minute {
+		t.Errorf("duration = %v", got)
+	}
+	if ev := cal.Events[1]; !ev.AllDay || ev.End.Sub(ev.Start) != 24*time.Hour {
+		t.Errorf("all day event = %+v", ev)
+	}
+	if got := cal.Events[2].Start.UTC().Hour(); got != 8 {
+		t.Errorf("Windows zone start hour = %d, want 8 UTC", got)
+	}
+
+	if _, err := Parse("BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:tomorrow\nEND:VEVENT\nEND:VCALENDAR\n"); err == nil {
+		t.Error("bad DTSTART should fail")
+	}
+	if _, err := Parse("not a calendar"); err == nil {
+		t.Error("missing VCALENDAR should fail")
+	}
+}
+
+func TestEvent_Card(t *testing.T) {
+	cal, err := Parse(invite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("no zoneinfo")
+	}
+	card := cal.Events[0].Card(cal.Method, london)
+	got := make(map[string]string)
+	for _, f := range card {
+		got[f.Label] = f.Value
+	}
+	if want := "Mon 2 Nov 2026 14:00–14:30 GMT (Mon 2 Nov 2026 09:00–09:30 EST America/New_York)"; got["When"] != want {
+		t.Errorf("When = %q, want %q", got["When"], want)
+	}
+	if got["Organizer"] != "Bob Smith <bob@example.com>" || got["Where"] != "Room 4; 2nd floor" {
+		t.Errorf("card = %+v", card)
+	}
+	if want := "Smith, Bob <bob@example.com> (accepted), alice@example.com"; got["Attendees"] != want {
+		t.Errorf("Attendees = %q", got["Attendees"])
+	}
+	if _, ok := got["Status"]; ok {
+		t.Error("a request should not be marked cancelled")
+	}
+	if card := cal.Events[0].Card("CANCEL", london); card[1] != (Field{"Status", "Cancelled"}) {
+		t.Errorf("cancel card = %+v", card)
+	}
+}
+
+func TestFromEmail(t *testing.T) {
+	email := &api.Email{RawEmail: "Content-Type: multipart/alternative; boundary=b\r\n\r\n" +
+		"--b\r\nContent-Type: text/plain\r\n\r\nYou are invited\r\n" +
+		"--b\r\nContent-Type: text/calendar; method=REQUEST; charset=UTF-8\r\nContent-Transfer-Encoding: 7bit\r\n\r\n" +
+		strings.Replace(invite, "METHOD:REQUEST\r\n", "", 1) +
+		"--b--\r\n"}
+	cal := FromEmail(email)
+	if cal == nil || cal.Method != "REQUEST" || cal.Events[0].Summary != "Quarterly review, Q4" {
+		t.Fatalf("calendar = %+v", cal)
+	}
+	if FromEmail(&api.Email{RawEmail: "Subject: hi\r\n\r\nno invite"}) != nil {
+		t.Error("plain email should have no calendar")
+	}
+}
+
+func TestReply(t *testing.T) {
+	now = func() time.Time { return time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	cal, err := Parse(invite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := cal.Events[0]
+	ev.Summary = strings.Repeat("Very long meeting title ", 5)
+	reply := Reply(ev, "ALICE@example.com", Decline, "Travelling, sorry")
+	for _, want := range []string{
+		"METHOD:REPLY\r\n",
+		"UID:abc123@google.com\r\n",
+		"SEQUENCE:2\r\n",
+		"DTSTAMP:20261018T120000Z\r\n",
+		"DTSTART:20261102T140000Z\r\n",
+		`ATTENDEE;PARTSTAT=DECLINED;CN="alice@example.com":mailto:ALICE@example.com`,
+		"COMMENT:Travelling\\, sorry\r\n",
+	} {
+		if !strings.Contains(reply, want) {
+			t.Errorf("reply missing %q:\n%s", want, reply)
+		}
+	}
+	for _, line := range strings.Split(reply, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line not folded: %q", line)
+		}
+	}
+
+	parsed, err := Parse(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := parsed.Events[0]
+	if parsed.Method != "REPLY" || got.Summary != ev.Summary || got.Attendees[0].Status != "DECLINED" || got.Organizer.Email != "bob@example.com" {
+		t.Errorf("round trip = %+v", parsed)
+	}
+}
+
+func TestFold(t *testing.T) {
+	tests := map[string]string{
+		"multibyte":    strings.Repeat("é", 60),
+		"continuation": "SUMMARY:" + strings.Repeat("\x80", 100),
+	}
+	for name, line := range tests {
+		t.Run(name, func(t *testing.T) {
+			folded := fold(line)
+			if got := strings.ReplaceAll(folded, "\r\n ", ""); got != line {
+				t.Errorf("unfolded = %q, want %q", got, line)
+			}
+			for _, l := range strings.Split(folded, "\r\n") {
+				if len(l) > 75 {
+					t.Errorf("line not folded: %q", l)
+				}
+			}
+		})
+	}
+}
+
+func TestParseResponse(t *testing.T) {
+	for input, want := range map[string]Response{"accept": Accept, "Decline": Decline, "tentative": Tentative} {
+		if got, err := ParseResponse(input); err != nil || got != want {
+			t.Errorf("ParseResponse(%q) = %q, %v", input, got, err)
+		}
+	}
+	if _, err := ParseResponse("perhaps"); err == nil {
+		t.Error("unknown response should fail")
+	}
+}
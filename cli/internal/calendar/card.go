@@ -0,0 +1,89 @@
+package calendar
+
+import (
+	"strings"
+	"time"
+)
+
+// Field is one labelled line of an event card.
+type Field struct {
+	Label string
+	Value string
+}
+
+// Card describes the event for display, with times shown in loc.
+func (ev Event) Card(method string, loc *time.Location) []Field {
+	fields := []Field{{"Event", ev.Summary}}
+	if method == "CANCEL" || ev.Status == "CANCELLED" {
+		fields = append(fields, Field{"Status", "Cancelled"})
+	}
+	fields = append(fields, Field{"When", ev.When(loc)})
+	if ev.Location != "" {
+		fields = append(fields, Field{"Where", ev.Location})
+	}
+	if ev.Organizer.Email != "" {
+		fields = append(fields, Field{"Organizer", ev.Organizer.String()})
+	}
+	if len(ev.Attendees) > 0 {
+		attendees := make([]string, len(ev.Attendees))
+		for i, a := range ev.Attendees {
+			attendees[i] = a.String()
+			if status := partstatLabel(a.Status); status != "" {
+				attendees[i] += " (" + status + ")"
+			}
+		}
+		fields = append(fields, Field{"Attendees", strings.Join(attendees, ", ")})
+	}
+	return fields
+}
+
+// When formats the event's time range in loc. Timed events in a named
+// zone that differs from loc also show the organizer's wall clock.
+func (ev Event) When(loc *time.Location) string {
+	if ev.Start.IsZero() {
+		return "(no time given)"
+	}
+	if ev.AllDay {
+		last := ev.End.AddDate(0, 0, -1)
+		if !last.After(ev.Start) {
+			return ev.Start.Format("Mon 2 Jan 2006") + " (all day)"
+		}
+		return ev.Start.Format("Mon 2 Jan") + " – " + last.Format("Mon 2 Jan 2006")
+	}
+	when := timeRange(ev.Start.In(loc), ev.End.In(loc))
+	if ev.TZID != "" {
+		start := ev.Start
+		if start.Location().String() != loc.String() {
+			when += " (" + timeRange(start, ev.End.In(start.Location())) + " " + start.Location().String() + ")"
+		}
+	}
+	return when
+}
+
+// timeRange formats start and end in start's zone, eliding the end date
+// when the event finishes the same day.
+func timeRange(start, end time.Time) string {
+	s := start.Format("Mon 2 Jan 2006 15:04")
+	switch {
+	case end.IsZero() || end.Equal(start):
+	case end.YearDay() == start.YearDay() && end.Year() == start.Year():
+		s += "–" + end.Format("15:04")
+	default:
+		s += " – " + end.Format("Mon 2 Jan 15:04")
+	}
+	return s + " " + start.Format("MST")
+}
+
+func partstatLabel(status string) string {
+	switch status {
+	case "ACCEPTED":
+		return "accepted"
+	case "DECLINED":
+		return "declined"
+	case "TENTATIVE":
+		return "tentative"
+	case "DELEGATED":
+		return "delegated"
+	}
+	return ""
+}
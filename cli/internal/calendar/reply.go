@@ -0,0 +1,133 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Response is an attendee's answer to an invitation.
+type Response string
+
+const (
+	Accept    Response = "ACCEPTED"
+	Decline   Response = "DECLINED"
+	Tentative Response = "TENTATIVE"
+)
+
+// ParseResponse reads accept, decline or tentative.
+func ParseResponse(s string) (Response, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "accept", "accepted", "yes":
+		return Accept, nil
+	case "decline", "declined", "no":
+		return Decline, nil
+	case "tentative", "maybe":
+		return Tentative, nil
+	}
+	return "", fmt.Errorf("unknown response %q (use accept, decline or tentative)", s)
+}
+
+// Verb describes the response for a subject line, as in "Accepted: Standup".
+func (r Response) Verb() string {
+	switch r {
+	case Accept:
+		return "Accepted"
+	case Decline:
+		return "Declined"
+	default:
+		return "Tentatively accepted"
+	}
+}
+
+// now is replaced in tests.
+var now = time.Now
+
+// Reply builds the METHOD:REPLY calendar telling the organizer how the
+// attendee at address responded to ev. comment is optional.
+func Reply(ev Event, address string, r Response, comment string) string {
+	attendee := Person{Email: address}
+	for _, a := range ev.Attendees {
+		if strings.EqualFold(a.Email, address) {
+			attendee.Name = a.Name
+			break
+		}
+	}
+
+	var b strings.Builder
+	line := func(s string) {
+		b.WriteString(fold(s))
+		b.WriteString("\r\n")
+	}
+	line("BEGIN:VCALENDAR")
+	line("PRODID:-//Mercury//Mercury CLI//EN")
+	line("VERSION:2.0")
+	line("METHOD:REPLY")
+	line("BEGIN:VEVENT")
+	line("UID:" + ev.UID)
+	if ev.RecurrenceID != "" {
+		line("RECURRENCE-ID:" + ev.RecurrenceID)
+	}
+	line(fmt.Sprintf("SEQUENCE:%d", ev.Sequence))
+	line("DTSTAMP:" + now().UTC().Format("20060102T150405Z"))
+	if !ev.Start.IsZero() {
+		line("DTSTART" + formatTime(ev.Start, ev.AllDay))
+	}
+	if !ev.End.IsZero() {
+		line("DTEND" + formatTime(ev.End, ev.AllDay))
+	}
+	if ev.Summary != "" {
+		line("SUMMARY:" + escape(ev.Summary))
+	}
+	line("ORGANIZER" + calAddress(ev.Organizer))
+	line("ATTENDEE;PARTSTAT=" + string(r) + calAddress(attendee))
+	if comment != "" {
+		line("COMMENT:" + escape(comment))
+	}
+	line("END:VEVENT")
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+func formatTime(t time.Time, allDay bool) string {
+	if allDay {
+		return ";VALUE=DATE:" + t.Format("20060102")
+	}
+	return ":" + t.UTC().Format("20060102T150405Z")
+}
+
+func calAddress(p Person) string {
+	s := ""
+	if p.Name != "" {
+		s += `;CN="` + strings.ReplaceAll(p.Name, `"`, "'") + `"`
+	}
+	return s + ":mailto:" + p.Email
+}
+
+// fold splits a content line longer than 75 octets, continuing each
+// piece on a line that starts with a space.
+func fold(s string) string {
+	const limit = 75
+	if len(s) <= limit {
+		return s
+	}
+	var b strings.Builder
+	width := limit
+	for len(s) > width {
+		cut := width
+		// Don't split a UTF-8 sequence.
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		if cut == 0 {
+			// Not valid UTF-8; split anywhere rather than loop forever.
+			cut = width
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		width = limit - 1
+	}
+	b.WriteString(s)
+	return b.String()
+}
@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"github.com/misty-step/mercury/cli/internal/api"
+)
+
+// ExportFormats lists the formats accepted by export.
+var ExportFormats = []string{"mbox"}
+
+// Sources supplies the dynamic completion candidates for Builtin.
+type Sources struct {
+	Profiles   func() []string
+	SortModes  func() []string
+	GroupModes func() []string
+	Settings   func() []string
+	Contacts   func() []string
+}
+
+// Builtin returns the standard commands.
+func Builtin(src Sources) *Registry {
+	folders := func() []string { return api.Folders }
+	return New(
+		Command{
+			Name:    "move",
+			Summary: "Move the marked or selected emails to a folder",
+			Args:    []Arg{{Name: "folder", Values: folders, Strict: true}},
+		},
+		Command{
+			Name:    "search",
+			Summary: "Search mail (see mercury search --help)",
+			Args:    []Arg{{Name: "query", Rest: true}},
+		},
+		Command{
+			Name:    "folder",
+			Summary: "List another folder",
+			Args:    []Arg{{Name: "folder", Values: folders, Strict: true}},
+		},
+		Command{
+			Name:    "profile",
+			Summary: "Switch to another profile",
+			Args:    []Arg{{Name: "name", Values: src.Profiles, Strict: src.Profiles != nil}},
+		},
+		Command{
+			Name:    "sort",
+			Summary: "Change the list order",
+			Args:    []Arg{{Name: "mode", Values: src.SortModes, Strict: src.SortModes != nil}},
+		},
+		Command{
+			Name:    "group",
+			Summary: "Group the list under headers",
+			Args:    []Arg{{Name: "mode", Values: src.GroupModes, Strict: src.GroupModes != nil}},
+		},
+		Command{
+			Name:    "export",
+			Summary: "Write emails to a file",
+			Args: []Arg{
+				{Name: "format", Values: func() []string { return ExportFormats }, Strict: true},
+				{Name: "path"},
+			},
+		},
+		Command{
+			Name:    "compose",
+			Summary: "Write an email, to contact names or addresses",
+			Args:    []Arg{{Name: "to", Values: src.Contacts, Optional: true, Rest: true}},
+		},
+		Command{
+			Name:    "set",
+			Summary: "Change a setting",
+			Args: []Arg{
+				{Name: "option", Values: src.Settings, Strict: src.Settings != nil},
+				{Name: "value"},
+			},
+		},
+	)
+}
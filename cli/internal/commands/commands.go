@@ -0,0 +1,289 @@
+// Package commands defines the ex-style commands shared by the TUI's ":"
+// command line and the CLI: their names, arguments, validation and
+// completion. Each host binds its own behaviour to a parsed Call.
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Arg describes a positional argument.
+type Arg struct {
+	Name string
+	// Values lists completion candidates; nil accepts free text.
+	Values func() []string
+	// Strict rejects values that Values does not list.
+	Strict bool
+	// Optional arguments may be omitted; only trailing ones should be.
+	Optional bool
+	// Rest takes the remainder of the line, quotes included, as this,
+	// the last, argument.
+	Rest bool
+}
+
+// Command describes one command and its arguments.
+type Command struct {
+	Name    string
+	Summary string
+	Args    []Arg
+}
+
+// Usage renders the command line syntax, e.g. "move <folder>".
+func (c Command) Usage() string {
+	parts := []string{c.Name}
+	for _, arg := range c.Args {
+		name := arg.Name
+		if arg.Rest {
+			name += "..."
+		}
+		if arg.Optional {
+			parts = append(parts, "["+name+"]")
+		} else {
+			parts = append(parts, "<"+name+">")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Check validates words as this command's arguments, joining a Rest
+// argument into a single value.
+func (c Command) Check(words []string) ([]string, error) {
+	return c.check(words, nil)
+}
+
+// check is Check with the raw text each word starts, for Rest arguments.
+func (c Command) check(words, raw []string) ([]string, error) {
+	var args []string
+	for i, arg := range c.Args {
+		if i >= len(words) {
+			if arg.Optional {
+				break
+			}
+			return nil, fmt.Errorf("usage: %s", c.Usage())
+		}
+		value := words[i]
+		if arg.Rest {
+			value = strings.Join(words[i:], " ")
+			if raw != nil {
+				value = strings.TrimSpace(raw[i])
+			}
+		}
+		if arg.Strict && arg.Values != nil && !contains(arg.Values(), value) {
+			return nil, fmt.Errorf("%s: unknown %s %q (valid: %s)", c.Name, arg.Name, value, strings.Join(arg.Values(), ", "))
+		}
+		args = append(args, value)
+		if arg.Rest {
+			return args, nil
+		}
+	}
+	if len(words) > len(c.Args) {
+		return nil, fmt.Errorf("usage: %s", c.Usage())
+	}
+	return args, nil
+}
+
+// CompleteArg lists candidates for argument i starting with prefix.
+func (c Command) CompleteArg(i int, prefix string) []string {
+	if i >= len(c.Args) {
+		last := len(c.Args) - 1
+		if last < 0 || !c.Args[last].Rest {
+			return nil
+		}
+		i = last
+	}
+	if c.Args[i].Values == nil {
+		return nil
+	}
+	return withPrefix(c.Args[i].Values(), prefix)
+}
+
+// Call is a parsed command line.
+type Call struct {
+	Command Command
+	Args    []string
+}
+
+// Registry holds the known commands.
+type Registry struct {
+	commands []Command
+}
+
+// New returns a registry of cmds.
+func New(cmds ...Command) *Registry {
+	return &Registry{commands: cmds}
+}
+
+// Commands returns every command sorted by name.
+func (r *Registry) Commands() []Command {
+	out := append([]Command(nil), r.commands...)
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Lookup finds a command by name or unambiguous prefix.
+func (r *Registry) Lookup(name string) (Command, error) {
+	var matches []Command
+	for _, c := range r.commands {
+		if c.Name == name {
+			return c, nil
+		}
+		if strings.HasPrefix(c.Name, name) {
+			matches = append(matches, c)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return Command{}, fmt.Errorf("unknown command %q", name)
+	case 1:
+		return matches[0], nil
+	default:
+		names := make([]string, len(matches))
+		for i, c := range matches {
+			names[i] = c.Name
+		}
+		sort.Strings(names)
+		return Command{}, fmt.Errorf("ambiguous command %q: %s", name, strings.Join(names, ", "))
+	}
+}
+
+// Parse splits and validates a command line.
+func (r *Registry) Parse(line string) (Call, error) {
+	words, starts, _, err := split(line)
+	if err != nil {
+		return Call{}, err
+	}
+	if len(words) == 0 {
+		return Call{}, fmt.Errorf("empty command")
+	}
+	cmd, err := r.Lookup(words[0])
+	if err != nil {
+		return Call{}, err
+	}
+	raw := make([]string, len(starts)-1)
+	for i, start := range starts[1:] {
+		raw[i] = line[start:]
+	}
+	args, err := cmd.check(words[1:], raw)
+	if err != nil {
+		return Call{}, err
+	}
+	return Call{Command: cmd, Args: args}, nil
+}
+
+// Complete lists candidates for the last word of a partial command line:
+// command names for the first word, argument values after that.
+func (r *Registry) Complete(line string) []string {
+	words, open, err := Split(line)
+	if err != nil {
+		return nil
+	}
+	if !open {
+		words = append(words, "")
+	}
+	prefix := words[len(words)-1]
+	if len(words) == 1 {
+		var names []string
+		for _, c := range r.Commands() {
+			names = append(names, c.Name)
+		}
+		return withPrefix(names, prefix)
+	}
+	cmd, err := r.Lookup(words[0])
+	if err != nil {
+		return nil
+	}
+	return cmd.CompleteArg(len(words)-2, prefix)
+}
+
+// Split breaks a line into words, honoring single and double quotes and
+// backslash escapes. open reports whether the line ends inside a word.
+func Split(line string) (words []string, open bool, err error) {
+	words, _, open, err = split(line)
+	return words, open, err
+}
+
+// split is Split that also returns the byte offset each word starts at.
+func split(line string) (words []string, starts []int, open bool, err error) {
+	var (
+		word    strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+	begin := func(i int) {
+		if !inWord {
+			starts = append(starts, i)
+			inWord = true
+		}
+	}
+	for i, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			begin(i)
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			begin(i)
+			quote = r
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			begin(i)
+			word.WriteRune(r)
+		}
+	}
+	if quote != 0 {
+		return nil, nil, false, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, starts, inWord, nil
+}
+
+// CommonPrefix returns the longest prefix shared by every candidate.
+func CommonPrefix(candidates []string) string {
+	if len(candidates) == 0 {
+		return ""
+	}
+	prefix := candidates[0]
+	for _, c := range candidates[1:] {
+		for !strings.HasPrefix(c, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+func withPrefix(values []string, prefix string) []string {
+	var out []string
+	for _, v := range values {
+		if strings.HasPrefix(v, prefix) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testRegistry() *Registry {
+	return Builtin(Sources{
+		Profiles:   func() []string { return []string{"personal", "work"} },
+		SortModes:  func() []string { return []string{"date", "sender", "subject"} },
+		GroupModes: func() []string { return []string{"none", "date", "domain"} },
+		Settings:   func() []string { return []string{"preview.wrap"} },
+		Contacts:   func() []string { return []string{"alice@example.com", "bob@example.com"} },
+	})
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		line  string
+		words []string
+		open  bool
+	}{
+		{"move archive", []string{"move", "archive"}, true},
+		{"move ", []string{"move"}, false},
+		{`search "exact phrase" from:bob`, []string{"search", "exact phrase", "from:bob"}, true},
+		{`export mbox 'my mail.mbox'`, []string{"export", "mbox", "my mail.mbox"}, true},
+		{`export mbox my\ mail.mbox`, []string{"export", "mbox", "my mail.mbox"}, true},
+		{"", nil, false},
+	}
+	for _, tt := range tests {
+		words, open, err := Split(tt.line)
+		if err != nil {
+			t.Fatalf("Split(%q) error = %v", tt.line, err)
+		}
+		if !reflect.DeepEqual(words, tt.words) || open != tt.open {
+			t.Errorf("Split(%q) = %q, %v; want %q, %v", tt.line, words, open, tt.words, tt.open)
+		}
+	}
+	if _, _, err := Split(`search "open`); err == nil {
+		t.Error("unterminated quote should fail")
+	}
+}
+
+func TestRegistryParse(t *testing.T) {
+	r := testRegistry()
+	tests := []struct {
+		line    string
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{line: "move archive", name: "move", args: []string{"archive"}},
+		{line: "mo trash", name: "move", args: []string{"trash"}},
+		{line: "search from:bob is:unread", name: "search", args: []string{"from:bob is:unread"}},
+		{line: `search  "exact phrase"  from:bob`, name: "search", args: []string{`"exact phrase"  from:bob`}},
+		{line: "export mbox ~/x.mbox", name: "export", args: []string{"mbox", "~/x.mbox"}},
+		{line: "set preview.wrap 80", name: "set", args: []string{"preview.wrap", "80"}},
+		{line: "move nowhere", wantErr: `unknown folder "nowhere"`},
+		{line: "profile", wantErr: "usage: profile <name>"},
+		{line: "folder inbox extra", wantErr: "usage: folder <folder>"},
+		{line: "s", wantErr: "ambiguous command"},
+		{line: "explode", wantErr: "unknown command"},
+	}
+	for _, tt := range tests {
+		call, err := r.Parse(tt.line)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse(%q) error = %v, want %q", tt.line, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.line, err)
+		}
+		if call.Command.Name != tt.name || !reflect.DeepEqual(call.Args, tt.args) {
+			t.Errorf("Parse(%q) = %s %q, want %s %q", tt.line, call.Command.Name, call.Args, tt.name, tt.args)
+		}
+	}
+}
+
+func TestRegistryComplete(t *testing.T) {
+	r := testRegistry()
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"", []string{"compose", "export", "folder", "group", "move", "profile", "search", "set", "sort"}},
+		{"s", []string{"search", "set", "sort"}},
+		{"move a", []string{"archive"}},
+		{"folder ", []string{"inbox", "archive", "sent", "drafts", "trash"}},
+		{"profile w", []string{"work"}},
+		{"sort s", []string{"sender", "subject"}},
+		{"group d", []string{"date", "domain"}},
+		{"set p", []string{"preview.wrap"}},
+		{"export m", []string{"mbox"}},
+		{"export mbox ", nil},
+		{"search from:", nil},
+		{"compose a", []string{"alice@example.com"}},
+	}
+	for _, tt := range tests {
+		if got := r.Complete(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Complete(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestCommandUsage(t *testing.T) {
+	r := testRegistry()
+	for name, want := range map[string]string{
+		"move":    "move <folder>",
+		"search":  "search <query...>",
+		"export":  "export <format> <path>",
+		"compose": "compose [to...]",
+	} {
+		cmd, _ := r.Lookup(name)
+		if got := cmd.Usage(); got != want {
+			t.Errorf("%s Usage() = %q, want %q", name, got, want)
+		}
+	}
+	if got := CommonPrefix([]string{"sender", "subject"}); got != "s" {
+		t.Errorf("CommonPrefix() = %q", got)
+	}
+}
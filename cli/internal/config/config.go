@@ -4,22 +4,152 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 )
 
 // Profile represents a Mercury account configuration
 type Profile struct {
-	Email   string `toml:"email"`
-	APIKey  string `toml:"api_key,omitempty"`
-	OPItem  string `toml:"op_item,omitempty"`
-	OPField string `toml:"op_field,omitempty"`
+	Email   string      `toml:"email"`
+	APIKey  string      `toml:"api_key,omitempty"`
+	OPItem  string      `toml:"op_item,omitempty"`
+	OPField string      `toml:"op_field,omitempty"`
+	PGP     PGPConfig   `toml:"pgp,omitempty"`
+	SMIME   SMIMEConfig `toml:"smime,omitempty"`
+}
+
+// PGPConfig selects a profile's OpenPGP keys. Paths may start with "~/".
+type PGPConfig struct {
+	// Keyring is a GnuPG home directory, or a keyring file of public keys.
+	// Empty uses gpg's default home.
+	Keyring string `toml:"keyring,omitempty"`
+	// KeyFiles are exported key files to use instead of a keyring.
+	KeyFiles []string `toml:"key_files,omitempty"`
+	// SignKey is the fingerprint, key ID or address to sign with and to
+	// encrypt sent mail to. Empty uses gpg's default key.
+	SignKey string `toml:"sign_key,omitempty"`
+}
+
+// SMIMEConfig holds a profile's S/MIME certificate and the authorities it
+// trusts. Paths may start with "~/".
+type SMIMEConfig struct {
+	// Cert is a PEM certificate to sign with, optionally followed by its
+	// intermediates.
+	Cert string `toml:"cert,omitempty"`
+	// Key is the certificate's unencrypted PEM private key.
+	Key string `toml:"key,omitempty"`
+	// TrustStore is a PEM bundle, or a directory of certificates, that
+	// signers must chain to. Empty uses the system trust store.
+	TrustStore string `toml:"trust_store,omitempty"`
+}
+
+// CacheConfig controls the local message cache
+type CacheConfig struct {
+	Dir       string `toml:"dir,omitempty"`
+	MaxSizeMB int    `toml:"max_size_mb,omitempty"`
+	Disabled  bool   `toml:"disabled,omitempty"`
+}
+
+// DefaultRefreshInterval is how often the TUI polls for new mail
+const DefaultRefreshInterval = time.Minute
+
+// DefaultReaderWidth is the TUI reader's wrap column
+const DefaultReaderWidth = 80
+
+// TUIConfig controls the interactive client
+type TUIConfig struct {
+	// RefreshInterval is a Go duration such as "30s"; "0" disables polling.
+	RefreshInterval string `toml:"refresh_interval,omitempty"`
+	// Notify is how new mail is announced: "bell" (default), "title" or "none".
+	Notify string `toml:"notify,omitempty"`
+	// Keys overrides key bindings by action name, e.g. delete = ["ctrl+d"].
+	Keys map[string]KeyList `toml:"keys,omitempty"`
+	// Theme names a built-in theme (dark, light, high-contrast) or one
+	// defined under Themes.
+	Theme string `toml:"theme,omitempty"`
+	// Themes defines user themes as color overrides by role, with an
+	// optional "base" naming the built-in theme they start from.
+	Themes map[string]map[string]string `toml:"themes,omitempty"`
+	// ReaderWidth is the reader mode wrap column; 0 fills the window.
+	ReaderWidth *int `toml:"reader_width,omitempty"`
+	// Opener is the command that opens links, run with the URL appended;
+	// empty uses open on macOS and xdg-open elsewhere.
+	Opener string `toml:"opener,omitempty"`
+}
+
+// KeyList is one or more keys; config may give a single string or an array
+type KeyList []string
+
+// UnmarshalTOML accepts either a string or an array of strings
+func (k *KeyList) UnmarshalTOML(v interface{}) error {
+	switch v := v.(type) {
+	case string:
+		*k = KeyList{v}
+	case []interface{}:
+		keys := make(KeyList, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("key %v is not a string", item)
+			}
+			keys[i] = s
+		}
+		*k = keys
+	default:
+		return fmt.Errorf("keys must be a string or an array of strings, got %T", v)
+	}
+	return nil
+}
+
+// ReaderWrap returns the reader wrap column, 0 meaning the window width
+func (t TUIConfig) ReaderWrap() int {
+	if t.ReaderWidth == nil {
+		return DefaultReaderWidth
+	}
+	return *t.ReaderWidth
+}
+
+// Refresh returns the polling interval, or 0 when polling is disabled
+func (t TUIConfig) Refresh() (time.Duration, error) {
+	if t.RefreshInterval == "" {
+		return DefaultRefreshInterval, nil
+	}
+	if t.RefreshInterval == "0" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(t.RefreshInterval)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("negative duration %q", t.RefreshInterval)
+	}
+	return d, nil
 }
 
 // Config represents the Mercury CLI configuration
 type Config struct {
 	Default  string             `toml:"default"`
 	Profiles map[string]Profile `toml:"profiles"`
+	Cache    CacheConfig        `toml:"cache,omitempty"`
+	TUI      TUIConfig          `toml:"tui,omitempty"`
+	// AuthServers are the authserv-ids whose Authentication-Results are
+	// trusted. Empty trusts the relay's own, mx.cloudflare.net.
+	AuthServers []string `toml:"authserv_ids,omitempty"`
+}
+
+// ExpandHome resolves a leading "~/" in a configured path.
+func ExpandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
 }
 
 // ConfigPath returns the path to the config file.
@@ -28,6 +158,35 @@ var ConfigPath = func() string {
 	return filepath.Join(home, ".config", "mercury", "config.toml")
 }
 
+// StatePath returns the path to the TUI state file, which remembers view
+// settings between sessions. It follows XDG_STATE_HOME.
+var StatePath = func() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "mercury", "tui.json")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "state", "mercury", "tui.json")
+}
+
+// DataDir returns the directory holding a profile's local data, such as
+// its contacts. It follows XDG_DATA_HOME; an empty profile is "default".
+var DataDir = func(profile string) (string, error) {
+	if strings.TrimSpace(profile) == "" {
+		profile = "default"
+	}
+	if strings.ContainsAny(profile, `/\`) || profile == "." || profile == ".." {
+		return "", fmt.Errorf("invalid profile name for data dir: %q", profile)
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "mercury", profile), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("locate data dir: %w", err)
+	}
+	return filepath.Join(home, ".local", "share", "mercury", profile), nil
+}
+
 // Load reads the config file, returning empty Config if not exists
 func Load() (*Config, error) {
 	path := ConfigPath()
@@ -47,6 +206,18 @@ func Load() (*Config, error) {
 		}
 	}
 
+	if _, err := cfg.TUI.Refresh(); err != nil {
+		return nil, fmt.Errorf("tui.refresh_interval: %w", err)
+	}
+	if cfg.TUI.ReaderWrap() < 0 {
+		return nil, fmt.Errorf("tui.reader_width: must be zero or positive")
+	}
+	switch cfg.TUI.Notify {
+	case "", "bell", "title", "none":
+	default:
+		return nil, fmt.Errorf("tui.notify: unknown value %q (want bell, title or none)", cfg.TUI.Notify)
+	}
+
 	return cfg, nil
 }
 
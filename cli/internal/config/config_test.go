@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestLoad_FileNotExists(t *testing.T) {
@@ -123,3 +124,141 @@ func TestGetProfile_NotFound(t *testing.T) {
 		t.Fatal("GetProfile() error = nil, want error")
 	}
 }
+
+func TestLoad_CacheSection(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.toml")
+	content := `[cache]
+dir = "/tmp/mercury-cache"
+max_size_mb = 50
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	originalPath := ConfigPath
+	ConfigPath = func() string { return configPath }
+	defer func() { ConfigPath = originalPath }()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Cache.Dir != "/tmp/mercury-cache" || cfg.Cache.MaxSizeMB != 50 || cfg.Cache.Disabled {
+		t.Errorf("Cache = %+v, want dir and max_size_mb set", cfg.Cache)
+	}
+}
+
+func TestLoad_ProfilePGP(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.toml")
+	content := `[profiles.work]
+email = "me@work.example"
+
+[profiles.work.pgp]
+key_files = ["~/keys/me.asc", "/etc/partner.asc"]
+sign_key = "0xABCDEF"
+
+[profiles.work.smime]
+cert = "~/certs/me.pem"
+key = "~/certs/me.key"
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	originalPath := ConfigPath
+	ConfigPath = func() string { return configPath }
+	defer func() { ConfigPath = originalPath }()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	pgp := cfg.Profiles["work"].PGP
+	if len(pgp.KeyFiles) != 2 || pgp.SignKey != "0xABCDEF" || pgp.Keyring != "" {
+		t.Errorf("PGP = %+v", pgp)
+	}
+	if smime := cfg.Profiles["work"].SMIME; smime.Cert != "~/certs/me.pem" || smime.Key != "~/certs/me.key" || smime.TrustStore != "" {
+		t.Errorf("SMIME = %+v", smime)
+	}
+
+	t.Setenv("HOME", "/home/me")
+	if got := ExpandHome(pgp.KeyFiles[0]); got != "/home/me/keys/me.asc" {
+		t.Errorf("ExpandHome = %q", got)
+	}
+	if got := ExpandHome(pgp.KeyFiles[1]); got != "/etc/partner.asc" {
+		t.Errorf("ExpandHome = %q", got)
+	}
+}
+
+func TestLoad_TUISection(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		refresh time.Duration
+		reader  int
+		wantErr bool
+	}{
+		{name: "default", content: "", refresh: DefaultRefreshInterval, reader: DefaultReaderWidth},
+		{name: "interval", content: "[tui]\nrefresh_interval = \"30s\"\nnotify = \"title\"\n", refresh: 30 * time.Second, reader: DefaultReaderWidth},
+		{name: "disabled", content: "[tui]\nrefresh_interval = \"0\"\n", refresh: 0, reader: DefaultReaderWidth},
+		{name: "reader fills window", content: "[tui]\nreader_width = 0\n", refresh: DefaultRefreshInterval, reader: 0},
+		{name: "bad reader width", content: "[tui]\nreader_width = -5\n", wantErr: true},
+		{name: "bad interval", content: "[tui]\nrefresh_interval = \"often\"\n", wantErr: true},
+		{name: "bad notify", content: "[tui]\nnotify = \"siren\"\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.toml")
+			if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+			originalPath := ConfigPath
+			ConfigPath = func() string { return configPath }
+			defer func() { ConfigPath = originalPath }()
+
+			cfg, err := Load()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Load() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if got, _ := cfg.TUI.Refresh(); got != tt.refresh {
+				t.Errorf("Refresh() = %v, want %v", got, tt.refresh)
+			}
+			if got := cfg.TUI.ReaderWrap(); got != tt.reader {
+				t.Errorf("ReaderWrap() = %d, want %d", got, tt.reader)
+			}
+		})
+	}
+}
+
+func TestLoad_TUIKeys(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	content := `[tui.keys]
+up = ["ctrl+p", "up"]
+delete = "ctrl+d"
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	originalPath := ConfigPath
+	ConfigPath = func() string { return configPath }
+	defer func() { ConfigPath = originalPath }()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := cfg.TUI.Keys["up"]; len(got) != 2 || got[0] != "ctrl+p" {
+		t.Errorf("up = %v", got)
+	}
+	if got := cfg.TUI.Keys["delete"]; len(got) != 1 || got[0] != "ctrl+d" {
+		t.Errorf("delete = %v", got)
+	}
+}
@@ -0,0 +1,350 @@
+// Package contacts keeps a profile's address book: people added by hand or
+// imported from vCards, and people collected from the mail the user reads
+// and sends, ranked by how often and how recently they appear.
+package contacts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+)
+
+// FileName is the contacts file in a profile's data directory.
+const FileName = "contacts.json"
+
+// HalfLife is how long it takes a contact's score to halve without mail.
+const HalfLife = 30 * 24 * time.Hour
+
+// Contact is one address in the book.
+type Contact struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email"`
+	// Count is how many times the address was seen in mail.
+	Count int `json:"count,omitempty"`
+	// Last is when the address was last seen, added or imported.
+	Last time.Time `json:"last,omitempty"`
+	// Saved marks a contact added by hand or imported, not just collected.
+	Saved bool `json:"saved,omitempty"`
+}
+
+// String formats the contact as an address, as in "Alice <alice@example.com>".
+func (c Contact) String() string {
+	if c.Name == "" {
+		return c.Email
+	}
+	return (&mail.Address{Name: c.Name, Address: c.Email}).String()
+}
+
+// Score ranks the contact: one point per sighting, plus one for a saved
+// contact, decaying by half every HalfLife since it was last seen.
+func (c Contact) Score(now time.Time) float64 {
+	weight := float64(c.Count)
+	if c.Saved {
+		weight++
+	}
+	age := now.Sub(c.Last)
+	if age < 0 {
+		age = 0
+	}
+	return weight * math.Pow(0.5, float64(age)/float64(HalfLife))
+}
+
+// Book is a profile's contacts, saved as JSON. It is safe for concurrent
+// use.
+type Book struct {
+	// Self lists the user's own addresses, which are never collected.
+	Self []string
+
+	mu       sync.Mutex
+	path     string
+	contacts []Contact
+	groups   map[string][]string // members by group name, without the "@"
+}
+
+// file is the on-disk form of a Book.
+type file struct {
+	Contacts []Contact           `json:"contacts"`
+	Groups   map[string][]string `json:"groups,omitempty"`
+}
+
+// now is the clock, replaced in tests.
+var now = time.Now
+
+// Open reads the book saved at path. A missing file is an empty book.
+func Open(path string) (*Book, error) {
+	b := &Book{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read contacts: %w", err)
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse contacts %s: %w", path, err)
+	}
+	b.contacts, b.groups = f.Contacts, f.Groups
+	return b, nil
+}
+
+// Save writes the book, replacing the file atomically.
+func (b *Book) Save() error {
+	b.mu.Lock()
+	data, err := json.MarshalIndent(file{Contacts: b.contacts, Groups: b.groups}, "", "  ")
+	b.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(b.path), 0o700); err != nil {
+		return fmt.Errorf("save contacts: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(b.path), ".contacts-*")
+	if err != nil {
+		return fmt.Errorf("save contacts: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("save contacts: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("save contacts: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), b.path); err != nil {
+		return fmt.Errorf("save contacts: %w", err)
+	}
+	return nil
+}
+
+// List returns every contact, best first.
+func (b *Book) List() []Contact {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := append([]Contact(nil), b.contacts...)
+	rank(out)
+	return out
+}
+
+// rank sorts contacts by score, then name and address.
+func rank(cs []Contact) {
+	t := now()
+	sort.SliceStable(cs, func(i, j int) bool {
+		si, sj := cs[i].Score(t), cs[j].Score(t)
+		if si != sj {
+			return si > sj
+		}
+		return strings.ToLower(cs[i].String()) < strings.ToLower(cs[j].String())
+	})
+}
+
+// index returns the position of address in the book, or -1.
+func (b *Book) index(address string) int {
+	for i, c := range b.contacts {
+		if strings.EqualFold(c.Email, address) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Add saves a contact, or updates the name of an existing one. address may
+// include the name, as in "Alice <alice@example.com>"; a non-empty name
+// takes precedence.
+func (b *Book) Add(name, address string) (Contact, error) {
+	addr, err := mail.ParseAddress(address)
+	if err != nil {
+		return Contact{}, fmt.Errorf("invalid address %q", address)
+	}
+	if name == "" {
+		name = addr.Name
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.merge(Contact{Name: name, Email: addr.Address, Last: now(), Saved: true})
+	return c, nil
+}
+
+// merge adds c or folds it into the contact with the same address.
+func (b *Book) merge(c Contact) Contact {
+	i := b.index(c.Email)
+	if i < 0 {
+		b.contacts = append(b.contacts, c)
+		return c
+	}
+	existing := &b.contacts[i]
+	if c.Name != "" && (c.Saved || existing.Name == "") {
+		existing.Name = c.Name
+	}
+	existing.Count += c.Count
+	if c.Last.After(existing.Last) {
+		existing.Last = c.Last
+	}
+	existing.Saved = existing.Saved || c.Saved
+	return *existing
+}
+
+// Remove deletes the contact query resolves to.
+func (b *Book) Remove(query string) (Contact, error) {
+	c, err := b.Resolve(query)
+	if err != nil {
+		return Contact{}, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if i := b.index(c.Email); i >= 0 {
+		b.contacts = append(b.contacts[:i], b.contacts[i+1:]...)
+		return c, nil
+	}
+	return Contact{}, fmt.Errorf("no contact %s", c.Email)
+}
+
+// Observe records each address in list, a header value such as
+// "Alice <alice@example.com>, bob@example.com", as seen at time at. It
+// returns how many addresses were recorded.
+func (b *Book) Observe(list string, at time.Time) int {
+	addrs, err := mail.ParseAddressList(list)
+	if err != nil {
+		// A lone bare address parses even where the list does not.
+		addr, err := mail.ParseAddress(strings.TrimSpace(list))
+		if err != nil {
+			return 0
+		}
+		addrs = []*mail.Address{addr}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := 0
+	for _, addr := range addrs {
+		if b.isSelf(addr.Address) {
+			continue
+		}
+		b.merge(Contact{Name: api.DecodeHeader(addr.Name), Email: addr.Address, Count: 1, Last: at})
+		n++
+	}
+	return n
+}
+
+// ObserveEmail records the sender of a received email, or the recipients
+// of a sent one.
+func (b *Book) ObserveEmail(e *api.Email) int {
+	at, err := api.ParseTimestamp(e.ReceivedAt)
+	if err != nil {
+		at = now()
+	}
+	if e.Folder == "sent" {
+		return b.Observe(e.Recipient, at)
+	}
+	return b.Observe(e.Sender, at)
+}
+
+func (b *Book) isSelf(address string) bool {
+	for _, self := range b.Self {
+		if strings.EqualFold(strings.TrimSpace(self), address) {
+			return true
+		}
+	}
+	return false
+}
+
+// Find returns the contacts matching query, best first: by address prefix,
+// or by a prefix of any word of the name.
+func (b *Book) Find(query string) []Contact {
+	query = strings.ToLower(strings.TrimSpace(query))
+	var out []Contact
+	for _, c := range b.List() {
+		if query == "" || matches(c, query) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func matches(c Contact, query string) bool {
+	if strings.HasPrefix(strings.ToLower(c.Email), query) {
+		return true
+	}
+	name := strings.ToLower(c.Name)
+	if strings.HasPrefix(name, query) {
+		return true
+	}
+	for _, word := range strings.Fields(name) {
+		if strings.HasPrefix(word, query) {
+			return true
+		}
+	}
+	return false
+}
+
+// exact reports a match on the whole name, first name or mailbox.
+func exact(c Contact, query string) bool {
+	name := strings.ToLower(c.Name)
+	local, _, _ := strings.Cut(strings.ToLower(c.Email), "@")
+	first, _, _ := strings.Cut(name, " ")
+	return name == query || first == query || local == query || strings.EqualFold(c.Email, query)
+}
+
+// Resolve finds the one contact query names. An address is returned as is,
+// known or not; a name must match a single contact, or a single one
+// exactly, by whole name, first name or mailbox.
+func (b *Book) Resolve(query string) (Contact, error) {
+	query = strings.TrimSpace(query)
+	if strings.Contains(query, "@") {
+		addr, err := mail.ParseAddress(query)
+		if err != nil {
+			return Contact{}, fmt.Errorf("invalid address %q", query)
+		}
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if i := b.index(addr.Address); i >= 0 {
+			return b.contacts[i], nil
+		}
+		return Contact{Name: addr.Name, Email: addr.Address}, nil
+	}
+	found := b.Find(query)
+	if len(found) > 1 {
+		var exacts []Contact
+		for _, c := range found {
+			if exact(c, strings.ToLower(query)) {
+				exacts = append(exacts, c)
+			}
+		}
+		if len(exacts) > 0 {
+			found = exacts
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Contact{}, fmt.Errorf("no contact matches %q", query)
+	case 1:
+		return found[0], nil
+	}
+	names := make([]string, 0, 3)
+	for _, c := range found[:min(len(found), 3)] {
+		names = append(names, c.String())
+	}
+	if len(found) > 3 {
+		names = append(names, "...")
+	}
+	return Contact{}, fmt.Errorf("%q matches %d contacts: %s", query, len(found), strings.Join(names, ", "))
+}
+
+// Addresses lists every address, best first, for completion.
+func (b *Book) Addresses() []string {
+	list := b.List()
+	out := make([]string, len(list))
+	for i, c := range list {
+		out[i] = c.Email
+	}
+	return out
+}
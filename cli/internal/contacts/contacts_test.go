@@ -0,0 +1,240 @@
+package contacts
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+)
+
+var testNow = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+func testBook(t *testing.T) *Book {
+	t.Helper()
+	now = func() time.Time { return testNow }
+	t.Cleanup(func() { now = time.Now })
+	b, err := Open(filepath.Join(t.TempDir(), "profile", FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestBook_ObserveAndRank(t *testing.T) {
+	b := testBook(t)
+	b.Self = []string{"me@example.com"}
+	old := testNow.Add(-90 * 24 * time.Hour)
+	for i := 0; i < 4; i++ {
+		b.Observe("Old Friend <old@example.com>", old)
+	}
+	b.Observe(`"Alice Smith" <alice@example.com>, bob@example.com, Me <me@example.com>`, testNow)
+	b.Observe("alice@example.com", testNow.Add(-time.Hour))
+
+	list := b.List()
+	if len(list) != 3 {
+		t.Fatalf("contacts = %+v", list)
+	}
+	// Four sightings three half-lives ago score below two today.
+	if list[0].Email != "alice@example.com" || list[0].Name != "Alice Smith" || list[0].Count != 2 || !list[0].Last.Equal(testNow) {
+		t.Errorf("first = %+v", list[0])
+	}
+	if list[2].Email != "old@example.com" {
+		t.Errorf("order = %v", b.Addresses())
+	}
+
+	received := &api.Email{Sender: "Carol <carol@example.com>", Folder: "inbox", ReceivedAt: "2026-10-17 09:00:00"}
+	sent := &api.Email{Sender: "me@example.com", Recipient: "dave@example.com", Folder: "sent"}
+	if b.ObserveEmail(received) != 1 || b.ObserveEmail(sent) != 1 {
+		t.Fatal("email not observed")
+	}
+	if _, err := b.Resolve("carol"); err != nil {
+		t.Error(err)
+	}
+	if _, err := b.Resolve("dave"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBook_Resolve(t *testing.T) {
+	b := testBook(t)
+	b.Add("Alice Smith", "alice@example.com")
+	b.Add("Alicia Keys", "ak@example.com")
+	b.Add("", "Bob Jones <bob@work.example>")
+	b.Add("Robert Jones", "robert@example.com")
+	b.Add("Bobby Tables", "bobby@example.com")
+
+	tests := []struct {
+		query string
+		want  string
+		err   string
+	}{
+		{query: "bob", want: "bob@work.example"}, // the exact first name beats "bobby"
+		{query: "alice", want: "alice@example.com"},
+		{query: "Smith", want: "alice@example.com"},
+		{query: "new@example.com", want: "new@example.com"},
+		{query: "ali", err: `"ali" matches 2 contacts: `},
+		{query: "jones", err: `"jones" matches 2 contacts`},
+		{query: "zed", err: `no contact matches "zed"`},
+	}
+	for _, tt := range tests {
+		c, err := b.Resolve(tt.query)
+		switch {
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("Resolve(%q) error = %v, want %q", tt.query, err, tt.err)
+		case tt.err == "" && (err != nil || c.Email != tt.want):
+			t.Errorf("Resolve(%q) = %+v, %v, want %s", tt.query, c, err, tt.want)
+		}
+	}
+
+	if _, err := b.Remove("bob"); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := b.Resolve("jones"); c.Email != "robert@example.com" {
+		t.Errorf("after rm, jones = %+v", c)
+	}
+}
+
+func TestBook_SaveOpen(t *testing.T) {
+	b := testBook(t)
+	b.Add("Alice", "alice@example.com")
+	if err := b.Save(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := Open(b.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := reopened.List(); len(list) != 1 || !list[0].Saved || list[0].Name != "Alice" {
+		t.Errorf("reopened = %+v", list)
+	}
+}
+
+func TestVCard(t *testing.T) {
+	card := "BEGIN:VCARD\r\nVERSION:3.0\r\nFN:Alice Smith\r\nN:Smith;Alice;;;\r\n" +
+		"EMAIL;TYPE=work:alice@work.example\r\nitem1.EMAIL;TYPE=INTERNET:alice@home.example\r\nEND:VCARD\r\n" +
+		"BEGIN:VCARD\nVERSION:2.1\nN;ENCODING=QUOTED-PRINTABLE:M=C3=BCller;J=\n=C3=BCrgen\nEMAIL:jm@example.com\nEND:VCARD\n" +
+		"BEGIN:VCARD\nFN:No Email\nEND:VCARD\n" +
+		"BEGIN:VCARD\nFN:Smith\\, Bob\nEMAIL:bob@ex\n ample.com\nEND:VCARD\n"
+	cards, err := ParseVCard(strings.NewReader(card))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"Alice Smith <alice@work.example>", "Alice Smith <alice@home.example>",
+		"Jürgen Müller <jm@example.com>", "Smith, Bob <bob@example.com>",
+	}
+	if len(cards) != len(want) {
+		t.Fatalf("cards = %+v", cards)
+	}
+	for i, c := range cards {
+		if got := c.Name + " <" + c.Email + ">"; got != want[i] {
+			t.Errorf("card %d = %q, want %q", i, got, want[i])
+		}
+	}
+
+	b := testBook(t)
+	b.Observe("alice@work.example", testNow)
+	read, added, err := b.Import(strings.NewReader(card))
+	if err != nil || read != 4 || added != 3 {
+		t.Errorf("Import = %d, %d, %v", read, added, err)
+	}
+
+	var out bytes.Buffer
+	if err := WriteVCard(&out, b.List()); err != nil {
+		t.Fatal(err)
+	}
+	again, err := ParseVCard(&out)
+	if err != nil || len(again) != 4 {
+		t.Fatalf("round trip = %+v, %v", again, err)
+	}
+	for _, c := range again {
+		if c.Email == "bob@example.com" && c.Name != "Smith, Bob" {
+			t.Errorf("escaped name = %q", c.Name)
+		}
+	}
+}
+
+func TestBook_Groups(t *testing.T) {
+	b := testBook(t)
+	b.Add("Alice Smith", "alice@example.com")
+	b.Add("Bob Jones", "bob@example.com")
+
+	if _, err := b.AddToGroup("board", "alice", "carol@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.AddToGroup("@All", "@board", "bob", "alice@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := b.Expand(`@all, "Jones, Bob" <bob@example.com>, dave@example.com`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var emails []string
+	for _, c := range got {
+		emails = append(emails, c.Email)
+	}
+	if want := "alice@example.com carol@example.com bob@example.com dave@example.com"; strings.Join(emails, " ") != want {
+		t.Errorf("Expand = %v, want %s", emails, want)
+	}
+	if got[0].Name != "Alice Smith" {
+		t.Errorf("members keep their contact names, got %+v", got[0])
+	}
+
+	for _, tt := range []struct {
+		group, member, err string
+	}{
+		{"board", "@all", "group cycle: @board -> @all -> @board"},
+		{"board", "@board", "group cycle: @board -> @board"},
+		{"board", "@nobody", `no group "@nobody"`},
+		{"bad name", "alice", "invalid group name"},
+		{"board", "zed", `no contact matches "zed"`},
+	} {
+		if _, err := b.AddToGroup(tt.group, tt.member); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("AddToGroup(%q, %q) error = %v, want %q", tt.group, tt.member, err, tt.err)
+		}
+	}
+	if g := b.Groups(); len(g) != 2 || len(g[1].Members) != 2 {
+		t.Errorf("a refused member changed the groups: %+v", g)
+	}
+
+	if err := b.Save(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := Open(b.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := reopened.GroupNames(); strings.Join(names, " ") != "@all @board" {
+		t.Errorf("reopened groups = %v", names)
+	}
+
+	if _, err := b.RemoveFromGroup("board", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.RemoveFromGroup("board", "alice"); err == nil {
+		t.Error("removing a missing member should fail")
+	}
+	if _, err := b.RemoveFromGroup("board"); err == nil || !strings.Contains(err.Error(), "@board is in @all") {
+		t.Errorf("removing a nested group: %v", err)
+	}
+	if _, err := b.RemoveFromGroup("all", "@board"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.RemoveFromGroup("board"); err != nil {
+		t.Fatal(err)
+	}
+	if names := b.GroupNames(); strings.Join(names, " ") != "@all" {
+		t.Errorf("groups = %v", names)
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	got := SplitList(` alice, "Smith, Bob" <bob@example.com>,, @board ,<a,b@example.com>`)
+	want := []string{"alice", `"Smith, Bob" <bob@example.com>`, "@board", "<a,b@example.com>"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("SplitList = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,281 @@
+package contacts
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GroupPrefix marks a group in a recipient list, as in "@board".
+const GroupPrefix = "@"
+
+// Group is a named distribution list. Its members are addresses and other
+// groups, written with GroupPrefix.
+type Group struct {
+	Name    string
+	Members []string
+}
+
+// IsGroup reports whether a recipient names a group rather than a person.
+func IsGroup(recipient string) bool {
+	return strings.HasPrefix(strings.TrimSpace(recipient), GroupPrefix)
+}
+
+// groupName normalizes "@Board" or "board" to "board" and checks that it is
+// a valid name: letters, digits, '.', '-' and '_', starting with a letter
+// or digit.
+func groupName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), GroupPrefix))
+	if name == "" {
+		return "", fmt.Errorf("group name required")
+	}
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+		case i > 0 && (r == '.' || r == '-' || r == '_'):
+		default:
+			return "", fmt.Errorf("invalid group name %q", GroupPrefix+name)
+		}
+	}
+	return name, nil
+}
+
+// Groups lists the groups by name.
+func (b *Book) Groups() []Group {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]Group, 0, len(b.groups))
+	for name, members := range b.groups {
+		out = append(out, Group{Name: name, Members: append([]string(nil), members...)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// GroupNames lists the groups as they are written in recipient lists, such
+// as "@board", for completion.
+func (b *Book) GroupNames() []string {
+	groups := b.Groups()
+	out := make([]string, len(groups))
+	for i, g := range groups {
+		out[i] = GroupPrefix + g.Name
+	}
+	return out
+}
+
+// AddToGroup adds members to a group, creating it if needed. A member is an
+// address, a contact name, which is stored as its address, or an existing
+// group. Adding a group that already contains this one is refused.
+func (b *Book) AddToGroup(name string, members ...string) (Group, error) {
+	name, err := groupName(name)
+	if err != nil {
+		return Group{}, err
+	}
+	resolved := make([]string, 0, len(members))
+	for _, m := range members {
+		member, err := b.member(m)
+		if err != nil {
+			return Group{}, err
+		}
+		resolved = append(resolved, member)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, m := range resolved {
+		if nested, ok := strings.CutPrefix(m, GroupPrefix); ok && b.groups[nested] == nil {
+			return Group{}, fmt.Errorf("no group %q", m)
+		}
+	}
+	previous, existed := b.groups[name]
+	current := append([]string(nil), previous...)
+	for _, m := range resolved {
+		if indexFold(current, m) < 0 {
+			current = append(current, m)
+		}
+	}
+	if b.groups == nil {
+		b.groups = make(map[string][]string)
+	}
+	b.groups[name] = current
+	if err := b.expandGroup(name, nil, make(map[string]bool), new([]Contact)); err != nil {
+		if existed {
+			b.groups[name] = previous
+		} else {
+			delete(b.groups, name)
+		}
+		return Group{}, err
+	}
+	return Group{Name: name, Members: append([]string(nil), current...)}, nil
+}
+
+// RemoveFromGroup removes members from a group, or the whole group when no
+// members are given. A group still nested in another is not removed.
+func (b *Book) RemoveFromGroup(name string, members ...string) (Group, error) {
+	name, err := groupName(name)
+	if err != nil {
+		return Group{}, err
+	}
+	// Members are matched as stored; names are resolved first, but a
+	// removed contact's address still matches literally.
+	targets := make([]string, len(members))
+	for i, m := range members {
+		targets[i] = strings.TrimSpace(m)
+		if resolved, err := b.member(m); err == nil {
+			targets[i] = resolved
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	current, ok := b.groups[name]
+	if !ok {
+		return Group{}, fmt.Errorf("no group %q", GroupPrefix+name)
+	}
+	if len(targets) == 0 {
+		for other, members := range b.groups {
+			if indexFold(members, GroupPrefix+name) >= 0 {
+				return Group{}, fmt.Errorf("%s is in %s; remove it there first", GroupPrefix+name, GroupPrefix+other)
+			}
+		}
+		delete(b.groups, name)
+		return Group{Name: name, Members: current}, nil
+	}
+	remaining := append([]string(nil), current...)
+	for _, t := range targets {
+		i := indexFold(remaining, t)
+		if i < 0 {
+			return Group{}, fmt.Errorf("%s is not in %s", t, GroupPrefix+name)
+		}
+		remaining = append(remaining[:i], remaining[i+1:]...)
+	}
+	b.groups[name] = remaining
+	return Group{Name: name, Members: remaining}, nil
+}
+
+// member normalizes a group member: a group as "@name", anything else as
+// the address it resolves to.
+func (b *Book) member(m string) (string, error) {
+	if IsGroup(m) {
+		name, err := groupName(m)
+		if err != nil {
+			return "", err
+		}
+		return GroupPrefix + name, nil
+	}
+	c, err := b.Resolve(m)
+	if err != nil {
+		return "", err
+	}
+	return c.Email, nil
+}
+
+// Expand resolves a recipient list, such as "alice, @board, bob@example.com",
+// into the people it names, in order and without duplicates: groups expand
+// to their members, nested groups included, and names resolve as with
+// Resolve.
+func (b *Book) Expand(list string) ([]Contact, error) {
+	var out []Contact
+	seen := make(map[string]bool)
+	for _, item := range SplitList(list) {
+		if IsGroup(item) {
+			name, err := groupName(item)
+			if err != nil {
+				return nil, err
+			}
+			b.mu.Lock()
+			err = b.expandGroup(name, nil, seen, &out)
+			b.mu.Unlock()
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		c, err := b.Resolve(item)
+		if err != nil {
+			return nil, err
+		}
+		if key := strings.ToLower(c.Email); !seen[key] {
+			seen[key] = true
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// expandGroup appends the members of a group to out, recursing into nested
+// groups. path holds the groups being expanded, to report cycles. The
+// caller holds b.mu.
+func (b *Book) expandGroup(name string, path []string, seen map[string]bool, out *[]Contact) error {
+	for i, p := range path {
+		if p == name {
+			cycle := append(append([]string(nil), path[i:]...), name)
+			return fmt.Errorf("group cycle: %s%s", GroupPrefix, strings.Join(cycle, " -> "+GroupPrefix))
+		}
+	}
+	members, ok := b.groups[name]
+	if !ok {
+		return fmt.Errorf("no group %q", GroupPrefix+name)
+	}
+	path = append(path[:len(path):len(path)], name)
+	for _, m := range members {
+		if nested, ok := strings.CutPrefix(m, GroupPrefix); ok {
+			if err := b.expandGroup(nested, path, seen, out); err != nil {
+				return err
+			}
+			continue
+		}
+		key := strings.ToLower(m)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		c := Contact{Email: m}
+		if i := b.index(m); i >= 0 {
+			c = b.contacts[i]
+		}
+		*out = append(*out, c)
+	}
+	return nil
+}
+
+// SplitList splits a recipient list at the commas that separate its items,
+// leaving those inside quoted names and angle brackets alone.
+func SplitList(list string) []string {
+	var items []string
+	var quoted bool
+	depth, start := 0, 0
+	for i := 0; i < len(list); i++ {
+		switch c := list[i]; {
+		case c == '\\' && quoted:
+			i++
+		case c == '"':
+			quoted = !quoted
+		case c == '<' && !quoted:
+			depth++
+		case c == '>' && !quoted && depth > 0:
+			depth--
+		case c == ',' && !quoted && depth == 0:
+			items = append(items, list[start:i])
+			start = i + 1
+		}
+	}
+	items = append(items, list[start:])
+	out := items[:0]
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// indexFold returns the position of s in values, ignoring case, or -1.
+func indexFold(values []string, s string) int {
+	for i, v := range values {
+		if strings.EqualFold(v, s) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,172 @@
+package contacts
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+)
+
+// ParseVCard reads the contacts in a vCard file (versions 2.1 to 4.0): one
+// contact per email address of each card, named by its FN, or its N when
+// FN is missing.
+func ParseVCard(r io.Reader) ([]Contact, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, fmt.Errorf("read vCard: %w", err)
+	}
+	var out []Contact
+	var name, structured string
+	var emails []string
+	inCard := false
+	for _, line := range lines {
+		prop, params, value, ok := splitProperty(line)
+		if !ok {
+			continue
+		}
+		switch {
+		case prop == "BEGIN" && strings.EqualFold(value, "VCARD"):
+			inCard, name, structured, emails = true, "", "", nil
+		case prop == "END" && strings.EqualFold(value, "VCARD"):
+			if !inCard {
+				continue
+			}
+			inCard = false
+			if name == "" {
+				name = structured
+			}
+			for _, email := range emails {
+				if addr, err := mail.ParseAddress(email); err == nil {
+					out = append(out, Contact{Name: name, Email: addr.Address, Saved: true})
+				}
+			}
+		case !inCard:
+		case prop == "FN":
+			name = decodeValue(params, value)
+		case prop == "N":
+			// Family;Given;Additional;Prefix;Suffix
+			parts := splitUnescaped(value, ';')
+			var words []string
+			for _, i := range []int{3, 1, 2, 0, 4} {
+				if i < len(parts) {
+					if word := decodeValue(params, parts[i]); word != "" {
+						words = append(words, word)
+					}
+				}
+			}
+			structured = strings.Join(words, " ")
+		case prop == "EMAIL":
+			emails = append(emails, strings.TrimPrefix(strings.TrimSpace(value), "mailto:"))
+		}
+	}
+	return out, nil
+}
+
+// unfold joins continuation lines, which start with a space or tab, and
+// vCard 2.1 quoted-printable soft line breaks.
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")):
+			lines[len(lines)-1] += line[1:]
+		case len(lines) > 0 && strings.HasSuffix(lines[len(lines)-1], "=") &&
+			strings.Contains(strings.ToUpper(lines[len(lines)-1]), "QUOTED-PRINTABLE"):
+			lines[len(lines)-1] = strings.TrimSuffix(lines[len(lines)-1], "=") + line
+		default:
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// splitProperty splits "item1.EMAIL;TYPE=work:value" into the upper-case
+// property name without its group, its parameters and the value.
+func splitProperty(line string) (string, []string, string, bool) {
+	colon := strings.IndexByte(line, ':')
+	if colon < 0 {
+		return "", nil, "", false
+	}
+	head := strings.Split(line[:colon], ";")
+	prop := strings.ToUpper(head[0])
+	if dot := strings.LastIndexByte(prop, '.'); dot >= 0 {
+		prop = prop[dot+1:]
+	}
+	return prop, head[1:], line[colon+1:], true
+}
+
+// decodeValue undoes quoted-printable and vCard escapes in a text value.
+func decodeValue(params []string, value string) string {
+	for _, p := range params {
+		if strings.EqualFold(p, "ENCODING=QUOTED-PRINTABLE") || strings.EqualFold(p, "QUOTED-PRINTABLE") {
+			if decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(value))); err == nil {
+				value = string(decoded)
+			}
+		}
+	}
+	r := strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return strings.TrimSpace(r.Replace(value))
+}
+
+// splitUnescaped splits s at sep, except where sep is escaped.
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	var cur strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == sep:
+			cur.WriteByte(sep)
+			i++
+		case s[i] == sep:
+			parts = append(parts, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(s[i])
+		}
+	}
+	return append(parts, cur.String())
+}
+
+// WriteVCard writes contacts as vCard 3.0, one card per contact.
+func WriteVCard(w io.Writer, contacts []Contact) error {
+	escape := strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\n", `\n`)
+	var b bytes.Buffer
+	for _, c := range contacts {
+		name := c.Name
+		if name == "" {
+			name = c.Email
+		}
+		b.WriteString("BEGIN:VCARD\r\nVERSION:3.0\r\n")
+		b.WriteString("FN:" + escape.Replace(name) + "\r\n")
+		b.WriteString("N:;" + escape.Replace(name) + ";;;\r\n")
+		b.WriteString("EMAIL;TYPE=INTERNET:" + c.Email + "\r\n")
+		b.WriteString("END:VCARD\r\n")
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// Import merges the cards in r into the book, returning how many contacts
+// were read and how many of them are new.
+func (b *Book) Import(r io.Reader) (read, added int, err error) {
+	cards, err := ParseVCard(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, c := range cards {
+		if b.index(c.Email) < 0 {
+			added++
+		}
+		c.Last = now()
+		b.merge(c)
+	}
+	return len(cards), added, nil
+}
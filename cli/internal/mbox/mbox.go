@@ -0,0 +1,102 @@
+// Package mbox writes emails in the mboxrd format.
+package mbox
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/mail"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+)
+
+// fromLine matches body lines that mboxrd quotes with an extra ">".
+var fromLine = regexp.MustCompile(`^>*From `)
+
+// Write appends emails to w, one mboxrd message each. Emails without a
+// raw message are written with headers rebuilt from their summary fields.
+func Write(w io.Writer, emails []api.Email) error {
+	bw := bufio.NewWriter(w)
+	for i := range emails {
+		if err := writeMessage(bw, &emails[i]); err != nil {
+			return fmt.Errorf("email #%d: %w", emails[i].ID, err)
+		}
+	}
+	return bw.Flush()
+}
+
+func writeMessage(w *bufio.Writer, e *api.Email) error {
+	if _, err := fmt.Fprintf(w, "From %s %s\n", envelopeSender(e.Sender), envelopeDate(e.ReceivedAt)); err != nil {
+		return err
+	}
+
+	raw := e.RawEmail
+	if strings.TrimSpace(raw) == "" {
+		raw = summaryMessage(e)
+	}
+	raw = strings.ReplaceAll(raw, "\r\n", "\n")
+	raw = strings.TrimSuffix(raw, "\n")
+	for _, line := range strings.Split(raw, "\n") {
+		if fromLine.MatchString(line) {
+			line = ">" + line
+		}
+		if _, err := w.WriteString(line + "\n"); err != nil {
+			return err
+		}
+	}
+	_, err := w.WriteString("\n")
+	return err
+}
+
+// envelopeSender returns the bare address for the "From " separator line
+func envelopeSender(sender string) string {
+	if addr, err := mail.ParseAddress(sender); err == nil {
+		return addr.Address
+	}
+	if fields := strings.Fields(sender); len(fields) == 1 {
+		return fields[0]
+	}
+	return "MAILER-DAEMON"
+}
+
+// envelopeDate formats received_at in asctime form, as mbox expects
+func envelopeDate(receivedAt string) string {
+	t, err := api.ParseTimestamp(receivedAt)
+	if err != nil {
+		t = time.Unix(0, 0).UTC()
+	}
+	return t.Format("Mon Jan _2 15:04:05 2006")
+}
+
+// summaryMessage rebuilds a header-only message from list fields
+func summaryMessage(e *api.Email) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "From: %s\n", e.Sender)
+	fmt.Fprintf(&sb, "To: %s\n", e.Recipient)
+	fmt.Fprintf(&sb, "Subject: %s\n", e.Subject)
+	if t, err := api.ParseTimestamp(e.ReceivedAt); err == nil {
+		fmt.Fprintf(&sb, "Date: %s\n", t.Format(time.RFC1123Z))
+	}
+	if e.MessageID != "" {
+		fmt.Fprintf(&sb, "Message-ID: %s\n", e.MessageID)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// WriteFile writes emails to a new mbox at path, replacing any file there.
+func WriteFile(path string, emails []api.Email) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := Write(f, emails); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,32 @@
+package mbox
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+)
+
+func TestWrite(t *testing.T) {
+	emails := []api.Email{
+		{
+			ID:         1,
+			Sender:     "Alice <alice@example.com>",
+			ReceivedAt: "2026-10-01 09:05:00",
+			RawEmail:   "From: alice@example.com\r\nSubject: Hi\r\n\r\nFrom the start\r\n>From quoted\r\nbye\r\n",
+		},
+		{ID: 2, Sender: "bob@example.com", Recipient: "me@example.com", Subject: "Summary only", ReceivedAt: "2026-10-02T10:00:00Z"},
+	}
+
+	var sb strings.Builder
+	if err := Write(&sb, emails); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	want := "From alice@example.com Thu Oct  1 09:05:00 2026\n" +
+		"From: alice@example.com\nSubject: Hi\n\n>From the start\n>>From quoted\nbye\n\n" +
+		"From bob@example.com Fri Oct  2 10:00:00 2026\n" +
+		"From: bob@example.com\nTo: me@example.com\nSubject: Summary only\nDate: Fri, 02 Oct 2026 10:00:00 +0000\n\n\n"
+	if got := sb.String(); got != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", got, want)
+	}
+}
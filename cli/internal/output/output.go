@@ -0,0 +1,244 @@
+// Package output renders command results in machine-readable formats.
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+// Format identifies how command results are written.
+type Format string
+
+const (
+	FormatTable Format = "table"
+	FormatJSON  Format = "json"
+	FormatJSONL Format = "jsonl"
+	FormatCSV   Format = "csv"
+	FormatTSV   Format = "tsv"
+)
+
+// Formats lists every supported format in the order shown in help text.
+var Formats = []Format{FormatTable, FormatJSON, FormatJSONL, FormatCSV, FormatTSV}
+
+// ParseFormat validates a user-supplied format name.
+func ParseFormat(s string) (Format, error) {
+	value := Format(strings.ToLower(strings.TrimSpace(s)))
+	if value == "" {
+		return FormatTable, nil
+	}
+	for _, f := range Formats {
+		if f == value {
+			return f, nil
+		}
+	}
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = string(f)
+	}
+	return "", fmt.Errorf("invalid output format %q (valid: %s)", s, strings.Join(names, ", "))
+}
+
+// Printer writes records as JSON, JSON Lines, CSV, TSV or a Go template.
+// Records are structs (or slices of structs); their json tags define the
+// field names used by every structured format.
+type Printer struct {
+	w      io.Writer
+	format Format
+	tmpl   *template.Template
+}
+
+// New creates a Printer. A non-empty tmpl takes precedence over the table
+// format but cannot be combined with another structured format.
+func New(w io.Writer, format, tmpl string) (*Printer, error) {
+	f, err := ParseFormat(format)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Printer{w: w, format: f}
+	if tmpl == "" {
+		return p, nil
+	}
+	if f != FormatTable {
+		return nil, fmt.Errorf("--template cannot be combined with --output %s", f)
+	}
+	if !strings.HasSuffix(tmpl, "\n") {
+		tmpl += "\n"
+	}
+	p.tmpl, err = template.New("output").Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+	return p, nil
+}
+
+// Human reports whether the caller should print its own human-readable output.
+func (p *Printer) Human() bool {
+	return p.format == FormatTable && p.tmpl == nil
+}
+
+// Print writes v, which must be a struct, a pointer to a struct, or a slice of either.
+func (p *Printer) Print(v interface{}) error {
+	records, elemType, isList := flatten(v)
+
+	if p.tmpl != nil {
+		for _, r := range records {
+			if err := p.tmpl.Execute(p.w, r.Interface()); err != nil {
+				return fmt.Errorf("execute template: %w", err)
+			}
+		}
+		return nil
+	}
+
+	switch p.format {
+	case FormatJSON:
+		enc := json.NewEncoder(p.w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if isList {
+			items := make([]interface{}, len(records))
+			for i, r := range records {
+				items[i] = r.Interface()
+			}
+			return enc.Encode(items)
+		}
+		return enc.Encode(records[0].Interface())
+	case FormatJSONL:
+		enc := json.NewEncoder(p.w)
+		enc.SetEscapeHTML(false)
+		for _, r := range records {
+			if err := enc.Encode(r.Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatCSV, FormatTSV:
+		return p.writeDelimited(records, elemType)
+	default:
+		return fmt.Errorf("format %s must be rendered by the caller", p.format)
+	}
+}
+
+func (p *Printer) writeDelimited(records []reflect.Value, elemType reflect.Type) error {
+	cols := Columns(elemType)
+	cw := csv.NewWriter(p.w)
+	if p.format == FormatTSV {
+		cw.Comma = '\t'
+	}
+
+	header := make([]string, len(cols))
+	for i, c := range cols {
+		header[i] = c.Name
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		row := make([]string, len(cols))
+		for i, c := range cols {
+			row[i] = formatValue(r.FieldByIndex(c.index))
+			if p.format == FormatTSV {
+				row[i] = tsvReplacer.Replace(row[i])
+			}
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// tsvReplacer keeps TSV rows on one line without relying on quoting.
+var tsvReplacer = strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ")
+
+// Column is one field of a record as it appears in CSV and TSV headers.
+type Column struct {
+	Name  string
+	index []int
+}
+
+// Columns returns the json-tagged fields of a record type in declaration
+// order. Embedded structs are flattened the same way encoding/json does.
+func Columns(t reflect.Type) []Column {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var cols []Column
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+			for _, c := range Columns(field.Type) {
+				cols = append(cols, Column{Name: c.Name, index: append([]int{i}, c.index...)})
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Name
+		if tag != "" {
+			name = strings.Split(tag, ",")[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+		}
+		cols = append(cols, Column{Name: name, index: []int{i}})
+	}
+	return cols
+}
+
+// flatten normalizes v into addressable struct values plus the element type.
+func flatten(v interface{}) ([]reflect.Value, reflect.Type, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice {
+		elemType := rv.Type().Elem()
+		records := make([]reflect.Value, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			records = append(records, deref(rv.Index(i)))
+		}
+		return records, elemType, true
+	}
+	rv = deref(rv)
+	return []reflect.Value{rv}, rv.Type(), false
+}
+
+func deref(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	return v
+}
+
+func formatValue(v reflect.Value) string {
+	v = deref(v)
+	if !v.IsValid() {
+		return ""
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Slice, reflect.Array:
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = formatValue(v.Index(i))
+		}
+		return strings.Join(parts, ";")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,169 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type testRecord struct {
+	ID      int    `json:"id"`
+	Subject string `json:"subject"`
+	Read    bool   `json:"read"`
+	secret  string
+}
+
+type testDetail struct {
+	testRecord
+	Body string `json:"body"`
+}
+
+func testRecords() []testRecord {
+	return []testRecord{
+		{ID: 1, Subject: "Hello, world", Read: true},
+		{ID: 2, Subject: "Tabs\tand\nnewlines"},
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Format
+		wantErr bool
+	}{
+		{"", FormatTable, false},
+		{"json", FormatJSON, false},
+		{" JSONL ", FormatJSONL, false},
+		{"csv", FormatCSV, false},
+		{"tsv", FormatTSV, false},
+		{"yaml", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFormat(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseFormat(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestNew_TemplateConflictsWithFormat(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "json", "{{.ID}}"); err == nil {
+		t.Error("expected error combining --template with json")
+	}
+	if _, err := New(&bytes.Buffer{}, "table", "{{.ID"); err == nil {
+		t.Error("expected parse error for invalid template")
+	}
+}
+
+func TestPrinter_Human(t *testing.T) {
+	p, _ := New(&bytes.Buffer{}, "table", "")
+	if !p.Human() {
+		t.Error("table without template should be human")
+	}
+	p, _ = New(&bytes.Buffer{}, "table", "{{.ID}}")
+	if p.Human() {
+		t.Error("template output should not be human")
+	}
+}
+
+func TestPrinter_Print(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		tmpl   string
+		value  interface{}
+		want   string
+	}{
+		{
+			name:   "json list",
+			format: "json",
+			value:  testRecords()[:1],
+			want:   "[\n  {\n    \"id\": 1,\n    \"subject\": \"Hello, world\",\n    \"read\": true\n  }\n]\n",
+		},
+		{
+			name:   "json empty list",
+			format: "json",
+			value:  []testRecord{},
+			want:   "[]\n",
+		},
+		{
+			name:   "json object",
+			format: "json",
+			value:  &testRecord{ID: 3},
+			want:   "{\n  \"id\": 3,\n  \"subject\": \"\",\n  \"read\": false\n}\n",
+		},
+		{
+			name:   "jsonl",
+			format: "jsonl",
+			value:  testRecords(),
+			want:   "{\"id\":1,\"subject\":\"Hello, world\",\"read\":true}\n{\"id\":2,\"subject\":\"Tabs\\tand\\nnewlines\",\"read\":false}\n",
+		},
+		{
+			name:   "csv",
+			format: "csv",
+			value:  testRecords()[:1],
+			want:   "id,subject,read\n1,\"Hello, world\",true\n",
+		},
+		{
+			name:   "tsv",
+			format: "tsv",
+			value:  testRecords(),
+			want:   "id\tsubject\tread\n1\tHello, world\ttrue\n2\tTabs and newlines\tfalse\n",
+		},
+		{
+			name:   "csv empty list keeps header",
+			format: "csv",
+			value:  []testRecord{},
+			want:   "id,subject,read\n",
+		},
+		{
+			name:   "csv flattens embedded struct",
+			format: "csv",
+			value:  testDetail{testRecord: testRecord{ID: 4, Subject: "s"}, Body: "b"},
+			want:   "id,subject,read,body\n4,s,false,b\n",
+		},
+		{
+			name:   "template",
+			format: "table",
+			tmpl:   "{{.ID}} {{.Subject}}",
+			value:  testRecords()[:1],
+			want:   "1 Hello, world\n",
+		},
+		{
+			name:   "template on embedded field",
+			format: "table",
+			tmpl:   "{{.ID}}:{{.Body}}",
+			value:  testDetail{testRecord: testRecord{ID: 4}, Body: "b"},
+			want:   "4:b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			p, err := New(&buf, tt.format, tt.tmpl)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if err := p.Print(tt.value); err != nil {
+				t.Fatalf("Print() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Print() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestPrinter_PrintTableIsCallerRendered(t *testing.T) {
+	p, _ := New(&bytes.Buffer{}, "table", "")
+	err := p.Print(testRecords())
+	if err == nil || !strings.Contains(err.Error(), "caller") {
+		t.Errorf("Print() error = %v, want caller-rendered error", err)
+	}
+}
@@ -0,0 +1,346 @@
+// Package pgp signs, encrypts, decrypts and verifies OpenPGP mail by
+// running gpg, following PGP/MIME (RFC 3156).
+package pgp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// gpgTimeout bounds a single gpg run. Signing and decrypting may wait on
+// a passphrase prompt, so it is generous.
+const gpgTimeout = 2 * time.Minute
+
+// Keyring runs gpg against one profile's keys.
+type Keyring struct {
+	// Home is a GnuPG home directory, or a keyring file holding public
+	// keys only. Empty uses gpg's default home.
+	Home string
+	// KeyFiles are key files imported into a temporary home, used instead
+	// of Home when set.
+	KeyFiles []string
+	// SignKey selects the signing key, as a fingerprint, key ID or
+	// address. Empty uses gpg's default key.
+	SignKey string
+	// Binary is the gpg executable, "gpg" when empty.
+	Binary string
+
+	mu      sync.Mutex
+	tmpHome string
+}
+
+// Close removes the temporary home created for KeyFiles.
+func (k *Keyring) Close() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.tmpHome == "" {
+		return nil
+	}
+	// The agent started for the temporary home would otherwise outlive it.
+	_ = exec.Command(gpgconf(k.binary()), "--homedir", k.tmpHome, "--kill", "gpg-agent").Run()
+	err := os.RemoveAll(k.tmpHome)
+	k.tmpHome = ""
+	return err
+}
+
+func (k *Keyring) binary() string {
+	if k.Binary != "" {
+		return k.Binary
+	}
+	return "gpg"
+}
+
+// gpgconf finds gpgconf next to the gpg binary.
+func gpgconf(gpg string) string {
+	if i := strings.LastIndex(gpg, "/"); i >= 0 {
+		return gpg[:i+1] + "gpgconf"
+	}
+	return "gpgconf"
+}
+
+// homeArgs selects the keys gpg should use, importing KeyFiles on first use.
+func (k *Keyring) homeArgs() ([]string, error) {
+	if len(k.KeyFiles) > 0 {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		if k.tmpHome == "" {
+			// A short path: the agent socket lives here and paths are limited.
+			dir, err := os.MkdirTemp("", "mercury-gpg-")
+			if err != nil {
+				return nil, fmt.Errorf("create keyring: %w", err)
+			}
+			args := append([]string{"--homedir", dir, "--batch", "--import"}, k.KeyFiles...)
+			if out, err := exec.Command(k.binary(), args...).CombinedOutput(); err != nil {
+				os.RemoveAll(dir)
+				return nil, fmt.Errorf("import key files: %s", strings.TrimSpace(string(out)))
+			}
+			k.tmpHome = dir
+		}
+		return []string{"--homedir", k.tmpHome}, nil
+	}
+	if k.Home == "" {
+		return nil, nil
+	}
+	info, err := os.Stat(k.Home)
+	if err != nil {
+		return nil, fmt.Errorf("keyring: %w", err)
+	}
+	if info.IsDir() {
+		return []string{"--homedir", k.Home}, nil
+	}
+	return []string{"--no-default-keyring", "--keyring", k.Home}, nil
+}
+
+// result is the output of one gpg run.
+type result struct {
+	stdout []byte
+	status []string // [GNUPG:] status lines, without the prefix
+	stderr string
+}
+
+// run executes gpg with machine-readable status on fd 3. A failing exit
+// still returns the result, since verification reports bad signatures
+// that way.
+func (k *Keyring) run(stdin []byte, args ...string) (*result, error) {
+	home, err := k.homeArgs()
+	if err != nil {
+		return nil, err
+	}
+	statusR, statusW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer statusR.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), gpgTimeout)
+	defer cancel()
+	full := append(append(home, "--batch", "--no-tty", "--status-fd", "3"), args...)
+	cmd := exec.CommandContext(ctx, k.binary(), full...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	cmd.ExtraFiles = []*os.File{statusW}
+
+	var status []byte
+	done := make(chan struct{})
+	go func() {
+		status, _ = io.ReadAll(statusR)
+		close(done)
+	}()
+	runErr := cmd.Run()
+	statusW.Close()
+	<-done
+
+	res := &result{stdout: stdout.Bytes(), stderr: strings.TrimSpace(stderr.String())}
+	for _, line := range strings.Split(string(status), "\n") {
+		if rest, ok := strings.CutPrefix(line, "[GNUPG:] "); ok {
+			res.status = append(res.status, strings.TrimSpace(rest))
+		}
+	}
+	if errors.Is(runErr, exec.ErrNotFound) {
+		return nil, fmt.Errorf("gpg not found; install GnuPG to use OpenPGP")
+	}
+	var exitErr *exec.ExitError
+	if runErr != nil && !errors.As(runErr, &exitErr) {
+		return nil, fmt.Errorf("gpg: %w", runErr)
+	}
+	if runErr != nil {
+		return res, &gpgError{stderr: res.stderr}
+	}
+	return res, nil
+}
+
+// gpgError is a non-zero gpg exit, described by its last stderr line.
+type gpgError struct {
+	stderr string
+}
+
+func (e *gpgError) Error() string {
+	lines := strings.Split(e.stderr, "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+		return "gpg: " + strings.TrimPrefix(last, "gpg: ")
+	}
+	return "gpg failed"
+}
+
+func (k *Keyring) signerArgs() []string {
+	if k.SignKey == "" {
+		return nil
+	}
+	return []string{"--local-user", k.SignKey}
+}
+
+// Sign returns an armored detached signature of data, hashed with SHA-256
+// to match the micalg PGP/MIME advertises.
+func (k *Keyring) Sign(data []byte) ([]byte, error) {
+	args := append([]string{"--armor", "--detach-sign", "--digest-algo", "SHA256"}, k.signerArgs()...)
+	res, err := k.run(data, args...)
+	if err != nil {
+		return nil, err
+	}
+	return res.stdout, nil
+}
+
+// ClearSign wraps text in an inline cleartext signature.
+func (k *Keyring) ClearSign(text []byte) ([]byte, error) {
+	args := append([]string{"--clearsign", "--digest-algo", "SHA256"}, k.signerArgs()...)
+	res, err := k.run(text, args...)
+	if err != nil {
+		return nil, err
+	}
+	return res.stdout, nil
+}
+
+// Encrypt encrypts data to the recipients' keys, signing it too when
+// sign is set. Hidden recipients, such as Bcc, get a copy without their
+// key IDs appearing in the message. The signing key is added as a
+// recipient so the sender can read their own copy.
+func (k *Keyring) Encrypt(data []byte, recipients, hidden []string, sign bool) ([]byte, error) {
+	if len(recipients)+len(hidden) == 0 {
+		return nil, fmt.Errorf("no recipients to encrypt to")
+	}
+	args := []string{"--armor", "--encrypt"}
+	for _, r := range recipients {
+		args = append(args, "--recipient", r)
+	}
+	for _, r := range hidden {
+		args = append(args, "--hidden-recipient", r)
+	}
+	if k.SignKey != "" {
+		args = append(args, "--encrypt-to", k.SignKey)
+	}
+	if sign {
+		args = append(args, "--sign", "--digest-algo", "SHA256")
+		args = append(args, k.signerArgs()...)
+	}
+	res, err := k.run(data, args...)
+	if err != nil {
+		return nil, err
+	}
+	return res.stdout, nil
+}
+
+// Decrypt decrypts an OpenPGP message, or unwraps a cleartext signed one,
+// reporting any signature it carried. Plaintext of an encrypted message is
+// returned only when gpg reports a complete, integrity-protected
+// decryption, so tampered or partially decrypted content is never shown.
+func (k *Keyring) Decrypt(data []byte) ([]byte, Verification, error) {
+	res, err := k.run(data, "--decrypt")
+	if res == nil {
+		return nil, Verification{}, err
+	}
+	v := parseVerification(res.status)
+	if d := parseDecryption(res.status); d.encrypted {
+		if derr := d.err(); derr != nil {
+			return nil, v, derr
+		}
+		// A bad signature fails the run but the content is still readable.
+		return res.stdout, v, nil
+	}
+	if err != nil && v.Status == NoSignature {
+		return nil, v, err
+	}
+	// A bad signature fails the run but the content is still readable.
+	return res.stdout, v, nil
+}
+
+// decryption is what gpg's status lines say about decrypting a message.
+type decryption struct {
+	encrypted bool // the input was encrypted, not just signed
+	okay      bool // DECRYPTION_OKAY
+	failed    bool // DECRYPTION_FAILED
+	integrity bool // the integrity check (MDC or AEAD) failed or was missing
+}
+
+func parseDecryption(status []string) decryption {
+	var d decryption
+	for _, line := range status {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "ENC_TO", "BEGIN_DECRYPTION", "NO_SECKEY", "DECRYPTION_KEY":
+			d.encrypted = true
+		case "DECRYPTION_OKAY":
+			d.encrypted, d.okay = true, true
+		case "DECRYPTION_FAILED":
+			d.encrypted, d.failed = true, true
+		case "BADMDC":
+			d.encrypted, d.integrity = true, true
+		case "DECRYPTION_INFO":
+			// DECRYPTION_INFO <mdc_method> <sym_algo> [<aead_algo>]: neither
+			// an MDC nor AEAD means the message has no integrity protection.
+			d.encrypted = true
+			noMDC := len(fields) > 1 && fields[1] == "0"
+			noAEAD := len(fields) < 4 || fields[3] == "0"
+			if noMDC && noAEAD {
+				d.integrity = true
+			}
+		}
+	}
+	return d
+}
+
+// err explains why decrypted plaintext cannot be trusted, or is nil.
+func (d decryption) err() error {
+	switch {
+	case d.integrity:
+		return errors.New("integrity check failed; the message may have been tampered with")
+	case d.failed || !d.okay:
+		return errors.New("decryption failed")
+	}
+	return nil
+}
+
+// userIDs lists the user IDs of the key with fingerprint.
+func (k *Keyring) userIDs(fingerprint string) ([]string, error) {
+	res, err := k.run(nil, "--with-colons", "--fixed-list-mode", "--list-keys", fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	var uids []string
+	for _, line := range strings.Split(string(res.stdout), "\n") {
+		fields := strings.Split(line, ":")
+		if fields[0] == "uid" && len(fields) > 9 {
+			// Colons inside the user ID are escaped as \x3a.
+			uids = append(uids, strings.ReplaceAll(fields[9], `\x3a`, ":"))
+		}
+	}
+	return uids, nil
+}
+
+// Verify checks a detached signature over data.
+func (k *Keyring) Verify(data, signature []byte) (Verification, error) {
+	f, err := os.CreateTemp("", "mercury-sig-*.asc")
+	if err != nil {
+		return Verification{}, err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(signature); err != nil {
+		f.Close()
+		return Verification{}, err
+	}
+	f.Close()
+
+	res, err := k.run(data, "--verify", f.Name(), "-")
+	if res == nil {
+		return Verification{}, err
+	}
+	v := parseVerification(res.status)
+	if v.Status == NoSignature {
+		if err == nil {
+			err = fmt.Errorf("no signature found")
+		}
+		return v, err
+	}
+	return v, nil
+}
@@ -0,0 +1,330 @@
+package pgp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+)
+
+// Kind is the OpenPGP protection an email carries.
+type Kind int
+
+const (
+	None Kind = iota
+	Signed
+	Encrypted
+)
+
+// Result is an opened OpenPGP email.
+type Result struct {
+	Encrypted bool
+	// Signature is nil when the content was not signed.
+	Signature *Verification
+	// Body is the plain text of the signed or decrypted content.
+	Body string
+	// Content is the signed or decrypted MIME entity, for further parsing.
+	Content *api.Email
+}
+
+// Badge describes the result in a short line for headers, as in
+// "Encrypted · Good signature from Alice <alice@example.com> (full trust)".
+func (r *Result) Badge() string {
+	var parts []string
+	if r.Encrypted {
+		parts = append(parts, "Encrypted")
+	}
+	if r.Signature != nil {
+		parts = append(parts, r.Signature.Summary())
+	} else {
+		parts = append(parts, "Not signed")
+	}
+	return strings.Join(parts, " · ")
+}
+
+const (
+	armoredMessage = "-----BEGIN PGP MESSAGE-----"
+	armoredSigned  = "-----BEGIN PGP SIGNED MESSAGE-----"
+)
+
+// entity is a MIME entity split into its header and raw body.
+type entity struct {
+	header    textproto.MIMEHeader
+	mediaType string
+	params    map[string]string
+	body      []byte
+}
+
+func parseEntity(raw []byte) (*entity, error) {
+	r := textproto.NewReader(bufio.NewReader(bytes.NewReader(raw)))
+	header, err := r.ReadMIMEHeader()
+	if err != nil && len(header) == 0 {
+		return nil, fmt.Errorf("parse MIME header: %w", err)
+	}
+	var body bytes.Buffer
+	_, _ = body.ReadFrom(r.R)
+	e := &entity{header: header, body: body.Bytes()}
+	e.mediaType, e.params, err = mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		e.mediaType, e.params = "text/plain", map[string]string{}
+	}
+	return e, nil
+}
+
+// decoded returns the body with its transfer encoding removed.
+func (e *entity) decoded() []byte {
+	switch strings.ToLower(strings.TrimSpace(e.header.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		clean := strings.Join(strings.Fields(string(e.body)), "")
+		data, err := base64.StdEncoding.DecodeString(clean)
+		if err != nil {
+			return e.body
+		}
+		return data
+	case "quoted-printable":
+		var out bytes.Buffer
+		if _, err := out.ReadFrom(quotedprintable.NewReader(bytes.NewReader(e.body))); err != nil {
+			return e.body
+		}
+		return out.Bytes()
+	}
+	return e.body
+}
+
+// Detect reports the protection of an email without running gpg: PGP/MIME
+// (RFC 3156), the same parts flattened into multipart/mixed by a relay, or
+// inline armor in a plain text body.
+func Detect(email *api.Email) Kind {
+	if strings.TrimSpace(email.RawEmail) == "" {
+		return None
+	}
+	e, err := parseEntity(api.Canonical(email.RawEmail))
+	if err != nil {
+		return None
+	}
+	return e.kind()
+}
+
+func (e *entity) kind() Kind {
+	switch {
+	case e.mediaType == "multipart/encrypted" && strings.EqualFold(e.params["protocol"], "application/pgp-encrypted"):
+		return Encrypted
+	case e.mediaType == "multipart/signed" && strings.EqualFold(e.params["protocol"], "application/pgp-signature"):
+		return Signed
+	case e.mediaType == "multipart/mixed" && e.flattenedCiphertext() != nil:
+		return Encrypted
+	case e.mediaType == "text/plain":
+		text := string(e.decoded())
+		switch {
+		case strings.Contains(text, armoredMessage):
+			return Encrypted
+		case strings.Contains(text, armoredSigned):
+			return Signed
+		}
+	}
+	return None
+}
+
+// Open decrypts and verifies a protected email. Content signed inside an
+// encrypted message (RFC 3156 section 6.1) is verified too. A good
+// signature by a key with no user ID for the From address is marked with
+// WrongSender, since any trusted key could otherwise vouch for anyone.
+func (k *Keyring) Open(email *api.Email) (*Result, error) {
+	e, err := parseEntity(api.Canonical(email.RawEmail))
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{}
+	if err := k.open(e, res, 0); err != nil {
+		return nil, err
+	}
+	if sig := res.Signature; sig != nil && sig.Status == Good {
+		from := email.FromAddress()
+		uids, _ := k.userIDs(sig.Fingerprint)
+		if from == "" || !hasAddress(append(uids, sig.Signer), from) {
+			sig.WrongSender = from
+			if from == "" {
+				sig.WrongSender = "an unknown sender"
+			}
+		}
+	}
+	if res.Content != nil && res.Body == "" {
+		res.Body = res.Content.Body()
+	}
+	return res, nil
+}
+
+func (k *Keyring) open(e *entity, res *Result, depth int) error {
+	if depth > 3 {
+		return fmt.Errorf("OpenPGP content nested too deeply")
+	}
+	switch e.kind() {
+	case Signed:
+		if e.mediaType == "text/plain" {
+			return k.openInline(e, res)
+		}
+		parts := api.SplitMultipart(e.body, e.params["boundary"])
+		if len(parts) != 2 {
+			return fmt.Errorf("multipart/signed has %d parts, want 2", len(parts))
+		}
+		sig, err := parseEntity(parts[1])
+		if err != nil {
+			return err
+		}
+		v, err := k.Verify(parts[0], sig.decoded())
+		if err != nil && v.Status == NoSignature {
+			return fmt.Errorf("verify signature: %w", err)
+		}
+		res.Signature = &v
+		res.Content = &api.Email{RawEmail: string(parts[0])}
+		return nil
+
+	case Encrypted:
+		if e.mediaType == "text/plain" {
+			return k.openInline(e, res)
+		}
+		ciphertext := e.flattenedCiphertext()
+		if e.mediaType == "multipart/encrypted" {
+			parts := api.SplitMultipart(e.body, e.params["boundary"])
+			if len(parts) != 2 {
+				return fmt.Errorf("multipart/encrypted has %d parts, want 2", len(parts))
+			}
+			payload, err := parseEntity(parts[1])
+			if err != nil {
+				return err
+			}
+			ciphertext = payload.decoded()
+		}
+		plain, v, err := k.Decrypt(ciphertext)
+		if err != nil {
+			return fmt.Errorf("decrypt: %w", err)
+		}
+		res.Encrypted = true
+		if v.Status != NoSignature {
+			res.Signature = &v
+		}
+		inner, err := parseEntity(api.Canonical(string(plain)))
+		if err != nil {
+			return err
+		}
+		if inner.kind() != None {
+			return k.open(inner, res, depth+1)
+		}
+		res.Content = &api.Email{RawEmail: string(plain)}
+		return nil
+	}
+	return fmt.Errorf("email is not signed or encrypted with OpenPGP")
+}
+
+// openInline handles armor pasted into a plain text body.
+func (k *Keyring) openInline(e *entity, res *Result) error {
+	text := string(e.decoded())
+	start := strings.Index(text, "-----BEGIN PGP ")
+	plain, v, err := k.Decrypt([]byte(text[start:]))
+	if err != nil {
+		return fmt.Errorf("decrypt: %w", err)
+	}
+	res.Encrypted = strings.HasPrefix(text[start:], armoredMessage)
+	if v.Status != NoSignature {
+		res.Signature = &v
+	}
+	res.Body = strings.TrimSpace(string(plain))
+	return nil
+}
+
+// flattenedCiphertext finds PGP/MIME parts a relay flattened into
+// multipart/mixed: an application/pgp-encrypted version part followed by
+// the encrypted payload.
+func (e *entity) flattenedCiphertext() []byte {
+	if e.mediaType != "multipart/mixed" {
+		return nil
+	}
+	version := false
+	for _, raw := range api.SplitMultipart(e.body, e.params["boundary"]) {
+		part, err := parseEntity(raw)
+		if err != nil {
+			continue
+		}
+		if part.mediaType == "application/pgp-encrypted" {
+			version = true
+			continue
+		}
+		if data := part.decoded(); version && bytes.Contains(data, []byte(armoredMessage)) {
+			return data
+		}
+	}
+	return nil
+}
+
+// Protect signs and/or encrypts req as a PGP/MIME message (RFC 3156):
+// multipart/signed with a detached signature, or multipart/encrypted. The
+// text and any attachments become the protected entity, and the result is
+// sent as a raw message so no relay re-encodes it.
+func (k *Keyring) Protect(req *api.SendRequest, sign, encrypt bool) error {
+	if req.HTML != "" {
+		return fmt.Errorf("OpenPGP messages must be plain text")
+	}
+	if !sign && !encrypt {
+		return nil
+	}
+	content := req.Entity()
+	boundary := api.NewBoundary()
+	var b bytes.Buffer
+	if !encrypt {
+		signature, err := k.Sign(content)
+		if err != nil {
+			return fmt.Errorf("sign: %w", err)
+		}
+		b.WriteString("Content-Type: multipart/signed; micalg=pgp-sha256;\r\n")
+		b.WriteString("\tprotocol=\"application/pgp-signature\"; boundary=\"" + boundary + "\"\r\n\r\n")
+		b.WriteString("This is an OpenPGP/MIME signed message (RFC 3156).\r\n")
+		b.WriteString("--" + boundary + "\r\n")
+		b.Write(content)
+		b.WriteString("\r\n--" + boundary + "\r\n")
+		b.WriteString("Content-Type: application/pgp-signature; name=\"signature.asc\"\r\n")
+		b.WriteString("Content-Description: OpenPGP digital signature\r\n\r\n")
+		b.Write(api.Canonical(string(signature)))
+		b.WriteString("\r\n--" + boundary + "--\r\n")
+		req.ComposeRaw(b.Bytes())
+		return nil
+	}
+
+	// Bcc recipients are hidden so the message does not list their keys.
+	ciphertext, err := k.Encrypt(content, addresses(req.To+","+req.Cc), addresses(req.Bcc), sign)
+	if err != nil {
+		return fmt.Errorf("encrypt: %w", err)
+	}
+	b.WriteString("Content-Type: multipart/encrypted;\r\n")
+	b.WriteString("\tprotocol=\"application/pgp-encrypted\"; boundary=\"" + boundary + "\"\r\n\r\n")
+	b.WriteString("This is an OpenPGP/MIME encrypted message (RFC 3156).\r\n")
+	b.WriteString("--" + boundary + "\r\n")
+	b.WriteString("Content-Type: application/pgp-encrypted\r\n")
+	b.WriteString("Content-Description: PGP/MIME version identification\r\n\r\n")
+	b.WriteString("Version: 1\r\n")
+	b.WriteString("\r\n--" + boundary + "\r\n")
+	b.WriteString("Content-Type: application/octet-stream; name=\"encrypted.asc\"\r\n")
+	b.WriteString("Content-Description: OpenPGP encrypted message\r\n")
+	b.WriteString("Content-Disposition: inline; filename=\"encrypted.asc\"\r\n\r\n")
+	b.Write(api.Canonical(string(ciphertext)))
+	b.WriteString("\r\n--" + boundary + "--\r\n")
+	req.ComposeRaw(b.Bytes())
+	return nil
+}
+
+// addresses parses a comma-separated recipient list into bare addresses.
+func addresses(list string) []string {
+	var out []string
+	for _, to := range strings.Split(list, ",") {
+		if addr, err := mail.ParseAddress(strings.TrimSpace(to)); err == nil {
+			out = append(out, addr.Address)
+		}
+	}
+	return out
+}
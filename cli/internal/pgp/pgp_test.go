@@ -0,0 +1,377 @@
+package pgp
+
+import (
+	"encoding/base64"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+)
+
+// testKeyring creates a home with a fresh, passphrase-less key for uid.
+func testKeyring(t *testing.T, uid string) *Keyring {
+	t.Helper()
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not installed")
+	}
+	home, err := os.MkdirTemp("", "gpgtest-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = exec.Command("gpgconf", "--homedir", home, "--kill", "gpg-agent").Run()
+		os.RemoveAll(home)
+	})
+	if uid != "" {
+		out, err := exec.Command("gpg", "--homedir", home, "--batch", "--passphrase", "",
+			"--quick-gen-key", uid, "future-default", "default", "never").CombinedOutput()
+		if err != nil {
+			t.Fatalf("generate key: %v\n%s", err, out)
+		}
+	}
+	return &Keyring{Home: home}
+}
+
+// relayed renders req as the relay delivers it: multipart/mixed with the
+// text first and each attachment base64 encoded.
+func relayed(req *api.SendRequest) *api.Email {
+	var b strings.Builder
+	b.WriteString("From: " + req.From + "\r\nTo: " + req.To + "\r\n")
+	b.WriteString("Content-Type: multipart/mixed; boundary=relay\r\n\r\n")
+	b.WriteString("--relay\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n" + req.Text + "\r\n")
+	for _, a := range req.Attachments {
+		b.WriteString("--relay\r\nContent-Type: " + a.ContentType + "\r\n")
+		b.WriteString("Content-Disposition: attachment; filename=" + a.Filename + "\r\n")
+		b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n" + a.Content + "\r\n")
+	}
+	b.WriteString("--relay--\r\n")
+	return &api.Email{RawEmail: b.String()}
+}
+
+func TestProtect_EncryptAndSign(t *testing.T) {
+	k := testKeyring(t, "Alice <alice@example.com>")
+	req := &api.SendRequest{From: "alice@example.com", To: "Alice <alice@example.com>", Subject: "Secret", Text: "Meet at noon.\nBring the keys."}
+	if err := k.Protect(req, true, true); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(req.Raw, "noon") || req.Text != "" || !strings.Contains(req.Raw, "Content-Type: multipart/encrypted;") ||
+		!strings.Contains(req.Raw, `protocol="application/pgp-encrypted"`) || !strings.Contains(req.Raw, "Subject: Secret\r\n") {
+		t.Fatalf("request = %+v", req)
+	}
+
+	email := &api.Email{RawEmail: req.Raw}
+	if Detect(email) != Encrypted {
+		t.Fatal("PGP/MIME not detected")
+	}
+	res, err := k.Open(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Encrypted || res.Signature == nil || !res.Signature.Trusted() {
+		t.Fatalf("result = %+v, signature %+v", res, res.Signature)
+	}
+	if res.Body != "Meet at noon.\r\nBring the keys." && res.Body != "Meet at noon.\nBring the keys." {
+		t.Errorf("body = %q", res.Body)
+	}
+	if want := "Encrypted · Good signature from Alice <alice@example.com> (ultimate trust)"; res.Badge() != want {
+		t.Errorf("badge = %q", res.Badge())
+	}
+
+	stranger := testKeyring(t, "")
+	if _, err := stranger.Open(email); err == nil || !strings.Contains(err.Error(), "decrypt") {
+		t.Errorf("open without the secret key: %v", err)
+	}
+}
+
+func TestProtect_HiddenBcc(t *testing.T) {
+	k := testKeyring(t, "Alice <alice@example.com>")
+	bob := testKeyring(t, "Bob <bob@example.com>")
+	exported, err := exec.Command("gpg", "--homedir", bob.Home, "--armor", "--export", "bob@example.com").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("gpg", "--homedir", k.Home, "--batch", "--import")
+	cmd.Stdin = strings.NewReader(string(exported))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("import: %v\n%s", err, out)
+	}
+	cmd = exec.Command("gpg", "--homedir", k.Home, "--batch", "--yes", "--quick-lsign-key", fingerprint(t, bob), "bob@example.com")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("sign bob's key: %v\n%s", err, out)
+	}
+
+	req := &api.SendRequest{From: "alice@example.com", To: "alice@example.com", Bcc: "Bob <bob@example.com>", Subject: "Hi", Text: "For Bob too."}
+	if err := k.Protect(req, false, true); err != nil {
+		t.Fatal(err)
+	}
+	email := &api.Email{RawEmail: req.Raw}
+	res, err := bob.Open(email)
+	if err != nil || res.Body != "For Bob too." {
+		t.Fatalf("Bcc recipient open = %+v, %v", res, err)
+	}
+
+	payload := email.Parts()[len(email.Parts())-1].Body
+	cmd = exec.Command("gpg", "--homedir", k.Home, "--batch", "--list-only", "--list-packets")
+	cmd.Stdin = strings.NewReader(string(payload))
+	packets, _ := cmd.CombinedOutput()
+	// Alice's key ID is listed; Bob's is replaced by zeros.
+	if strings.Count(string(packets), ":pubkey enc packet:") != 2 || strings.Count(string(packets), "keyid 0000000000000000") != 1 {
+		t.Errorf("Bcc key ID not hidden:\n%s", packets)
+	}
+}
+
+// fingerprint returns the fingerprint of the only key in k.
+func fingerprint(t *testing.T, k *Keyring) string {
+	t.Helper()
+	out, err := exec.Command("gpg", "--homedir", k.Home, "--with-colons", "--list-keys").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if fields := strings.Split(line, ":"); fields[0] == "fpr" {
+			return fields[9]
+		}
+	}
+	t.Fatal("no fingerprint")
+	return ""
+}
+
+func TestOpen_SignerNotSender(t *testing.T) {
+	k := testKeyring(t, "Alice <alice@example.com>")
+	req := &api.SendRequest{From: "ceo@example.com", To: "bob@example.com", Subject: "Wire", Text: "Pay them."}
+	if err := k.Protect(req, true, false); err != nil {
+		t.Fatal(err)
+	}
+	res, err := k.Open(&api.Email{RawEmail: req.Raw})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := res.Signature
+	if sig == nil || sig.Status != Good || sig.Trusted() || sig.WrongSender != "ceo@example.com" ||
+		!strings.Contains(res.Badge(), "key is not for ceo@example.com") {
+		t.Errorf("signature = %+v, badge %q", sig, res.Badge())
+	}
+}
+
+func TestOpen_RelayedCiphertext(t *testing.T) {
+	// Messages sent before PGP/MIME went out raw carried its parts as
+	// attachments of a multipart/mixed message.
+	k := testKeyring(t, "Alice <alice@example.com>")
+	ciphertext, err := k.Encrypt(api.TextEntity("Old secret"), []string{"alice@example.com"}, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	email := relayed(&api.SendRequest{
+		From: "alice@example.com", To: "alice@example.com",
+		Text: "This is an OpenPGP/MIME encrypted message (RFC 3156).",
+		Attachments: []api.Attachment{
+			{Filename: "version.txt", ContentType: "application/pgp-encrypted", Content: base64.StdEncoding.EncodeToString([]byte("Version: 1\r\n"))},
+			{Filename: "encrypted.asc", ContentType: "application/octet-stream", Content: base64.StdEncoding.EncodeToString(ciphertext)},
+		},
+	})
+	res, err := k.Open(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Encrypted || res.Body != "Old secret" {
+		t.Errorf("result = %+v", res)
+	}
+}
+
+func TestProtect_SignOnly(t *testing.T) {
+	k := testKeyring(t, "Alice <alice@example.com>")
+	req := &api.SendRequest{From: "alice@example.com", To: "bob@example.com", Subject: "Hi", Text: "Signed hello",
+		Attachments: []api.Attachment{{Filename: "a.txt", ContentType: "text/plain", Content: base64.StdEncoding.EncodeToString([]byte("attached"))}}}
+	if err := k.Protect(req, true, false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(req.Raw, "Content-Type: multipart/signed; micalg=pgp-sha256;") || req.Attachments != nil {
+		t.Fatalf("request = %+v", req)
+	}
+	email := &api.Email{RawEmail: req.Raw}
+	if Detect(email) != Signed {
+		t.Fatal("multipart/signed not detected")
+	}
+	res, err := k.Open(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Encrypted || res.Signature == nil || res.Signature.Status != Good || res.Body != "Signed hello" {
+		t.Errorf("result = %+v", res)
+	}
+
+	stranger := testKeyring(t, "")
+	res, err = stranger.Open(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Signature.Status != UnknownKey || !strings.HasPrefix(res.Badge(), "Signed by unknown key ") {
+		t.Errorf("unknown signer: %+v", res.Signature)
+	}
+
+	tampered := &api.Email{RawEmail: strings.Replace(req.Raw, "Signed hello", "Signed HELLO", 1)}
+	if res, err := k.Open(tampered); err != nil || res.Signature.Status != Bad {
+		t.Errorf("tampered message: %+v, %v", res, err)
+	}
+}
+
+func TestOpen_MultipartSigned(t *testing.T) {
+	k := testKeyring(t, "Alice <alice@example.com>")
+	content := "Content-Type: text/plain; charset=utf-8\r\n\r\nThe quarterly numbers.\r\n"
+	sig, err := k.Sign([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := func(content string) *api.Email {
+		// LF line endings, as stored by some servers; signatures cover CRLF.
+		raw := "From: alice@example.com\r\n" +
+			"Content-Type: multipart/signed; micalg=pgp-sha256; protocol=\"application/pgp-signature\"; boundary=\"sig\"\r\n\r\n" +
+			"--sig\r\n" + content + "\r\n--sig\r\n" +
+			"Content-Type: application/pgp-signature; name=signature.asc\r\n\r\n" + string(sig) + "\r\n--sig--\r\n"
+		return &api.Email{RawEmail: strings.ReplaceAll(raw, "\r\n", "\n")}
+	}
+
+	res, err := k.Open(message(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Signature == nil || res.Signature.Status != Good || res.Signature.Fingerprint == "" || res.Body != "The quarterly numbers." {
+		t.Errorf("result = %+v, signature %+v", res, res.Signature)
+	}
+
+	res, err = k.Open(message(strings.Replace(content, "quarterly", "QUARTERLY", 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Signature.Status != Bad || !strings.HasPrefix(res.Signature.Summary(), "BAD signature") {
+		t.Errorf("tampered content verified: %+v", res.Signature)
+	}
+}
+
+func TestKeyring_KeyFiles(t *testing.T) {
+	source := testKeyring(t, "Alice <alice@example.com>")
+	exported, err := exec.Command("gpg", "--homedir", source.Home, "--armor", "--export", "alice@example.com").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := source.Home + "/alice.asc"
+	if err := os.WriteFile(file, exported, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sig, err := source.Sign([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k := &Keyring{KeyFiles: []string{file}}
+	defer k.Close()
+	v, err := k.Verify([]byte("hello"), sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Imported keys are not certified, so the signature is good but unverified.
+	if v.Status != Good || v.Trusted() || v.Summary() != "Good signature from Alice <alice@example.com> (unverified key)" {
+		t.Errorf("verification = %+v", v)
+	}
+	home := k.tmpHome
+	k.Close()
+	if _, err := os.Stat(home); !os.IsNotExist(err) {
+		t.Errorf("temporary home %s not removed", home)
+	}
+}
+
+func TestParseVerification(t *testing.T) {
+	v := parseVerification([]string{
+		"NEWSIG",
+		"GOODSIG 0123456789ABCDEF Bob %28work%29 <bob@example.com>",
+		"VALIDSIG FPR 2026-10-18 1760000000 0 4 0 22 8 00 FPR",
+		"TRUST_MARGINAL 0 pgp",
+	})
+	if v.Status != Good || v.Signer != "Bob (work) <bob@example.com>" || v.Fingerprint != "FPR" || v.Trust != TrustMarginal || v.Trusted() {
+		t.Errorf("verification = %+v", v)
+	}
+	v = parseVerification([]string{"ERRSIG 0123456789ABCDEF 22 8 00 1760000000 9 ABCDEF0123"})
+	if v.Status != UnknownKey || v.KeyID != "0123456789ABCDEF" || v.Fingerprint != "ABCDEF0123" {
+		t.Errorf("verification = %+v", v)
+	}
+	if v := parseVerification(nil); v.Status != NoSignature || v.Summary() != "Not signed" {
+		t.Errorf("verification = %+v", v)
+	}
+
+	// With several signatures the worst one is reported, whatever the order.
+	bad := []string{"NEWSIG", "BADSIG 1111111111111111 Mallory <m@example.com>"}
+	good := []string{"NEWSIG", "GOODSIG 2222222222222222 Bob <bob@example.com>", "VALIDSIG FPR2 x", "TRUST_ULTIMATE 0 pgp"}
+	unknown := []string{"NEWSIG", "ERRSIG 3333333333333333 22 8 00 1760000000 9 -"}
+	expired := []string{"NEWSIG", "EXPKEYSIG 4444444444444444 Old <old@example.com>"}
+	marginal := []string{"NEWSIG", "GOODSIG 5555555555555555 Carol <carol@example.com>", "TRUST_MARGINAL 0 pgp"}
+	join := func(groups ...[]string) []string {
+		var out []string
+		for _, g := range groups {
+			out = append(out, g...)
+		}
+		return out
+	}
+	for _, tt := range []struct {
+		status []string
+		want   SigStatus
+		keyID  string
+	}{
+		{join(bad, good), Bad, "1111111111111111"},
+		{join(good, bad), Bad, "1111111111111111"},
+		{join(good, unknown), UnknownKey, "3333333333333333"},
+		{join(expired, good), ExpiredKey, "4444444444444444"},
+		{join(good, marginal), Good, "5555555555555555"},
+		// gpg without NEWSIG lines still separates the results
+		{[]string{"BADSIG 1111111111111111 M", "GOODSIG 2222222222222222 B"}, Bad, "1111111111111111"},
+	} {
+		if v := parseVerification(tt.status); v.Status != tt.want || v.KeyID != tt.keyID {
+			t.Errorf("parseVerification(%q) = %+v, want %v from %s", tt.status, v, tt.want, tt.keyID)
+		}
+	}
+}
+
+func TestParseDecryption(t *testing.T) {
+	for _, tt := range []struct {
+		status []string
+		err    string
+	}{
+		{[]string{"ENC_TO ABC 18 0", "BEGIN_DECRYPTION", "DECRYPTION_INFO 2 9 0", "DECRYPTION_OKAY", "GOODMDC", "END_DECRYPTION"}, ""},
+		{[]string{"ENC_TO ABC 18 0", "BEGIN_DECRYPTION", "DECRYPTION_INFO 0 9 2", "DECRYPTION_OKAY", "END_DECRYPTION"}, ""},
+		{[]string{"ENC_TO ABC 18 0", "BEGIN_DECRYPTION", "DECRYPTION_INFO 2 9 0", "BADMDC", "DECRYPTION_OKAY"}, "integrity"},
+		{[]string{"BEGIN_DECRYPTION", "DECRYPTION_INFO 0 9 0", "DECRYPTION_OKAY"}, "integrity"},
+		{[]string{"ENC_TO ABC 18 0", "BEGIN_DECRYPTION", "DECRYPTION_OKAY", "DECRYPTION_FAILED"}, "decryption failed"},
+		{[]string{"ENC_TO ABC 18 0", "NO_SECKEY ABC", "BEGIN_DECRYPTION", "DECRYPTION_FAILED"}, "decryption failed"},
+		// a signature status alone does not vouch for the plaintext
+		{[]string{"ENC_TO ABC 18 0", "BEGIN_DECRYPTION", "GOODSIG ABC Alice"}, "decryption failed"},
+	} {
+		d := parseDecryption(tt.status)
+		err := d.err()
+		if !d.encrypted || (tt.err == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("parseDecryption(%q) = %+v, err %v; want %q", tt.status, d, err, tt.err)
+		}
+	}
+	if parseDecryption([]string{"NEWSIG", "GOODSIG ABC Alice"}).encrypted {
+		t.Error("cleartext signature treated as encrypted")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want Kind
+	}{
+		{"Content-Type: text/plain\r\n\r\nhello", None},
+		{"Content-Type: multipart/encrypted; protocol=\"application/pgp-encrypted\"; boundary=b\r\n\r\n--b--\r\n", Encrypted},
+		{"Content-Type: multipart/signed; protocol=\"application/pgp-signature\"; boundary=b\r\n\r\n--b--\r\n", Signed},
+		{"Content-Type: multipart/signed; protocol=\"application/pkcs7-signature\"; boundary=b\r\n\r\n--b--\r\n", None},
+		{"Content-Type: text/plain\r\nContent-Transfer-Encoding: base64\r\n\r\n" +
+			base64.StdEncoding.EncodeToString([]byte(armoredMessage+"\nxyz\n")), Encrypted},
+	}
+	for _, tt := range tests {
+		if got := Detect(&api.Email{RawEmail: tt.raw}); got != tt.want {
+			t.Errorf("Detect(%q) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
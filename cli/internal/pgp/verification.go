@@ -0,0 +1,233 @@
+package pgp
+
+import (
+	"net/mail"
+	"net/url"
+	"strings"
+)
+
+// SigStatus is the outcome of checking a signature.
+type SigStatus int
+
+const (
+	NoSignature SigStatus = iota
+	Good
+	Bad
+	// UnknownKey means the signer's public key is not in the keyring.
+	UnknownKey
+	ExpiredKey
+	RevokedKey
+	ExpiredSignature
+)
+
+func (s SigStatus) String() string {
+	switch s {
+	case Good:
+		return "good"
+	case Bad:
+		return "bad"
+	case UnknownKey:
+		return "unknown-key"
+	case ExpiredKey:
+		return "expired-key"
+	case RevokedKey:
+		return "revoked-key"
+	case ExpiredSignature:
+		return "expired-signature"
+	}
+	return "none"
+}
+
+// Trust is how far the keyring trusts that a key belongs to its user ID.
+type Trust string
+
+const (
+	TrustUnknown  Trust = ""
+	TrustNever    Trust = "never"
+	TrustMarginal Trust = "marginal"
+	TrustFull     Trust = "full"
+	TrustUltimate Trust = "ultimate"
+)
+
+// Verification is the result of checking a signature.
+type Verification struct {
+	Status      SigStatus
+	Signer      string // user ID of the signing key
+	KeyID       string
+	Fingerprint string
+	Trust       Trust
+	// WrongSender is the From address when no user ID of the signing key
+	// names it, so the key does not vouch for the sender.
+	WrongSender string
+}
+
+// Trusted reports a good signature by a key the keyring fully trusts,
+// issued for the address the message is from.
+func (v Verification) Trusted() bool {
+	return v.Status == Good && (v.Trust == TrustFull || v.Trust == TrustUltimate) && v.WrongSender == ""
+}
+
+// hasAddress reports whether one of uids, such as "Alice <alice@example.com>"
+// or a bare address, is for address.
+func hasAddress(uids []string, address string) bool {
+	for _, uid := range uids {
+		if addr, err := mail.ParseAddress(uid); err == nil && strings.EqualFold(addr.Address, address) {
+			return true
+		}
+	}
+	return false
+}
+
+// Summary describes the verification in a sentence fragment, as in
+// "Good signature from Alice <alice@example.com> (full trust)".
+func (v Verification) Summary() string {
+	who := v.Signer
+	if who == "" {
+		who = "key " + v.KeyID
+	}
+	switch v.Status {
+	case Good:
+		trust := "unverified key"
+		if v.Trust != TrustUnknown {
+			trust = string(v.Trust) + " trust"
+		}
+		if v.Trust == TrustNever {
+			trust = "key marked untrusted"
+		}
+		if v.WrongSender != "" {
+			trust += "; key is not for " + v.WrongSender
+		}
+		return "Good signature from " + who + " (" + trust + ")"
+	case Bad:
+		return "BAD signature claiming " + who
+	case UnknownKey:
+		return "Signed by unknown key " + v.KeyID
+	case ExpiredKey:
+		return "Signature from " + who + " with an expired key"
+	case RevokedKey:
+		return "Signature from " + who + " with a revoked key"
+	case ExpiredSignature:
+		return "Expired signature from " + who
+	}
+	return "Not signed"
+}
+
+// severity orders statuses from harmless to alarming, for combining the
+// signatures of a message.
+func (s SigStatus) severity() int {
+	switch s {
+	case Good:
+		return 1
+	case ExpiredSignature:
+		return 2
+	case ExpiredKey:
+		return 3
+	case UnknownKey:
+		return 4
+	case RevokedKey:
+		return 5
+	case Bad:
+		return 6
+	}
+	return 0
+}
+
+// trustRank orders trust levels, lowest first.
+func trustRank(t Trust) int {
+	switch t {
+	case TrustNever:
+		return 0
+	case TrustUnknown:
+		return 1
+	case TrustMarginal:
+		return 2
+	case TrustFull:
+		return 3
+	}
+	return 4
+}
+
+// parseVerification reads gpg's status lines. When a message carries
+// several signatures the worst one is reported, so a single bad or
+// unverifiable signature is never hidden behind a good one; among good
+// signatures, the least trusted is reported.
+func parseVerification(status []string) Verification {
+	var sigs []Verification
+	// current returns the signature the status lines describe, starting a
+	// new one at NEWSIG or when a second result line arrives without it.
+	current := func(result bool) *Verification {
+		if len(sigs) == 0 || (result && sigs[len(sigs)-1].Status != NoSignature) {
+			sigs = append(sigs, Verification{})
+		}
+		return &sigs[len(sigs)-1]
+	}
+	for _, line := range status {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		signer := func(status SigStatus) {
+			v := current(true)
+			v.Status = status
+			if len(fields) > 1 {
+				v.KeyID = fields[1]
+			}
+			if len(fields) > 2 {
+				uid := strings.Join(fields[2:], " ")
+				if decoded, err := url.PathUnescape(uid); err == nil {
+					uid = decoded
+				}
+				v.Signer = uid
+			}
+		}
+		switch fields[0] {
+		case "NEWSIG":
+			sigs = append(sigs, Verification{})
+		case "GOODSIG":
+			signer(Good)
+		case "BADSIG":
+			signer(Bad)
+		case "EXPSIG":
+			signer(ExpiredSignature)
+		case "EXPKEYSIG":
+			signer(ExpiredKey)
+		case "REVKEYSIG":
+			signer(RevokedKey)
+		case "ERRSIG":
+			// rc 9 is a missing public key; anything else is unverifiable too.
+			v := current(true)
+			v.Status = UnknownKey
+			if len(fields) > 1 {
+				v.KeyID = fields[1]
+			}
+			if len(fields) > 7 && fields[7] != "-" {
+				v.Fingerprint = fields[7]
+			}
+		case "VALIDSIG":
+			if len(fields) > 1 {
+				current(false).Fingerprint = fields[1]
+			}
+		case "TRUST_UNDEFINED":
+			current(false).Trust = TrustUnknown
+		case "TRUST_NEVER":
+			current(false).Trust = TrustNever
+		case "TRUST_MARGINAL":
+			current(false).Trust = TrustMarginal
+		case "TRUST_FULLY":
+			current(false).Trust = TrustFull
+		case "TRUST_ULTIMATE":
+			current(false).Trust = TrustUltimate
+		}
+	}
+
+	var worst Verification
+	for i, v := range sigs {
+		switch {
+		case i == 0, v.Status.severity() > worst.Status.severity():
+			worst = v
+		case v.Status == worst.Status && trustRank(v.Trust) < trustRank(worst.Trust):
+			worst = v
+		}
+	}
+	return worst
+}
@@ -0,0 +1,269 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+)
+
+// Field names a searchable text part of an email.
+type Field string
+
+const (
+	FieldFrom    Field = "from"
+	FieldTo      Field = "to"
+	FieldSubject Field = "subject"
+	FieldBody    Field = "body"
+)
+
+var textFields = []Field{FieldSubject, FieldFrom, FieldTo, FieldBody}
+
+// Doc is the indexed metadata of one email.
+type Doc struct {
+	ID            int
+	Sender        string
+	Recipient     string
+	Subject       string
+	ReceivedAt    string
+	Date          time.Time
+	Read          bool
+	Starred       bool
+	Folder        string
+	HasBody       bool
+	HasAttachment bool
+	// DMARC is the DMARC result from Authentication-Results, "" until the
+	// headers have been seen.
+	DMARC string
+}
+
+// Index is a positional inverted index. Its fields are exported so callers
+// can persist it with encoding/gob; use the methods to modify it.
+type Index struct {
+	Docs map[int]*Doc
+	// Postings maps a field-qualified token to the word positions at which
+	// it occurs in each document.
+	Postings map[string]map[int][]int
+}
+
+// NewIndex returns an empty index.
+func NewIndex() *Index {
+	return &Index{Docs: make(map[int]*Doc), Postings: make(map[string]map[int][]int)}
+}
+
+// Add indexes an email. Header fields and flags are replaced on every call;
+// the body is indexed the first time the raw email is available.
+func (idx *Index) Add(email *api.Email) {
+	if email == nil || email.ID <= 0 {
+		return
+	}
+	doc, ok := idx.Docs[email.ID]
+	if ok {
+		idx.unpost(doc.ID, FieldFrom, doc.Sender)
+		idx.unpost(doc.ID, FieldTo, doc.Recipient)
+		idx.unpost(doc.ID, FieldSubject, doc.Subject)
+	} else {
+		doc = &Doc{ID: email.ID}
+		idx.Docs[email.ID] = doc
+	}
+
+	doc.Sender = api.DecodeHeader(email.Sender)
+	doc.Recipient = api.DecodeHeader(email.Recipient)
+	doc.Subject = api.DecodeHeader(email.Subject)
+	doc.ReceivedAt = email.ReceivedAt
+	doc.Date, _ = api.ParseTimestamp(email.ReceivedAt)
+	doc.Read = email.Read()
+	doc.Starred = email.Starred()
+	doc.Folder = email.Folder
+
+	idx.post(doc.ID, FieldFrom, doc.Sender)
+	idx.post(doc.ID, FieldTo, doc.Recipient)
+	idx.post(doc.ID, FieldSubject, doc.Subject)
+
+	if auth := email.Authentication(); auth.Known() {
+		doc.DMARC = string(auth.DMARC.Result)
+	}
+
+	if !doc.HasBody && strings.TrimSpace(email.RawEmail) != "" {
+		doc.HasBody = true
+		doc.HasAttachment = email.HasAttachments()
+		idx.post(doc.ID, FieldBody, email.Body())
+	}
+}
+
+// SetFlags updates read, starred and folder state without reindexing text.
+func (idx *Index) SetFlags(id int, read, starred *bool, folder *string) {
+	doc, ok := idx.Docs[id]
+	if !ok {
+		return
+	}
+	if read != nil {
+		doc.Read = *read
+	}
+	if starred != nil {
+		doc.Starred = *starred
+	}
+	if folder != nil {
+		doc.Folder = *folder
+	}
+}
+
+// Remove drops a document and all of its postings.
+func (idx *Index) Remove(id int) {
+	if _, ok := idx.Docs[id]; !ok {
+		return
+	}
+	delete(idx.Docs, id)
+	for token, docs := range idx.Postings {
+		if _, ok := docs[id]; ok {
+			delete(docs, id)
+			if len(docs) == 0 {
+				delete(idx.Postings, token)
+			}
+		}
+	}
+}
+
+// Search returns the documents matching q, newest first.
+func (idx *Index) Search(q *Query) []*Doc {
+	var results []*Doc
+	for _, id := range idx.candidates(q) {
+		doc := idx.Docs[id]
+		if idx.matches(doc, q) {
+			results = append(results, doc)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if !results[i].Date.Equal(results[j].Date) {
+			return results[i].Date.After(results[j].Date)
+		}
+		return results[i].ID > results[j].ID
+	})
+	return results
+}
+
+// candidates narrows the search using the rarest term of a positive,
+// single-field text clause, falling back to every document.
+func (idx *Index) candidates(q *Query) []int {
+	var best map[int][]int
+	found := false
+	for _, c := range q.Clauses {
+		if c.Negate || len(c.terms) == 0 || len(c.fields()) != 1 {
+			continue
+		}
+		postings := idx.Postings[key(c.fields()[0], c.terms[0])]
+		if !found || len(postings) < len(best) {
+			best, found = postings, true
+		}
+	}
+
+	var ids []int
+	if found {
+		for id := range best {
+			ids = append(ids, id)
+		}
+		return ids
+	}
+	for id := range idx.Docs {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (idx *Index) matches(doc *Doc, q *Query) bool {
+	for _, c := range q.Clauses {
+		if idx.matchClause(doc, c) == c.Negate {
+			return false
+		}
+	}
+	return true
+}
+
+func (idx *Index) matchClause(doc *Doc, c Clause) bool {
+	switch c.Op {
+	case "is":
+		switch c.Value {
+		case "unread":
+			return !doc.Read
+		case "read":
+			return doc.Read
+		case "starred":
+			return doc.Starred
+		case "unstarred":
+			return !doc.Starred
+		}
+	case "has":
+		return doc.HasAttachment
+	case "in":
+		return strings.EqualFold(doc.Folder, c.Value)
+	case "dmarc":
+		return doc.DMARC == c.Value
+	case "after":
+		return !doc.Date.IsZero() && !doc.Date.Before(c.date)
+	case "before":
+		return !doc.Date.IsZero() && doc.Date.Before(c.date)
+	default:
+		for _, f := range c.fields() {
+			if idx.phraseIn(doc.ID, f, c.terms) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// phraseIn reports whether terms occur consecutively in a document field.
+func (idx *Index) phraseIn(id int, field Field, terms []string) bool {
+	first := idx.Postings[key(field, terms[0])][id]
+	for _, start := range first {
+		matched := true
+		for i := 1; i < len(terms); i++ {
+			if !containsInt(idx.Postings[key(field, terms[i])][id], start+i) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (idx *Index) post(id int, field Field, text string) {
+	for pos, token := range Tokenize(text) {
+		k := key(field, token)
+		docs := idx.Postings[k]
+		if docs == nil {
+			docs = make(map[int][]int)
+			idx.Postings[k] = docs
+		}
+		docs[id] = append(docs[id], pos)
+	}
+}
+
+func (idx *Index) unpost(id int, field Field, text string) {
+	for _, token := range Tokenize(text) {
+		k := key(field, token)
+		if docs, ok := idx.Postings[k]; ok {
+			delete(docs, id)
+			if len(docs) == 0 {
+				delete(idx.Postings, k)
+			}
+		}
+	}
+}
+
+func key(field Field, token string) string {
+	return string(field) + ":" + token
+}
+
+func containsInt(values []int, want int) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
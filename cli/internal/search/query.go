@@ -0,0 +1,210 @@
+// Package search implements Mercury's mail query language and a local
+// inverted index over decoded headers and bodies.
+//
+// A query is a space-separated list of clauses, all of which must match:
+//
+//	invoice                 word in subject, sender, recipient or body
+//	"exact phrase"          consecutive words anywhere
+//	from:stripe             word or phrase in the sender
+//	to:, subject:, body:    word or phrase in that field
+//	is:unread, is:read, is:starred, is:unstarred
+//	has:attachment
+//	in:archive              folder
+//	dmarc:fail              DMARC result in Authentication-Results
+//	after:2026-09-01        received on or after the date (UTC)
+//	before:2026-10-01       received before the date (UTC)
+//	-clause                 negates any clause
+//
+// Any other word with a colon, such as a URL or "Re:", is free text.
+package search
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+)
+
+// Clause is one condition of a query.
+type Clause struct {
+	// Op is the operator before the colon, or "" for free text.
+	Op     string
+	Value  string
+	Negate bool
+
+	terms []string
+	date  time.Time
+}
+
+// Query is a parsed search expression.
+type Query struct {
+	Clauses []Clause
+}
+
+// dateLayout is the accepted format for after: and before:.
+const dateLayout = "2006-01-02"
+
+// Parse parses a query string.
+func Parse(input string) (*Query, error) {
+	q := &Query{}
+	rest := strings.TrimSpace(input)
+	for rest != "" {
+		clause, remaining, err := parseClause(rest)
+		if err != nil {
+			return nil, err
+		}
+		if err := clause.compile(); err != nil {
+			return nil, err
+		}
+		q.Clauses = append(q.Clauses, clause)
+		rest = strings.TrimLeftFunc(remaining, unicode.IsSpace)
+	}
+	return q, nil
+}
+
+// Empty reports whether the query has no clauses and so matches everything.
+func (q *Query) Empty() bool {
+	return len(q.Clauses) == 0
+}
+
+// String renders the query in canonical form.
+func (q *Query) String() string {
+	parts := make([]string, len(q.Clauses))
+	for i, c := range q.Clauses {
+		parts[i] = c.String()
+	}
+	return strings.Join(parts, " ")
+}
+
+// String renders the clause in canonical form.
+func (c Clause) String() string {
+	var sb strings.Builder
+	if c.Negate {
+		sb.WriteByte('-')
+	}
+	if c.Op != "" {
+		sb.WriteString(c.Op)
+		sb.WriteByte(':')
+	}
+	if strings.ContainsAny(c.Value, " \t") || c.Value == "" {
+		sb.WriteString(`"` + c.Value + `"`)
+	} else {
+		sb.WriteString(c.Value)
+	}
+	return sb.String()
+}
+
+func parseClause(s string) (Clause, string, error) {
+	var c Clause
+	if strings.HasPrefix(s, "-") && len(s) > 1 && !unicode.IsSpace(rune(s[1])) {
+		c.Negate = true
+		s = s[1:]
+	}
+
+	if !strings.HasPrefix(s, `"`) {
+		if colon := strings.IndexByte(s, ':'); colon > 0 && isOperator(s[:colon]) {
+			c.Op = strings.ToLower(s[:colon])
+			s = s[colon+1:]
+		}
+	}
+
+	value, rest, err := parseValue(s)
+	if err != nil {
+		return c, "", err
+	}
+	if c.Op != "" && value == "" {
+		return c, "", fmt.Errorf("search: %s: needs a value", c.Op)
+	}
+	c.Value = value
+	return c, rest, nil
+}
+
+func parseValue(s string) (string, string, error) {
+	if strings.HasPrefix(s, `"`) {
+		end := strings.IndexByte(s[1:], '"')
+		if end < 0 {
+			return "", "", fmt.Errorf("search: unterminated quote in %q", s)
+		}
+		return s[1 : end+1], s[end+2:], nil
+	}
+	end := strings.IndexFunc(s, unicode.IsSpace)
+	if end < 0 {
+		return s, "", nil
+	}
+	return s[:end], s[end:], nil
+}
+
+// operators are the names compile accepts before a colon.
+var operators = map[string]bool{
+	"from": true, "to": true, "subject": true, "body": true, "is": true,
+	"has": true, "in": true, "dmarc": true, "after": true, "before": true,
+}
+
+func isOperator(s string) bool {
+	return operators[strings.ToLower(s)]
+}
+
+func (c *Clause) compile() error {
+	switch c.Op {
+	case "", "from", "to", "subject", "body":
+		c.terms = Tokenize(c.Value)
+		if len(c.terms) == 0 {
+			return fmt.Errorf("search: %q has no searchable words", c.String())
+		}
+	case "is":
+		c.Value = strings.ToLower(c.Value)
+		switch c.Value {
+		case "unread", "read", "starred", "unstarred":
+		default:
+			return fmt.Errorf("search: unknown is:%s (valid: unread, read, starred, unstarred)", c.Value)
+		}
+	case "has":
+		c.Value = strings.ToLower(c.Value)
+		if c.Value != "attachment" {
+			return fmt.Errorf("search: unknown has:%s (valid: attachment)", c.Value)
+		}
+	case "in":
+		c.Value = strings.ToLower(c.Value)
+	case "dmarc":
+		c.Value = strings.ToLower(c.Value)
+		switch api.AuthResult(c.Value) {
+		case api.AuthPass, api.AuthFail, api.AuthNone, api.AuthTempError, api.AuthPermError:
+		default:
+			return fmt.Errorf("search: unknown dmarc:%s (valid: pass, fail, none, temperror, permerror)", c.Value)
+		}
+	case "after", "before":
+		d, err := time.Parse(dateLayout, c.Value)
+		if err != nil {
+			return fmt.Errorf("search: %s: expects YYYY-MM-DD, got %q", c.Op, c.Value)
+		}
+		c.date = d
+	default:
+		return fmt.Errorf("search: unknown operator %q", c.Op+":")
+	}
+	return nil
+}
+
+// fields returns the text fields a clause searches.
+func (c Clause) fields() []Field {
+	switch c.Op {
+	case "from":
+		return []Field{FieldFrom}
+	case "to":
+		return []Field{FieldTo}
+	case "subject":
+		return []Field{FieldSubject}
+	case "body":
+		return []Field{FieldBody}
+	default:
+		return textFields
+	}
+}
+
+// Tokenize splits text into lowercase words of letters and digits.
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,215 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+)
+
+func TestParse(t *testing.T) {
+	q, err := Parse(`from:stripe subject:invoice is:unread after:2026-09-01 has:attachment "exact phrase" -in:trash`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := []Clause{
+		{Op: "from", Value: "stripe"},
+		{Op: "subject", Value: "invoice"},
+		{Op: "is", Value: "unread"},
+		{Op: "after", Value: "2026-09-01"},
+		{Op: "has", Value: "attachment"},
+		{Op: "", Value: "exact phrase"},
+		{Op: "in", Value: "trash", Negate: true},
+	}
+	if len(q.Clauses) != len(want) {
+		t.Fatalf("got %d clauses, want %d: %+v", len(q.Clauses), len(want), q.Clauses)
+	}
+	for i, w := range want {
+		c := q.Clauses[i]
+		if c.Op != w.Op || c.Value != w.Value || c.Negate != w.Negate {
+			t.Errorf("clause %d = %+v, want %+v", i, c, w)
+		}
+	}
+	if got := q.String(); got != `from:stripe subject:invoice is:unread after:2026-09-01 has:attachment "exact phrase" -in:trash` {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestParse_QuotedOperatorValue(t *testing.T) {
+	q, err := Parse(`from:"Jane Doe"`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(q.Clauses) != 1 || q.Clauses[0].Op != "from" || q.Clauses[0].Value != "Jane Doe" {
+		t.Errorf("Clauses = %+v", q.Clauses)
+	}
+}
+
+func TestParse_UnknownOperatorIsText(t *testing.T) {
+	q, err := Parse(`https://example.com Re: invoice note: FROM:stripe`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := []Clause{
+		{Value: "https://example.com"},
+		{Value: "Re:"},
+		{Value: "invoice"},
+		{Value: "note:"},
+		{Op: "from", Value: "stripe"},
+	}
+	if len(q.Clauses) != len(want) {
+		t.Fatalf("Clauses = %+v", q.Clauses)
+	}
+	for i, w := range want {
+		if c := q.Clauses[i]; c.Op != w.Op || c.Value != w.Value {
+			t.Errorf("clause %d = %+v, want %+v", i, c, w)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, input := range []string{
+		`"unterminated`,
+		`is:maybe`,
+		`has:pdf`,
+		`dmarc:maybe`,
+		`after:yesterday`,
+		`from:`,
+		`--`,
+	} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q) expected error", input)
+		}
+	}
+}
+
+func TestParse_Empty(t *testing.T) {
+	q, err := Parse("   ")
+	if err != nil || !q.Empty() {
+		t.Errorf("Parse(blank) = %+v, %v; want empty query", q, err)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("Billing <billing@Stripe.com>: Ünïcode 2026!")
+	want := []string{"billing", "billing", "stripe", "com", "ünïcode", "2026"}
+	if len(got) != len(want) {
+		t.Fatalf("Tokenize() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Tokenize()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+const invoiceRaw = "Authentication-Results: mx.cloudflare.net; spf=pass; dmarc=fail header.from=stripe.com\r\n" +
+	"From: Stripe <billing@stripe.com>\r\n" +
+	"Content-Type: multipart/mixed; boundary=XYZ\r\n\r\n" +
+	"--XYZ\r\nContent-Type: text/plain\r\n\r\nYour invoice for September is ready.\r\n" +
+	"--XYZ\r\nContent-Type: application/pdf\r\nContent-Disposition: attachment; filename=invoice.pdf\r\n\r\nPDF\r\n" +
+	"--XYZ--\r\n"
+
+func testIndex() *Index {
+	idx := NewIndex()
+	idx.Add(&api.Email{
+		ID: 1, Sender: "Stripe <billing@stripe.com>", Recipient: "me@example.com",
+		Subject: "Your invoice", ReceivedAt: "2026-09-15 08:00:00", Folder: "inbox",
+		RawEmail: invoiceRaw,
+	})
+	idx.Add(&api.Email{
+		ID: 2, Sender: "alice@example.com", Recipient: "me@example.com",
+		Subject: "=?UTF-8?Q?Caf=C3=A9_plans?=", ReceivedAt: "2026-08-01 10:00:00", Folder: "inbox", IsRead: 1, IsStarred: 1,
+		RawEmail: "Authentication-Results: mx.cloudflare.net; dmarc=pass\r\nSubject: x\r\n\r\nLet's meet at the ready room for the invoice chat.",
+	})
+	idx.Add(&api.Email{
+		ID: 3, Sender: "bob@example.com", Subject: "Invoice overdue",
+		ReceivedAt: "2026-10-01 10:00:00", Folder: "archive",
+	})
+	return idx
+}
+
+func TestIndex_Search(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{`invoice`, []int{3, 1, 2}},
+		{`from:stripe`, []int{1}},
+		{`from:billing@stripe.com`, []int{1}},
+		{`subject:invoice is:unread`, []int{3, 1}},
+		{`is:starred`, []int{2}},
+		{`has:attachment`, []int{1}},
+		{`"invoice for september"`, []int{1}},
+		{`"september invoice"`, nil},
+		{`after:2026-09-01`, []int{3, 1}},
+		{`before:2026-09-01`, []int{2}},
+		{`in:archive`, []int{3}},
+		{`dmarc:fail`, []int{1}},
+		{`-dmarc:pass`, []int{3, 1}},
+		{`invoice -in:archive`, []int{1, 2}},
+		{`café`, []int{2}},
+		{`body:ready -from:stripe`, []int{2}},
+		{`nothing-matches-this`, nil},
+		{``, []int{3, 1, 2}},
+	}
+
+	idx := testIndex()
+	for _, tt := range tests {
+		q, err := Parse(tt.query)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.query, err)
+		}
+		docs := idx.Search(q)
+		var got []int
+		for _, d := range docs {
+			got = append(got, d.ID)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestIndex_AddUpdatesHeadersAndFlags(t *testing.T) {
+	idx := testIndex()
+	idx.Add(&api.Email{ID: 3, Sender: "carol@example.com", Subject: "Paid", ReceivedAt: "2026-10-01 10:00:00", Folder: "archive", IsRead: 1})
+
+	q, _ := Parse("subject:overdue")
+	if docs := idx.Search(q); len(docs) != 0 {
+		t.Errorf("stale subject still indexed: %+v", docs)
+	}
+	q, _ = Parse("from:carol is:read")
+	if docs := idx.Search(q); len(docs) != 1 {
+		t.Errorf("updated sender not found: %+v", docs)
+	}
+
+	starred := true
+	idx.SetFlags(3, nil, &starred, nil)
+	q, _ = Parse("is:starred in:archive")
+	if docs := idx.Search(q); len(docs) != 1 || docs[0].ID != 3 {
+		t.Errorf("SetFlags not applied: %+v", docs)
+	}
+}
+
+func TestIndex_Remove(t *testing.T) {
+	idx := testIndex()
+	idx.Remove(1)
+
+	q, _ := Parse("stripe")
+	if docs := idx.Search(q); len(docs) != 0 {
+		t.Errorf("removed doc still matches: %+v", docs)
+	}
+	for token, docs := range idx.Postings {
+		if _, ok := docs[1]; ok {
+			t.Errorf("posting %q still references removed doc", token)
+		}
+	}
+}
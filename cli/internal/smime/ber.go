@@ -0,0 +1,171 @@
+package smime
+
+import (
+	"errors"
+	"fmt"
+)
+
+// toDER rewrites BER, as produced by streaming S/MIME signers, into the DER
+// encoding/asn1 accepts: indefinite lengths become definite and
+// constructed OCTET STRINGs are joined into one. DER passes through
+// unchanged.
+func toDER(ber []byte) ([]byte, error) {
+	out, rest, err := normalize(ber, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("trailing data after ASN.1 structure")
+	}
+	return out, nil
+}
+
+// maxDepth bounds nesting, against hostile input.
+const maxDepth = 64
+
+// normalize converts the first TLV of b and returns the remainder.
+func normalize(b []byte, depth int) ([]byte, []byte, error) {
+	if depth > maxDepth {
+		return nil, nil, errors.New("ASN.1 nested too deeply")
+	}
+	tag, b, err := readTag(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(b) == 0 {
+		return nil, nil, errors.New("truncated ASN.1 length")
+	}
+	constructed := tag[0]&0x20 != 0
+
+	var content []byte
+	if b[0] == 0x80 {
+		if !constructed {
+			return nil, nil, errors.New("indefinite length on a primitive ASN.1 value")
+		}
+		b = b[1:]
+		for {
+			if len(b) >= 2 && b[0] == 0 && b[1] == 0 {
+				b = b[2:]
+				break
+			}
+			if len(b) == 0 {
+				return nil, nil, errors.New("unterminated indefinite-length ASN.1 value")
+			}
+			var child []byte
+			child, b, err = normalize(b, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			content = append(content, child...)
+		}
+	} else {
+		n, rest, err := readLength(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		if n > len(rest) {
+			return nil, nil, errors.New("truncated ASN.1 value")
+		}
+		body := rest[:n]
+		b = rest[n:]
+		if !constructed {
+			content = body
+		} else {
+			for len(body) > 0 {
+				var child []byte
+				child, body, err = normalize(body, depth+1)
+				if err != nil {
+					return nil, nil, err
+				}
+				content = append(content, child...)
+			}
+		}
+	}
+
+	// A constructed OCTET STRING is the concatenation of its pieces.
+	if len(tag) == 1 && tag[0] == 0x24 {
+		joined, err := joinOctets(content)
+		if err != nil {
+			return nil, nil, err
+		}
+		return encode([]byte{0x04}, joined), b, nil
+	}
+	return encode(tag, content), b, nil
+}
+
+// joinOctets concatenates the contents of a run of DER OCTET STRINGs.
+func joinOctets(b []byte) ([]byte, error) {
+	var out []byte
+	for len(b) > 0 {
+		if b[0] != 0x04 {
+			return nil, fmt.Errorf("unexpected tag %#x in constructed OCTET STRING", b[0])
+		}
+		n, rest, err := readLength(b[1:])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rest[:n]...)
+		b = rest[n:]
+	}
+	return out, nil
+}
+
+func readTag(b []byte) ([]byte, []byte, error) {
+	if len(b) == 0 {
+		return nil, nil, errors.New("truncated ASN.1 tag")
+	}
+	n := 1
+	if b[0]&0x1f == 0x1f {
+		for {
+			if n >= len(b) {
+				return nil, nil, errors.New("truncated ASN.1 tag")
+			}
+			n++
+			if b[n-1]&0x80 == 0 {
+				break
+			}
+		}
+	}
+	return b[:n], b[n:], nil
+}
+
+func readLength(b []byte) (int, []byte, error) {
+	if len(b) == 0 {
+		return 0, nil, errors.New("truncated ASN.1 length")
+	}
+	if b[0] < 0x80 {
+		return int(b[0]), b[1:], nil
+	}
+	count := int(b[0] & 0x7f)
+	if count == 0 || count > 4 || count >= len(b) {
+		return 0, nil, errors.New("invalid ASN.1 length")
+	}
+	n := 0
+	for _, c := range b[1 : 1+count] {
+		n = n<<8 | int(c)
+	}
+	rest := b[1+count:]
+	if n > len(rest) {
+		return 0, nil, errors.New("truncated ASN.1 value")
+	}
+	return n, rest, nil
+}
+
+// encode writes tag, a DER length and content.
+func encode(tag, content []byte) []byte {
+	out := append([]byte{}, tag...)
+	n := len(content)
+	switch {
+	case n < 0x80:
+		out = append(out, byte(n))
+	case n < 0x100:
+		out = append(out, 0x81, byte(n))
+	case n < 0x10000:
+		out = append(out, 0x82, byte(n>>8), byte(n))
+	case n < 0x1000000:
+		out = append(out, 0x83, byte(n>>16), byte(n>>8), byte(n))
+	default:
+		out = append(out, 0x84, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(out, content...)
+}
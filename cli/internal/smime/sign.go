@@ -0,0 +1,139 @@
+package smime
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Signer is a signing certificate with its private key.
+type Signer struct {
+	Certificate *x509.Certificate
+	// Chain holds intermediates to include for recipients.
+	Chain []*x509.Certificate
+	Key   crypto.Signer
+}
+
+// now is the signing time, replaced in tests.
+var now = time.Now
+
+// Sign creates a SignedData ContentInfo over content with SHA-256. An
+// opaque signature carries the content; a detached one does not. RSA and
+// ECDSA keys are supported.
+func (s *Signer) Sign(content []byte, detached bool) ([]byte, error) {
+	var sigAlg pkix.AlgorithmIdentifier
+	switch s.Key.Public().(type) {
+	case *rsa.PublicKey:
+		sigAlg = pkix.AlgorithmIdentifier{Algorithm: oidRSA, Parameters: asn1.NullRawValue}
+	case *ecdsa.PublicKey:
+		sigAlg = pkix.AlgorithmIdentifier{Algorithm: append(append(asn1.ObjectIdentifier{}, oidECDSAWithSHA2...), 2)}
+	default:
+		return nil, fmt.Errorf("unsupported S/MIME key type %T", s.Key.Public())
+	}
+	digestAlg := pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue}
+
+	digest := sha256.Sum256(content)
+	attrs, err := signedAttributes(digest[:])
+	if err != nil {
+		return nil, err
+	}
+	attrsDigest := sha256.Sum256(attrs)
+	signature, err := s.Key.Sign(rand.Reader, attrsDigest[:], crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("sign: %w", err)
+	}
+
+	sid, err := asn1.Marshal(issuerAndSerial{
+		Issuer: asn1.RawValue{FullBytes: s.Certificate.RawIssuer},
+		Serial: s.Certificate.SerialNumber,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var certs []byte
+	for _, c := range append([]*x509.Certificate{s.Certificate}, s.Chain...) {
+		certs = append(certs, c.Raw...)
+	}
+
+	si, err := asn1.Marshal(struct {
+		Version            int
+		SID                asn1.RawValue
+		DigestAlgorithm    pkix.AlgorithmIdentifier
+		SignedAttrs        asn1.RawValue
+		SignatureAlgorithm pkix.AlgorithmIdentifier
+		Signature          []byte
+	}{
+		Version:            1,
+		SID:                asn1.RawValue{FullBytes: sid},
+		DigestAlgorithm:    digestAlg,
+		SignedAttrs:        asn1.RawValue{FullBytes: append([]byte{0xa0}, attrs[1:]...)},
+		SignatureAlgorithm: sigAlg,
+		Signature:          signature,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encode signer info: %w", err)
+	}
+	encap := encapContentInfo{ContentType: oidData}
+	if !detached {
+		encap.Content = content
+	}
+	sd, err := asn1.Marshal(struct {
+		Version          int
+		DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+		EncapContentInfo encapContentInfo
+		Certificates     asn1.RawValue
+		SignerInfos      []asn1.RawValue `asn1:"set"`
+	}{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{digestAlg},
+		EncapContentInfo: encap,
+		Certificates:     asn1.RawValue{FullBytes: encode([]byte{0xa0}, certs)},
+		SignerInfos:      []asn1.RawValue{{FullBytes: si}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encode signed data: %w", err)
+	}
+	return asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{FullBytes: encode([]byte{0xa0}, sd)},
+	})
+}
+
+// signedAttributes encodes the content type, signing time and message
+// digest as the DER SET the signature covers, sorted as DER requires.
+func signedAttributes(digest []byte) ([]byte, error) {
+	values := []struct {
+		oid   asn1.ObjectIdentifier
+		value any
+	}{
+		{oidAttrContentType, oidData},
+		{oidAttrSigningTime, now().UTC().Truncate(time.Second)},
+		{oidAttrMessageDigest, digest},
+	}
+	var encoded [][]byte
+	for _, v := range values {
+		value, err := asn1.Marshal(v.value)
+		if err != nil {
+			return nil, err
+		}
+		attr, err := asn1.Marshal(attribute{
+			Type:   v.oid,
+			Values: asn1.RawValue{FullBytes: encode([]byte{0x31}, value)},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoded = append(encoded, attr)
+	}
+	sort.Slice(encoded, func(i, j int) bool { return bytes.Compare(encoded[i], encoded[j]) < 0 })
+	return encode([]byte{0x31}, bytes.Join(encoded, nil)), nil
+}
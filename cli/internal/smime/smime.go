@@ -0,0 +1,409 @@
+// Package smime parses, verifies and creates the CMS SignedData objects
+// (RFC 5652) that S/MIME signed mail carries (RFC 8551).
+package smime
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	// Registered for the digests signatures may use.
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidEnvelopedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+
+	oidAttrContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttrMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidAttrSigningTime   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+
+	oidSHA1   = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+
+	oidRSA           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidSHA1WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 5}
+	oidSHA256WithRSA = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidSHA384WithRSA = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}
+	oidSHA512WithRSA = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}
+	oidECPublicKey   = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidECDSAWithSHA1 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 1}
+	oidECDSAWithSHA2 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3} // .2 to .4 for SHA-256 to SHA-512
+)
+
+// ErrEncrypted is returned for S/MIME enveloped (encrypted) data, which is
+// not supported.
+var ErrEncrypted = errors.New("S/MIME encrypted mail is not supported")
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type encapContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     []byte `asn1:"explicit,optional,omitempty,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type signerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type issuerAndSerial struct {
+	Issuer asn1.RawValue
+	Serial *big.Int
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue `asn1:"set"`
+}
+
+// SignedData is a parsed CMS SignedData object.
+type SignedData struct {
+	sd           signedData
+	content      []byte
+	certificates []*x509.Certificate
+}
+
+// Parse reads a DER or BER encoded ContentInfo holding SignedData.
+func Parse(data []byte) (*SignedData, error) {
+	der, err := toDER(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse PKCS #7: %w", err)
+	}
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(der, &ci); err != nil {
+		return nil, fmt.Errorf("parse PKCS #7: %w", err)
+	}
+	switch {
+	case ci.ContentType.Equal(oidEnvelopedData):
+		return nil, ErrEncrypted
+	case !ci.ContentType.Equal(oidSignedData):
+		return nil, fmt.Errorf("PKCS #7 content type %v is not signed data", ci.ContentType)
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("parse signed data: %w", err)
+	}
+	s := &SignedData{sd: sd, content: sd.EncapContentInfo.Content}
+	if len(sd.Certificates.Bytes) > 0 {
+		s.certificates, err = x509.ParseCertificates(sd.Certificates.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse signer certificates: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// Content returns the signed content of an opaque signature, nil for a
+// detached one.
+func (s *SignedData) Content() []byte {
+	return s.content
+}
+
+// Certificates returns the certificates the signer included.
+func (s *SignedData) Certificates() []*x509.Certificate {
+	return s.certificates
+}
+
+// Status is the outcome of verifying a signature.
+type Status int
+
+const (
+	// Valid is a correct signature by a certificate the trust store accepts.
+	Valid Status = iota
+	// Untrusted is a correct signature by a certificate that does not chain
+	// to the trust store, or is not valid for email.
+	Untrusted
+	// Invalid is a signature that does not match the content.
+	Invalid
+)
+
+func (s Status) String() string {
+	switch s {
+	case Valid:
+		return "valid"
+	case Untrusted:
+		return "untrusted"
+	}
+	return "invalid"
+}
+
+// Verification is the result of checking a signature.
+type Verification struct {
+	Status Status
+	// Signer is the signing certificate, nil when it was not included.
+	Signer      *x509.Certificate
+	SigningTime time.Time
+	// Reason explains an Untrusted or Invalid status.
+	Reason string
+}
+
+// SignerName is the signer's email address, or the certificate's subject.
+func (v Verification) SignerName() string {
+	if v.Signer == nil {
+		return "unknown signer"
+	}
+	if len(v.Signer.EmailAddresses) > 0 {
+		return v.Signer.EmailAddresses[0]
+	}
+	return v.Signer.Subject.CommonName
+}
+
+// CheckSender downgrades a valid verification to untrusted unless the
+// signing certificate was issued for from, the address the message claims
+// to come from. Any trusted certificate could otherwise vouch for mail
+// from anyone.
+func (v Verification) CheckSender(from string) Verification {
+	if v.Status != Valid {
+		return v
+	}
+	for _, address := range v.Signer.EmailAddresses {
+		if from != "" && strings.EqualFold(address, from) {
+			return v
+		}
+	}
+	if from == "" {
+		from = "an unknown sender"
+	}
+	v.Status, v.Reason = Untrusted, fmt.Sprintf("certificate is not for %s", from)
+	return v
+}
+
+// Summary describes the verification in a sentence fragment, as in
+// "Valid signature from alice@example.com (issued by Example CA)".
+func (v Verification) Summary() string {
+	switch v.Status {
+	case Valid:
+		return fmt.Sprintf("Valid signature from %s (issued by %s)", v.SignerName(), v.Signer.Issuer.CommonName)
+	case Untrusted:
+		return fmt.Sprintf("Signature from %s is not trusted: %s", v.SignerName(), v.Reason)
+	}
+	return "INVALID signature: " + v.Reason
+}
+
+// Verify checks every signer's signature over content, or over the
+// encapsulated content when content is nil, and each signing certificate's
+// chain to roots. nil roots uses the system trust store. Of several
+// signers, the worst outcome is reported.
+func (s *SignedData) Verify(content []byte, roots *x509.CertPool) Verification {
+	if content == nil {
+		content = s.content
+	}
+	if len(s.sd.SignerInfos) == 0 {
+		return Verification{Status: Invalid, Reason: "no signers"}
+	}
+	var worst Verification
+	for i, si := range s.sd.SignerInfos {
+		if v := s.verifySigner(si, content, roots); i == 0 || v.Status > worst.Status {
+			worst = v
+		}
+	}
+	return worst
+}
+
+// verifySigner checks one SignerInfo.
+func (s *SignedData) verifySigner(si signerInfo, content []byte, roots *x509.CertPool) Verification {
+	cert := s.signerCertificate(si)
+	v := Verification{Signer: cert}
+	if cert == nil {
+		v.Status, v.Reason = Invalid, "signer certificate not included"
+		return v
+	}
+
+	hash, ok := digestHash(si.DigestAlgorithm.Algorithm)
+	if !ok {
+		v.Status, v.Reason = Invalid, fmt.Sprintf("unsupported digest algorithm %v", si.DigestAlgorithm.Algorithm)
+		return v
+	}
+	h := hash.New()
+	h.Write(content)
+	digest := h.Sum(nil)
+
+	signed := content
+	if len(si.SignedAttrs.FullBytes) > 0 {
+		attrs, err := parseAttributes(si.SignedAttrs.Bytes)
+		if err != nil {
+			v.Status, v.Reason = Invalid, err.Error()
+			return v
+		}
+		var messageDigest []byte
+		var contentType asn1.ObjectIdentifier
+		for _, a := range attrs {
+			switch {
+			case a.Type.Equal(oidAttrContentType):
+				_, _ = asn1.Unmarshal(a.Values.Bytes, &contentType)
+			case a.Type.Equal(oidAttrMessageDigest):
+				_, _ = asn1.Unmarshal(a.Values.Bytes, &messageDigest)
+			case a.Type.Equal(oidAttrSigningTime):
+				_, _ = asn1.Unmarshal(a.Values.Bytes, &v.SigningTime)
+			}
+		}
+		// Without this check a signature over one content type could be
+		// replayed as another (RFC 5652, section 11.1).
+		if !contentType.Equal(s.sd.EncapContentInfo.ContentType) {
+			v.Status, v.Reason = Invalid, "signed content type does not match the content"
+			return v
+		}
+		if !bytes.Equal(messageDigest, digest) {
+			v.Status, v.Reason = Invalid, "content does not match the signed digest"
+			return v
+		}
+		// The signature covers the attributes as a DER SET, not as the
+		// implicitly tagged field they are stored in.
+		signed = append([]byte{0x31}, si.SignedAttrs.FullBytes[1:]...)
+	}
+
+	algo, ok := signatureAlgorithm(si.SignatureAlgorithm.Algorithm, hash)
+	if !ok {
+		v.Status, v.Reason = Invalid, fmt.Sprintf("unsupported signature algorithm %v", si.SignatureAlgorithm.Algorithm)
+		return v
+	}
+	if err := cert.CheckSignature(algo, signed, si.Signature); err != nil {
+		v.Status, v.Reason = Invalid, err.Error()
+		return v
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range s.certificates {
+		if c != cert {
+			intermediates.AddCert(c)
+		}
+	}
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection},
+		// The signing time is the signer's claim; a certificate that has
+		// since expired or is not yet valid must not vouch for it.
+		CurrentTime: now(),
+	}
+	if _, err := cert.Verify(opts); err != nil {
+		v.Status, v.Reason = Untrusted, strings.TrimPrefix(err.Error(), "x509: ")
+		return v
+	}
+	v.Status = Valid
+	return v
+}
+
+// signerCertificate finds the certificate a SignerInfo names, by issuer
+// and serial number or by subject key identifier.
+func (s *SignedData) signerCertificate(si signerInfo) *x509.Certificate {
+	if si.SID.Class == asn1.ClassContextSpecific && si.SID.Tag == 0 {
+		for _, c := range s.certificates {
+			if bytes.Equal(c.SubjectKeyId, si.SID.Bytes) {
+				return c
+			}
+		}
+		return nil
+	}
+	var ias issuerAndSerial
+	if _, err := asn1.Unmarshal(si.SID.FullBytes, &ias); err != nil {
+		return nil
+	}
+	for _, c := range s.certificates {
+		if c.SerialNumber.Cmp(ias.Serial) == 0 && bytes.Equal(c.RawIssuer, ias.Issuer.FullBytes) {
+			return c
+		}
+	}
+	return nil
+}
+
+func parseAttributes(b []byte) ([]attribute, error) {
+	var attrs []attribute
+	for len(b) > 0 {
+		var a attribute
+		rest, err := asn1.Unmarshal(b, &a)
+		if err != nil {
+			return nil, fmt.Errorf("parse signed attributes: %w", err)
+		}
+		attrs = append(attrs, a)
+		b = rest
+	}
+	return attrs, nil
+}
+
+func digestHash(oid asn1.ObjectIdentifier) (crypto.Hash, bool) {
+	switch {
+	case oid.Equal(oidSHA1):
+		return crypto.SHA1, true
+	case oid.Equal(oidSHA256):
+		return crypto.SHA256, true
+	case oid.Equal(oidSHA384):
+		return crypto.SHA384, true
+	case oid.Equal(oidSHA512):
+		return crypto.SHA512, true
+	}
+	return 0, false
+}
+
+// signatureAlgorithm maps a SignerInfo's signature algorithm to x509's.
+// Signers often give only the key type, leaving the hash to the digest
+// algorithm.
+func signatureAlgorithm(oid asn1.ObjectIdentifier, hash crypto.Hash) (x509.SignatureAlgorithm, bool) {
+	rsa := map[crypto.Hash]x509.SignatureAlgorithm{
+		crypto.SHA1: x509.SHA1WithRSA, crypto.SHA256: x509.SHA256WithRSA,
+		crypto.SHA384: x509.SHA384WithRSA, crypto.SHA512: x509.SHA512WithRSA,
+	}
+	ecdsa := map[crypto.Hash]x509.SignatureAlgorithm{
+		crypto.SHA1: x509.ECDSAWithSHA1, crypto.SHA256: x509.ECDSAWithSHA256,
+		crypto.SHA384: x509.ECDSAWithSHA384, crypto.SHA512: x509.ECDSAWithSHA512,
+	}
+	switch {
+	case oid.Equal(oidRSA):
+		algo, ok := rsa[hash]
+		return algo, ok
+	case oid.Equal(oidSHA1WithRSA):
+		return x509.SHA1WithRSA, true
+	case oid.Equal(oidSHA256WithRSA):
+		return x509.SHA256WithRSA, true
+	case oid.Equal(oidSHA384WithRSA):
+		return x509.SHA384WithRSA, true
+	case oid.Equal(oidSHA512WithRSA):
+		return x509.SHA512WithRSA, true
+	case oid.Equal(oidECPublicKey):
+		algo, ok := ecdsa[hash]
+		return algo, ok
+	case oid.Equal(oidECDSAWithSHA1):
+		return x509.ECDSAWithSHA1, true
+	case len(oid) == len(oidECDSAWithSHA2)+1 && oid[:len(oidECDSAWithSHA2)].Equal(oidECDSAWithSHA2):
+		switch oid[len(oid)-1] {
+		case 2:
+			return x509.ECDSAWithSHA256, true
+		case 3:
+			return x509.ECDSAWithSHA384, true
+		case 4:
+			return x509.ECDSAWithSHA512, true
+		}
+	}
+	return x509.UnknownSignatureAlgorithm, false
+}
@@ -0,0 +1,280 @@
+package smime
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testPKI struct {
+	ca     *x509.Certificate
+	roots  *x509.CertPool
+	signer *Signer
+}
+
+// newPKI issues an email protection certificate for address from a fresh
+// CA.
+func newPKI(t *testing.T, address string, rsaKey bool) *testPKI {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+
+	signer := &Signer{}
+	if rsaKey {
+		signer.Key, err = rsa.GenerateKey(rand.Reader, 2048)
+	} else {
+		signer.Key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every CA is "Test CA", so a random serial tells leaves apart.
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafTemplate := &x509.Certificate{
+		SerialNumber:   serial,
+		Subject:        pkix.Name{CommonName: "Alice"},
+		EmailAddresses: []string{address},
+		NotBefore:      time.Now().Add(-time.Hour),
+		NotAfter:       time.Now().Add(time.Hour),
+		KeyUsage:       x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, ca, signer.Key.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer.Certificate, _ = x509.ParseCertificate(leafDER)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	return &testPKI{ca: ca, roots: roots, signer: signer}
+}
+
+func TestSignVerify_Opaque(t *testing.T) {
+	for _, rsaKey := range []bool{false, true} {
+		pki := newPKI(t, "alice@example.com", rsaKey)
+		content := []byte("Content-Type: text/plain\r\n\r\nHello\r\n")
+		der, err := pki.signer.Sign(content, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sd, err := Parse(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(sd.Content(), content) {
+			t.Errorf("content = %q", sd.Content())
+		}
+		v := sd.Verify(nil, pki.roots)
+		if v.Status != Valid || v.SignerName() != "alice@example.com" || v.SigningTime.IsZero() {
+			t.Errorf("rsa=%v: verification = %+v", rsaKey, v)
+		}
+		if want := "Valid signature from alice@example.com (issued by Test CA)"; v.Summary() != want {
+			t.Errorf("summary = %q", v.Summary())
+		}
+	}
+}
+
+func TestSignVerify_Detached(t *testing.T) {
+	pki := newPKI(t, "alice@example.com", false)
+	content := []byte("Content-Type: text/plain\r\n\r\nPay invoice 42\r\n")
+	der, err := pki.signer.Sign(content, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd, err := Parse(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sd.Content() != nil {
+		t.Errorf("detached signature carries content %q", sd.Content())
+	}
+	if v := sd.Verify(content, pki.roots); v.Status != Valid {
+		t.Errorf("verification = %+v", v)
+	}
+	tampered := bytes.Replace(content, []byte("42"), []byte("43"), 1)
+	if v := sd.Verify(tampered, pki.roots); v.Status != Invalid || !strings.HasPrefix(v.Summary(), "INVALID signature") {
+		t.Errorf("tampered content: %+v", v)
+	}
+
+	other := newPKI(t, "mallory@example.com", false)
+	v := sd.Verify(content, other.roots)
+	if v.Status != Untrusted || !strings.Contains(v.Summary(), "not trusted: certificate signed by unknown authority") {
+		t.Errorf("foreign trust store: %+v, %q", v, v.Summary())
+	}
+}
+
+func TestVerify_Checks(t *testing.T) {
+	pki := newPKI(t, "alice@example.com", false)
+	content := []byte("Content-Type: text/plain\r\n\r\nPay invoice 42\r\n")
+	parse := func(p *testPKI) *SignedData {
+		der, err := p.signer.Sign(content, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sd, err := Parse(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sd
+	}
+
+	// A second signer the trust store does not know spoils the first.
+	sd := parse(pki)
+	other := parse(newPKI(t, "mallory@example.com", false))
+	sd.sd.SignerInfos = append(sd.sd.SignerInfos, other.sd.SignerInfos...)
+	sd.certificates = append(sd.certificates, other.certificates...)
+	if v := sd.Verify(content, pki.roots); v.Status != Untrusted || v.SignerName() != "mallory@example.com" {
+		t.Errorf("two signers: %+v", v)
+	}
+	forged := sd.sd.SignerInfos[0]
+	forged.Signature = append([]byte{}, forged.Signature...)
+	forged.Signature[len(forged.Signature)-1] ^= 1
+	sd.sd.SignerInfos = append(sd.sd.SignerInfos, forged)
+	if v := sd.Verify(content, pki.roots); v.Status != Invalid {
+		t.Errorf("a bad third signature: %+v", v)
+	}
+
+	sd = parse(pki)
+	sd.sd.EncapContentInfo.ContentType = oidSignedData
+	if v := sd.Verify(content, pki.roots); v.Status != Invalid || !strings.Contains(v.Reason, "content type") {
+		t.Errorf("content type mismatch: %+v", v)
+	}
+
+	// The certificate must be valid now, not just when the signer says
+	// they signed.
+	sd = parse(pki)
+	now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	defer func() { now = time.Now }()
+	if v := sd.Verify(content, pki.roots); v.Status != Untrusted || !strings.Contains(v.Reason, "expired") {
+		t.Errorf("expired certificate: %+v", v)
+	}
+	now = time.Now
+
+	v := sd.Verify(content, pki.roots)
+	if got := v.CheckSender("Alice@Example.com"); got.Status != Valid {
+		t.Errorf("CheckSender(alice) = %+v", got)
+	}
+	for _, from := range []string{"bob@example.com", ""} {
+		if got := v.CheckSender(from); got.Status != Untrusted {
+			t.Errorf("CheckSender(%q) = %+v", from, got)
+		}
+	}
+}
+
+func TestParse_BER(t *testing.T) {
+	// An indefinite-length SEQUENCE holding a constructed OCTET STRING.
+	ber := []byte{0x30, 0x80, 0x24, 0x80, 0x04, 0x02, 'h', 'e', 0x04, 0x03, 'l', 'l', 'o', 0x00, 0x00, 0x00, 0x00}
+	der, err := toDER(ber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0x30, 0x07, 0x04, 0x05, 'h', 'e', 'l', 'l', 'o'}
+	if !bytes.Equal(der, want) {
+		t.Errorf("toDER = % x, want % x", der, want)
+	}
+	if _, err := toDER([]byte{0x30, 0x80, 0x04, 0x01}); err == nil {
+		t.Error("truncated BER accepted")
+	}
+}
+
+func TestParse_Enveloped(t *testing.T) {
+	// ContentInfo with the enveloped-data type and an empty SEQUENCE.
+	der := []byte{0x30, 0x0f, 0x06, 0x09, 0x2a, 0x86, 0x48, 0x86, 0xf7, 0x0d, 0x01, 0x07, 0x03, 0xa0, 0x02, 0x30, 0x00}
+	if _, err := Parse(der); err != ErrEncrypted {
+		t.Errorf("Parse = %v, want ErrEncrypted", err)
+	}
+}
+
+// TestVerify_OpenSSL checks interoperability with the BER that openssl's
+// streaming signer writes.
+func TestVerify_OpenSSL(t *testing.T) {
+	if _, err := exec.LookPath("openssl"); err != nil {
+		t.Skip("openssl not installed")
+	}
+	pki := newPKI(t, "alice@example.com", true)
+	dir := t.TempDir()
+	keyDER, err := x509.MarshalPKCS8PrivateKey(pki.signer.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, kind string, der []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	certFile := write("cert.pem", "CERTIFICATE", pki.signer.Certificate.Raw)
+	keyFile := write("key.pem", "PRIVATE KEY", keyDER)
+	caFile := write("ca.pem", "CERTIFICATE", pki.ca.Raw)
+	content := filepath.Join(dir, "content.txt")
+	if err := os.WriteFile(content, []byte("Hello from openssl\r\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command("openssl", "cms", "-sign", "-stream", "-nodetach", "-binary", "-outform", "DER",
+		"-in", content, "-signer", certFile, "-inkey", keyFile).Output()
+	if err != nil {
+		t.Fatalf("openssl cms -sign: %v", err)
+	}
+	sd, err := Parse(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots, err := LoadTrustStore(caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := sd.Verify(nil, roots); v.Status != Valid || string(sd.Content()) != "Hello from openssl\r\n" {
+		t.Errorf("verification = %+v, content %q", v, sd.Content())
+	}
+
+	// And openssl accepts ours.
+	signer, err := LoadSigner(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ours, err := signer.Sign([]byte("Hello from mercury\r\n"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := filepath.Join(dir, "signed.der")
+	if err := os.WriteFile(signed, ours, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	verified, err := exec.Command("openssl", "cms", "-verify", "-binary", "-inform", "DER", "-in", signed, "-CAfile", caFile).Output()
+	if err != nil || string(verified) != "Hello from mercury\r\n" {
+		t.Errorf("openssl cms -verify: %v, %q", err, verified)
+	}
+}
@@ -0,0 +1,128 @@
+package smime
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LoadTrustStore reads the certificates S/MIME signers must chain to from
+// a PEM bundle, or from every .pem, .crt and .cer file in a directory. An
+// empty path returns nil, which verification treats as the system trust
+// store.
+func LoadTrustStore(path string) (*x509.CertPool, error) {
+	if path == "" {
+		return nil, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("read trust store: %w", err)
+	}
+	files := []string{path}
+	if info.IsDir() {
+		files = nil
+		for _, pattern := range []string{"*.pem", "*.crt", "*.cer"} {
+			matches, _ := filepath.Glob(filepath.Join(path, pattern))
+			files = append(files, matches...)
+		}
+	}
+	pool := x509.NewCertPool()
+	count := 0
+	for _, file := range files {
+		certs, err := readCertificates(file)
+		if err != nil {
+			return nil, fmt.Errorf("read trust store: %w", err)
+		}
+		for _, c := range certs {
+			pool.AddCert(c)
+			count++
+		}
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("trust store %s has no certificates", path)
+	}
+	return pool, nil
+}
+
+// LoadSigner reads a PEM certificate, optionally followed by its chain,
+// and the matching PEM private key (PKCS #8, PKCS #1 or SEC 1).
+func LoadSigner(certFile, keyFile string) (*Signer, error) {
+	certs, err := readCertificates(certFile)
+	if err != nil {
+		return nil, fmt.Errorf("read S/MIME certificate: %w", err)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%s has no certificate", certFile)
+	}
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read S/MIME key: %w", err)
+	}
+	var key crypto.Signer
+	for block, rest := pem.Decode(data); block != nil && key == nil; block, rest = pem.Decode(rest) {
+		key, err = parseKey(block)
+		if err != nil {
+			return nil, fmt.Errorf("parse S/MIME key %s: %w", keyFile, err)
+		}
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%s has no private key", keyFile)
+	}
+	return &Signer{Certificate: certs[0], Chain: certs[1:], Key: key}, nil
+}
+
+func parseKey(block *pem.Block) (crypto.Signer, error) {
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported key type %T", key)
+		}
+		return signer, nil
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "ENCRYPTED PRIVATE KEY":
+		return nil, fmt.Errorf("encrypted keys are not supported; export the key without a passphrase")
+	}
+	return nil, nil
+}
+
+// readCertificates reads PEM certificates, or a single DER certificate.
+func readCertificates(file string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var certs []*x509.Certificate
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		certs = append(certs, c)
+	}
+	if len(certs) == 0 {
+		if c, err := x509.ParseCertificate(data); err == nil {
+			certs = append(certs, c)
+		}
+	}
+	return certs, nil
+}
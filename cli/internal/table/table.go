@@ -0,0 +1,222 @@
+// Package table renders width-aware, Unicode-safe text tables for the CLI.
+package table
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/fatih/color"
+	"github.com/mattn/go-runewidth"
+)
+
+// DefaultWidth is used when the terminal width cannot be determined.
+const DefaultWidth = 78
+
+// ellipsis marks truncated cells.
+const ellipsis = "…"
+
+// Align controls how a cell is padded within its column.
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignRight
+)
+
+// Column describes one table column.
+type Column struct {
+	Header string
+	// Min is the narrowest the column may shrink to when space is short.
+	Min int
+	// Max caps the column width; zero means unlimited.
+	Max int
+	// Flex columns give up space first when the table is too wide.
+	Flex  bool
+	Align Align
+}
+
+// Row is one line of cells with an optional style applied to the whole line.
+type Row struct {
+	Cells []string
+	Style *color.Color
+}
+
+// Table collects rows and renders them to fit a target width.
+type Table struct {
+	Columns []Column
+	Rows    []Row
+	// Width is the total display width available, including separators.
+	Width int
+	// HeaderStyle styles the header line; nil leaves it plain.
+	HeaderStyle *color.Color
+}
+
+// New creates a table that fits within width display columns.
+func New(width int, columns ...Column) *Table {
+	return &Table{Columns: columns, Width: width}
+}
+
+// Add appends a row. Missing cells render empty; extra cells are ignored.
+func (t *Table) Add(style *color.Color, cells ...string) {
+	t.Rows = append(t.Rows, Row{Cells: cells, Style: style})
+}
+
+// Render writes the table to w.
+func (t *Table) Render(w io.Writer) error {
+	widths := t.layout()
+
+	if t.hasHeaders() {
+		headers := make([]string, len(t.Columns))
+		for i, c := range t.Columns {
+			headers[i] = c.Header
+		}
+		if err := t.writeLine(w, headers, widths, t.HeaderStyle); err != nil {
+			return err
+		}
+	}
+	for _, row := range t.Rows {
+		if err := t.writeLine(w, row.Cells, widths, row.Style); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Table) hasHeaders() bool {
+	for _, c := range t.Columns {
+		if c.Header != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Table) writeLine(w io.Writer, cells []string, widths []int, style *color.Color) error {
+	parts := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		cell := ""
+		if i < len(cells) {
+			cell = sanitize(cells[i])
+		}
+		cell = Truncate(cell, widths[i])
+		if c.Align == AlignRight {
+			parts[i] = runewidth.FillLeft(cell, widths[i])
+		} else if i < len(t.Columns)-1 {
+			parts[i] = runewidth.FillRight(cell, widths[i])
+		} else {
+			parts[i] = cell
+		}
+	}
+	line := strings.Join(parts, " ")
+	if style != nil {
+		line = style.Sprint(line)
+	}
+	_, err := io.WriteString(w, line+"\n")
+	return err
+}
+
+// layout computes column widths: natural content width capped by Max, then
+// shrunk toward Min (flex columns first) until the row fits in Width.
+func (t *Table) layout() []int {
+	widths := make([]int, len(t.Columns))
+	for i, c := range t.Columns {
+		widths[i] = StringWidth(c.Header)
+		for _, row := range t.Rows {
+			if i < len(row.Cells) {
+				if w := StringWidth(sanitize(row.Cells[i])); w > widths[i] {
+					widths[i] = w
+				}
+			}
+		}
+		if c.Max > 0 && widths[i] > c.Max {
+			widths[i] = c.Max
+		}
+	}
+
+	if t.Width <= 0 {
+		return widths
+	}
+
+	excess := t.total(widths) - t.Width
+	for _, flexPass := range []bool{true, false} {
+		for excess > 0 {
+			i := t.widestShrinkable(widths, flexPass)
+			if i < 0 {
+				break
+			}
+			widths[i]--
+			excess--
+		}
+	}
+	return widths
+}
+
+func (t *Table) total(widths []int) int {
+	sum := len(widths) - 1
+	if sum < 0 {
+		sum = 0
+	}
+	for _, w := range widths {
+		sum += w
+	}
+	return sum
+}
+
+// widestShrinkable returns the widest column still above its minimum, so
+// shrinking trims long columns before short ones.
+func (t *Table) widestShrinkable(widths []int, flex bool) int {
+	best := -1
+	for i, c := range t.Columns {
+		if c.Flex != flex || widths[i] <= c.Min {
+			continue
+		}
+		if best < 0 || widths[i] > widths[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// sanitize flattens control whitespace so a cell stays on one line.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\r' || r == '\t' {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+// StringWidth returns the number of terminal cells s occupies.
+func StringWidth(s string) int {
+	return runewidth.StringWidth(s)
+}
+
+// Truncate shortens s to at most max display cells, never splitting a rune,
+// and marks the cut with an ellipsis.
+func Truncate(s string, max int) string {
+	if max <= 0 {
+		return ""
+	}
+	if runewidth.StringWidth(s) <= max {
+		return s
+	}
+	return runewidth.Truncate(s, max, ellipsis)
+}
+
+// TerminalWidth reports the display width for output to f. It honors
+// $COLUMNS, then the terminal size, and falls back to DefaultWidth.
+func TerminalWidth(f *os.File) int {
+	if v, err := strconv.Atoi(strings.TrimSpace(os.Getenv("COLUMNS"))); err == nil && v > 0 {
+		return v
+	}
+	if f != nil {
+		if w, _, err := term.GetSize(f.Fd()); err == nil && w > 0 {
+			return w
+		}
+	}
+	return DefaultWidth
+}
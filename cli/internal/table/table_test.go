@@ -0,0 +1,121 @@
+package table
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		input string
+		max   int
+		want  string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 5, "hello"},
+		{"hello world", 6, "hello…"},
+		{"héllo wörld", 6, "héllo…"},
+		{"日本語のメール", 7, "日本語…"},
+		{"📬📬📬📬", 5, "📬📬…"},
+		{"anything", 0, ""},
+	}
+
+	for _, tt := range tests {
+		got := Truncate(tt.input, tt.max)
+		if got != tt.want {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tt.input, tt.max, got, tt.want)
+		}
+		if w := StringWidth(got); w > tt.max {
+			t.Errorf("Truncate(%q, %d) width = %d, exceeds max", tt.input, tt.max, w)
+		}
+	}
+}
+
+func TestRender_AlignsWideRunes(t *testing.T) {
+	tbl := New(0, Column{}, Column{})
+	tbl.Add(nil, "田中", "a")
+	tbl.Add(nil, "bob", "b")
+
+	var buf bytes.Buffer
+	if err := tbl.Render(&buf); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	if StringWidth(lines[0]) != StringWidth(lines[1]) {
+		t.Errorf("misaligned rows: %q (%d) vs %q (%d)", lines[0], StringWidth(lines[0]), lines[1], StringWidth(lines[1]))
+	}
+}
+
+func TestRender_FitsWidthShrinkingFlexFirst(t *testing.T) {
+	tbl := New(30,
+		Column{Min: 4, Align: AlignRight},
+		Column{Min: 5, Flex: true},
+		Column{Min: 8},
+	)
+	tbl.Add(nil, "[12]", strings.Repeat("subject ", 10), "2026-10-18")
+
+	var buf bytes.Buffer
+	if err := tbl.Render(&buf); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	line := strings.TrimRight(buf.String(), "\n")
+	if w := StringWidth(line); w > 30 {
+		t.Errorf("line width = %d, want <= 30: %q", w, line)
+	}
+	if !strings.HasSuffix(line, "2026-10-18") {
+		t.Errorf("fixed column was shrunk: %q", line)
+	}
+	if !strings.Contains(line, "…") {
+		t.Errorf("flex column not truncated: %q", line)
+	}
+}
+
+func TestRender_HeadersAndMax(t *testing.T) {
+	tbl := New(0, Column{Header: "ID", Align: AlignRight}, Column{Header: "NAME", Max: 3})
+	tbl.Add(nil, "7", "alice")
+
+	var buf bytes.Buffer
+	if err := tbl.Render(&buf); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	want := "ID NA…\n 7 al…\n"
+	if buf.String() != want {
+		t.Errorf("Render() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestRender_RowStyle(t *testing.T) {
+	original := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = original }()
+
+	tbl := New(0, Column{})
+	tbl.Add(color.New(color.Bold), "unread")
+
+	var buf bytes.Buffer
+	if err := tbl.Render(&buf); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "\x1b[1m") {
+		t.Errorf("expected bold escape in %q", buf.String())
+	}
+}
+
+func TestTerminalWidth_Columns(t *testing.T) {
+	t.Setenv("COLUMNS", "132")
+	if got := TerminalWidth(nil); got != 132 {
+		t.Errorf("TerminalWidth() = %d, want 132", got)
+	}
+
+	t.Setenv("COLUMNS", "")
+	if got := TerminalWidth(nil); got != DefaultWidth {
+		t.Errorf("TerminalWidth() = %d, want %d", got, DefaultWidth)
+	}
+}
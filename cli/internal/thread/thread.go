@@ -0,0 +1,395 @@
+// Package thread groups emails into conversations using the JWZ threading
+// algorithm (https://www.jwz.org/doc/threading.html) over Message-ID,
+// In-Reply-To and References, falling back to the subject line.
+package thread
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+)
+
+// Container is a node in a thread tree. Containers for messages that are
+// referenced but not present have a nil Email.
+type Container struct {
+	ID       string
+	Email    *api.Email
+	Parent   *Container
+	Children []*Container
+}
+
+// Entry is one message of a flattened thread.
+type Entry struct {
+	Email *api.Email
+	Depth int
+}
+
+// Build threads emails and returns the root containers, most recently
+// active conversation first. Emails are referenced, not copied.
+func Build(emails []api.Email) []*Container {
+	b := &builder{ids: make(map[string]*Container)}
+	for i := range emails {
+		b.add(&emails[i])
+	}
+
+	var roots []*Container
+	for _, c := range b.order {
+		if c.Parent == nil {
+			roots = append(roots, c)
+		}
+	}
+	roots = prune(roots, true)
+	roots = groupBySubject(roots)
+
+	for _, r := range roots {
+		sortChildren(r)
+	}
+	sort.SliceStable(roots, func(i, j int) bool {
+		return latest(roots[i]).After(latest(roots[j]))
+	})
+	return roots
+}
+
+// Find returns the root whose tree contains the email with id.
+func Find(roots []*Container, id int) *Container {
+	for _, r := range roots {
+		found := false
+		r.walk(0, func(c *Container, _ int) {
+			if c.Email != nil && c.Email.ID == id {
+				found = true
+			}
+		})
+		if found {
+			return r
+		}
+	}
+	return nil
+}
+
+// Flatten lists the messages of a tree depth-first. Placeholder containers
+// are skipped and their children shown at the placeholder's depth.
+func Flatten(root *Container) []Entry {
+	var entries []Entry
+	if root == nil {
+		return entries
+	}
+	root.walk(0, func(c *Container, depth int) {
+		if c.Email != nil {
+			entries = append(entries, Entry{Email: c.Email, Depth: depth})
+		}
+	})
+	return entries
+}
+
+// Count returns the number of messages in the tree.
+func (c *Container) Count() int {
+	n := 0
+	c.walk(0, func(node *Container, _ int) {
+		if node.Email != nil {
+			n++
+		}
+	})
+	return n
+}
+
+// Key identifies the conversation for collapse state.
+func (c *Container) Key() string {
+	return c.ID
+}
+
+// Subject returns the subject of the first message in the tree.
+func (c *Container) Subject() string {
+	if e := c.first(); e != nil {
+		return e.Subject
+	}
+	return ""
+}
+
+func (c *Container) first() *api.Email {
+	if c.Email != nil {
+		return c.Email
+	}
+	for _, child := range c.Children {
+		if e := child.first(); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// walk visits the tree depth-first, passing the display depth.
+func (c *Container) walk(depth int, fn func(*Container, int)) {
+	fn(c, depth)
+	next := depth + 1
+	if c.Email == nil {
+		next = depth
+	}
+	for _, child := range c.Children {
+		child.walk(next, fn)
+	}
+}
+
+type builder struct {
+	ids   map[string]*Container
+	order []*Container
+}
+
+func (b *builder) get(id string) *Container {
+	c, ok := b.ids[id]
+	if !ok {
+		c = &Container{ID: id}
+		b.ids[id] = c
+		b.order = append(b.order, c)
+	}
+	return c
+}
+
+func (b *builder) add(email *api.Email) {
+	headers := email.Headers()
+	id := firstMessageID(headers["message-id"])
+	if id == "" {
+		id = firstMessageID(email.MessageID)
+	}
+	if id == "" || (b.ids[id] != nil && b.ids[id].Email != nil) {
+		// Missing or duplicate Message-IDs still get their own node.
+		id = fmt.Sprintf("mercury-%d", email.ID)
+	}
+	c := b.get(id)
+	c.Email = email
+
+	refs := messageIDs(headers["references"])
+	if irt := firstMessageID(headers["in-reply-to"]); irt != "" && (len(refs) == 0 || refs[len(refs)-1] != irt) {
+		refs = append(refs, irt)
+	}
+
+	var prev *Container
+	for _, ref := range refs {
+		rc := b.get(ref)
+		if prev != nil && rc.Parent == nil && rc != prev && !isAncestor(rc, prev) {
+			link(prev, rc)
+		}
+		prev = rc
+	}
+
+	if prev == c || (prev != nil && isAncestor(c, prev)) {
+		return
+	}
+	if c.Parent != nil {
+		unlink(c)
+	}
+	if prev != nil {
+		link(prev, c)
+	}
+}
+
+// LinkIDs returns the Message-IDs that tie email into a conversation: its
+// own, then those in its References and In-Reply-To headers.
+func LinkIDs(email *api.Email) []string {
+	headers := email.Headers()
+	var ids []string
+	id := firstMessageID(headers["message-id"])
+	if id == "" {
+		id = firstMessageID(email.MessageID)
+	}
+	if id != "" {
+		ids = append(ids, id)
+	}
+	ids = append(ids, messageIDs(headers["references"])...)
+	if irt := firstMessageID(headers["in-reply-to"]); irt != "" {
+		ids = append(ids, irt)
+	}
+	return ids
+}
+
+// isAncestor reports whether a is c or one of c's ancestors.
+func isAncestor(a, c *Container) bool {
+	for n := c; n != nil; n = n.Parent {
+		if n == a {
+			return true
+		}
+	}
+	return false
+}
+
+func link(parent, child *Container) {
+	child.Parent = parent
+	parent.Children = append(parent.Children, child)
+}
+
+func unlink(c *Container) {
+	p := c.Parent
+	for i, child := range p.Children {
+		if child == c {
+			p.Children = append(p.Children[:i], p.Children[i+1:]...)
+			break
+		}
+	}
+	c.Parent = nil
+}
+
+// prune drops empty placeholders and promotes the children of placeholders
+// (at the root only when that yields a single root).
+func prune(cs []*Container, root bool) []*Container {
+	var out []*Container
+	for _, c := range cs {
+		c.Children = prune(c.Children, false)
+		if c.Email == nil {
+			if len(c.Children) == 0 {
+				continue
+			}
+			if !root || len(c.Children) == 1 {
+				for _, child := range c.Children {
+					child.Parent = c.Parent
+				}
+				out = append(out, c.Children...)
+				continue
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// groupBySubject merges root threads that share a base subject, so replies
+// whose headers were stripped still join their conversation.
+func groupBySubject(roots []*Container) []*Container {
+	table := make(map[string]*Container)
+	for _, r := range roots {
+		subject := r.Subject()
+		base := BaseSubject(subject)
+		if base == "" {
+			continue
+		}
+		existing, ok := table[base]
+		if !ok ||
+			(r.Email == nil && existing.Email != nil) ||
+			(existing.Email != nil && r.Email != nil && IsReply(existing.Email.Subject) && !IsReply(r.Email.Subject)) {
+			table[base] = r
+		}
+	}
+
+	var out []*Container
+	for _, r := range roots {
+		if r.Parent != nil {
+			continue // already merged under another root
+		}
+		base := BaseSubject(r.Subject())
+		that, ok := table[base]
+		if base == "" || !ok || that == r {
+			out = append(out, r)
+			continue
+		}
+
+		switch {
+		case that.Email == nil && r.Email == nil:
+			for _, child := range r.Children {
+				link(that, child)
+			}
+		case that.Email == nil:
+			link(that, r)
+		case that.Email != nil && !IsReply(that.Email.Subject) && r.Email != nil && IsReply(r.Email.Subject):
+			link(that, r)
+		default:
+			// Neither is clearly the parent: make them siblings under a placeholder.
+			placeholder := &Container{ID: "subject:" + base}
+			link(placeholder, that)
+			link(placeholder, r)
+			table[base] = placeholder
+			replaced := false
+			for i, o := range out {
+				if o == that {
+					out[i] = placeholder
+					replaced = true
+				}
+			}
+			if !replaced {
+				out = append(out, placeholder)
+			}
+		}
+	}
+	return out
+}
+
+func sortChildren(c *Container) {
+	sort.SliceStable(c.Children, func(i, j int) bool {
+		return earliest(c.Children[i]).Before(earliest(c.Children[j]))
+	})
+	for _, child := range c.Children {
+		sortChildren(child)
+	}
+}
+
+func earliest(c *Container) time.Time {
+	var t time.Time
+	c.walk(0, func(n *Container, _ int) {
+		if n.Email == nil {
+			return
+		}
+		if d, err := api.ParseTimestamp(n.Email.ReceivedAt); err == nil && (t.IsZero() || d.Before(t)) {
+			t = d
+		}
+	})
+	return t
+}
+
+func latest(c *Container) time.Time {
+	var t time.Time
+	c.walk(0, func(n *Container, _ int) {
+		if n.Email == nil {
+			return
+		}
+		if d, err := api.ParseTimestamp(n.Email.ReceivedAt); err == nil && d.After(t) {
+			t = d
+		}
+	})
+	return t
+}
+
+var messageIDPattern = regexp.MustCompile(`<([^<>\s]+)>`)
+
+// messageIDs extracts the angle-bracketed IDs from a header value in order.
+func messageIDs(value string) []string {
+	var ids []string
+	for _, m := range messageIDPattern.FindAllStringSubmatch(value, -1) {
+		ids = append(ids, strings.ToLower(m[1]))
+	}
+	return ids
+}
+
+// firstMessageID returns the first ID in value, accepting a bare ID without
+// angle brackets.
+func firstMessageID(value string) string {
+	if ids := messageIDs(value); len(ids) > 0 {
+		return ids[0]
+	}
+	value = strings.TrimSpace(value)
+	if value == "" || strings.ContainsAny(value, " \t<>") {
+		return ""
+	}
+	return strings.ToLower(value)
+}
+
+var replyPrefix = regexp.MustCompile(`(?i)^\s*(re|fwd?|aw|sv)(\[\d+\])?\s*:\s*`)
+
+// BaseSubject strips reply and forward prefixes and normalizes whitespace
+// and case so related subjects compare equal.
+func BaseSubject(subject string) string {
+	s := subject
+	for {
+		stripped := replyPrefix.ReplaceAllString(s, "")
+		if stripped == s {
+			break
+		}
+		s = stripped
+	}
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// IsReply reports whether the subject carries a reply or forward prefix.
+func IsReply(subject string) bool {
+	return replyPrefix.MatchString(subject)
+}
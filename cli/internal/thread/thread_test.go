@@ -0,0 +1,191 @@
+package thread
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+)
+
+func email(id int, subject, date string, headers map[string]string) api.Email {
+	data, _ := json.Marshal(headers)
+	return api.Email{ID: id, Subject: subject, ReceivedAt: date, HeadersJSON: string(data), MessageID: headers["message-id"]}
+}
+
+func ids(entries []Entry) []int {
+	var out []int
+	for _, e := range entries {
+		out = append(out, e.Email.ID)
+	}
+	return out
+}
+
+func depths(entries []Entry) []int {
+	var out []int
+	for _, e := range entries {
+		out = append(out, e.Depth)
+	}
+	return out
+}
+
+func equal(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBuild_ReferencesChain(t *testing.T) {
+	emails := []api.Email{
+		email(3, "Re: Plan", "2026-10-03 10:00:00", map[string]string{
+			"message-id": "<c@x>", "in-reply-to": "<b@x>", "references": "<a@x> <b@x>",
+		}),
+		email(1, "Plan", "2026-10-01 10:00:00", map[string]string{"message-id": "<a@x>"}),
+		email(2, "Re: Plan", "2026-10-02 10:00:00", map[string]string{
+			"message-id": "<b@x>", "in-reply-to": "<a@x>",
+		}),
+		email(4, "Re: Plan", "2026-10-02 12:00:00", map[string]string{
+			"message-id": "<d@x>", "references": "<a@x>",
+		}),
+		email(5, "Unrelated", "2026-09-01 10:00:00", map[string]string{"message-id": "<z@x>"}),
+	}
+
+	roots := Build(emails)
+	if len(roots) != 2 {
+		t.Fatalf("got %d roots, want 2", len(roots))
+	}
+	entries := Flatten(roots[0])
+	if got := ids(entries); !equal(got, []int{1, 2, 3, 4}) {
+		t.Errorf("thread order = %v, want [1 2 3 4]", got)
+	}
+	if got := depths(entries); !equal(got, []int{0, 1, 2, 1}) {
+		t.Errorf("depths = %v, want [0 1 2 1]", got)
+	}
+	if roots[0].Count() != 4 {
+		t.Errorf("Count() = %d, want 4", roots[0].Count())
+	}
+	if Find(roots, 5) != roots[1] {
+		t.Error("Find(5) should return the unrelated root")
+	}
+}
+
+func TestBuild_MissingParentPlaceholder(t *testing.T) {
+	// Two replies to a message we never received share a placeholder parent.
+	emails := []api.Email{
+		email(1, "Re: Launch", "2026-10-01 10:00:00", map[string]string{"message-id": "<r1@x>", "references": "<root@x>"}),
+		email(2, "Re: Launch", "2026-10-02 10:00:00", map[string]string{"message-id": "<r2@x>", "references": "<root@x>"}),
+	}
+
+	roots := Build(emails)
+	if len(roots) != 1 {
+		t.Fatalf("got %d roots, want 1", len(roots))
+	}
+	entries := Flatten(roots[0])
+	if got := ids(entries); !equal(got, []int{1, 2}) {
+		t.Errorf("ids = %v, want [1 2]", got)
+	}
+	if got := depths(entries); !equal(got, []int{0, 0}) {
+		t.Errorf("depths = %v, want siblings at depth 0", got)
+	}
+}
+
+func TestBuild_SubjectFallback(t *testing.T) {
+	// Without threading headers, replies join the original by subject.
+	emails := []api.Email{
+		{ID: 2, Subject: "RE: Re: Budget", ReceivedAt: "2026-10-02 10:00:00"},
+		{ID: 1, Subject: "Budget", ReceivedAt: "2026-10-01 10:00:00"},
+		{ID: 3, Subject: "Fwd: budget", ReceivedAt: "2026-10-03 10:00:00"},
+		{ID: 4, Subject: "Other", ReceivedAt: "2026-10-04 10:00:00"},
+	}
+
+	roots := Build(emails)
+	if len(roots) != 2 {
+		t.Fatalf("got %d roots, want 2", len(roots))
+	}
+	if roots[0].Email == nil || roots[0].Email.ID != 4 {
+		t.Errorf("most recent thread should be first")
+	}
+	if got := ids(Flatten(roots[1])); !equal(got, []int{1, 2, 3}) {
+		t.Errorf("budget thread = %v, want [1 2 3]", got)
+	}
+}
+
+func TestBuild_SameSubjectNonRepliesBecomeSiblings(t *testing.T) {
+	emails := []api.Email{
+		{ID: 1, Subject: "Weekly report", ReceivedAt: "2026-10-01 10:00:00"},
+		{ID: 2, Subject: "Weekly report", ReceivedAt: "2026-10-08 10:00:00"},
+	}
+
+	roots := Build(emails)
+	if len(roots) != 1 {
+		t.Fatalf("got %d roots, want 1", len(roots))
+	}
+	if got := depths(Flatten(roots[0])); !equal(got, []int{0, 0}) {
+		t.Errorf("depths = %v, want siblings", got)
+	}
+}
+
+func TestBuild_DuplicateAndLoopingIDs(t *testing.T) {
+	emails := []api.Email{
+		email(1, "A", "2026-10-01 10:00:00", map[string]string{"message-id": "<a@x>", "references": "<b@x>"}),
+		email(2, "B", "2026-10-02 10:00:00", map[string]string{"message-id": "<b@x>", "references": "<a@x>"}),
+		email(3, "A copy", "2026-10-03 10:00:00", map[string]string{"message-id": "<a@x>"}),
+	}
+
+	roots := Build(emails)
+	total := 0
+	for _, r := range roots {
+		total += r.Count()
+	}
+	if total != 3 {
+		t.Errorf("threads hold %d messages, want all 3", total)
+	}
+}
+
+func TestLinkIDs(t *testing.T) {
+	e := email(1, "Re: Plan", "2026-10-02 10:00:00", map[string]string{
+		"message-id": "<C@x>", "references": "<a@x> <b@x>", "in-reply-to": "<b@x>",
+	})
+	got := LinkIDs(&e)
+	want := []string{"c@x", "a@x", "b@x", "b@x"}
+	if len(got) != len(want) {
+		t.Fatalf("LinkIDs() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("LinkIDs()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	// A list summary carries only its Message-ID.
+	summary := api.Email{ID: 2, MessageID: "<d@x>"}
+	if got := LinkIDs(&summary); len(got) != 1 || got[0] != "d@x" {
+		t.Errorf("LinkIDs(summary) = %v", got)
+	}
+}
+
+func TestBaseSubject(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Hello", "hello"},
+		{"Re: Hello", "hello"},
+		{"RE: Fwd: re[2]: Hello  World", "hello world"},
+		{"AW: Hello", "hello"},
+		{"Reply needed", "reply needed"},
+	}
+	for _, tt := range tests {
+		if got := BaseSubject(tt.input); got != tt.want {
+			t.Errorf("BaseSubject(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+	if IsReply("Reply needed") || !IsReply("Re: x") {
+		t.Error("IsReply misclassified subjects")
+	}
+}
@@ -0,0 +1,312 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+)
+
+// bulkWorkers bounds concurrent API calls for actions on many emails
+const bulkWorkers = api.ConcurrentRequests
+
+// maxErrorSummary is how many per-item failures the status bar lists
+const maxErrorSummary = 3
+
+type bulkKind int
+
+const (
+	bulkDelete bulkKind = iota
+	bulkArchive
+	bulkRead
+	bulkStar
+	bulkUnstar
+	bulkMove
+)
+
+// progress names the operation while it runs
+func (k bulkKind) progress() string {
+	return [...]string{"Deleting", "Archiving", "Marking read", "Starring", "Unstarring", "Moving"}[k]
+}
+
+// done names the operation once finished
+func (k bulkKind) done() string {
+	return [...]string{"Deleted", "Archived", "Marked read", "Starred", "Unstarred", "Moved"}[k]
+}
+
+type bulkResult struct {
+	Email api.Email
+	Err   error
+}
+
+// bulkJob applies one operation to many emails through a worker pool,
+// streaming results back to the model as BulkProgress messages
+type bulkJob struct {
+	Kind   bulkKind
+	Folder string // destination for bulkMove
+	Emails []api.Email
+	Done   int
+	Failed []bulkResult
+	Gen    int // Model.gen when the job started
+
+	client  *api.Client // fixed at start, so a profile switch cannot redirect the job
+	once    sync.Once
+	results chan bulkResult
+}
+
+func newBulkJob(client *api.Client, kind bulkKind, folder string, emails []api.Email) *bulkJob {
+	return &bulkJob{Kind: kind, Folder: folder, Emails: emails, client: client, results: make(chan bulkResult, len(emails))}
+}
+
+// apply performs the job's operation on a single email
+func (j *bulkJob) apply(email api.Email) error {
+	switch j.Kind {
+	case bulkDelete:
+		return j.client.DeleteEmail(email.ID, false)
+	case bulkArchive:
+		return j.client.ArchiveEmail(email.ID)
+	case bulkRead:
+		return j.client.MarkAsRead(email.ID)
+	case bulkStar:
+		return j.client.StarEmail(email.ID, true)
+	case bulkUnstar:
+		return j.client.StarEmail(email.ID, false)
+	default:
+		return j.client.MoveEmail(email.ID, j.Folder)
+	}
+}
+
+// run starts the workers on first use and waits for the next result
+func (j *bulkJob) run() tea.Cmd {
+	return func() tea.Msg {
+		j.once.Do(func() {
+			go forEach(j.Emails, j.apply, j.results)
+		})
+		return BulkProgress{Job: j, Result: <-j.results}
+	}
+}
+
+// forEach calls fn on every email, at most bulkWorkers at a time, sending
+// each outcome to out
+func forEach(emails []api.Email, fn func(api.Email) error, out chan<- bulkResult) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, bulkWorkers)
+	for _, email := range emails {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(email api.Email) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			out <- bulkResult{Email: email, Err: fn(email)}
+		}(email)
+	}
+	wg.Wait()
+}
+
+// startBulk runs an operation over emails in the background
+func startBulk(m Model, kind bulkKind, folder string, emails []api.Email) (Model, tea.Cmd) {
+	if len(emails) == 0 {
+		return m, nil
+	}
+	job := newBulkJob(m.client, kind, folder, emails)
+	job.Gen = m.gen
+	m.jobs = append(m.jobs, job)
+	return m, tea.Batch(job.run(), m.spinner.Tick)
+}
+
+// targets returns the marked emails, or the selected one when none are marked
+func (m Model) targets() []api.Email {
+	if m.list.MarkedCount() > 0 {
+		return m.list.Marked()
+	}
+	if selected := m.list.SelectedEmail(); selected != nil {
+		return []api.Email{*selected}
+	}
+	return nil
+}
+
+// bulkRead marks the targeted unread emails as read
+func bulkMarkRead(m Model) (Model, tea.Cmd) {
+	var unread []api.Email
+	for _, email := range m.targets() {
+		if email.IsRead == 0 {
+			unread = append(unread, email)
+		}
+	}
+	m.err = nil
+	m.list.ClearMarks()
+	return startBulk(m, bulkRead, "", unread)
+}
+
+// bulkToggleStar stars the targets, or unstars them if all are starred
+func bulkToggleStar(m Model) (Model, tea.Cmd) {
+	emails := m.targets()
+	kind := bulkUnstar
+	for _, email := range emails {
+		if email.IsStarred == 0 {
+			kind = bulkStar
+			break
+		}
+	}
+	m.err = nil
+	m.list.ClearMarks()
+	return startBulk(m, kind, "", emails)
+}
+
+// bulkMoveTo moves the targets to folder
+func bulkMoveTo(m Model, folder string) (Model, tea.Cmd) {
+	if !api.ValidFolder(folder) {
+		m.err = fmt.Errorf("unknown folder %q (valid: %s)", folder, strings.Join(api.Folders, ", "))
+		return m, nil
+	}
+	emails := m.targets()
+	m.err = nil
+	m.list.ClearMarks()
+	return startBulk(m, bulkMove, folder, emails)
+}
+
+// bulkProgress records one result, applies its effect and reports completion
+func bulkProgress(m Model, msg BulkProgress) (Model, tea.Cmd) {
+	job := msg.Job
+	job.Done++
+	var cmd tea.Cmd
+	if msg.Result.Err != nil {
+		job.Failed = append(job.Failed, msg.Result)
+	}
+	switch {
+	case job.Gen != m.gen:
+		// Started before a folder or profile switch; the email is not
+		// in this listing.
+	case msg.Result.Err != nil:
+		if job.Kind == bulkDelete || job.Kind == bulkArchive {
+			m, cmd = restoreEmails(m, []api.Email{msg.Result.Email})
+		}
+	default:
+		m, cmd = applyResult(m, job, msg.Result.Email)
+	}
+
+	if job.Done < len(job.Emails) {
+		return m, tea.Batch(cmd, job.run())
+	}
+	for i, j := range m.jobs {
+		if j == job {
+			m.jobs = append(m.jobs[:i:i], m.jobs[i+1:]...)
+			break
+		}
+	}
+	if len(job.Failed) > 0 {
+		m.err = errorSummary(job)
+	} else if len(m.pending) == 0 {
+		m.status = fmt.Sprintf("%s %s", job.Kind.done(), plural(len(job.Emails), "email"))
+	}
+	return m, cmd
+}
+
+// applyResult reflects a successful operation in the loaded emails
+func applyResult(m Model, job *bulkJob, email api.Email) (Model, tea.Cmd) {
+	update := func(e *api.Email) {
+		switch job.Kind {
+		case bulkRead:
+			e.IsRead = 1
+		case bulkStar:
+			e.IsStarred = 1
+		case bulkUnstar:
+			e.IsStarred = 0
+		case bulkMove:
+			e.Folder = job.Folder
+		}
+	}
+	switch job.Kind {
+	case bulkDelete, bulkArchive:
+		// Already hidden when the undo window opened.
+		return m, nil
+	case bulkMove:
+		if m.searchQuery == "" && job.Folder != m.folder {
+			return removeEmails(m, []int{email.ID})
+		}
+	}
+
+	for i := range m.emails {
+		if m.emails[i].ID == email.ID {
+			update(&m.emails[i])
+		}
+	}
+	for i := range m.inbox {
+		if m.inbox[i].ID == email.ID {
+			update(&m.inbox[i])
+		}
+	}
+	if m.currentEmail != nil && m.currentEmail.ID == email.ID {
+		update(m.currentEmail)
+	}
+	if m.threaded {
+		return m, m.buildThreads()
+	}
+	selectedID := m.selectedID()
+	m.list.SetEmails(m.emails)
+	m.list.SelectID(selectedID)
+	return m, nil
+}
+
+// errorSummary lists the first few per-item failures of a job
+func errorSummary(job *bulkJob) error {
+	failed := append([]bulkResult(nil), job.Failed...)
+	sort.Slice(failed, func(i, j int) bool { return failed[i].Email.ID < failed[j].Email.ID })
+	parts := make([]string, 0, maxErrorSummary)
+	for i, r := range failed {
+		if i == maxErrorSummary {
+			parts = append(parts, fmt.Sprintf("and %d more", len(failed)-maxErrorSummary))
+			break
+		}
+		parts = append(parts, fmt.Sprintf("#%d: %v", r.Email.ID, r.Err))
+	}
+	return fmt.Errorf("%s failed for %d of %d: %s", strings.ToLower(job.Kind.progress()), len(failed), len(job.Emails), strings.Join(parts, "; "))
+}
+
+// bulkStatus shows the progress of running jobs
+func (m Model) bulkStatus() string {
+	parts := make([]string, len(m.jobs))
+	for i, job := range m.jobs {
+		parts[i] = fmt.Sprintf("%s %d/%d", job.Kind.progress(), job.Done, len(job.Emails))
+	}
+	return strings.Join(parts, " · ")
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// startMove opens the destination folder prompt
+func startMove(m Model) (Model, tea.Cmd) {
+	if len(m.targets()) == 0 {
+		return m, nil
+	}
+	m.moving = true
+	m.moveInput.SetValue("")
+	return m, m.moveInput.Focus()
+}
+
+// updateMoveInput handles keys while the folder prompt has focus
+func updateMoveInput(m Model, msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.moving = false
+		m.moveInput.Blur()
+		return m, nil
+	case tea.KeyEnter:
+		m.moving = false
+		m.moveInput.Blur()
+		return bulkMoveTo(m, strings.TrimSpace(m.moveInput.Value()))
+	}
+
+	var cmd tea.Cmd
+	m.moveInput, cmd = m.moveInput.Update(msg)
+	return m, cmd
+}
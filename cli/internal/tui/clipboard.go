@@ -0,0 +1,32 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// clipboardOutput receives OSC 52 sequences, which the terminal passes on
+// to the system clipboard, over SSH too
+var clipboardOutput io.Writer = os.Stderr
+
+// copyText puts text on the clipboard, reporting what was copied
+func copyText(text, what string) tea.Cmd {
+	return func() tea.Msg {
+		seq := osc52.New(text)
+		switch {
+		case os.Getenv("TMUX") != "":
+			seq = seq.Tmux()
+		case strings.HasPrefix(os.Getenv("TERM"), "screen"):
+			seq = seq.Screen()
+		}
+		if _, err := seq.WriteTo(clipboardOutput); err != nil {
+			return ErrMsg{Err: fmt.Errorf("copy %s: %w", what, err)}
+		}
+		return Copied{What: what}
+	}
+}
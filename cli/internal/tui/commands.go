@@ -6,14 +6,12 @@ import (
 	"github.com/misty-step/mercury/cli/internal/api"
 )
 
-// fetchEmails fetches the email list asynchronously
-func fetchEmails(client *api.Client, limit, offset int) tea.Cmd {
+// loadCachedEmails shows the locally cached list while the network fetch runs
+func (m Model) loadCachedEmails(limit, offset int) tea.Cmd {
+	client, folder, gen := m.client, m.folder, m.gen
 	return func() tea.Msg {
-		resp, err := client.ListEmails(limit, offset, "inbox")
-		if err != nil {
-			return ErrMsg{Err: err}
-		}
-		return EmailsFetched{Emails: resp.Emails, Total: resp.Total}
+		resp := client.CachedEmails(limit, offset, folder)
+		return EmailsFetched{Emails: resp.Emails, Total: resp.Total, Offset: offset, Cached: true, Gen: gen}
 	}
 }
 
@@ -27,23 +25,3 @@ func fetchEmail(client *api.Client, id int) tea.Cmd {
 		return EmailFetched{Email: *email}
 	}
 }
-
-// markRead marks an email as read
-func markRead(client *api.Client, id int) tea.Cmd {
-	return func() tea.Msg {
-		if err := client.MarkAsRead(id); err != nil {
-			return ErrMsg{Err: err}
-		}
-		return EmailMarked{ID: id}
-	}
-}
-
-// deleteEmail soft-deletes an email
-func deleteEmail(client *api.Client, id int) tea.Cmd {
-	return func() tea.Msg {
-		if err := client.DeleteEmail(id, false); err != nil {
-			return ErrMsg{Err: err}
-		}
-		return EmailDeleted{ID: id}
-	}
-}
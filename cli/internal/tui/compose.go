@@ -79,12 +79,16 @@ func startCompose(m Model, to, subject string, headers map[string]string) (Model
 	// Write template
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("To: %s\n", to))
+	sb.WriteString("Cc: \n")
 	sb.WriteString(fmt.Sprintf("Subject: %s\n", subject))
 	for k, v := range headers {
 		sb.WriteString(fmt.Sprintf("%s: %s\n", k, v))
 	}
 	sb.WriteString("\n")
 	sb.WriteString("# Write your message above. Lines starting with # are ignored.\n")
+	if m.contactBook() != nil {
+		sb.WriteString("# To, Cc and Bcc take addresses, contact names and @groups, separated by commas.\n")
+	}
 	sb.WriteString("# Save and close the editor to send, or delete all content to cancel.\n")
 
 	if _, err := tmpFile.WriteString(sb.String()); err != nil {
@@ -208,7 +212,7 @@ func handleEditorClose(m Model, tmpFile string, editorErr error) (Model, tea.Cmd
 
 	// Parse content
 	lines := strings.Split(string(content), "\n")
-	var to, subject, body string
+	var to, cc, bcc, subject, body string
 	headers := make(map[string]string)
 	inHeaders := true
 	var bodyLines []string
@@ -225,6 +229,10 @@ func handleEditorClose(m Model, tmpFile string, editorErr error) (Model, tea.Cmd
 				switch strings.ToLower(key) {
 				case "to":
 					to = val
+				case "cc":
+					cc = val
+				case "bcc":
+					bcc = val
 				case "subject":
 					subject = val
 				default:
@@ -248,8 +256,26 @@ func handleEditorClose(m Model, tmpFile string, editorErr error) (Model, tea.Cmd
 		return m, nil
 	}
 
-	// Validate To field
-	if to == "" || !strings.Contains(to, "@") {
+	// Expand contact names and groups, then validate the recipients
+	// and send each person one copy, in the first field naming them
+	var recipients [3][]string
+	grouped := false
+	seen := make(map[string]bool)
+	for i, list := range []string{to, cc, bcc} {
+		addrs, g, err := m.expandRecipients(list)
+		if err != nil {
+			m.err = fmt.Errorf("invalid recipient: %w. Press 'c' to edit draft", err)
+			return m, nil
+		}
+		for _, addr := range addrs {
+			if key := strings.ToLower(addr); !seen[key] {
+				seen[key] = true
+				recipients[i] = append(recipients[i], addr)
+			}
+		}
+		grouped = grouped || g
+	}
+	if len(recipients[0]) == 0 {
 		m.err = fmt.Errorf("invalid recipient: To must be non-empty and contain @. Press 'c' to edit draft")
 		return m, nil
 	}
@@ -260,16 +286,25 @@ func handleEditorClose(m Model, tmpFile string, editorErr error) (Model, tea.Cmd
 		return m, nil
 	}
 
+	req := &api.SendRequest{
+		To:      strings.Join(recipients[0], ", "),
+		Cc:      strings.Join(recipients[1], ", "),
+		Bcc:     strings.Join(recipients[2], ", "),
+		Subject: subject,
+		Text:    body,
+		Headers: headers,
+	}
+	// A group is confirmed first, showing who it expanded to
+	if grouped {
+		m.sending = &sendPrompt{req: req}
+		return m, nil
+	}
+
 	_ = os.Remove(tmpFile)
 	m.compose = nil
 
 	// Send the email
-	return m, sendEmail(m.client, &api.SendRequest{
-		To:      to,
-		Subject: subject,
-		Text:    body,
-		Headers: headers,
-	})
+	return m, sendEmail(m.client, req)
 }
 
 func extractEmailAddress(sender string) string {
@@ -309,6 +344,12 @@ func sendEmail(client *api.Client, req *api.SendRequest) tea.Cmd {
 		if err != nil {
 			return ErrMsg{Err: err}
 		}
-		return EmailSent{MessageID: resp.MessageID}
+		to := req.To
+		for _, list := range []string{req.Cc, req.Bcc} {
+			if list != "" {
+				to += ", " + list
+			}
+		}
+		return EmailSent{MessageID: resp.MessageID, To: to}
 	}
 }
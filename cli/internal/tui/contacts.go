@@ -0,0 +1,160 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/contacts"
+	"github.com/misty-step/mercury/cli/internal/table"
+)
+
+// contactBook returns the active profile's address book, nil without one
+func (m Model) contactBook() *contacts.Book {
+	if m.opts.Contacts == nil {
+		return nil
+	}
+	return m.opts.Contacts(m.profile)
+}
+
+// startNewCompose asks for the recipient on the command line, where tab
+// completes contacts, before opening the editor. Without an address book,
+// or with a draft in progress, the editor opens straight away.
+func startNewCompose(m Model) (Model, tea.Cmd) {
+	if m.compose != nil || m.contactBook() == nil {
+		return startCompose(m, "", "", nil)
+	}
+	m, cmd := startCommand(m)
+	m.commandInput.SetValue("compose ")
+	m.commandInput.CursorEnd()
+	return m, cmd
+}
+
+// expandRecipients resolves a recipient list, such as "alice, @board", to
+// addresses, reporting whether a group was expanded. Without an address
+// book every item must be an address.
+func (m Model) expandRecipients(list string) ([]string, bool, error) {
+	book := m.contactBook()
+	grouped := false
+	for _, item := range contacts.SplitList(list) {
+		if book == nil && !strings.Contains(item, "@") {
+			return nil, false, fmt.Errorf("%q is not an address", item)
+		}
+		grouped = grouped || contacts.IsGroup(item)
+	}
+	if book == nil {
+		var addrs []string
+		for _, item := range contacts.SplitList(list) {
+			addrs = append(addrs, extractEmailAddress(item))
+		}
+		return addrs, false, nil
+	}
+	people, err := book.Expand(list)
+	if err != nil {
+		return nil, false, err
+	}
+	addrs := make([]string, len(people))
+	for i, c := range people {
+		addrs[i] = c.Email
+	}
+	return addrs, grouped, nil
+}
+
+// composeTo opens the editor for :compose, with the named contacts written
+// out as addresses; groups stay as they are until the message is sent
+func composeTo(m Model, to string) (Model, tea.Cmd) {
+	book := m.contactBook()
+	var items []string
+	for _, item := range contacts.SplitList(to) {
+		if book != nil && !contacts.IsGroup(item) {
+			c, err := book.Resolve(item)
+			if err != nil {
+				m.err = fmt.Errorf("compose: %w", err)
+				return m, nil
+			}
+			item = c.String()
+		}
+		items = append(items, item)
+	}
+	if _, _, err := m.expandRecipients(to); err != nil {
+		m.err = fmt.Errorf("compose: %w", err)
+		return m, nil
+	}
+	return startCompose(m, strings.Join(items, ", "), "", nil)
+}
+
+// sendPrompt is a message to a group awaiting confirmation of its
+// expanded recipients
+type sendPrompt struct {
+	req *api.SendRequest
+}
+
+// updateSendPrompt sends the message on y; any other key returns to the
+// list with the draft kept, so c reopens it
+func updateSendPrompt(m Model, msg tea.KeyMsg) (Model, tea.Cmd) {
+	p := m.sending
+	m.sending = nil
+	if msg.String() != "y" {
+		m.status = "Not sent; press c to edit the draft"
+		return m, nil
+	}
+	if m.compose != nil {
+		_ = os.Remove(m.compose.TmpFile)
+		m.compose = nil
+	}
+	return m, sendEmail(m.client, p.req)
+}
+
+// sendPromptView lists the expanded recipients, truncated to the window
+func (m Model) sendPromptView() string {
+	req := m.sending.req
+	var all []string
+	for _, list := range []string{req.To, req.Cc, req.Bcc} {
+		all = append(all, contacts.SplitList(list)...)
+	}
+	question := fmt.Sprintf("Send to %d recipients?  y send · any other key keeps the draft  ", len(all))
+	room := m.width - len(question) - 2
+	if room <= 0 {
+		return question
+	}
+	return question + table.Truncate(strings.Join(all, ", "), room)
+}
+
+// observeSender adds the sender of an unread email to the address book,
+// once per email a session, so browsing does not inflate the ranking
+func observeSender(m Model, email *api.Email) (Model, tea.Cmd) {
+	book := m.contactBook()
+	if book == nil || email.Read() || m.observed[email.ID] {
+		return m, nil
+	}
+	if m.observed == nil {
+		m.observed = make(map[int]bool)
+	}
+	m.observed[email.ID] = true
+	if book.ObserveEmail(email) == 0 {
+		return m, nil
+	}
+	return m, saveContacts(book)
+}
+
+// observeRecipients adds the recipients of sent mail to the address book
+func observeRecipients(m Model, to string) tea.Cmd {
+	book := m.contactBook()
+	if book == nil || book.Observe(to, time.Now()) == 0 {
+		return nil
+	}
+	return saveContacts(book)
+}
+
+// saveContacts writes the address book off the UI thread. It is best
+// effort: a failed save never interrupts reading or sending.
+func saveContacts(book *contacts.Book) tea.Cmd {
+	return func() tea.Msg {
+		_ = book.Save()
+		return nil
+	}
+}
@@ -1,71 +1,410 @@
 package tui
 
-import "github.com/charmbracelet/bubbles/key"
-
-type keyMap struct {
-	Up       key.Binding
-	Down     key.Binding
-	Enter    key.Binding
-	Tab      key.Binding
-	Quit     key.Binding
-	Refresh  key.Binding
-	MarkRead key.Binding
-	Delete   key.Binding
-	Compose  key.Binding
-	Reply    key.Binding
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// KeyMap holds the TUI key bindings
+type KeyMap struct {
+	Up          key.Binding
+	Down        key.Binding
+	Enter       key.Binding
+	Tab         key.Binding
+	Quit        key.Binding
+	Refresh     key.Binding
+	MarkRead    key.Binding
+	Delete      key.Binding
+	Archive     key.Binding
+	Star        key.Binding
+	Move        key.Binding
+	Mark        key.Binding
+	MarkRange   key.Binding
+	MarkAll     key.Binding
+	Undo        key.Binding
+	Compose     key.Binding
+	Reply       key.Binding
+	Search      key.Binding
+	Command     key.Binding
+	Back        key.Binding
+	Threads     key.Binding
+	Fold        key.Binding
+	Help        key.Binding
+	Reader      key.Binding
+	Shrink      key.Binding
+	Grow        key.Binding
+	Links       key.Binding
+	Yank        key.Binding
+	YankBody    key.Binding
+	Headers     key.Binding
+	Source      key.Binding
+	Structure   key.Binding
+	Unsubscribe key.Binding
+
+	// Reader mode only; these shadow the bindings above while reading.
+	NextMessage key.Binding
+	PrevMessage key.Binding
+	NextUnread  key.Binding
+	PageAdvance key.Binding
+	Find        key.Binding
+
+	// Right after a yank only.
+	YankID key.Binding
 }
 
-var keys = keyMap{
-	Up: key.NewBinding(
-		key.WithKeys("up", "k"),
-		key.WithHelp("↑/k", "up"),
-	),
-	Down: key.NewBinding(
-		key.WithKeys("down", "j"),
-		key.WithHelp("↓/j", "down"),
-	),
-	Enter: key.NewBinding(
-		key.WithKeys("enter"),
-		key.WithHelp("enter", "preview"),
-	),
-	Tab: key.NewBinding(
-		key.WithKeys("tab"),
-		key.WithHelp("tab", "switch"),
-	),
-	Quit: key.NewBinding(
-		key.WithKeys("q", "ctrl+c"),
-		key.WithHelp("q", "quit"),
-	),
-	Refresh: key.NewBinding(
-		key.WithKeys("r"),
-		key.WithHelp("r", "refresh"),
-	),
-	MarkRead: key.NewBinding(
-		key.WithKeys("m"),
-		key.WithHelp("m", "mark read"),
-	),
-	Delete: key.NewBinding(
-		key.WithKeys("d"),
-		key.WithHelp("d", "delete"),
-	),
-	Compose: key.NewBinding(
-		key.WithKeys("c"),
-		key.WithHelp("c", "compose"),
-	),
-	Reply: key.NewBinding(
-		key.WithKeys("R"),
-		key.WithHelp("R", "reply"),
-	),
+// DefaultKeyMap returns the built-in bindings
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		Up: key.NewBinding(
+			key.WithKeys("up", "k"),
+			key.WithHelp("↑/k", "up"),
+		),
+		Down: key.NewBinding(
+			key.WithKeys("down", "j"),
+			key.WithHelp("↓/j", "down"),
+		),
+		Enter: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "preview"),
+		),
+		Tab: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "switch"),
+		),
+		Quit: key.NewBinding(
+			key.WithKeys("q", "ctrl+c"),
+			key.WithHelp("q", "quit"),
+		),
+		Refresh: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "refresh"),
+		),
+		MarkRead: key.NewBinding(
+			key.WithKeys("m"),
+			key.WithHelp("m", "mark read"),
+		),
+		Delete: key.NewBinding(
+			key.WithKeys("d"),
+			key.WithHelp("d", "delete"),
+		),
+		Archive: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", "archive"),
+		),
+		Star: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "star"),
+		),
+		Move: key.NewBinding(
+			key.WithKeys("M"),
+			key.WithHelp("M", "move"),
+		),
+		Mark: key.NewBinding(
+			key.WithKeys(" "),
+			key.WithHelp("space", "mark"),
+		),
+		MarkRange: key.NewBinding(
+			key.WithKeys("V"),
+			key.WithHelp("V", "mark range"),
+		),
+		MarkAll: key.NewBinding(
+			key.WithKeys("*"),
+			key.WithHelp("*", "mark all"),
+		),
+		Undo: key.NewBinding(
+			key.WithKeys("u"),
+			key.WithHelp("u", "undo"),
+		),
+		Compose: key.NewBinding(
+			key.WithKeys("c"),
+			key.WithHelp("c", "compose"),
+		),
+		Reply: key.NewBinding(
+			key.WithKeys("R"),
+			key.WithHelp("R", "reply"),
+		),
+		Search: key.NewBinding(
+			key.WithKeys("/"),
+			key.WithHelp("/", "search"),
+		),
+		Command: key.NewBinding(
+			key.WithKeys(":"),
+			key.WithHelp(":", "command"),
+		),
+		Back: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "clear search"),
+		),
+		Threads: key.NewBinding(
+			key.WithKeys("t"),
+			key.WithHelp("t", "threads"),
+		),
+		Fold: key.NewBinding(
+			key.WithKeys("z"),
+			key.WithHelp("z", "expand/collapse"),
+		),
+		Help: key.NewBinding(
+			key.WithKeys("?"),
+			key.WithHelp("?", "help"),
+		),
+		Reader: key.NewBinding(
+			key.WithKeys("f"),
+			key.WithHelp("f", "reader"),
+		),
+		Links: key.NewBinding(
+			key.WithKeys("L"),
+			key.WithHelp("L", "links"),
+		),
+		Yank: key.NewBinding(
+			key.WithKeys("y"),
+			key.WithHelp("y", "copy sender"),
+		),
+		YankBody: key.NewBinding(
+			key.WithKeys("Y"),
+			key.WithHelp("Y", "copy body"),
+		),
+		YankID: key.NewBinding(
+			key.WithKeys("i"),
+			key.WithHelp("yi", "copy message ID"),
+		),
+		Headers: key.NewBinding(
+			key.WithKeys("H"),
+			key.WithHelp("H", "all headers"),
+		),
+		Source: key.NewBinding(
+			key.WithKeys("O"),
+			key.WithHelp("O", "source"),
+		),
+		Structure: key.NewBinding(
+			key.WithKeys("S"),
+			key.WithHelp("S", "MIME structure"),
+		),
+		Unsubscribe: key.NewBinding(
+			key.WithKeys("U"),
+			key.WithHelp("U", "unsubscribe"),
+		),
+		Shrink: key.NewBinding(
+			key.WithKeys("<"),
+			key.WithHelp("<", "shrink list"),
+		),
+		Grow: key.NewBinding(
+			key.WithKeys(">"),
+			key.WithHelp(">", "grow list"),
+		),
+		NextMessage: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "next"),
+		),
+		PrevMessage: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("p", "previous"),
+		),
+		NextUnread: key.NewBinding(
+			key.WithKeys("N"),
+			key.WithHelp("N", "next unread"),
+		),
+		PageAdvance: key.NewBinding(
+			key.WithKeys(" "),
+			key.WithHelp("space", "page/next"),
+		),
+		Find: key.NewBinding(
+			key.WithKeys("/"),
+			key.WithHelp("/", "find"),
+		),
+	}
 }
 
-func (k keyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.Compose, k.Refresh, k.MarkRead, k.Delete, k.Reply, k.Tab, k.Quit}
+func (k KeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Compose, k.Refresh, k.MarkRead, k.Delete, k.Archive, k.Reply, k.Search, k.Help, k.Quit}
 }
 
-func (k keyMap) FullHelp() [][]key.Binding {
+func (k KeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.Up, k.Down, k.Enter},
-		{k.Compose, k.Refresh, k.MarkRead, k.Delete, k.Reply},
-		{k.Tab, k.Quit},
+		{k.Compose, k.Refresh, k.MarkRead, k.Reply},
+		{k.Delete, k.Archive, k.Star, k.Move, k.Undo, k.Unsubscribe},
+		{k.Mark, k.MarkRange, k.MarkAll},
+		{k.Search, k.Command, k.Back, k.Threads, k.Fold},
+		{k.Reader, k.Links, k.Yank, k.YankBody, k.YankID},
+		{k.Headers, k.Source, k.Structure},
+		{k.Tab, k.Shrink, k.Grow},
+		{k.Help, k.Quit},
+	}
+}
+
+// readerKeys is the help shown in reader mode
+type readerKeys KeyMap
+
+func (k readerKeys) ShortHelp() []key.Binding {
+	return []key.Binding{k.NextMessage, k.PrevMessage, k.NextUnread, k.PageAdvance, k.Find, k.Reply, k.Back, k.Help}
+}
+
+func (k readerKeys) FullHelp() [][]key.Binding {
+	return append(KeyMap(k).FullHelp(), []key.Binding{k.NextMessage, k.PrevMessage, k.NextUnread, k.PageAdvance, k.Find})
+}
+
+// namedBinding pairs a binding with its [tui.keys] action name
+type namedBinding struct {
+	Name    string
+	Binding *key.Binding
+}
+
+// scopedActions are active only in one mode, where they take precedence:
+// reader mode, or right after a yank
+var scopedActions = map[string]string{
+	"next_message": "reader",
+	"prev_message": "reader",
+	"next_unread":  "reader",
+	"page_advance": "reader",
+	"find":         "reader",
+	"yank_id":      "yank",
+}
+
+// actions lists every binding by its config name
+func (k *KeyMap) actions() []namedBinding {
+	return []namedBinding{
+		{"up", &k.Up},
+		{"down", &k.Down},
+		{"enter", &k.Enter},
+		{"tab", &k.Tab},
+		{"quit", &k.Quit},
+		{"refresh", &k.Refresh},
+		{"mark_read", &k.MarkRead},
+		{"delete", &k.Delete},
+		{"archive", &k.Archive},
+		{"star", &k.Star},
+		{"move", &k.Move},
+		{"mark", &k.Mark},
+		{"mark_range", &k.MarkRange},
+		{"mark_all", &k.MarkAll},
+		{"undo", &k.Undo},
+		{"compose", &k.Compose},
+		{"reply", &k.Reply},
+		{"search", &k.Search},
+		{"command", &k.Command},
+		{"back", &k.Back},
+		{"threads", &k.Threads},
+		{"fold", &k.Fold},
+		{"help", &k.Help},
+		{"reader", &k.Reader},
+		{"links", &k.Links},
+		{"yank_sender", &k.Yank},
+		{"yank_body", &k.YankBody},
+		{"toggle_headers", &k.Headers},
+		{"toggle_source", &k.Source},
+		{"toggle_structure", &k.Structure},
+		{"unsubscribe", &k.Unsubscribe},
+		{"shrink_list", &k.Shrink},
+		{"grow_list", &k.Grow},
+		{"next_message", &k.NextMessage},
+		{"prev_message", &k.PrevMessage},
+		{"next_unread", &k.NextUnread},
+		{"page_advance", &k.PageAdvance},
+		{"find", &k.Find},
+		{"yank_id", &k.YankID},
+	}
+}
+
+// KeyActions returns the action names accepted in [tui.keys]
+func KeyActions() []string {
+	var k KeyMap
+	actions := k.actions()
+	names := make([]string, len(actions))
+	for i, a := range actions {
+		names[i] = a.Name
+	}
+	return names
+}
+
+// NewKeyMap applies overrides, keyed by action name, to the default
+// bindings. It rejects unknown actions and keys bound to two actions.
+func NewKeyMap(overrides map[string][]string) (KeyMap, error) {
+	k := DefaultKeyMap()
+	byName := make(map[string]*key.Binding)
+	for _, a := range k.actions() {
+		byName[a.Name] = a.Binding
+	}
+
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		binding, ok := byName[name]
+		if !ok {
+			return KeyMap{}, fmt.Errorf("unknown key action %q (valid: %s)", name, strings.Join(KeyActions(), ", "))
+		}
+		keys, err := normalizeKeys(overrides[name])
+		if err != nil {
+			return KeyMap{}, fmt.Errorf("key action %q: %w", name, err)
+		}
+		binding.SetKeys(keys...)
+		binding.SetHelp(helpKeys(keys), binding.Help().Desc)
+	}
+
+	// Scoped bindings may reuse other keys, but not each other's.
+	var conflicts []string
+	owner := make(map[string]string)
+	for _, a := range k.actions() {
+		for _, s := range a.Binding.Keys() {
+			slot := s
+			if scope := scopedActions[a.Name]; scope != "" {
+				slot = scope + ":" + s
+			}
+			if other, ok := owner[slot]; ok {
+				conflicts = append(conflicts, fmt.Sprintf("%s is bound to both %s and %s", helpKeys([]string{s}), other, a.Name))
+				continue
+			}
+			owner[slot] = a.Name
+		}
+	}
+	if len(conflicts) > 0 {
+		return KeyMap{}, fmt.Errorf("conflicting key bindings: %s", strings.Join(conflicts, "; "))
+	}
+	return k, nil
+}
+
+// normalizeKeys validates a configured key list, accepting "space" for " "
+func normalizeKeys(keys []string) ([]string, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys given")
+	}
+	seen := make(map[string]bool, len(keys))
+	out := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if k == "space" {
+			k = " "
+		}
+		if k == "" {
+			return nil, fmt.Errorf("empty key")
+		}
+		if seen[k] {
+			return nil, fmt.Errorf("%s listed twice", helpKeys([]string{k}))
+		}
+		seen[k] = true
+		out = append(out, k)
+	}
+	return out, nil
+}
+
+// helpKeys renders keys for the help bar
+func helpKeys(keys []string) string {
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		switch k {
+		case " ":
+			names[i] = "space"
+		case "up":
+			names[i] = "↑"
+		case "down":
+			names[i] = "↓"
+		default:
+			names[i] = k
+		}
 	}
+	return strings.Join(names, "/")
 }
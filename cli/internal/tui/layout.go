@@ -0,0 +1,138 @@
+package tui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type layoutMode int
+
+const (
+	layoutSideBySide layoutMode = iota
+	layoutStacked
+	layoutSingle
+)
+
+const (
+	// wideWidth is the narrowest window that fits the panes side by side
+	wideWidth = 100
+	// tallHeight is the shortest window that fits the panes stacked
+	tallHeight = 30
+
+	// defaultSplit is the list's share of the window
+	defaultSplit = 1.0 / 3
+	minSplit     = 0.2
+	maxSplit     = 0.8
+	splitStep    = 0.05
+)
+
+// chooseLayout picks how to arrange the panes in a window
+func chooseLayout(width, height int) layoutMode {
+	switch {
+	case width >= wideWidth:
+		return layoutSideBySide
+	case height >= tallHeight:
+		return layoutStacked
+	default:
+		return layoutSingle
+	}
+}
+
+// pane is a panel's outer size, borders included
+type pane struct {
+	width, height int
+}
+
+// inner is the size left for a panel's content inside its border
+func (p pane) inner() (int, int) {
+	return max(p.width-2, 0), max(p.height-2, 0)
+}
+
+// panes places the list and preview. In the single-pane layout both fill
+// the window and the focused one is shown.
+func (m Model) panes() (layoutMode, pane, pane) {
+	content := max(m.height-lipgloss.Height(m.statusView()), 0)
+	full := pane{m.width, content}
+	if m.reading {
+		return layoutSingle, full, full
+	}
+	mode := chooseLayout(m.width, m.height)
+	switch mode {
+	case layoutSideBySide:
+		listWidth := int(float64(m.width) * m.split())
+		return mode, pane{listWidth, content}, pane{m.width - listWidth, content}
+	case layoutStacked:
+		listHeight := int(float64(content) * m.split())
+		return mode, pane{m.width, listHeight}, pane{m.width, content - listHeight}
+	default:
+		return mode, full, full
+	}
+}
+
+// singlePane reports whether only the focused pane fits the window
+func (m Model) singlePane() bool {
+	mode, _, _ := m.panes()
+	return mode == layoutSingle
+}
+
+// split is the list's share of the window, as last resized
+func (m Model) split() float64 {
+	if m.state.Split < minSplit || m.state.Split > maxSplit {
+		return defaultSplit
+	}
+	return m.state.Split
+}
+
+// layout sizes the panes for the window and the reader mode
+func (m Model) layout() Model {
+	_, list, preview := m.panes()
+	m.list.SetSize(list.inner())
+	m.preview.SetSize(preview.inner())
+	if m.reading {
+		m.preview.SetWrap(m.readerWrap())
+	} else {
+		m.preview.SetWrap(m.previewWrap)
+	}
+	return m
+}
+
+// resizeSplit grows or shrinks the list's share and remembers it
+func resizeSplit(m Model, delta float64) (Model, tea.Cmd) {
+	split := m.split() + delta
+	split = min(max(split, minSplit), maxSplit)
+	m.state.Split = split
+	m.status = fmt.Sprintf("List %d%%", int(split*100+0.5))
+	if m.singlePane() {
+		m.status += " (applies when the window fits two panes)"
+	}
+	return m.layout(), m.state.save()
+}
+
+// panesView renders the panes in the current layout
+func (m Model) panesView() string {
+	mode, list, preview := m.panes()
+	listStyle := m.styles.blurredPanel
+	previewStyle := m.styles.blurredPanel
+	if m.focus == focusList && !m.reading {
+		listStyle = m.styles.focusedPanel
+	} else {
+		previewStyle = m.styles.focusedPanel
+	}
+	render := func(style lipgloss.Style, p pane, content string) string {
+		width, height := p.inner()
+		return style.Copy().Width(width).Height(height).MaxHeight(p.height).Render(content)
+	}
+
+	switch {
+	case mode == layoutSideBySide:
+		return lipgloss.JoinHorizontal(lipgloss.Top, render(listStyle, list, m.list.View()), render(previewStyle, preview, m.preview.View()))
+	case mode == layoutStacked:
+		return lipgloss.JoinVertical(lipgloss.Left, render(listStyle, list, m.list.View()), render(previewStyle, preview, m.preview.View()))
+	case m.focus == focusList && !m.reading:
+		return render(listStyle, list, m.list.View())
+	default:
+		return render(previewStyle, preview, m.preview.View())
+	}
+}
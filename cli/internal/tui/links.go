@@ -0,0 +1,129 @@
+package tui
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+)
+
+// linkPicker is the open link overlay
+type linkPicker struct {
+	links  []api.Link
+	cursor int
+}
+
+var (
+	openLink = key.NewBinding(key.WithKeys("enter", "o"), key.WithHelp("enter/o", "open"))
+	copyLink = key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "copy"))
+)
+
+// defaultOpener is the platform's command for opening a URL
+func defaultOpener() []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"open"}
+	case "windows":
+		return []string{"rundll32", "url.dll,FileProtocolHandler"}
+	default:
+		return []string{"xdg-open"}
+	}
+}
+
+// startLinks lists the open message's links
+func startLinks(m Model) (Model, tea.Cmd) {
+	if m.currentEmail == nil {
+		return m, nil
+	}
+	links := m.currentEmail.Links()
+	if len(links) == 0 {
+		m.status = "No links in this message"
+		return m, nil
+	}
+	m.picker = &linkPicker{links: links}
+	return m, nil
+}
+
+// updateLinkPicker moves through the links, opening or copying one
+func updateLinkPicker(m Model, msg tea.KeyMsg) (Model, tea.Cmd) {
+	p := m.picker
+	switch {
+	case key.Matches(msg, m.keys.Back), key.Matches(msg, m.keys.Quit), key.Matches(msg, m.keys.Links):
+		m.picker = nil
+	case key.Matches(msg, m.keys.Up):
+		p.cursor = max(p.cursor-1, 0)
+	case key.Matches(msg, m.keys.Down):
+		p.cursor = min(p.cursor+1, len(p.links)-1)
+	case key.Matches(msg, openLink):
+		m.picker = nil
+		return m, m.openURL(p.links[p.cursor].URL)
+	case key.Matches(msg, copyLink):
+		m.picker = nil
+		return m, copyText(p.links[p.cursor].URL, "link")
+	case len(msg.Runes) == 1 && msg.Runes[0] >= '1' && msg.Runes[0] <= '9':
+		if n := int(msg.Runes[0] - '1'); n < len(p.links) {
+			p.cursor = n
+		}
+	}
+	return m, nil
+}
+
+// openURL runs the configured opener on url
+func (m Model) openURL(url string) tea.Cmd {
+	opener := m.opts.Opener
+	if len(opener) == 0 {
+		opener = defaultOpener()
+	}
+	return func() tea.Msg {
+		cmd := exec.Command(opener[0], append(opener[1:], url)...)
+		if err := cmd.Run(); err != nil {
+			return ErrMsg{Err: fmt.Errorf("open %s: %w", url, err)}
+		}
+		return LinkOpened{URL: url}
+	}
+}
+
+// linkPickerView renders the links in a centered box, flagging lookalikes
+func (m Model) linkPickerView(height int) string {
+	p := m.picker
+	width := max(min(m.width-8, 100), 20)
+	// each link takes up to two lines; keep the cursor in view
+	visible := max((height-8)/2, 1)
+	start := min(max(p.cursor-visible/2, 0), max(len(p.links)-visible, 0))
+	end := min(start+visible, len(p.links))
+	var rows []string
+	for i := start; i < end; i++ {
+		link := p.links[i]
+		prefix := "  "
+		style := m.styles.headerValue
+		if i == p.cursor {
+			prefix = "> "
+			style = m.styles.subject
+		}
+		row := style.Render(ansi.Truncate(fmt.Sprintf("%s%d. %s", prefix, i+1, link.URL), width, "…"))
+		switch {
+		case link.Lookalike:
+			row += "\n" + m.styles.statusError.Render(ansi.Truncate(fmt.Sprintf("     ⚠ shows %q but leads to %s", link.Text, link.Host()), width, "…"))
+		case link.Text != "" && link.Text != link.URL:
+			row += "\n" + m.styles.statusText.Render(ansi.Truncate("     "+link.Text, width, "…"))
+		}
+		rows = append(rows, row)
+	}
+	footer := m.help.ShortHelpView([]key.Binding{openLink, copyLink, m.keys.Back})
+	box := m.styles.helpOverlay.Render(lipgloss.JoinVertical(
+		lipgloss.Left,
+		m.styles.subject.Render(fmt.Sprintf("Links (%d)", len(p.links))),
+		"",
+		strings.Join(rows, "\n"),
+		"",
+		footer,
+	))
+	return lipgloss.Place(m.width, height, lipgloss.Center, lipgloss.Center, box)
+}
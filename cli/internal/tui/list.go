@@ -2,16 +2,32 @@ package tui
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
 	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/table"
+	"github.com/misty-step/mercury/cli/internal/thread"
 )
 
 // EmailItem wraps api.Email to implement list.Item
 type EmailItem struct {
 	Email api.Email
+	// Depth indents replies in the threaded view.
+	Depth int
+	// ThreadKey identifies the conversation in the threaded view.
+	ThreadKey string
+	// ThreadSize is the number of messages hidden behind a collapsed
+	// conversation, or 0 when the row is not a collapsed thread.
+	ThreadSize int
+	// Marked is set when the row is part of the multi-selection.
+	Marked bool
+	// Auth is the Authentication-Results verdict, known once the full
+	// message has been fetched.
+	Auth api.AuthVerdict
 }
 
 func (i EmailItem) Title() string {
@@ -19,80 +35,394 @@ func (i EmailItem) Title() string {
 	if i.Email.IsRead == 0 {
 		unread = "*"
 	}
-	sender := truncateSender(i.Email.Sender, 18)
-	return fmt.Sprintf("%s [%3d] %s", unread, i.Email.ID, sender)
+	sender := table.Truncate(i.Email.Sender, 18)
+	title := fmt.Sprintf("%s%s [%3d] %s", strings.Repeat("  ", i.Depth), unread, i.Email.ID, sender)
+	if i.Email.IsStarred == 1 {
+		title += " ★"
+	}
+	if i.ThreadSize > 1 {
+		title += fmt.Sprintf(" (%d)", i.ThreadSize)
+	}
+	if i.Auth.DMARC.Result.Failed() {
+		title += " ⚠"
+	}
+	if i.Marked {
+		title = "✓ " + title
+	}
+	return title
 }
 
 func (i EmailItem) Description() string {
-	return truncate(i.Email.Subject, 40)
+	return table.Truncate(i.Email.Subject, 40)
 }
 
 func (i EmailItem) FilterValue() string {
 	return i.Email.Subject + " " + i.Email.Sender
 }
 
-func truncateSender(s string, max int) string {
-	if len(s) <= max {
-		return s
-	}
-	return s[:max-2] + ".."
+// groupHeader labels a group of emails; the cursor skips over it
+type groupHeader struct {
+	Label string
+	Count int
 }
 
-func truncate(s string, max int) string {
-	if len(s) <= max {
-		return s
-	}
-	return s[:max-2] + ".."
+func (h groupHeader) FilterValue() string {
+	return ""
 }
 
 // ListModel wraps bubbles list
 type ListModel struct {
 	list   list.Model
 	emails []api.Email
+	marked map[int]api.Email // multi-selection by email ID
+	anchor int               // row last toggled, where a range mark starts
+	sort   string            // one of SortModes, "" for date order
+	group  string            // one of GroupModes, "" for none
+	auth   map[int]api.AuthVerdict
 }
 
 func NewListModel(width, height int) ListModel {
-	delegate := list.NewDefaultDelegate()
-
-	// Style unread emails as bold
-	delegate.Styles.NormalTitle = delegate.Styles.NormalTitle.Bold(false)
-	delegate.Styles.SelectedTitle = delegate.Styles.SelectedTitle.Bold(true)
-
-	l := list.New([]list.Item{}, delegate, width, height)
+	theme := themes[DefaultTheme]
+	l := list.New([]list.Item{}, newEmailDelegate(theme), width, height)
 	l.Title = "Inbox"
 	l.SetShowStatusBar(false)
-	l.SetFilteringEnabled(true)
-	l.Styles.Title = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("62"))
+	// "/" runs a full-text search instead of the built-in fuzzy filter.
+	l.SetFilteringEnabled(false)
+	l.Styles.Title = newStyles(theme).listTitle
 
-	return ListModel{list: l}
+	return ListModel{list: l, marked: make(map[int]api.Email)}
 }
 
 func (m ListModel) Update(msg tea.Msg) (ListModel, tea.Cmd) {
+	index := m.list.Index()
 	var cmd tea.Cmd
 	m.list, cmd = m.list.Update(msg)
+	m.skipHeader(m.list.Index() < index)
 	return m, cmd
 }
 
+// skipHeader moves the cursor off a group header, onward in the direction
+// of travel or back the other way at either end of the list
+func (m *ListModel) skipHeader(up bool) {
+	items := m.list.Items()
+	index := m.list.Index()
+	if index >= len(items) {
+		return
+	}
+	if _, ok := items[index].(groupHeader); !ok {
+		return
+	}
+	step := 1
+	if up {
+		step = -1
+	}
+	for _, dir := range []int{step, -step} {
+		for i := index + dir; i >= 0 && i < len(items); i += dir {
+			if _, ok := items[i].(EmailItem); ok {
+				m.list.Select(i)
+				return
+			}
+		}
+	}
+}
+
 func (m ListModel) View() string {
 	return m.list.View()
 }
 
 func (m *ListModel) SetEmails(emails []api.Email) {
 	m.emails = emails
-	items := make([]list.Item, len(emails))
-	for i, e := range emails {
-		items[i] = EmailItem{Email: e}
+	marked := make(map[int]api.Email, len(m.marked))
+	items := make([]list.Item, 0, len(emails))
+	for _, group := range groupEmails(sortEmails(emails, m.sort), m.group, timeNow()) {
+		if group.Label != "" {
+			items = append(items, groupHeader{Label: group.Label, Count: len(group.Emails)})
+		}
+		for _, e := range group.Emails {
+			_, ok := m.marked[e.ID]
+			if ok {
+				marked[e.ID] = e
+			}
+			items = append(items, EmailItem{Email: e, Marked: ok, Auth: m.authOf(&e)})
+		}
+	}
+	m.marked = marked
+	m.list.SetItems(items)
+	m.skipHeader(false)
+}
+
+// SetSort orders the flat list from the next SetEmails; threaded views
+// stay in conversation order
+func (m *ListModel) SetSort(mode string) {
+	m.sort = mode
+}
+
+// SetGroup groups the flat list under headers from the next SetEmails
+func (m *ListModel) SetGroup(mode string) {
+	m.group = mode
+}
+
+// SetThreads shows conversations, expanding those marked in expanded and
+// collapsing the rest to their most recent message.
+func (m *ListModel) SetThreads(roots []*thread.Container, expanded map[string]bool) {
+	var items []list.Item
+	for _, root := range roots {
+		entries := thread.Flatten(root)
+		if len(entries) == 0 {
+			continue
+		}
+		if expanded[root.Key()] || len(entries) == 1 {
+			for _, entry := range entries {
+				_, marked := m.marked[entry.Email.ID]
+				items = append(items, EmailItem{Email: *entry.Email, Depth: entry.Depth, ThreadKey: root.Key(), Marked: marked, Auth: m.authOf(entry.Email)})
+			}
+			continue
+		}
+		latest := entries[0]
+		for _, entry := range entries[1:] {
+			if entry.Email.ReceivedAt > latest.Email.ReceivedAt {
+				latest = entry
+			}
+		}
+		_, marked := m.marked[latest.Email.ID]
+		item := EmailItem{Email: *latest.Email, ThreadKey: root.Key(), ThreadSize: len(entries), Marked: marked, Auth: m.authOf(latest.Email)}
+		for _, entry := range entries {
+			// A collapsed thread reads as unread while any message is.
+			if !entry.Email.Read() {
+				item.Email.IsRead = 0
+			}
+		}
+		items = append(items, item)
 	}
 	m.list.SetItems(items)
 }
 
 func (m ListModel) SelectedEmail() *api.Email {
-	if item := m.list.SelectedItem(); item != nil {
+	if item, ok := m.SelectedItem(); ok {
+		return &item.Email
+	}
+	return nil
+}
+
+func (m ListModel) SelectedItem() (EmailItem, bool) {
+	item, ok := m.list.SelectedItem().(EmailItem)
+	return item, ok
+}
+
+// SelectID moves the cursor to the email with id, reporting whether it is listed.
+func (m *ListModel) SelectID(id int) bool {
+	if id == 0 {
+		return false
+	}
+	if i := m.indexOf(id); i >= 0 {
+		m.list.Select(i)
+		return true
+	}
+	return false
+}
+
+// Step moves the cursor delta rows, skipping headers, or with unread set,
+// to the next unread email that way. It reports whether the cursor moved.
+func (m *ListModel) Step(delta int, unread bool) bool {
+	items := m.list.Items()
+	for i := m.list.Index() + delta; i >= 0 && i < len(items); i += delta {
+		if ei, ok := items[i].(EmailItem); ok && (!unread || !ei.Email.Read()) {
+			m.list.Select(i)
+			return true
+		}
+	}
+	return false
+}
+
+// indexOf returns the row showing the email with id, or -1
+func (m ListModel) indexOf(id int) int {
+	for i, item := range m.list.Items() {
+		if ei, ok := item.(EmailItem); ok && ei.Email.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// ToggleMark adds the selected row to the multi-selection, or removes it
+func (m *ListModel) ToggleMark() {
+	index := m.list.Index()
+	item, ok := m.SelectedItem()
+	if !ok {
+		return
+	}
+	if _, marked := m.marked[item.Email.ID]; marked {
+		delete(m.marked, item.Email.ID)
+	} else {
+		m.marked[item.Email.ID] = item.Email
+	}
+	m.anchor = index
+	m.refreshMarks()
+}
+
+// MarkRange marks every row between the last toggled row and the cursor
+func (m *ListModel) MarkRange() {
+	items := m.list.Items()
+	if len(items) == 0 {
+		return
+	}
+	from, to := m.anchor, m.list.Index()
+	if from >= len(items) {
+		from = len(items) - 1
+	}
+	if from > to {
+		from, to = to, from
+	}
+	for _, item := range items[from : to+1] {
 		if ei, ok := item.(EmailItem); ok {
-			return &ei.Email
+			m.marked[ei.Email.ID] = ei.Email
+		}
+	}
+	m.anchor = m.list.Index()
+	m.refreshMarks()
+}
+
+// MarkAll marks every visible row, or clears the marks if all are marked
+func (m *ListModel) MarkAll() {
+	items := m.list.Items()
+	all := len(items) > 0
+	for _, item := range items {
+		if ei, ok := item.(EmailItem); ok {
+			if _, marked := m.marked[ei.Email.ID]; !marked {
+				all = false
+				m.marked[ei.Email.ID] = ei.Email
+			}
+		}
+	}
+	if all {
+		m.marked = make(map[int]api.Email)
+	}
+	m.refreshMarks()
+}
+
+// ClearMarks empties the multi-selection
+func (m *ListModel) ClearMarks() {
+	if len(m.marked) == 0 {
+		return
+	}
+	m.marked = make(map[int]api.Email)
+	m.refreshMarks()
+}
+
+// Marked returns the multi-selection, newest first
+func (m ListModel) Marked() []api.Email {
+	emails := make([]api.Email, 0, len(m.marked))
+	for _, email := range m.marked {
+		emails = append(emails, email)
+	}
+	sort.Slice(emails, func(i, j int) bool {
+		if emails[i].ReceivedAt != emails[j].ReceivedAt {
+			return emails[i].ReceivedAt > emails[j].ReceivedAt
+		}
+		return emails[i].ID > emails[j].ID
+	})
+	return emails
+}
+
+func (m ListModel) MarkedCount() int {
+	return len(m.marked)
+}
+
+// refreshMarks updates row markers after the selection changes
+func (m *ListModel) refreshMarks() {
+	for i, item := range m.list.Items() {
+		ei, ok := item.(EmailItem)
+		if !ok {
+			continue
+		}
+		_, marked := m.marked[ei.Email.ID]
+		if ei.Marked != marked {
+			ei.Marked = marked
+			m.list.SetItem(i, ei)
 		}
 	}
-	return nil
+}
+
+// authOf returns the verdict remembered for an email, reading it from the
+// email's headers when they are present
+func (m *ListModel) authOf(e *api.Email) api.AuthVerdict {
+	if v, ok := m.auth[e.ID]; ok {
+		return v
+	}
+	if e.RawEmail == "" && e.HeadersJSON == "" {
+		return api.AuthVerdict{}
+	}
+	v := e.Authentication()
+	m.remember(e.ID, v)
+	return v
+}
+
+func (m *ListModel) remember(id int, v api.AuthVerdict) {
+	if m.auth == nil {
+		m.auth = make(map[int]api.AuthVerdict)
+	}
+	m.auth[id] = v
+}
+
+// SetAuth records a fetched email's verdict and badges its row
+func (m *ListModel) SetAuth(email *api.Email) {
+	v := email.Authentication()
+	m.remember(email.ID, v)
+	for i, item := range m.list.Items() {
+		if ei, ok := item.(EmailItem); ok && ei.Email.ID == email.ID && ei.Auth != v {
+			ei.Auth = v
+			m.list.SetItem(i, ei)
+		}
+	}
+}
+
+// SetTheme restyles the title and rows
+func (m *ListModel) SetTheme(t Theme) {
+	m.list.SetDelegate(newEmailDelegate(t))
+	m.list.Styles.Title = newStyles(t).listTitle
+}
+
+// SetKeys hands cursor movement to the TUI's bindings and drops the list's
+// own quit and help keys, which the TUI handles. The list's paging keys
+// keep only keys no action uses, now or by default, so a key freed by
+// rebinding an action does nothing rather than page the list
+func (m *ListModel) SetKeys(keys KeyMap) {
+	defaults := list.DefaultKeyMap()
+	m.list.KeyMap.CursorUp = keys.Up
+	m.list.KeyMap.CursorDown = keys.Down
+	m.list.KeyMap.Quit.SetEnabled(false)
+	m.list.KeyMap.ForceQuit.SetEnabled(false)
+	m.list.KeyMap.ShowFullHelp.SetEnabled(false)
+	m.list.KeyMap.CloseFullHelp.SetEnabled(false)
+
+	taken := make(map[string]bool)
+	for _, k := range []KeyMap{keys, DefaultKeyMap()} {
+		for _, a := range k.actions() {
+			for _, s := range a.Binding.Keys() {
+				taken[s] = true
+			}
+		}
+	}
+	paging := []struct{ binding, initial *key.Binding }{
+		{&m.list.KeyMap.PrevPage, &defaults.PrevPage},
+		{&m.list.KeyMap.NextPage, &defaults.NextPage},
+		{&m.list.KeyMap.GoToStart, &defaults.GoToStart},
+		{&m.list.KeyMap.GoToEnd, &defaults.GoToEnd},
+	}
+	for _, p := range paging {
+		var free []string
+		for _, s := range p.initial.Keys() {
+			if !taken[s] {
+				free = append(free, s)
+			}
+		}
+		p.binding.SetKeys(free...)
+		p.binding.SetEnabled(len(free) > 0)
+	}
+}
+
+func (m *ListModel) SetTitle(title string) {
+	m.list.Title = title
 }
 
 func (m *ListModel) SetSize(w, h int) {
@@ -103,6 +433,11 @@ func (m ListModel) Index() int {
 	return m.list.Index()
 }
 
+func (m ListModel) Len() int {
+	return len(m.list.Items())
+}
+
 func (m *ListModel) SetIndex(index int) {
 	m.list.Select(index)
+	m.skipHeader(false)
 }
@@ -1,22 +1,34 @@
 package tui
 
-import "github.com/misty-step/mercury/cli/internal/api"
+import (
+	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/pgp"
+)
 
 type EmailsFetched struct {
 	Emails []api.Email
 	Total  int
+	Offset int  // position of the first email; > 0 for a lazily loaded page
+	Cached bool // served from the local cache while a refresh is in flight
+	Gen    int  // Model.gen when the fetch started
 }
 
 type EmailFetched struct {
 	Email api.Email
 }
 
-type EmailMarked struct {
-	ID int
+// UndoTick expires pending actions whose undo window has passed
+type UndoTick struct{}
+
+// BulkProgress reports one finished item of a bulk job
+type BulkProgress struct {
+	Job    *bulkJob
+	Result bulkResult
 }
 
-type EmailDeleted struct {
-	ID int
+// PendingCommitted reports the pending actions sent by commitPending
+type PendingCommitted struct {
+	Jobs []*bulkJob
 }
 
 type EditorClosed struct {
@@ -26,6 +38,72 @@ type EditorClosed struct {
 
 type EmailSent struct {
 	MessageID string
+	To        string // recipients, for the address book
+}
+
+// PollTick triggers a background check for new mail
+type PollTick struct{}
+
+// ProfileSwitched carries a client for the profile picked with :profile
+type ProfileSwitched struct {
+	Name   string
+	Client *api.Client
+}
+
+// Exported reports a finished :export
+type Exported struct {
+	Path  string
+	Count int
+}
+
+// LinkOpened reports a link handed to the opener
+type LinkOpened struct {
+	URL string
+}
+
+// Copied reports text put on the clipboard
+type Copied struct {
+	What string
+}
+
+// PGPOpened carries a decrypted or verified email
+type PGPOpened struct {
+	ID     int
+	Result *pgp.Result
+	Err    error
+}
+
+// SMIMEVerified carries the S/MIME verification of an email
+type SMIMEVerified struct {
+	ID     int
+	Result *api.SMIMEResult
+	Err    error
+}
+
+// Unsubscribed reports a list left with the unsubscribe key
+type Unsubscribed struct {
+	Sender string
+}
+
+type NewEmails struct {
+	Emails []api.Email
+	Gen    int // Model.gen when the poll started
+}
+
+type PollFailed struct {
+	Err error
+}
+
+// ThreadsBuilt carries threading headers fetched for the threaded view
+type ThreadsBuilt struct {
+	Headers map[int]string // by email ID, as headers_json
+	Err     error          // emails whose headers could not be fetched
+	Gen     int            // Model.gen when the build started
+}
+
+type SearchResults struct {
+	Query  string
+	Emails []api.Email
 }
 
 type ErrMsg struct {
@@ -1,12 +1,19 @@
 package tui
 
 import (
+	"strings"
+	"time"
+
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
 
 	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/commands"
+	"github.com/misty-step/mercury/cli/internal/contacts"
+	"github.com/misty-step/mercury/cli/internal/pgp"
+	"github.com/misty-step/mercury/cli/internal/thread"
 )
 
 type focus int
@@ -16,6 +23,30 @@ const (
 	focusPreview
 )
 
+// Options configures optional TUI behaviour
+type Options struct {
+	RefreshInterval time.Duration // background poll interval, 0 to disable
+	Notify          string        // new mail announcement: "bell", "title" or "none"
+	Keys            *KeyMap       // bindings from NewKeyMap, nil for the defaults
+	Theme           *Theme        // palette, nil for DefaultTheme
+	Profiles        []string      // profile names offered by :profile
+	State           *State        // remembered view settings, nil to forget them on exit
+	ReaderWidth     int           // reader mode wrap column, 0 to fill the window
+	Opener          []string      // command that opens a link, given the URL as its last argument; nil for the platform default
+	Profile         string        // profile the session starts as, passed to the callbacks below
+	// OpenPGP decrypts and verifies signed or encrypted mail with the
+	// profile's keys; nil shows it as is
+	OpenPGP func(profile string, email *api.Email) (*pgp.Result, error)
+	// VerifySMIME verifies S/MIME signed mail against the profile's trust
+	// store; nil shows it unverified
+	VerifySMIME func(profile string, email *api.Email) (*api.SMIMEResult, error)
+	// SwitchProfile opens a client for the named profile; nil disables :profile
+	SwitchProfile func(name string) (*api.Client, error)
+	// Contacts returns the profile's address book; nil disables contact
+	// names and collection
+	Contacts func(profile string) *contacts.Book
+}
+
 type Model struct {
 	focus        focus
 	width        int
@@ -23,7 +54,8 @@ type Model struct {
 	list         ListModel
 	preview      PreviewModel
 	emails       []api.Email
-	selected     int
+	total        int  // emails in the inbox, loaded or not
+	loadingMore  bool // a lazy page load is in flight
 	currentEmail *api.Email
 	err          error
 	status       string
@@ -32,23 +64,123 @@ type Model struct {
 	client       *api.Client
 	help         help.Model
 	compose      *ComposeState
+	folder       string // folder being listed
+	profile      string // profile the client is for, "" for the default
+	gen          int    // bumped on folder or profile switches, to drop stale fetches
+	searchInput  textinput.Model
+	searching    bool        // search input has focus
+	searchQuery  string      // active search shown in the list, "" for the inbox
+	inbox        []api.Email // inbox listing stashed while search results are shown
+	threaded     bool
+	threads      []*thread.Container
+	threadHeads  map[int]string  // threading headers of loaded emails, by ID
+	expanded     map[string]bool // conversations expanded in the threaded view
+	opts         Options
+	pollFailures int             // consecutive failed polls, for backoff
+	newCount     int             // mail arrived by polling since the list was last looked at
+	pending      []pendingAction // deletes and archives inside their undo window
+	undoTicking  bool
+	quitting     bool       // quit once commitPending reports back
+	jobs         []*bulkJob // bulk operations in flight
+	moveInput    textinput.Model
+	moving       bool // folder prompt has focus
+	commandInput textinput.Model
+	commanding   bool     // ":" command line has focus
+	completions  []string // candidates listed after an ambiguous tab
+	registry     *commands.Registry
+	state        *State
+	previewWrap  int  // preview.wrap column, 0 for none
+	reading      bool // full-screen reader is open
+	findInput    textinput.Model
+	finding      bool               // in-message search has focus
+	picker       *linkPicker        // link picker overlay, nil when closed
+	yank         yankStep           // what the previous key copied, for yank_id
+	unsubscribe  *unsubscribePrompt // unsubscribe awaiting confirmation
+	sending      *sendPrompt        // group message awaiting confirmation
+	observed     map[int]bool       // emails whose sender is in the address book this session
+	keys         KeyMap
+	showHelp     bool // full help overlay is open
+	styles       styles
 }
 
-func NewModel(client *api.Client) Model {
+func NewModel(client *api.Client, opts Options) Model {
+	theme := themes[DefaultTheme]
+	if opts.Theme != nil {
+		theme = *opts.Theme
+	}
+	styles := newStyles(theme)
 	spin := spinner.New()
 	spin.Spinner = spinner.Line
-	spin.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("69"))
-	return Model{
-		focus:   focusList,
-		list:    NewListModel(0, 0),
-		preview: NewPreviewModel(0, 0),
-		loading: true,
-		spinner: spin,
-		client:  client,
-		help:    help.New(),
+	spin.Style = styles.spinner
+	keys := DefaultKeyMap()
+	if opts.Keys != nil {
+		keys = *opts.Keys
+	}
+	list := NewListModel(0, 0)
+	list.SetKeys(keys)
+	list.SetTheme(theme)
+	preview := NewPreviewModel(0, 0)
+	preview.SetKeys(keys)
+	preview.SetTheme(theme)
+	helpModel := help.New()
+	helpModel.Styles = helpStyles(theme)
+	list.SetTitle(folderTitle("inbox", client))
+	input := textinput.New()
+	input.Prompt = "/"
+	input.Placeholder = `from:alice is:unread "exact phrase"`
+	move := textinput.New()
+	move.Prompt = "Move to: "
+	move.Placeholder = strings.Join(api.Folders, ", ")
+	move.ShowSuggestions = true
+	move.SetSuggestions(api.Folders)
+	command := textinput.New()
+	command.Prompt = ":"
+	find := textinput.New()
+	find.Prompt = "/"
+	find.Placeholder = "find in message (enter again for the next match)"
+	state := opts.State
+	if state == nil {
+		state = &State{}
+	}
+	m := Model{
+		focus:        focusList,
+		folder:       "inbox",
+		list:         list,
+		preview:      preview,
+		loading:      true,
+		spinner:      spin,
+		client:       client,
+		help:         helpModel,
+		searchInput:  input,
+		moveInput:    move,
+		commandInput: command,
+		findInput:    find,
+		registry:     newRegistry(opts, opts.Profile),
+		profile:      opts.Profile,
+		opts:         opts,
+		keys:         keys,
+		styles:       styles,
+		state:        state,
 	}
+	return m.applyView()
+}
+
+// folderTitle names a folder for the list title, e.g. "Inbox"
+func folderTitle(folder string, client *api.Client) string {
+	title := strings.ToUpper(folder[:1]) + folder[1:]
+	if client != nil && client.Offline {
+		title += " (offline)"
+	}
+	return title
 }
 
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(fetchEmails(m.client, 50, 0), m.spinner.Tick)
+	var poll tea.Cmd
+	if m.client != nil && !m.client.Offline && m.opts.RefreshInterval > 0 {
+		poll = schedulePoll(m.opts.RefreshInterval)
+	}
+	if m.client != nil && m.client.Cache != nil && !m.client.Offline {
+		return tea.Batch(tea.Sequence(m.loadCachedEmails(pageSize, 0), m.fetchEmails(pageSize, 0)), m.spinner.Tick, poll)
+	}
+	return tea.Batch(m.fetchEmails(pageSize, 0), m.spinner.Tick, poll)
 }
@@ -0,0 +1,105 @@
+package tui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+)
+
+const (
+	// pageSize is how many emails each lazy load fetches
+	pageSize = 50
+	// maxPageSize is the server's cap on a single list request
+	maxPageSize = 100
+	// prefetchMargin starts loading the next page this many rows before the end
+	prefetchMargin = 10
+)
+
+// fetchEmails fetches limit emails of the listed folder from offset, in as
+// many requests as the server's page cap needs
+func (m Model) fetchEmails(limit, offset int) tea.Cmd {
+	client, folder, gen := m.client, m.folder, m.gen
+	return func() tea.Msg {
+		var emails []api.Email
+		total := 0
+		for len(emails) < limit {
+			n := limit - len(emails)
+			if n > maxPageSize {
+				n = maxPageSize
+			}
+			resp, err := client.ListEmails(n, offset+len(emails), folder)
+			if err != nil {
+				return ErrMsg{Err: err}
+			}
+			emails = append(emails, resp.Emails...)
+			total = resp.Total
+			if len(resp.Emails) < n {
+				break
+			}
+		}
+		return EmailsFetched{Emails: emails, Total: total, Offset: offset, Gen: gen}
+	}
+}
+
+// refreshEmails reloads every page loaded so far, so a refresh never
+// shrinks the list out from under the cursor
+func refreshEmails(m Model) tea.Cmd {
+	limit := len(m.emails)
+	if m.searchQuery != "" {
+		limit = len(m.inbox)
+	}
+	if limit < pageSize {
+		limit = pageSize
+	}
+	return m.fetchEmails(limit, 0)
+}
+
+// maybeLoadMore fetches the next page once the cursor nears the end of the list
+func maybeLoadMore(m Model) (Model, tea.Cmd) {
+	if m.loadingMore || m.searchQuery != "" || len(m.emails) >= m.total {
+		return m, nil
+	}
+	if m.list.Index() < m.list.Len()-prefetchMargin {
+		return m, nil
+	}
+	m.loadingMore = true
+	m.loading = true
+	return m, tea.Batch(m.fetchEmails(pageSize, len(m.emails)), m.spinner.Tick)
+}
+
+// appendPage adds a lazily loaded page below the emails already listed
+func appendPage(m Model, msg EmailsFetched) (Model, tea.Cmd) {
+	m.loadingMore = false
+	m.loading = false
+	m.err = nil
+	m.total = msg.Total
+	seen := make(map[int]bool, len(m.emails))
+	for _, email := range m.emails {
+		seen[email.ID] = true
+	}
+	for _, email := range msg.Emails {
+		if !seen[email.ID] {
+			m.emails = append(m.emails, email)
+		}
+	}
+	m.list.SetTitle(m.listTitle())
+	if m.threaded {
+		m.loading = true
+		return m, tea.Batch(m.buildThreads(), m.spinner.Tick)
+	}
+	selectedID := m.selectedID()
+	m.list.SetEmails(m.emails)
+	m.list.SelectID(selectedID)
+	return m, nil
+}
+
+// listTitle titles the folder list with how much of it is loaded
+func (m Model) listTitle() string {
+	title := folderTitle(m.folder, m.client)
+	if m.total > len(m.emails) {
+		title += fmt.Sprintf(" · loaded %d of %d", len(m.emails), m.total)
+	}
+	return title
+}
@@ -0,0 +1,347 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/commands"
+	"github.com/misty-step/mercury/cli/internal/mbox"
+	"github.com/misty-step/mercury/cli/internal/search"
+	"github.com/misty-step/mercury/cli/internal/table"
+)
+
+// Settings lists the options accepted by :set
+var Settings = []string{"preview.wrap", "reader.wrap"}
+
+// newRegistry binds the shared command set to this session's profiles and
+// the address book of the profile in use
+func newRegistry(opts Options, profile string) *commands.Registry {
+	src := commands.Sources{
+		SortModes:  func() []string { return SortModes },
+		GroupModes: func() []string { return GroupModes },
+		Settings:   func() []string { return Settings },
+	}
+	if len(opts.Profiles) > 0 {
+		profiles := opts.Profiles
+		src.Profiles = func() []string { return profiles }
+	}
+	if opts.Contacts != nil {
+		src.Contacts = func() []string {
+			if book := opts.Contacts(profile); book != nil {
+				return append(book.GroupNames(), book.Addresses()...)
+			}
+			return nil
+		}
+	}
+	return commands.Builtin(src)
+}
+
+// startCommand opens the ":" command line
+func startCommand(m Model) (Model, tea.Cmd) {
+	m.commanding = true
+	m.completions = nil
+	m.commandInput.SetValue("")
+	return m, m.commandInput.Focus()
+}
+
+// updateCommandInput handles keys while the command line has focus
+func updateCommandInput(m Model, msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.commanding = false
+		m.commandInput.Blur()
+		return m, nil
+	case tea.KeyTab:
+		return completeCommand(m), nil
+	case tea.KeyEnter:
+		m.commanding = false
+		m.commandInput.Blur()
+		line := strings.TrimSpace(m.commandInput.Value())
+		if line == "" {
+			return m, nil
+		}
+		call, err := m.registry.Parse(line)
+		if err != nil {
+			m.err = err
+			return m, nil
+		}
+		m.err = nil
+		return runCommand(m, call)
+	}
+
+	m.completions = nil
+	var cmd tea.Cmd
+	m.commandInput, cmd = m.commandInput.Update(msg)
+	return m, cmd
+}
+
+// completeCommand extends the last word to the candidates' common prefix,
+// listing them when more than one matches
+func completeCommand(m Model) Model {
+	line := m.commandInput.Value()
+	candidates := m.registry.Complete(line)
+	m.completions = nil
+	if len(candidates) == 0 {
+		return m
+	}
+	base := line[:strings.LastIndexAny(line, " \t")+1]
+	completion := commands.CommonPrefix(candidates)
+	if len(candidates) == 1 {
+		completion += " "
+	} else {
+		m.completions = candidates
+	}
+	m.commandInput.SetValue(base + completion)
+	m.commandInput.CursorEnd()
+	return m
+}
+
+// commandView renders the command line with any pending completions
+func (m Model) commandView() string {
+	view := m.commandInput.View()
+	if len(m.completions) == 0 {
+		return view
+	}
+	room := m.width - len(m.commandInput.Value()) - 4
+	if room <= 0 {
+		return view
+	}
+	return view + "  " + m.styles.statusText.Render(table.Truncate(strings.Join(m.completions, "  "), room))
+}
+
+// runCommand carries out a parsed command line
+func runCommand(m Model, call commands.Call) (Model, tea.Cmd) {
+	switch call.Command.Name {
+	case "move":
+		return bulkMoveTo(m, call.Args[0])
+	case "search":
+		return applySearch(m, call.Args[0])
+	case "folder":
+		return switchFolder(m, call.Args[0])
+	case "profile":
+		return switchProfile(m, call.Args[0])
+	case "sort":
+		return setView(m, call.Args[0], "")
+	case "group":
+		return setView(m, "", call.Args[0])
+	case "export":
+		return exportEmails(m, call.Args[0], call.Args[1])
+	case "set":
+		return setOption(m, call.Args[0], call.Args[1])
+	case "compose":
+		to := ""
+		if len(call.Args) > 0 {
+			to = call.Args[0]
+		}
+		return composeTo(m, to)
+	}
+	m.err = fmt.Errorf("%s: not available in the TUI", call.Command.Name)
+	return m, nil
+}
+
+// resetListing forgets the loaded list ahead of showing another one.
+// Fetches already in flight are dropped when they arrive.
+func resetListing(m Model) Model {
+	m.gen++
+	m.emails = nil
+	m.inbox = nil
+	m.searchQuery = ""
+	m.total = 0
+	m.loadingMore = false
+	m.newCount = 0
+	m.threads = nil
+	m.threadHeads = nil
+	m.expanded = nil
+	m.currentEmail = nil
+	m.err = nil
+	m.list.ClearMarks()
+	m.list.SetEmails(nil)
+	m.preview.SetEmail(nil)
+	return m
+}
+
+// switchFolder lists another folder, first sending any pending actions
+func switchFolder(m Model, folder string) (Model, tea.Cmd) {
+	if folder == m.folder && m.searchQuery == "" {
+		return m, nil
+	}
+	m, commit := commitPending(m)
+	m = resetListing(m)
+	m.folder = folder
+	m = m.applyView()
+	m.list.SetTitle(m.listTitle())
+	m.loading = true
+	return m, tea.Batch(tea.Sequence(commit, m.fetchEmails(pageSize, 0)), m.spinner.Tick)
+}
+
+// switchProfile opens a client for another profile, first sending any
+// pending actions with the current one
+func switchProfile(m Model, name string) (Model, tea.Cmd) {
+	if m.opts.SwitchProfile == nil {
+		m.err = errors.New("profile switching is not available")
+		return m, nil
+	}
+	m, commit := commitPending(m)
+	m.loading = true
+	open := m.opts.SwitchProfile
+	return m, tea.Batch(tea.Sequence(commit, func() tea.Msg {
+		client, err := open(name)
+		if err != nil {
+			return ErrMsg{Err: fmt.Errorf("switch to profile %q: %w", name, err)}
+		}
+		return ProfileSwitched{Name: name, Client: client}
+	}), m.spinner.Tick)
+}
+
+// profileSwitched lists the current folder with the new profile's client
+func profileSwitched(m Model, msg ProfileSwitched) (Model, tea.Cmd) {
+	m = resetListing(m)
+	m.list.auth = nil // IDs belong to the previous profile
+	m.client = msg.Client
+	m.profile = msg.Name
+	m.registry = newRegistry(m.opts, msg.Name)
+	m.pollFailures = 0
+	m.status = "Profile " + msg.Name
+	m.list.SetTitle(m.listTitle())
+	m.loading = true
+	return m, tea.Batch(m.fetchEmails(pageSize, 0), m.spinner.Tick)
+}
+
+// setView changes the folder's sort or grouping, whichever is given, and
+// remembers it for the next session
+func setView(m Model, sortMode, groupMode string) (Model, tea.Cmd) {
+	view := m.state.view(m.folder)
+	if sortMode != "" {
+		view.Sort = sortMode
+		m.status = "Sorted by " + sortMode
+	}
+	if groupMode != "" {
+		view.Group = groupMode
+		m.status = "Grouped by " + groupMode
+		if groupMode == "none" {
+			m.status = "Ungrouped"
+		}
+	}
+	save := m.state.setView(m.folder, view)
+	m = m.applyView()
+	if m.threaded {
+		m.status += " (flat view only)"
+	}
+	return m, save
+}
+
+// applyView orders and groups the list as remembered for the folder
+func (m Model) applyView() Model {
+	view := m.state.view(m.folder)
+	m.list.SetSort(view.Sort)
+	m.list.SetGroup(view.Group)
+	if m.threaded {
+		return m
+	}
+	selectedID := m.selectedID()
+	m.list.SetEmails(m.emails)
+	m.list.SelectID(selectedID)
+	return m
+}
+
+// exportEmails writes the marked emails, or everything listed, to path
+func exportEmails(m Model, format, path string) (Model, tea.Cmd) {
+	emails := m.list.Marked()
+	if len(emails) == 0 {
+		emails = append([]api.Email(nil), m.emails...)
+	}
+	if len(emails) == 0 {
+		m.err = errors.New("export: nothing to export")
+		return m, nil
+	}
+	path = expandHome(path)
+	client := m.client
+	m.loading = true
+	return m, tea.Batch(func() tea.Msg {
+		var err error
+		switch format {
+		case "mbox":
+			ids := make([]int, len(emails))
+			for i, email := range emails {
+				ids[i] = email.ID
+			}
+			// A message without its body must not be exported as if whole.
+			full, fetchErr := fetchFull(client, ids)
+			if fetchErr != nil {
+				err = fetchErr
+				break
+			}
+			out := make([]api.Email, len(emails))
+			for i, email := range emails {
+				out[i] = email
+				if f, ok := full[email.ID]; ok {
+					out[i] = *f
+				}
+			}
+			err = mbox.WriteFile(path, out)
+		default:
+			err = fmt.Errorf("unknown format %q", format)
+		}
+		if err != nil {
+			return ErrMsg{Err: fmt.Errorf("export: %w", err)}
+		}
+		return Exported{Path: path, Count: len(emails)}
+	}, m.spinner.Tick)
+}
+
+// expandHome resolves a leading "~/", which the command line does not
+// pass through a shell
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// setOption changes a session setting
+func setOption(m Model, name, value string) (Model, tea.Cmd) {
+	switch name {
+	case "preview.wrap":
+		width, err := strconv.Atoi(value)
+		if err != nil || width < 0 {
+			m.err = fmt.Errorf("preview.wrap: %q is not a column count (0 turns wrapping off)", value)
+			return m, nil
+		}
+		m.previewWrap = width
+	case "reader.wrap":
+		width, err := strconv.Atoi(value)
+		if err != nil || width < 0 {
+			m.err = fmt.Errorf("reader.wrap: %q is not a column count (0 fills the window)", value)
+			return m, nil
+		}
+		m.opts.ReaderWidth = width
+	}
+	m.status = fmt.Sprintf("%s = %s", name, value)
+	return m.layout(), nil
+}
+
+// applySearch runs a query typed at the search prompt or :search
+func applySearch(m Model, input string) (Model, tea.Cmd) {
+	query, err := search.Parse(input)
+	if err != nil {
+		m.err = err
+		return m, nil
+	}
+	if query.Empty() {
+		return clearSearch(m)
+	}
+	m.err = nil
+	m.loading = true
+	return m, tea.Batch(runSearch(m.client, query, m.searchSource()), m.spinner.Tick)
+}
@@ -0,0 +1,64 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/pgp"
+)
+
+// pgpState is the OpenPGP outcome of the previewed email
+type pgpState struct {
+	pending bool // gpg is still running
+	result  *pgp.Result
+	err     error
+}
+
+// openPGP decrypts and verifies email with the profile's keys off the UI
+// thread
+func openPGP(open func(string, *api.Email) (*pgp.Result, error), profile string, email api.Email) tea.Cmd {
+	return func() tea.Msg {
+		result, err := open(profile, &email)
+		return PGPOpened{ID: email.ID, Result: result, Err: err}
+	}
+}
+
+// pgpOpened shows the decrypted body and signature if the email is still
+// the one previewed
+func pgpOpened(m Model, msg PGPOpened) (Model, tea.Cmd) {
+	if m.currentEmail == nil || m.currentEmail.ID != msg.ID {
+		return m, nil
+	}
+	m.preview.SetPGP(&pgpState{result: msg.Result, err: msg.Err})
+	return m, nil
+}
+
+// SetPGP shows the OpenPGP outcome above the body, and the decrypted text
+func (m *PreviewModel) SetPGP(state *pgpState) {
+	m.pgp = state
+	if m.email != nil {
+		m.viewport.SetContent(m.render())
+	}
+}
+
+// pgpBadge marks a trusted signature, a bad one, or anything in between
+func (m *PreviewModel) pgpBadge() string {
+	state := m.pgp
+	switch {
+	case state.pending:
+		return m.styles.placeholder.Render("Decrypting and verifying…")
+	case state.err != nil:
+		return m.styles.statusError.Render("✗ " + state.err.Error())
+	case state.result == nil:
+		return m.styles.placeholder.Render("– Not signed")
+	}
+	sig := state.result.Signature
+	switch {
+	case sig != nil && sig.Trusted():
+		return m.styles.headerValue.Render("✓ " + state.result.Badge())
+	case sig != nil && (sig.Status == pgp.Bad || sig.Status == pgp.RevokedKey):
+		return m.styles.statusError.Render("✗ " + state.result.Badge())
+	default:
+		return m.styles.placeholder.Render("– " + state.result.Badge())
+	}
+}
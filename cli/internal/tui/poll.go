@@ -0,0 +1,178 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+)
+
+// maxPollBackoff caps the polling delay after repeated failures
+const maxPollBackoff = 15 * time.Minute
+
+// schedulePoll fires the next background poll after d
+func schedulePoll(d time.Duration) tea.Cmd {
+	return tea.Tick(d, func(time.Time) tea.Msg {
+		return PollTick{}
+	})
+}
+
+// pollEmails lists mail in the listed folder received at or after since,
+// or the first page when nothing is loaded yet. It pages until a short page
+// so a burst of mail leaves no gap; mergeNewEmails drops what is listed.
+func (m Model) pollEmails(since string) tea.Cmd {
+	client, folder, gen := m.client, m.folder, m.gen
+	return func() tea.Msg {
+		if since == "" {
+			resp, err := client.ListEmails(pageSize, 0, folder)
+			if err != nil {
+				return PollFailed{Err: err}
+			}
+			return NewEmails{Emails: resp.Emails, Gen: gen}
+		}
+		var emails []api.Email
+		for {
+			resp, err := client.ListEmailsSince(since, maxPageSize, len(emails), folder)
+			if err != nil {
+				return PollFailed{Err: err}
+			}
+			emails = append(emails, resp.Emails...)
+			if len(resp.Emails) < maxPageSize {
+				return NewEmails{Emails: emails, Gen: gen}
+			}
+		}
+	}
+}
+
+// pollDelay doubles the refresh interval for each consecutive failure
+func (m Model) pollDelay() time.Duration {
+	delay := m.opts.RefreshInterval
+	for i := 0; i < m.pollFailures && delay < maxPollBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxPollBackoff && m.opts.RefreshInterval < maxPollBackoff {
+		delay = maxPollBackoff
+	}
+	return delay
+}
+
+// handlePollTick polls for new mail unless the editor is open
+func handlePollTick(m Model) (Model, tea.Cmd) {
+	if m.compose != nil {
+		return m, schedulePoll(m.pollDelay())
+	}
+	return m, m.pollEmails(newestReceivedAt(m.listedInbox()))
+}
+
+// mergeNewEmails adds polled mail above the inbox without moving the
+// selection or preview, then schedules the next poll
+func mergeNewEmails(m Model, msg NewEmails) (Model, tea.Cmd) {
+	m.pollFailures = 0
+	next := schedulePoll(m.pollDelay())
+	if msg.Gen != m.gen {
+		// Polled before a folder or profile switch.
+		return m, next
+	}
+
+	inbox := m.listedInbox()
+	listed := make(map[int]bool, len(inbox))
+	for _, email := range inbox {
+		listed[email.ID] = true
+	}
+	var fresh []api.Email
+	for _, email := range msg.Emails {
+		if !listed[email.ID] {
+			listed[email.ID] = true
+			fresh = append(fresh, email)
+		}
+	}
+	if len(fresh) == 0 {
+		return m, next
+	}
+
+	merged := append(fresh, inbox...)
+	m.total += len(fresh)
+	m.newCount += len(fresh)
+	notify := m.notifyNewMail()
+	if m.searchQuery != "" {
+		m.inbox = merged
+		return m, tea.Batch(next, notify)
+	}
+
+	m.emails = merged
+	m.list.SetTitle(m.listTitle())
+	if m.threaded {
+		return m, tea.Batch(next, notify, m.buildThreads())
+	}
+	selectedID := m.selectedID()
+	m.list.SetEmails(m.emails)
+	if m.list.SelectID(selectedID) {
+		return m, tea.Batch(next, notify)
+	}
+	// Nothing was selected: the list was empty until now.
+	m.list.SetIndex(0)
+	m.loading = true
+	return m, tea.Batch(next, notify, fetchEmail(m.client, m.list.SelectedEmail().ID), m.spinner.Tick)
+}
+
+// pollFailed backs off quietly; offline clients stop polling
+func pollFailed(m Model, msg PollFailed) (Model, tea.Cmd) {
+	if errors.Is(msg.Err, api.ErrOffline) {
+		return m, nil
+	}
+	m.pollFailures++
+	delay := m.pollDelay()
+	m.status = fmt.Sprintf("Refresh failed, retrying in %s", delay)
+	return m, schedulePoll(delay)
+}
+
+// notifyNewMail rings the bell or updates the window title
+func (m Model) notifyNewMail() tea.Cmd {
+	switch m.opts.Notify {
+	case "none":
+		return nil
+	case "title":
+		return tea.SetWindowTitle(fmt.Sprintf("Mercury (%d new)", m.newCount))
+	default:
+		return func() tea.Msg {
+			fmt.Fprint(os.Stderr, "\a")
+			return nil
+		}
+	}
+}
+
+// clearNewBadge dismisses the new mail badge once the list has been seen
+func clearNewBadge(m Model) (Model, tea.Cmd) {
+	if m.newCount == 0 {
+		return m, nil
+	}
+	m.newCount = 0
+	if m.opts.Notify == "title" {
+		return m, tea.SetWindowTitle("Mercury")
+	}
+	return m, nil
+}
+
+// listedInbox returns the inbox listing, including one stashed by a search
+func (m Model) listedInbox() []api.Email {
+	if m.searchQuery != "" {
+		return m.inbox
+	}
+	return m.emails
+}
+
+// newestReceivedAt returns the latest received_at among emails, verbatim
+// as the server sent it so it compares correctly in a since filter
+func newestReceivedAt(emails []api.Email) string {
+	newest := ""
+	for _, email := range emails {
+		if email.ReceivedAt > newest {
+			newest = email.ReceivedAt
+		}
+	}
+	return newest
+}
@@ -1,31 +1,45 @@
 package tui
 
 import (
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 	"github.com/misty-step/mercury/cli/internal/api"
-)
-
-var (
-	headerLabelStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("242"))
-	headerValueStyle = lipgloss.NewStyle()
-	subjectStyle     = lipgloss.NewStyle().Bold(true)
-	dividerStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	"github.com/misty-step/mercury/cli/internal/calendar"
 )
 
 type PreviewModel struct {
 	viewport viewport.Model
 	email    *api.Email
 	ready    bool
+	styles   styles
+	wrap     int // body wrap column, 0 for none
+	mode     previewMode
+	find     string
+	matches  []int       // content lines containing find
+	match    int         // index in matches of the line last jumped to
+	pgp      *pgpState   // OpenPGP outcome, nil for unprotected mail
+	smime    *smimeState // S/MIME outcome, nil for unsigned mail
 }
 
 func NewPreviewModel(width, height int) PreviewModel {
 	vp := viewport.New(width, height)
 	vp.Style = lipgloss.NewStyle().Padding(0, 1)
-	return PreviewModel{viewport: vp}
+	return PreviewModel{viewport: vp, styles: newStyles(themes[DefaultTheme])}
+}
+
+// SetTheme restyles the headers and divider
+func (m *PreviewModel) SetTheme(t Theme) {
+	m.styles = newStyles(t)
+	m.viewport.Style = m.viewport.Style.Foreground(t.Text)
+	if m.email != nil {
+		m.SetEmail(m.email)
+	}
 }
 
 func (m PreviewModel) Update(msg tea.Msg) (PreviewModel, tea.Cmd) {
@@ -34,57 +48,286 @@ func (m PreviewModel) Update(msg tea.Msg) (PreviewModel, tea.Cmd) {
 	return m, cmd
 }
 
+// SetKeys scrolls the preview with the TUI's up and down bindings
+func (m *PreviewModel) SetKeys(keys KeyMap) {
+	m.viewport.KeyMap.Up = keys.Up
+	m.viewport.KeyMap.Down = keys.Down
+}
+
 func (m PreviewModel) View() string {
 	if m.email == nil {
-		return lipgloss.NewStyle().Foreground(lipgloss.Color("242")).Render("Select an email to preview")
+		return m.styles.placeholder.Render("Select an email to preview")
 	}
 	return m.viewport.View()
 }
 
+// SetWrap wraps the body at width columns, or stops wrapping when 0
+func (m *PreviewModel) SetWrap(width int) {
+	m.wrap = width
+	if m.email != nil {
+		m.SetEmail(m.email)
+	}
+}
+
 func (m *PreviewModel) SetEmail(email *api.Email) {
+	if email == nil || m.email == nil || email.ID != m.email.ID {
+		// A different message drops the in-message search.
+		m.find, m.matches = "", nil
+		m.pgp, m.smime = nil, nil
+	}
 	m.email = email
 	if email == nil {
 		m.viewport.SetContent("")
 		return
 	}
+	m.viewport.SetContent(m.render())
+	m.viewport.GotoTop()
+}
+
+// previewMode picks what the preview shows of a message
+type previewMode int
+
+const (
+	previewBody      previewMode = iota
+	previewHeaders               // every header above the body
+	previewRaw                   // the RFC 822 source
+	previewStructure             // the MIME tree in place of the body
+)
+
+// ToggleMode switches to mode, or back to the body if it is already shown,
+// and returns the mode now shown
+func (m *PreviewModel) ToggleMode(mode previewMode) previewMode {
+	if m.mode == mode {
+		mode = previewBody
+	}
+	m.mode = mode
+	if m.email != nil {
+		m.viewport.SetContent(m.render())
+		m.viewport.GotoTop()
+	}
+	return mode
+}
+
+// render lays out the headers and body, highlighting find matches
+func (m *PreviewModel) render() string {
+	email := m.email
 
 	var sb strings.Builder
+	var body string
+	if m.mode == previewRaw {
+		body = strings.ReplaceAll(email.RawEmail, "\r\n", "\n")
+		if body == "" {
+			body = "(No raw source)"
+		}
+	} else {
+		m.renderHeaders(&sb)
+		body = m.renderBody()
+	}
+	if m.wrap > 0 {
+		body = ansi.Wrap(body, m.wrap, "")
+	}
+	headerLines := strings.Count(sb.String(), "\n")
+	m.matches = nil
+	if m.find != "" {
+		lines := strings.Split(body, "\n")
+		for i, line := range lines {
+			if highlighted, ok := highlight(line, m.find, m.styles.match); ok {
+				lines[i] = highlighted
+				m.matches = append(m.matches, headerLines+i)
+			}
+		}
+		body = strings.Join(lines, "\n")
+	}
+	sb.WriteString(body)
+	return sb.String()
+}
+
+// renderHeaders writes the header block and divider
+func (m *PreviewModel) renderHeaders(sb *strings.Builder) {
+	email := m.email
+	if m.mode == previewHeaders {
+		for _, f := range email.HeaderFields() {
+			line := m.styles.headerLabel.Render(f.Name+": ") + m.styles.headerValue.Render(f.Value)
+			if m.wrap > 0 {
+				line = ansi.Wrap(line, m.wrap, "")
+			}
+			sb.WriteString(line)
+			sb.WriteString("\n")
+		}
+	} else {
+		sb.WriteString(m.styles.headerLabel.Render("From: "))
+		sb.WriteString(m.styles.headerValue.Render(email.Sender))
+		sb.WriteString("\n")
 
-	// Headers
-	sb.WriteString(headerLabelStyle.Render("From: "))
-	sb.WriteString(headerValueStyle.Render(email.Sender))
-	sb.WriteString("\n")
+		sb.WriteString(m.styles.headerLabel.Render("To: "))
+		sb.WriteString(m.styles.headerValue.Render(email.Recipient))
+		sb.WriteString("\n")
 
-	sb.WriteString(headerLabelStyle.Render("To: "))
-	sb.WriteString(headerValueStyle.Render(email.Recipient))
-	sb.WriteString("\n")
+		sb.WriteString(m.styles.headerLabel.Render("Subject: "))
+		sb.WriteString(m.styles.subject.Render(email.Subject))
+		sb.WriteString("\n")
 
-	sb.WriteString(headerLabelStyle.Render("Subject: "))
-	sb.WriteString(subjectStyle.Render(email.Subject))
-	sb.WriteString("\n")
+		sb.WriteString(m.styles.headerLabel.Render("Date: "))
+		sb.WriteString(m.styles.headerValue.Render(email.ReceivedAt))
+		sb.WriteString("\n")
 
-	sb.WriteString(headerLabelStyle.Render("Date: "))
-	sb.WriteString(headerValueStyle.Render(email.ReceivedAt))
-	sb.WriteString("\n")
+		if auth := email.Authentication(); auth.Known() {
+			sb.WriteString(m.styles.headerLabel.Render("Auth: "))
+			sb.WriteString(m.authBadges(auth))
+			sb.WriteString("\n")
+		}
+
+		if m.pgp != nil {
+			sb.WriteString(m.styles.headerLabel.Render("PGP: "))
+			sb.WriteString(m.pgpBadge())
+			sb.WriteString("\n")
+		}
+
+		if m.smime != nil {
+			sb.WriteString(m.styles.headerLabel.Render("S/MIME: "))
+			sb.WriteString(m.smimeBadge())
+			sb.WriteString("\n")
+		}
+	}
 
 	// Divider
 	dividerWidth := m.viewport.Width - 2
+	if m.wrap > 0 && m.wrap < dividerWidth {
+		dividerWidth = m.wrap
+	}
 	if dividerWidth < 0 {
 		dividerWidth = 0
 	}
 	divider := strings.Repeat("─", dividerWidth)
-	sb.WriteString(dividerStyle.Render(divider))
+	sb.WriteString(m.styles.divider.Render(divider))
 	sb.WriteString("\n\n")
+}
 
-	// Body
-	body := email.Body()
-	if body == "" {
+// authBadges marks each check passed, failed or neither, naming the
+// From domain DMARC was checked against
+func (m *PreviewModel) authBadges(v api.AuthVerdict) string {
+	badge := func(name string, check api.AuthCheck) string {
+		switch {
+		case check.Result == api.AuthPass:
+			return m.styles.headerValue.Render("✓ " + name)
+		case check.Result.Failed():
+			if name == "DMARC" && check.Domain != "" {
+				name += " (" + check.Domain + ")"
+			}
+			return m.styles.statusError.Render("✗ " + name + " " + string(check.Result))
+		default:
+			return m.styles.placeholder.Render("– " + name)
+		}
+	}
+	return badge("SPF", v.SPF) + "  " + badge("DKIM", v.DKIM) + "  " + badge("DMARC", v.DMARC)
+}
+
+// renderBody returns the body text, or the MIME tree in structure mode
+func (m *PreviewModel) renderBody() string {
+	if m.mode == previewStructure {
+		root := m.email.Structure()
+		if root == nil {
+			return "(No raw source)"
+		}
+		return strings.Join(root.Tree(), "\n")
+	}
+	card := m.eventCards()
+	body := m.email.Body()
+	if m.smime != nil && m.smime.result != nil {
+		body = m.smime.result.Content.Body()
+	}
+	if m.pgp != nil && m.pgp.result != nil {
+		body = m.pgp.result.Body
+	}
+	if body == "" && card == "" {
 		body = "(No content)"
 	}
-	sb.WriteString(body)
+	return card + body
+}
 
-	m.viewport.SetContent(sb.String())
-	m.viewport.GotoTop()
+// eventCards renders the calendar invitation's events, each followed by a
+// blank line
+func (m *PreviewModel) eventCards() string {
+	cal := calendar.FromEmail(m.email)
+	if cal == nil {
+		return ""
+	}
+	var sb strings.Builder
+	for _, event := range cal.Events {
+		for _, f := range event.Card(cal.Method, time.Local) {
+			sb.WriteString(m.styles.headerLabel.Render(fmt.Sprintf("%-11s", f.Label+":")))
+			sb.WriteString(m.styles.headerValue.Render(f.Value))
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Find highlights query in the body and scrolls to its first match,
+// returning the number of matching lines
+func (m *PreviewModel) Find(query string) int {
+	m.find = query
+	m.match = 0
+	if m.email == nil {
+		return 0
+	}
+	m.viewport.SetContent(m.render())
+	if len(m.matches) > 0 {
+		m.viewport.SetYOffset(m.matches[0])
+	}
+	return len(m.matches)
+}
+
+// NextMatch scrolls to the next matching line, wrapping around at the end,
+// and returns its 1-based position among the matches
+func (m *PreviewModel) NextMatch() (int, int) {
+	if len(m.matches) == 0 {
+		return 0, 0
+	}
+	m.match = (m.match + 1) % len(m.matches)
+	m.viewport.SetYOffset(m.matches[m.match])
+	return m.match + 1, len(m.matches)
+}
+
+// Finding returns the active in-message search
+func (m PreviewModel) Finding() string {
+	return m.find
+}
+
+// AtBottom reports whether the end of the message is in view
+func (m PreviewModel) AtBottom() bool {
+	return m.viewport.AtBottom()
+}
+
+// PageDown scrolls down one screen
+func (m *PreviewModel) PageDown() {
+	m.viewport.PageDown()
+}
+
+// highlight styles every case-insensitive occurrence of query in line
+func highlight(line, query string, style lipgloss.Style) (string, bool) {
+	lower, q := strings.ToLower(line), strings.ToLower(query)
+	if len(lower) != len(line) {
+		// Case folding changed byte offsets; match case-sensitively.
+		lower, q = line, query
+	}
+	if !strings.Contains(lower, q) {
+		return line, false
+	}
+	var sb strings.Builder
+	i := 0
+	for {
+		j := strings.Index(lower[i:], q)
+		if j < 0 {
+			break
+		}
+		sb.WriteString(line[i : i+j])
+		sb.WriteString(style.Render(line[i+j : i+j+len(q)]))
+		i += j + len(q)
+	}
+	sb.WriteString(line[i:])
+	return sb.String(), true
 }
 
 func (m *PreviewModel) SetSize(w, h int) {
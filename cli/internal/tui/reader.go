@@ -0,0 +1,122 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// toggleReader shows the open message full screen, or returns to the panes
+func toggleReader(m Model) (Model, tea.Cmd) {
+	if !m.reading && m.currentEmail == nil {
+		return m, nil
+	}
+	m.reading = !m.reading
+	return m.layout(), nil
+}
+
+// updateReader handles the reader's own keys, reporting whether msg was one
+func updateReader(m Model, msg tea.KeyMsg) (Model, tea.Cmd, bool) {
+	var cmd tea.Cmd
+	switch {
+	case key.Matches(msg, m.keys.Back) && m.preview.Finding() != "":
+		m.preview.Find("")
+	case key.Matches(msg, m.keys.Back), key.Matches(msg, m.keys.Reader):
+		m, cmd = toggleReader(m)
+	case key.Matches(msg, m.keys.NextMessage):
+		m, cmd = readerStep(m, 1, false)
+	case key.Matches(msg, m.keys.PrevMessage):
+		m, cmd = readerStep(m, -1, false)
+	case key.Matches(msg, m.keys.NextUnread):
+		m, cmd = readerStep(m, 1, true)
+	case key.Matches(msg, m.keys.PageAdvance):
+		if m.preview.AtBottom() {
+			m, cmd = readerStep(m, 1, false)
+		} else {
+			m.preview.PageDown()
+		}
+	case key.Matches(msg, m.keys.Find):
+		m.finding = true
+		m.findInput.SetValue("")
+		cmd = m.findInput.Focus()
+	default:
+		return m, nil, false
+	}
+	return m, cmd, true
+}
+
+// togglePreviewMode shows all headers, the source or the MIME structure in
+// the preview, or goes back to the body
+func togglePreviewMode(m Model, mode previewMode) (Model, tea.Cmd) {
+	switch m.preview.ToggleMode(mode) {
+	case previewHeaders:
+		m.status = "Showing all headers"
+	case previewRaw:
+		m.status = "Showing the message source"
+	case previewStructure:
+		m.status = "Showing the MIME structure"
+	default:
+		m.status = "Showing the message"
+	}
+	return m, nil
+}
+
+// readerStep opens the next or previous message in list order, or with
+// unread set, the next unread one
+func readerStep(m Model, delta int, unread bool) (Model, tea.Cmd) {
+	if !m.list.Step(delta, unread) {
+		m.status = "No more messages"
+		if unread {
+			m.status = "No more unread messages"
+		}
+		return m, nil
+	}
+	var more, clear tea.Cmd
+	m, more = maybeLoadMore(m)
+	m, clear = clearNewBadge(m)
+	m.loading = true
+	m.err = nil
+	return m, tea.Batch(more, clear, fetchEmail(m.client, m.list.SelectedEmail().ID), m.spinner.Tick)
+}
+
+// updateFindInput handles keys while the in-message search has focus. An
+// empty search moves to the next match of the last one.
+func updateFindInput(m Model, msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.finding = false
+		m.findInput.Blur()
+		return m, nil
+	case tea.KeyEnter:
+		m.finding = false
+		m.findInput.Blur()
+		query := m.findInput.Value()
+		if query == "" || query == m.preview.Finding() {
+			if n, total := m.preview.NextMatch(); total > 0 {
+				m.status = fmt.Sprintf("Match %d of %d", n, total)
+			}
+			return m, nil
+		}
+		if total := m.preview.Find(query); total > 0 {
+			m.status = fmt.Sprintf("Match 1 of %d", total)
+		} else {
+			m.status = fmt.Sprintf("No matches for %q", query)
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.findInput, cmd = m.findInput.Update(msg)
+	return m, cmd
+}
+
+// readerWrap is the reader's wrap column: the configured width, or the
+// window's when that is narrower or none is set
+func (m Model) readerWrap() int {
+	fit := m.width - 4
+	if m.opts.ReaderWidth > 0 && m.opts.ReaderWidth < fit {
+		return m.opts.ReaderWidth
+	}
+	return fit
+}
@@ -0,0 +1,111 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/search"
+)
+
+// searcher is implemented by caches that maintain a full-text index.
+type searcher interface {
+	Search(q *search.Query, limit int) []api.Email
+}
+
+// searchLimit caps the number of results shown in the list.
+const searchLimit = 200
+
+// startSearch focuses the search input
+func startSearch(m Model) (Model, tea.Cmd) {
+	m.searching = true
+	m.searchInput.SetValue(m.searchQuery)
+	m.searchInput.CursorEnd()
+	return m, m.searchInput.Focus()
+}
+
+// updateSearchInput handles keys while the search input has focus
+func updateSearchInput(m Model, msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.searching = false
+		m.searchInput.Blur()
+		return m, nil
+	case tea.KeyEnter:
+		m.searching = false
+		m.searchInput.Blur()
+		return applySearch(m, m.searchInput.Value())
+	}
+
+	var cmd tea.Cmd
+	m.searchInput, cmd = m.searchInput.Update(msg)
+	return m, cmd
+}
+
+// searchSource is the listing searched when no full-text index is available
+func (m Model) searchSource() []api.Email {
+	if m.searchQuery != "" {
+		return m.inbox
+	}
+	return m.emails
+}
+
+// runSearch queries the cache index, or indexes the given emails in memory
+// when the client has no searchable cache
+func runSearch(client *api.Client, query *search.Query, loaded []api.Email) tea.Cmd {
+	return func() tea.Msg {
+		if client != nil {
+			if s, ok := client.Cache.(searcher); ok {
+				return SearchResults{Query: query.String(), Emails: s.Search(query, searchLimit)}
+			}
+		}
+
+		idx := search.NewIndex()
+		byID := make(map[int]api.Email, len(loaded))
+		for i := range loaded {
+			idx.Add(&loaded[i])
+			byID[loaded[i].ID] = loaded[i]
+		}
+		docs := idx.Search(query)
+		emails := make([]api.Email, 0, len(docs))
+		for _, doc := range docs {
+			emails = append(emails, byID[doc.ID])
+		}
+		return SearchResults{Query: query.String(), Emails: emails}
+	}
+}
+
+// showSearchResults replaces the list with search results, stashing the inbox
+func showSearchResults(m Model, msg SearchResults) (Model, tea.Cmd) {
+	m.loading = false
+	if m.searchQuery == "" {
+		m.inbox = m.emails
+	}
+	m.searchQuery = msg.Query
+	m.list.SetTitle("Search: " + msg.Query)
+	m.emails = msg.Emails
+	m.list.SetEmails(m.emails)
+	m.currentEmail = nil
+	m.preview.SetEmail(nil)
+	if len(m.emails) == 0 {
+		m.status = "No matches"
+		return m, nil
+	}
+	m.list.SetIndex(0)
+	m.loading = true
+	return m, tea.Batch(fetchEmail(m.client, m.list.SelectedEmail().ID), m.spinner.Tick)
+}
+
+// clearSearch restores the inbox listing and refreshes it
+func clearSearch(m Model) (Model, tea.Cmd) {
+	if m.searchQuery == "" {
+		return m, nil
+	}
+	m.searchQuery = ""
+	m.emails = m.inbox
+	m.inbox = nil
+	m.list.SetTitle(m.listTitle())
+	m.list.SetEmails(m.emails)
+	m.loading = true
+	m.err = nil
+	return m, tea.Batch(refreshEmails(m), m.spinner.Tick)
+}
@@ -0,0 +1,64 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/smime"
+)
+
+// smimeState is the S/MIME outcome of the previewed email
+type smimeState struct {
+	pending bool // still verifying
+	result  *api.SMIMEResult
+	err     error
+}
+
+// verifySMIME checks email's signature against the profile's trust store
+// off the UI thread
+func verifySMIME(verify func(string, *api.Email) (*api.SMIMEResult, error), profile string, email api.Email) tea.Cmd {
+	return func() tea.Msg {
+		result, err := verify(profile, &email)
+		return SMIMEVerified{ID: email.ID, Result: result, Err: err}
+	}
+}
+
+// smimeVerified shows the signature and signed content if the email is
+// still the one previewed
+func smimeVerified(m Model, msg SMIMEVerified) (Model, tea.Cmd) {
+	if m.currentEmail == nil || m.currentEmail.ID != msg.ID {
+		return m, nil
+	}
+	m.preview.SetSMIME(&smimeState{result: msg.Result, err: msg.Err})
+	return m, nil
+}
+
+// SetSMIME shows the S/MIME outcome above the body, and the signed text
+func (m *PreviewModel) SetSMIME(state *smimeState) {
+	m.smime = state
+	if m.email != nil {
+		m.viewport.SetContent(m.render())
+	}
+}
+
+// smimeBadge marks a valid signature, an invalid one, or an untrusted signer
+func (m *PreviewModel) smimeBadge() string {
+	state := m.smime
+	switch {
+	case state.pending:
+		return m.styles.placeholder.Render("Verifying…")
+	case state.err != nil:
+		return m.styles.statusError.Render("✗ " + state.err.Error())
+	case state.result == nil:
+		return m.styles.placeholder.Render("– Not signed")
+	}
+	v := state.result.Verification
+	switch v.Status {
+	case smime.Valid:
+		return m.styles.headerValue.Render("✓ " + v.Summary())
+	case smime.Invalid:
+		return m.styles.statusError.Render("✗ " + v.Summary())
+	default:
+		return m.styles.placeholder.Render("– " + v.Summary())
+	}
+}
@@ -0,0 +1,139 @@
+package tui
+
+import (
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/thread"
+)
+
+// SortModes lists the list orders, the first being the default
+var SortModes = []string{"date", "sender", "subject", "unread-first", "starred-first"}
+
+// GroupModes lists the list groupings, the first being the default
+var GroupModes = []string{"none", "date", "domain"}
+
+func validMode(modes []string, mode string) bool {
+	for _, m := range modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// sortEmails returns a copy of emails in the given order. "date" keeps
+// the server's newest-first order, and the others fall back to it for ties.
+func sortEmails(emails []api.Email, mode string) []api.Email {
+	var less func(a, b *api.Email) bool
+	switch mode {
+	case "sender":
+		less = func(a, b *api.Email) bool { return senderKey(a.Sender) < senderKey(b.Sender) }
+	case "subject":
+		less = func(a, b *api.Email) bool { return subjectKey(a.Subject) < subjectKey(b.Subject) }
+	case "unread-first":
+		less = func(a, b *api.Email) bool { return !a.Read() && b.Read() }
+	case "starred-first":
+		less = func(a, b *api.Email) bool { return a.Starred() && !b.Starred() }
+	default:
+		return emails
+	}
+	sorted := append([]api.Email(nil), emails...)
+	sort.SliceStable(sorted, func(i, j int) bool { return less(&sorted[i], &sorted[j]) })
+	return sorted
+}
+
+// senderKey orders senders by display name, or address when there is none
+func senderKey(sender string) string {
+	if addr, err := mail.ParseAddress(sender); err == nil {
+		if addr.Name != "" {
+			return strings.ToLower(addr.Name)
+		}
+		return strings.ToLower(addr.Address)
+	}
+	return strings.ToLower(strings.TrimSpace(sender))
+}
+
+// subjectKey orders subjects without their Re:/Fwd: prefixes
+func subjectKey(subject string) string {
+	return strings.ToLower(thread.BaseSubject(subject))
+}
+
+// emailGroup is a run of emails under one header
+type emailGroup struct {
+	Label  string
+	Emails []api.Email
+}
+
+// groupEmails splits sorted emails into groups, keeping their order within
+// each. Date groups run newest first; domains run alphabetically.
+func groupEmails(emails []api.Email, mode string, now time.Time) []emailGroup {
+	var key func(e *api.Email) (label string, rank string)
+	switch mode {
+	case "date":
+		key = func(e *api.Email) (string, string) { return dateGroup(e.ReceivedAt, now) }
+	case "domain":
+		key = func(e *api.Email) (string, string) {
+			domain := senderDomain(e.Sender)
+			return domain, domain
+		}
+	default:
+		return []emailGroup{{Emails: emails}}
+	}
+
+	byRank := make(map[string]*emailGroup)
+	var ranks []string
+	for _, email := range emails {
+		label, rank := key(&email)
+		g, ok := byRank[rank]
+		if !ok {
+			g = &emailGroup{Label: label}
+			byRank[rank] = g
+			ranks = append(ranks, rank)
+		}
+		g.Emails = append(g.Emails, email)
+	}
+	sort.Strings(ranks)
+	groups := make([]emailGroup, len(ranks))
+	for i, rank := range ranks {
+		groups[i] = *byRank[rank]
+	}
+	return groups
+}
+
+// dateGroup buckets a received_at timestamp relative to now, in local time
+func dateGroup(receivedAt string, now time.Time) (label, rank string) {
+	t, err := api.ParseTimestamp(receivedAt)
+	if err != nil {
+		return "Older", "3"
+	}
+	now = now.Local()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	// Weeks start on Monday.
+	week := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	switch t = t.Local(); {
+	case !t.Before(today):
+		return "Today", "0"
+	case !t.Before(today.AddDate(0, 0, -1)):
+		return "Yesterday", "1"
+	case !t.Before(week):
+		return "This week", "2"
+	default:
+		return "Older", "3"
+	}
+}
+
+// senderDomain returns the lowercased domain of a sender address
+func senderDomain(sender string) string {
+	address := sender
+	if addr, err := mail.ParseAddress(sender); err == nil {
+		address = addr.Address
+	}
+	if at := strings.LastIndex(address, "@"); at >= 0 {
+		return strings.ToLower(strings.Trim(address[at+1:], "> "))
+	}
+	return "(no domain)"
+}
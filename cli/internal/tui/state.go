@@ -0,0 +1,103 @@
+package tui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// State is what the TUI remembers between sessions
+type State struct {
+	Folders map[string]FolderView `json:"folders,omitempty"`
+	// Split is the list's share of the window, 0 for the default
+	Split float64 `json:"split,omitempty"`
+
+	path string // file the state is saved to, "" to keep it in memory
+}
+
+// FolderView is how one folder's list is ordered and grouped
+type FolderView struct {
+	Sort  string `json:"sort,omitempty"`
+	Group string `json:"group,omitempty"`
+}
+
+// LoadState reads the state file at path. A missing file is an empty
+// state; an unreadable one is reported alongside an empty state, which
+// still saves to path.
+func LoadState(path string) (*State, error) {
+	s := &State{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("read tui state: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return &State{path: path}, fmt.Errorf("parse tui state %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// view returns the remembered view of folder, dropping modes this
+// version does not know
+func (s *State) view(folder string) FolderView {
+	v := s.Folders[folder]
+	if !validMode(SortModes, v.Sort) {
+		v.Sort = SortModes[0]
+	}
+	if !validMode(GroupModes, v.Group) {
+		v.Group = GroupModes[0]
+	}
+	return v
+}
+
+// setView remembers folder's view and returns a command saving the state
+func (s *State) setView(folder string, v FolderView) tea.Cmd {
+	if s.Folders == nil {
+		s.Folders = make(map[string]FolderView)
+	}
+	s.Folders[folder] = v
+	return s.save()
+}
+
+// save writes a snapshot of the state, replacing the file atomically
+func (s *State) save() tea.Cmd {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	path := s.path
+	return func() tea.Msg {
+		if err == nil {
+			err = writeFileAtomic(path, append(data, '\n'))
+		}
+		if err != nil {
+			return ErrMsg{Err: fmt.Errorf("save tui state: %w", err)}
+		}
+		return nil
+	}
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tui-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,227 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme is the TUI palette. Colors are ANSI numbers ("62") or hex ("#5f5fd7").
+type Theme struct {
+	Accent   lipgloss.Color // focused borders, spinner, badges
+	Title    lipgloss.Color // list title
+	Text     lipgloss.Color // ordinary text; "" for the terminal default
+	Muted    lipgloss.Color // labels, descriptions, status text
+	Border   lipgloss.Color // unfocused borders and dividers
+	Error    lipgloss.Color
+	Unread   lipgloss.Color
+	Starred  lipgloss.Color
+	Selected lipgloss.Color // cursor row
+}
+
+var themes = map[string]Theme{
+	"dark": {
+		Accent:   "69",
+		Title:    "62",
+		Muted:    "242",
+		Border:   "240",
+		Error:    "9",
+		Unread:   "255",
+		Starred:  "214",
+		Selected: "170",
+	},
+	"light": {
+		Accent:   "26",
+		Title:    "25",
+		Text:     "235",
+		Muted:    "240",
+		Border:   "248",
+		Error:    "160",
+		Unread:   "16",
+		Starred:  "130",
+		Selected: "90",
+	},
+	"high-contrast": {
+		Accent:   "14",
+		Title:    "15",
+		Text:     "15",
+		Muted:    "15",
+		Border:   "15",
+		Error:    "9",
+		Unread:   "15",
+		Starred:  "11",
+		Selected: "14",
+	},
+}
+
+// DefaultTheme is used when no theme is configured
+const DefaultTheme = "dark"
+
+// ThemeNames lists the built-in themes
+func ThemeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupTheme returns a built-in theme by name
+func LookupTheme(name string) (Theme, error) {
+	t, ok := themes[name]
+	if !ok {
+		return Theme{}, fmt.Errorf("unknown theme %q (built-in: %s)", name, strings.Join(ThemeNames(), ", "))
+	}
+	return t, nil
+}
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// With returns the theme with colors overridden by role name
+func (t Theme) With(colors map[string]string) (Theme, error) {
+	roles := map[string]*lipgloss.Color{
+		"accent":   &t.Accent,
+		"title":    &t.Title,
+		"text":     &t.Text,
+		"muted":    &t.Muted,
+		"border":   &t.Border,
+		"error":    &t.Error,
+		"unread":   &t.Unread,
+		"starred":  &t.Starred,
+		"selected": &t.Selected,
+	}
+	names := make([]string, 0, len(colors))
+	for name := range colors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := colors[name]
+		role, ok := roles[name]
+		if !ok {
+			return Theme{}, fmt.Errorf("unknown theme color %q (valid: accent, title, text, muted, border, error, unread, starred, selected)", name)
+		}
+		if n, err := strconv.Atoi(value); (err != nil || n < 0 || n > 255) && !hexColor.MatchString(value) {
+			return Theme{}, fmt.Errorf("%s: invalid color %q (want 0-255 or #rrggbb)", name, value)
+		}
+		*role = lipgloss.Color(value)
+	}
+	return t, nil
+}
+
+// styles are the lipgloss styles derived from a theme
+type styles struct {
+	focusedPanel lipgloss.Style
+	blurredPanel lipgloss.Style
+	statusText   lipgloss.Style
+	statusError  lipgloss.Style
+	statusBar    lipgloss.Style
+	helpOverlay  lipgloss.Style
+	badge        lipgloss.Style
+	spinner      lipgloss.Style
+	listTitle    lipgloss.Style
+	headerLabel  lipgloss.Style
+	headerValue  lipgloss.Style
+	subject      lipgloss.Style
+	divider      lipgloss.Style
+	placeholder  lipgloss.Style
+	match        lipgloss.Style
+}
+
+func newStyles(t Theme) styles {
+	text := lipgloss.NewStyle().Foreground(t.Text)
+	return styles{
+		focusedPanel: lipgloss.NewStyle().Border(lipgloss.ThickBorder()).BorderForeground(t.Accent),
+		blurredPanel: lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(t.Border),
+		statusText:   lipgloss.NewStyle().Foreground(t.Muted),
+		statusError:  lipgloss.NewStyle().Foreground(t.Error).Bold(true),
+		statusBar:    lipgloss.NewStyle(),
+		helpOverlay:  lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(t.Accent).Padding(1, 2),
+		badge:        lipgloss.NewStyle().Foreground(t.Accent).Bold(true),
+		spinner:      lipgloss.NewStyle().Foreground(t.Accent),
+		listTitle:    lipgloss.NewStyle().Bold(true).Foreground(t.Title),
+		headerLabel:  lipgloss.NewStyle().Foreground(t.Muted),
+		headerValue:  text,
+		subject:      text.Bold(true),
+		divider:      lipgloss.NewStyle().Foreground(t.Border),
+		placeholder:  lipgloss.NewStyle().Foreground(t.Muted),
+		match:        lipgloss.NewStyle().Reverse(true),
+	}
+}
+
+// helpStyles colors the help bar and overlay
+func helpStyles(t Theme) help.Styles {
+	s := help.New().Styles
+	key := lipgloss.NewStyle().Foreground(t.Accent)
+	desc := lipgloss.NewStyle().Foreground(t.Muted)
+	sep := lipgloss.NewStyle().Foreground(t.Border)
+	s.ShortKey, s.FullKey = key, key
+	s.ShortDesc, s.FullDesc = desc, desc
+	s.ShortSeparator, s.FullSeparator, s.Ellipsis = sep, sep, sep
+	return s
+}
+
+// emailDelegate renders list rows, styling unread, starred and marked
+// emails distinctly from one another and from the cursor row
+type emailDelegate struct {
+	list.DefaultDelegate
+	theme Theme
+}
+
+func newEmailDelegate(t Theme) emailDelegate {
+	d := list.NewDefaultDelegate()
+	d.Styles.NormalTitle = d.Styles.NormalTitle.Foreground(t.Text)
+	d.Styles.NormalDesc = d.Styles.NormalDesc.Foreground(t.Muted)
+	d.Styles.SelectedTitle = d.Styles.SelectedTitle.Foreground(t.Selected).BorderForeground(t.Selected).Bold(true)
+	d.Styles.SelectedDesc = d.Styles.SelectedDesc.Foreground(t.Selected).BorderForeground(t.Selected)
+	d.Styles.DimmedTitle = d.Styles.DimmedTitle.Foreground(t.Muted)
+	d.Styles.DimmedDesc = d.Styles.DimmedDesc.Foreground(t.Muted)
+	return emailDelegate{DefaultDelegate: d, theme: t}
+}
+
+func (d emailDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	if h, ok := item.(groupHeader); ok {
+		d.renderHeader(w, m, h)
+		return
+	}
+	ei, ok := item.(EmailItem)
+	if !ok {
+		d.DefaultDelegate.Render(w, m, index, item)
+		return
+	}
+	row := d.DefaultDelegate
+	unread := ei.Email.IsRead == 0
+	switch {
+	case ei.Email.IsStarred == 1:
+		row.Styles.NormalTitle = row.Styles.NormalTitle.Foreground(d.theme.Starred).Bold(unread)
+	case unread:
+		row.Styles.NormalTitle = row.Styles.NormalTitle.Foreground(d.theme.Unread).Bold(true)
+	}
+	if ei.Marked {
+		row.Styles.NormalTitle = row.Styles.NormalTitle.Underline(true)
+		row.Styles.SelectedTitle = row.Styles.SelectedTitle.Underline(true)
+	}
+	// The cursor row keeps its own color; unread stays bold and starred
+	// stays marked by its ★.
+	row.Styles.SelectedTitle = row.Styles.SelectedTitle.Bold(unread)
+	row.Render(w, m, index, item)
+}
+
+// renderHeader draws a group label in place of a row, filling the row's
+// height so paging stays even
+func (d emailDelegate) renderHeader(w io.Writer, m list.Model, h groupHeader) {
+	label := fmt.Sprintf("── %s (%d) ", h.Label, h.Count)
+	if rule := m.Width() - lipgloss.Width(label) - 2; rule > 0 {
+		label += strings.Repeat("─", rule)
+	}
+	style := lipgloss.NewStyle().Foreground(d.theme.Accent).Bold(true).Padding(0, 0, 0, 2)
+	fmt.Fprint(w, style.Render(label)+strings.Repeat("\n", d.Height()-1))
+}
@@ -0,0 +1,176 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/thread"
+)
+
+// buildThreads fetches the threading headers of loaded emails not seen
+// before; showThreads groups the emails into conversations once they
+// arrive
+func (m Model) buildThreads() tea.Cmd {
+	client, gen := m.client, m.gen
+	var missing []int
+	for _, email := range m.emails {
+		if _, ok := m.threadHeads[email.ID]; !ok && email.HeadersJSON == "" {
+			missing = append(missing, email.ID)
+		}
+	}
+	return func() tea.Msg {
+		full, err := fetchFull(client, missing)
+		heads := make(map[int]string, len(full))
+		for id, email := range full {
+			heads[id] = threadHeaders(email)
+		}
+		return ThreadsBuilt{Headers: heads, Err: err, Gen: gen}
+	}
+}
+
+// threadHeaders keeps the headers threading reads, as headers_json
+func threadHeaders(email *api.Email) string {
+	all := email.Headers()
+	kept := make(map[string]string)
+	for _, name := range []string{"message-id", "references", "in-reply-to"} {
+		if value := all[name]; value != "" {
+			kept[name] = value
+		}
+	}
+	data, _ := json.Marshal(kept)
+	return string(data)
+}
+
+// threadEmails returns the loaded emails with the threading headers held
+// for them
+func (m Model) threadEmails() []api.Email {
+	emails := append([]api.Email(nil), m.emails...)
+	for i := range emails {
+		if heads, ok := m.threadHeads[emails[i].ID]; ok {
+			emails[i].HeadersJSON = heads
+		}
+	}
+	return emails
+}
+
+// fetchFull fetches emails in full, at most api.ConcurrentRequests at a
+// time. Fetches go through the client's cache, so only the first full view
+// of a message costs a request. Emails that fail are missing from the
+// result and summarized in the error.
+func fetchFull(client *api.Client, ids []int) (map[int]*api.Email, error) {
+	full := make(map[int]*api.Email, len(ids))
+	if client == nil || len(ids) == 0 {
+		return full, nil
+	}
+
+	var mu sync.Mutex
+	var failed []int
+	errs := make(map[int]error)
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, api.ConcurrentRequests)
+	for _, id := range ids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(id int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			email, err := client.GetEmail(id)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed = append(failed, id)
+				errs[id] = err
+				return
+			}
+			full[id] = email
+		}(id)
+	}
+	wg.Wait()
+	if len(failed) == 0 {
+		return full, nil
+	}
+	sort.Ints(failed)
+	return full, fmt.Errorf("fetch %d of %d emails: #%d: %w", len(failed), len(ids), failed[0], errs[failed[0]])
+}
+
+// toggleThreads switches between flat and threaded list views
+func toggleThreads(m Model) (Model, tea.Cmd) {
+	m.threaded = !m.threaded
+	if !m.threaded {
+		m.threads = nil
+		selectedID := m.selectedID()
+		m.list.SetEmails(m.emails)
+		m.list.SelectID(selectedID)
+		m.status = "Flat view"
+		return m, nil
+	}
+	m.loading = true
+	m.err = nil
+	return m, tea.Batch(m.buildThreads(), m.spinner.Tick)
+}
+
+// toggleFold expands or collapses the selected conversation
+func toggleFold(m Model) (Model, tea.Cmd) {
+	if !m.threaded {
+		return m, nil
+	}
+	item, ok := m.list.SelectedItem()
+	if !ok || item.ThreadKey == "" {
+		return m, nil
+	}
+	if m.expanded == nil {
+		m.expanded = make(map[string]bool)
+	}
+	m.expanded[item.ThreadKey] = !m.expanded[item.ThreadKey]
+	m.list.SetThreads(m.threads, m.expanded)
+	m.list.SelectID(item.Email.ID)
+	return m, nil
+}
+
+// showThreads threads the loaded emails with the headers fetched so far,
+// keeping the selection
+func showThreads(m Model, msg ThreadsBuilt) (Model, tea.Cmd) {
+	if msg.Gen != m.gen {
+		// Built before a folder or profile switch.
+		return m, nil
+	}
+	m.loading = false
+	if m.threadHeads == nil {
+		m.threadHeads = make(map[int]string)
+	}
+	for id, heads := range msg.Headers {
+		m.threadHeads[id] = heads
+	}
+	if msg.Err != nil {
+		// Emails that failed are threaded by subject and retried on the
+		// next build.
+		m.err = fmt.Errorf("threads: %w", msg.Err)
+	}
+	if !m.threaded {
+		return m, nil
+	}
+	selectedID := m.selectedID()
+	m.threads = thread.Build(m.threadEmails())
+	m.list.SetThreads(m.threads, m.expanded)
+	if !m.list.SelectID(selectedID) {
+		m.list.SetIndex(0)
+	}
+	if selected := m.list.SelectedEmail(); selected != nil && (m.currentEmail == nil || m.currentEmail.ID != selected.ID) {
+		m.loading = true
+		return m, tea.Batch(fetchEmail(m.client, selected.ID), m.spinner.Tick)
+	}
+	return m, nil
+}
+
+// selectedID returns the ID of the selected email, or 0
+func (m Model) selectedID() int {
+	if selected := m.list.SelectedEmail(); selected != nil {
+		return selected.ID
+	}
+	return 0
+}
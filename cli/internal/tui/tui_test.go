@@ -1,12 +1,28 @@
 package tui
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/contacts"
+	"github.com/misty-step/mercury/cli/internal/pgp"
+	"github.com/misty-step/mercury/cli/internal/search"
+	"github.com/misty-step/mercury/cli/internal/smime"
+	"github.com/muesli/termenv"
 )
 
 // mockClient implements a minimal test client
@@ -49,7 +65,7 @@ func TestModel_Init(t *testing.T) {
 	// For now, test the fetch command directly
 
 	// Verify Init returns a fetch command
-	m := NewModel(nil)
+	m := NewModel(nil, Options{})
 	cmd := m.Init()
 	if cmd == nil {
 		t.Fatal("expected init cmd, got nil")
@@ -58,7 +74,7 @@ func TestModel_Init(t *testing.T) {
 }
 
 func TestModel_Update_WindowSize(t *testing.T) {
-	m := NewModel(nil)
+	m := NewModel(nil, Options{})
 
 	msg := tea.WindowSizeMsg{Width: 120, Height: 40}
 	updated, _ := m.Update(msg)
@@ -73,7 +89,7 @@ func TestModel_Update_WindowSize(t *testing.T) {
 }
 
 func TestModel_Update_Quit(t *testing.T) {
-	m := NewModel(nil)
+	m := NewModel(nil, Options{})
 
 	msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}}
 	_, cmd := m.Update(msg)
@@ -85,7 +101,7 @@ func TestModel_Update_Quit(t *testing.T) {
 }
 
 func TestModel_Update_Tab(t *testing.T) {
-	m := NewModel(nil)
+	m := NewModel(nil, Options{})
 
 	// Start with list focus
 	if m.focus != focusList {
@@ -111,7 +127,7 @@ func TestModel_Update_Tab(t *testing.T) {
 }
 
 func TestModel_Update_EmailsFetched(t *testing.T) {
-	m := NewModel(nil)
+	m := NewModel(nil, Options{})
 	m.loading = true
 
 	// When emails are fetched with results, loading stays true while fetching first email detail
@@ -129,7 +145,7 @@ func TestModel_Update_EmailsFetched(t *testing.T) {
 }
 
 func TestModel_Update_EmailsFetched_Empty(t *testing.T) {
-	m := NewModel(nil)
+	m := NewModel(nil, Options{})
 	m.loading = true
 
 	// When no emails, loading should be false
@@ -146,7 +162,7 @@ func TestModel_Update_EmailsFetched_Empty(t *testing.T) {
 }
 
 func TestModel_Update_ErrMsg(t *testing.T) {
-	m := NewModel(nil)
+	m := NewModel(nil, Options{})
 	m.loading = true
 
 	testErr := fmt.Errorf("test error")
@@ -161,3 +177,1671 @@ func TestModel_Update_ErrMsg(t *testing.T) {
 		t.Errorf("err = %v, want %v", model.err, testErr)
 	}
 }
+
+func TestModel_Search(t *testing.T) {
+	m := NewModel(nil, Options{})
+	updated, _ := m.Update(EmailsFetched{Emails: testEmails(), Total: 2})
+	m = updated.(Model)
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'/'}})
+	m = updated.(Model)
+	if !m.searching {
+		t.Fatal("expected search input to be active after /")
+	}
+
+	// Typed keys go to the input, not the list bindings.
+	for _, r := range "from:bob q" {
+		updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		m = updated.(Model)
+	}
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(Model)
+	if m.searching || cmd == nil {
+		t.Fatal("expected enter to close the input and run the search")
+	}
+
+	results := runSearch(nil, mustParse(t, "from:bob"), m.searchSource())().(SearchResults)
+	if len(results.Emails) != 1 || results.Emails[0].ID != 2 {
+		t.Fatalf("search results = %+v, want bob's email", results.Emails)
+	}
+
+	updated, _ = m.Update(results)
+	m = updated.(Model)
+	if m.searchQuery != "from:bob" || len(m.emails) != 1 || len(m.inbox) != 2 {
+		t.Errorf("after results: query=%q emails=%d inbox=%d", m.searchQuery, len(m.emails), len(m.inbox))
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = updated.(Model)
+	if m.searchQuery != "" || len(m.emails) != 2 {
+		t.Errorf("esc should restore inbox: query=%q emails=%d", m.searchQuery, len(m.emails))
+	}
+}
+
+func TestModel_SearchParseError(t *testing.T) {
+	m := NewModel(nil, Options{})
+	m.searching = true
+	m.searchInput.SetValue("is:maybe")
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model := updated.(Model)
+	if model.err == nil {
+		t.Error("expected parse error to be shown")
+	}
+}
+
+func mustParse(t *testing.T, input string) *search.Query {
+	t.Helper()
+	q, err := search.Parse(input)
+	if err != nil {
+		t.Fatalf("Parse(%q) error = %v", input, err)
+	}
+	return q
+}
+
+func TestModel_Threads(t *testing.T) {
+	emails := testEmails()
+	emails = append(emails, api.Email{ID: 3, Sender: "bob@example.com", Subject: "Re: Hello", ReceivedAt: emails[0].ReceivedAt, IsRead: 1})
+
+	m := NewModel(nil, Options{})
+	updated, _ := m.Update(EmailsFetched{Emails: emails, Total: 3})
+	m = updated.(Model)
+
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'t'}})
+	m = updated.(Model)
+	if !m.threaded || cmd == nil {
+		t.Fatal("expected t to switch to the threaded view")
+	}
+
+	updated, _ = m.Update(m.buildThreads()().(ThreadsBuilt))
+	m = updated.(Model)
+	if got := len(m.list.list.Items()); got != 2 {
+		t.Fatalf("collapsed items = %d, want 2", got)
+	}
+
+	var collapsed EmailItem
+	for i, item := range m.list.list.Items() {
+		if ei := item.(EmailItem); ei.ThreadSize == 2 {
+			collapsed = ei
+			m.list.SetIndex(i)
+		}
+	}
+	if collapsed.ThreadSize != 2 || collapsed.Email.IsRead != 0 {
+		t.Fatalf("collapsed thread = %+v, want 2 messages shown unread", collapsed)
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'z'}})
+	m = updated.(Model)
+	if got := len(m.list.list.Items()); got != 3 {
+		t.Fatalf("expanded items = %d, want 3", got)
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'t'}})
+	m = updated.(Model)
+	if m.threaded || len(m.list.list.Items()) != 3 {
+		t.Errorf("t again should restore the flat list")
+	}
+}
+
+func pageOfEmails(from, n int) []api.Email {
+	emails := make([]api.Email, n)
+	for i := range emails {
+		id := from - i
+		emails[i] = api.Email{ID: id, Sender: "alice@example.com", Subject: fmt.Sprintf("Message %d", id), ReceivedAt: "2026-10-01T12:00:00Z", IsRead: 1}
+	}
+	return emails
+}
+
+func TestModel_LoadMore(t *testing.T) {
+	m := NewModel(nil, Options{})
+	updated, _ := m.Update(EmailsFetched{Emails: pageOfEmails(120, 50), Total: 120})
+	m = updated.(Model)
+	if title := m.list.list.Title; title != "Inbox · loaded 50 of 120" {
+		t.Errorf("title = %q", title)
+	}
+
+	m.list.SetIndex(30)
+	if _, cmd := maybeLoadMore(m); cmd != nil {
+		t.Error("should not load more far from the end of the list")
+	}
+	m.list.SetIndex(45)
+	m, cmd := maybeLoadMore(m)
+	if cmd == nil || !m.loadingMore {
+		t.Fatal("expected the next page to load near the end of the list")
+	}
+
+	// A duplicate of an already listed email (shifted by new mail) is dropped.
+	updated, _ = m.Update(EmailsFetched{Emails: pageOfEmails(71, 50), Total: 121, Offset: 50})
+	m = updated.(Model)
+	if len(m.emails) != 99 || m.loadingMore {
+		t.Fatalf("after page: emails=%d loadingMore=%v", len(m.emails), m.loadingMore)
+	}
+	if id := m.selectedID(); id != 75 {
+		t.Errorf("selection moved to #%d, want #75", id)
+	}
+}
+
+func TestModel_RefreshKeepsSelectionByID(t *testing.T) {
+	m := NewModel(nil, Options{})
+	updated, _ := m.Update(EmailsFetched{Emails: pageOfEmails(10, 10), Total: 10})
+	m = updated.(Model)
+	m.list.SetIndex(3)
+
+	// New mail arrives at the top; the cursor stays on the same email.
+	updated, _ = m.Update(EmailsFetched{Emails: pageOfEmails(12, 12), Total: 12})
+	m = updated.(Model)
+	if id := m.selectedID(); id != 7 {
+		t.Errorf("selection = #%d, want #7", id)
+	}
+
+	// The selected email is gone; the cursor keeps its position.
+	emails := append(pageOfEmails(12, 5), pageOfEmails(6, 6)...)
+	updated, _ = m.Update(EmailsFetched{Emails: emails, Total: 11})
+	m = updated.(Model)
+	if index := m.list.Index(); index != 5 {
+		t.Errorf("index = %d, want 5", index)
+	}
+}
+
+func TestModel_MergeNewEmails(t *testing.T) {
+	m := NewModel(nil, Options{RefreshInterval: time.Minute, Notify: "none"})
+	updated, _ := m.Update(EmailsFetched{Emails: pageOfEmails(10, 10), Total: 10})
+	m = updated.(Model)
+	m.list.SetIndex(2)
+	updated, _ = m.Update(EmailFetched{Email: api.Email{ID: 8, RawEmail: "body"}})
+	m = updated.(Model)
+
+	// #10 is already listed; only #11 and #12 are new.
+	updated, cmd := m.Update(NewEmails{Emails: pageOfEmails(12, 3)})
+	m = updated.(Model)
+	if cmd == nil {
+		t.Fatal("expected the next poll to be scheduled")
+	}
+	if len(m.emails) != 12 || m.total != 12 || m.newCount != 2 {
+		t.Fatalf("emails=%d total=%d newCount=%d, want 12, 12, 2", len(m.emails), m.total, m.newCount)
+	}
+	if id := m.selectedID(); id != 8 || m.currentEmail == nil || m.currentEmail.ID != 8 {
+		t.Errorf("selection or preview moved: selected #%d", id)
+	}
+	if m.loading {
+		t.Error("merging new mail should not refetch the preview")
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m = updated.(Model)
+	if m.newCount != 0 {
+		t.Error("moving through the list should clear the new mail badge")
+	}
+}
+
+func TestModel_PollPagesSameSecondMail(t *testing.T) {
+	// 150 emails arrive in the same second as the two already listed.
+	stored := pageOfEmails(152, 152)
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		q := r.URL.Query()
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		offset, _ := strconv.Atoi(q.Get("offset"))
+		var page []api.Email
+		for _, e := range stored {
+			if e.ReceivedAt >= q.Get("since") {
+				page = append(page, e)
+			}
+		}
+		total := len(page)
+		page = page[min(offset, total):min(offset+limit, total)]
+		_ = json.NewEncoder(w).Encode(api.EmailListResponse{Emails: page, Total: total})
+	}))
+	defer server.Close()
+
+	m := NewModel(api.NewClientNoAuth(server.URL), Options{RefreshInterval: time.Minute, Notify: "none"})
+	updated, _ := m.Update(EmailsFetched{Emails: pageOfEmails(2, 2), Total: 2})
+	m = updated.(Model)
+	updated, _ = m.Update(m.pollEmails(newestReceivedAt(m.emails))())
+	m = updated.(Model)
+	if requests != 2 || len(m.emails) != 152 || m.newCount != 150 {
+		t.Errorf("requests=%d emails=%d new=%d, want 2, 152, 150", requests, len(m.emails), m.newCount)
+	}
+}
+
+func TestModel_PollBackoff(t *testing.T) {
+	m := NewModel(nil, Options{RefreshInterval: time.Minute})
+	for i := 0; i < 3; i++ {
+		updated, _ := m.Update(PollFailed{Err: fmt.Errorf("boom")})
+		m = updated.(Model)
+	}
+	if got := m.pollDelay(); got != 8*time.Minute {
+		t.Errorf("delay after 3 failures = %v, want 8m", got)
+	}
+	for i := 0; i < 5; i++ {
+		updated, _ := m.Update(PollFailed{Err: fmt.Errorf("boom")})
+		m = updated.(Model)
+	}
+	if got := m.pollDelay(); got != maxPollBackoff {
+		t.Errorf("delay = %v, want cap %v", got, maxPollBackoff)
+	}
+
+	updated, _ := m.Update(NewEmails{})
+	m = updated.(Model)
+	if got := m.pollDelay(); got != time.Minute {
+		t.Errorf("delay after success = %v, want 1m", got)
+	}
+
+	if _, cmd := m.Update(PollFailed{Err: api.ErrOffline}); cmd != nil {
+		t.Error("offline clients should stop polling")
+	}
+}
+
+func TestModel_PollPausedWhileComposing(t *testing.T) {
+	m := NewModel(nil, Options{RefreshInterval: time.Minute})
+	m.compose = &ComposeState{TmpFile: "/tmp/draft"}
+	_, cmd := m.Update(PollTick{})
+	if cmd == nil {
+		t.Fatal("expected the poll to be rescheduled")
+	}
+}
+
+func TestModel_ThreadsFetchHeadersOnce(t *testing.T) {
+	receivedAt := time.Now().UTC().Format(time.RFC3339)
+	emails := []api.Email{
+		{ID: 1, Subject: "Budget", ReceivedAt: receivedAt},
+		{ID: 2, Subject: "Lunch", ReceivedAt: receivedAt},
+		// A retitled reply to #1: only its References place it.
+		{ID: 3, Subject: "Numbers", ReceivedAt: receivedAt},
+		{ID: 4, Subject: "Offsite", ReceivedAt: receivedAt},
+	}
+	raw := map[int]string{
+		1: "Message-ID: <a@x>\r\n\r\nbody",
+		2: "Message-ID: <b@x>\r\n\r\nbody",
+		3: "Message-ID: <c@x>\r\nReferences: <a@x>\r\n\r\nbody",
+	}
+	var mu sync.Mutex
+	fetches := map[int]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/emails/"))
+		mu.Lock()
+		fetches[id]++
+		mu.Unlock()
+		if raw[id] == "" {
+			http.Error(w, `{"error":"boom"}`, http.StatusInternalServerError)
+			return
+		}
+		email := emails[id-1]
+		email.RawEmail = raw[id]
+		_ = json.NewEncoder(w).Encode(api.EmailResponse{Email: email})
+	}))
+	defer server.Close()
+
+	m := NewModel(api.NewClientNoAuth(server.URL), Options{})
+	updated, _ := m.Update(EmailsFetched{Emails: emails, Total: 4})
+	m = updated.(Model)
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'t'}})
+	m = updated.(Model)
+	updated, _ = m.Update(m.buildThreads()())
+	m = updated.(Model)
+	if len(m.threads) != 3 {
+		t.Errorf("threads = %d, want #1 and #3 together", len(m.threads))
+	}
+	if m.err == nil || !strings.Contains(m.err.Error(), "#4") {
+		t.Errorf("failed fetch not reported: %v", m.err)
+	}
+
+	// Later rebuilds reuse the headers held and retry only the failure.
+	updated, _ = m.Update(m.buildThreads()())
+	m = updated.(Model)
+	if fetches[1] != 1 || fetches[3] != 1 || fetches[4] != 2 || len(m.threads) != 3 {
+		t.Errorf("fetches = %v, threads = %d", fetches, len(m.threads))
+	}
+}
+
+func TestModel_StaleThreadsDropped(t *testing.T) {
+	m := NewModel(nil, Options{})
+	updated, _ := m.Update(EmailsFetched{Emails: testEmails(), Total: 2})
+	m = updated.(Model)
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'t'}})
+	m = updated.(Model)
+	built := m.buildThreads()().(ThreadsBuilt)
+
+	m, _ = switchFolder(m, "archive")
+	updated, _ = m.Update(EmailsFetched{Emails: []api.Email{{ID: 40, Folder: "archive"}}, Total: 1, Gen: m.gen})
+	m = updated.(Model)
+	updated, _ = m.Update(built)
+	m = updated.(Model)
+	if m.threads != nil || m.list.Len() != 0 {
+		t.Errorf("inbox threads shown after switching to the archive: %d rows", m.list.Len())
+	}
+}
+
+func TestModel_UndoDelete(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	m := NewModel(nil, Options{})
+	m.width = 80
+	updated, _ := m.Update(EmailsFetched{Emails: pageOfEmails(3, 3), Total: 3})
+	m = updated.(Model)
+	m.list.SetIndex(1)
+
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}})
+	m = updated.(Model)
+	if cmd == nil || len(m.emails) != 2 || len(m.pending) != 1 {
+		t.Fatalf("after d: emails=%d pending=%d", len(m.emails), len(m.pending))
+	}
+	if got := m.undoStatus(); got != "Deleted — u to undo (5s)" {
+		t.Errorf("undoStatus() = %q", got)
+	}
+
+	// A refresh inside the window must not bring the row back.
+	updated, _ = m.Update(EmailsFetched{Emails: pageOfEmails(3, 3), Total: 3})
+	m = updated.(Model)
+	if len(m.emails) != 2 || m.total != 2 {
+		t.Errorf("refresh showed pending delete: emails=%d total=%d", len(m.emails), m.total)
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'u'}})
+	m = updated.(Model)
+	if len(m.emails) != 3 || len(m.pending) != 0 || m.selectedID() != 2 {
+		t.Fatalf("after undo: emails=%d pending=%d selected=#%d", len(m.emails), len(m.pending), m.selectedID())
+	}
+}
+
+func TestModel_ArchiveCommitsAfterWindow(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	m := NewModel(nil, Options{})
+	updated, _ := m.Update(EmailsFetched{Emails: pageOfEmails(3, 3), Total: 3})
+	m = updated.(Model)
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}})
+	m = updated.(Model)
+	if !strings.HasPrefix(m.undoStatus(), "Archived") {
+		t.Errorf("undoStatus() = %q", m.undoStatus())
+	}
+
+	now = now.Add(3 * time.Second)
+	updated, _ = m.Update(UndoTick{})
+	m = updated.(Model)
+	if len(m.pending) != 1 {
+		t.Fatal("action committed before the undo window closed")
+	}
+
+	now = now.Add(3 * time.Second)
+	updated, cmd := m.Update(UndoTick{})
+	m = updated.(Model)
+	if len(m.pending) != 0 || cmd == nil || m.undoTicking || len(m.jobs) != 1 {
+		t.Fatalf("expired action not committed: pending=%d jobs=%d", len(m.pending), len(m.jobs))
+	}
+
+	// A failed commit puts the email back.
+	job := m.jobs[0]
+	updated, _ = m.Update(BulkProgress{Job: job, Result: bulkResult{Email: job.Emails[0], Err: fmt.Errorf("boom")}})
+	m = updated.(Model)
+	if len(m.emails) != 3 || m.err == nil || len(m.jobs) != 0 {
+		t.Errorf("after failure: emails=%d err=%v jobs=%d", len(m.emails), m.err, len(m.jobs))
+	}
+}
+
+func TestModel_QuitReportsFailedCommit(t *testing.T) {
+	m := NewModel(nil, Options{})
+	updated, _ := m.Update(EmailsFetched{Emails: pageOfEmails(3, 3), Total: 3})
+	m = updated.(Model)
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}})
+	m = updated.(Model)
+	deleted := m.pending[0].Emails
+
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}})
+	m = updated.(Model)
+	if cmd == nil || !m.quitting || len(m.pending) != 0 {
+		t.Fatalf("q with a pending delete: quitting=%v pending=%d", m.quitting, len(m.pending))
+	}
+
+	// The delete failed: stay open, report it and put the email back.
+	job := newBulkJob(nil, bulkDelete, "", deleted)
+	job.Gen = m.gen
+	job.Failed = []bulkResult{{Email: deleted[0], Err: fmt.Errorf("boom")}}
+	updated, _ = m.Update(PendingCommitted{Jobs: []*bulkJob{job}})
+	m = updated.(Model)
+	if m.quitting || m.err == nil || !strings.Contains(m.err.Error(), "q to quit anyway") || len(m.emails) != 3 {
+		t.Fatalf("after failed commit: quitting=%v err=%v emails=%d", m.quitting, m.err, len(m.emails))
+	}
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}}); cmd == nil || cmd() != tea.Quit() {
+		t.Error("q with nothing pending should quit")
+	}
+
+	m.quitting = true
+	job.Failed = nil
+	if _, cmd := m.Update(PendingCommitted{Jobs: []*bulkJob{job}}); cmd == nil || cmd() != tea.Quit() {
+		t.Error("a clean commit should finish the quit")
+	}
+}
+
+func TestModel_FailedCommitAfterFolderSwitch(t *testing.T) {
+	m := NewModel(nil, Options{})
+	updated, _ := m.Update(EmailsFetched{Emails: pageOfEmails(3, 3), Total: 3})
+	m = updated.(Model)
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}})
+	m = updated.(Model)
+	deleted := m.pending[0].Emails
+	gen := m.gen
+
+	m, _ = switchFolder(m, "archive")
+	updated, _ = m.Update(EmailsFetched{Emails: []api.Email{{ID: 40, Folder: "archive"}}, Total: 1, Gen: m.gen})
+	m = updated.(Model)
+
+	// The inbox delete fails after the archive is listed; it must not show
+	// up there, but the failure is still reported.
+	job := newBulkJob(nil, bulkDelete, "", deleted)
+	job.Gen = gen
+	job.Failed = []bulkResult{{Email: deleted[0], Err: fmt.Errorf("boom")}}
+	updated, _ = m.Update(PendingCommitted{Jobs: []*bulkJob{job}})
+	m = updated.(Model)
+	if len(m.emails) != 1 || m.emails[0].ID != 40 {
+		t.Errorf("inbox email restored into the archive: %+v", m.emails)
+	}
+	if !strings.Contains(m.status, "boom") {
+		t.Errorf("status = %q, want the failure", m.status)
+	}
+
+	// Likewise for a bulk job still running when the folder changed.
+	updated, _ = m.Update(BulkProgress{Job: job, Result: bulkResult{Email: deleted[0], Err: fmt.Errorf("boom")}})
+	m = updated.(Model)
+	if len(m.emails) != 1 {
+		t.Errorf("bulk failure restored into another folder: %+v", m.emails)
+	}
+}
+
+func TestListModel_Marks(t *testing.T) {
+	l := NewListModel(40, 20)
+	l.SetEmails(pageOfEmails(6, 6))
+
+	l.SetIndex(1)
+	l.ToggleMark()
+	l.SetIndex(3)
+	l.MarkRange()
+	if got := l.MarkedCount(); got != 3 {
+		t.Fatalf("after range: marked = %d, want 3", got)
+	}
+	if marked := l.Marked(); marked[0].ID != 5 || marked[2].ID != 3 {
+		t.Errorf("Marked() = %+v, want #5..#3 newest first", marked)
+	}
+	if item, _ := l.SelectedItem(); !item.Marked || !strings.HasPrefix(item.Title(), "✓") {
+		t.Errorf("selected row not shown as marked: %q", item.Title())
+	}
+
+	l.MarkAll()
+	if got := l.MarkedCount(); got != 6 {
+		t.Errorf("after mark all: marked = %d, want 6", got)
+	}
+	l.MarkAll()
+	if got := l.MarkedCount(); got != 0 {
+		t.Errorf("mark all twice should clear, marked = %d", got)
+	}
+
+	// Marks survive a refresh but not the disappearance of the email.
+	l.SetIndex(0)
+	l.ToggleMark()
+	l.SetIndex(1)
+	l.ToggleMark()
+	l.SetEmails(pageOfEmails(5, 5))
+	if got := l.MarkedCount(); got != 1 {
+		t.Errorf("after refresh: marked = %d, want 1", got)
+	}
+}
+
+func TestModel_BulkStar(t *testing.T) {
+	var mu sync.Mutex
+	starred := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/4") {
+			http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+			return
+		}
+		mu.Lock()
+		starred[r.URL.Path] = true
+		mu.Unlock()
+		fmt.Fprint(w, `{"success":true}`)
+	}))
+	defer server.Close()
+
+	m := NewModel(api.NewClientNoAuth(server.URL), Options{})
+	updated, _ := m.Update(EmailsFetched{Emails: pageOfEmails(6, 6), Total: 6})
+	m = updated.(Model)
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'*'}})
+	m = updated.(Model)
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'s'}})
+	m = updated.(Model)
+	if len(m.jobs) != 1 || m.list.MarkedCount() != 0 {
+		t.Fatalf("jobs=%d marked=%d, want 1 job and marks cleared", len(m.jobs), m.list.MarkedCount())
+	}
+	job := m.jobs[0]
+	for len(m.jobs) > 0 {
+		updated, _ = m.Update(job.run()())
+		m = updated.(Model)
+	}
+
+	if len(starred) != 5 {
+		t.Errorf("starred %d emails on the server, want 5", len(starred))
+	}
+	for _, email := range m.emails {
+		if want := email.ID != 4; (email.IsStarred == 1) != want {
+			t.Errorf("#%d starred = %d", email.ID, email.IsStarred)
+		}
+	}
+	if m.err == nil || !strings.Contains(m.err.Error(), "1 of 6: #4:") {
+		t.Errorf("error summary = %v", m.err)
+	}
+}
+
+func TestNewKeyMap(t *testing.T) {
+	k, err := NewKeyMap(map[string][]string{"up": {"ctrl+p", "up"}, "down": {"ctrl+n", "down"}, "mark": {"space", "x"}})
+	if err != nil {
+		t.Fatalf("NewKeyMap() error = %v", err)
+	}
+	if got := k.Up.Help().Key; got != "ctrl+p/↑" {
+		t.Errorf("up help = %q", got)
+	}
+	if got := k.Mark.Keys(); len(got) != 2 || got[0] != " " {
+		t.Errorf("mark keys = %q", got)
+	}
+	// Reader keys may shadow the list's.
+	if _, err := NewKeyMap(map[string][]string{"next_message": {"j"}}); err != nil {
+		t.Errorf("reader key shadowing down: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		overrides map[string][]string
+		want      string
+	}{
+		{"unknown action", map[string][]string{"explode": {"x"}}, `unknown key action "explode"`},
+		{"empty", map[string][]string{"quit": {}}, "no keys given"},
+		{"duplicate", map[string][]string{"quit": {"q", "q"}}, "q listed twice"},
+		{"conflict with default", map[string][]string{"delete": {"r"}}, "r is bound to both refresh and delete"},
+		{"conflict between overrides", map[string][]string{"star": {"ctrl+s"}, "move": {"ctrl+s"}}, "ctrl+s is bound to both star and move"},
+		{"conflict between reader keys", map[string][]string{"next_message": {"N"}}, "N is bound to both next_message and next_unread"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyMap(tt.overrides)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewKeyMap() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestModel_CustomKeys(t *testing.T) {
+	k, err := NewKeyMap(map[string][]string{"down": {"ctrl+n"}, "help": {"f1"}})
+	if err != nil {
+		t.Fatalf("NewKeyMap() error = %v", err)
+	}
+	m := NewModel(nil, Options{Keys: &k})
+	m.width, m.height = 100, 30
+	updated, _ := m.Update(EmailsFetched{Emails: pageOfEmails(3, 3), Total: 3})
+	m = updated.(Model)
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlN})
+	m = updated.(Model)
+	if m.list.Index() != 1 {
+		t.Errorf("ctrl+n should move down, index = %d", m.list.Index())
+	}
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	m = updated.(Model)
+	if m.list.Index() != 1 {
+		t.Errorf("j is no longer bound, index = %d", m.list.Index())
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyF1})
+	m = updated.(Model)
+	if !m.showHelp || !strings.Contains(m.View(), "ctrl+n") {
+		t.Fatal("f1 should open the help overlay listing active bindings")
+	}
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}})
+	m = updated.(Model)
+	if m.showHelp || len(m.pending) != 0 {
+		t.Error("a key should close the overlay without acting")
+	}
+}
+
+func TestModel_FreedKeyDoesNotPage(t *testing.T) {
+	k, err := NewKeyMap(map[string][]string{"delete": {"ctrl+d"}})
+	if err != nil {
+		t.Fatalf("NewKeyMap() error = %v", err)
+	}
+	m := NewModel(nil, Options{Keys: &k})
+	m.width, m.height = 100, 30
+	updated, _ := m.Update(EmailsFetched{Emails: pageOfEmails(60, 60), Total: 60})
+	m = updated.(Model)
+	if m.list.list.Paginator.TotalPages < 2 {
+		t.Fatalf("want several pages, got %d", m.list.list.Paginator.TotalPages)
+	}
+
+	// "d" no longer deletes and must not fall through to the list's paging
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}})
+	m = updated.(Model)
+	if m.list.list.Paginator.Page != 0 || m.list.Index() != 0 || len(m.pending) != 0 {
+		t.Errorf("d acted after rebinding delete: page %d, index %d", m.list.list.Paginator.Page, m.list.Index())
+	}
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyPgDown})
+	m = updated.(Model)
+	if m.list.list.Paginator.Page != 1 {
+		t.Errorf("pgdown should still page, page = %d", m.list.list.Paginator.Page)
+	}
+}
+
+func TestEmailDelegate_RowStyles(t *testing.T) {
+	original := lipgloss.ColorProfile()
+	lipgloss.SetColorProfile(termenv.ANSI256)
+	defer lipgloss.SetColorProfile(original)
+
+	theme, _ := LookupTheme("dark")
+	l := NewListModel(60, 20)
+	l.SetTheme(theme)
+	l.SetEmails([]api.Email{
+		{ID: 1, Sender: "a@example.com", IsRead: 1},
+		{ID: 2, Sender: "b@example.com", IsRead: 0},
+		{ID: 3, Sender: "c@example.com", IsRead: 1, IsStarred: 1},
+	})
+
+	d := newEmailDelegate(theme)
+	render := func(index int) string {
+		var sb strings.Builder
+		d.Render(&sb, l.list, index, l.list.Items()[index])
+		return sb.String()
+	}
+
+	l.SetIndex(2)
+	if read := render(0); strings.Contains(read, "\x1b[1;") {
+		t.Errorf("read row should not be bold: %q", read)
+	}
+	if unread := render(1); !strings.Contains(unread, "\x1b[1;38;5;255m") {
+		t.Errorf("unread row should be bold in the unread color: %q", unread)
+	}
+	if starred := render(2); !strings.Contains(starred, "38;5;170") || !strings.Contains(starred, "★") {
+		t.Errorf("selected starred row should use the cursor color and show ★: %q", starred)
+	}
+	l.SetIndex(0)
+	if starred := render(2); !strings.Contains(starred, "38;5;214") {
+		t.Errorf("starred row should use the starred color: %q", starred)
+	}
+}
+
+func TestEmailDelegate_NoColorRows(t *testing.T) {
+	original := lipgloss.ColorProfile()
+	lipgloss.SetColorProfile(termenv.Ascii)
+	defer lipgloss.SetColorProfile(original)
+
+	theme, _ := LookupTheme("dark")
+	l := NewListModel(60, 20)
+	l.SetTheme(theme)
+	l.SetEmails([]api.Email{
+		{ID: 1, Sender: "a@example.com", IsRead: 1},
+		{ID: 2, Sender: "b@example.com", IsRead: 0},
+		{ID: 3, Sender: "c@example.com", IsRead: 1, IsStarred: 1},
+		{ID: 4, Sender: "d@example.com", IsRead: 1},
+	})
+	l.SetIndex(3)
+	l.ToggleMark()
+	l.SetIndex(0)
+
+	d := newEmailDelegate(theme)
+	row := func(index int) string {
+		var sb strings.Builder
+		d.Render(&sb, l.list, index, l.list.Items()[index])
+		line, _, _ := strings.Cut(sb.String(), "\n")
+		return line
+	}
+	for i, want := range []string{"│   [  1]", "  * [  2]", "    [  3] c@example.com ★", "  ✓   [  4]"} {
+		got := row(i)
+		if strings.Contains(got, "\x1b") || !strings.HasPrefix(got, want) {
+			t.Errorf("row %d = %q, want plain text starting %q", i, got, want)
+		}
+	}
+}
+
+func TestThemeWith(t *testing.T) {
+	theme, err := LookupTheme("high-contrast")
+	if err != nil {
+		t.Fatalf("LookupTheme() error = %v", err)
+	}
+	if theme, err = theme.With(map[string]string{"accent": "#ff8800", "error": "196"}); err != nil || theme.Accent != "#ff8800" {
+		t.Fatalf("With() = %+v, %v", theme, err)
+	}
+	if _, err := theme.With(map[string]string{"accent": "orange"}); err == nil {
+		t.Error("invalid color should fail")
+	}
+	if _, err := theme.With(map[string]string{"sparkle": "1"}); err == nil {
+		t.Error("unknown role should fail")
+	}
+	if _, err := LookupTheme("neon"); err == nil {
+		t.Error("unknown theme should fail")
+	}
+}
+
+// typeCommand opens the command line and types line into it
+func typeCommand(m Model, line string) Model {
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{':'}})
+	m = updated.(Model)
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(line)})
+	return updated.(Model)
+}
+
+func TestModel_CommandCompletion(t *testing.T) {
+	m := NewModel(nil, Options{Profiles: []string{"personal", "work"}})
+	m = typeCommand(m, "fo")
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m = updated.(Model)
+	if got := m.commandInput.Value(); got != "folder " {
+		t.Fatalf("completed %q, want %q", got, "folder ")
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	m = updated.(Model)
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m = updated.(Model)
+	if got := m.commandInput.Value(); got != "folder archive " {
+		t.Errorf("completed %q", got)
+	}
+
+	m.commandInput.SetValue("s")
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m = updated.(Model)
+	if got := strings.Join(m.completions, " "); got != "search set sort" {
+		t.Errorf("candidates = %q", got)
+	}
+
+	m.commandInput.SetValue("profile w")
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m = updated.(Model)
+	if got := m.commandInput.Value(); got != "profile work " {
+		t.Errorf("completed %q", got)
+	}
+}
+
+func TestModel_CommandFolder(t *testing.T) {
+	var folders []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		folders = append(folders, r.URL.Query().Get("folder"))
+		fmt.Fprint(w, `{"emails":[{"id":9,"subject":"Gone"}],"total":1}`)
+	}))
+	defer server.Close()
+
+	m := NewModel(api.NewClientNoAuth(server.URL), Options{})
+	updated, _ := m.Update(EmailsFetched{Emails: pageOfEmails(3, 3), Total: 3})
+	m = updated.(Model)
+	stale := m.fetchEmails(pageSize, 0)
+
+	m = typeCommand(m, "folder trash")
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(Model)
+	if m.folder != "trash" || len(m.emails) != 0 || m.list.list.Title != "Trash" {
+		t.Fatalf("folder=%q emails=%d title=%q", m.folder, len(m.emails), m.list.list.Title)
+	}
+	if cmd == nil {
+		t.Fatal("switching folders should fetch")
+	}
+
+	// A fetch started before the switch is dropped.
+	updated, _ = m.Update(stale())
+	m = updated.(Model)
+	if len(m.emails) != 0 {
+		t.Errorf("stale fetch listed %d emails", len(m.emails))
+	}
+	updated, _ = m.Update(m.fetchEmails(pageSize, 0)())
+	m = updated.(Model)
+	if len(m.emails) != 1 || folders[len(folders)-1] != "trash" {
+		t.Errorf("emails=%d folders=%q", len(m.emails), folders)
+	}
+}
+
+func TestModel_CommandErrors(t *testing.T) {
+	m := NewModel(nil, Options{})
+	for line, want := range map[string]string{
+		"move nowhere":       `unknown folder "nowhere"`,
+		"frobnicate":         `unknown command "frobnicate"`,
+		"profile work":       "profile switching is not available",
+		"set preview.wrap x": "not a column count",
+	} {
+		m := typeCommand(m, line)
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		m = updated.(Model)
+		if m.err == nil || !strings.Contains(m.err.Error(), want) {
+			t.Errorf(":%s error = %v, want %q", line, m.err, want)
+		}
+	}
+}
+
+func TestModel_CommandSortAndExport(t *testing.T) {
+	m := NewModel(nil, Options{})
+	emails := pageOfEmails(3, 3)
+	emails[0].Sender = "Carol <carol@example.com>"
+	emails[1].Sender = "Bob <bob@example.com>"
+	updated, _ := m.Update(EmailsFetched{Emails: emails, Total: 3})
+	m = updated.(Model)
+
+	m = typeCommand(m, "sort sender")
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(Model)
+	var order []int
+	for _, item := range m.list.list.Items() {
+		order = append(order, item.(EmailItem).Email.ID)
+	}
+	if fmt.Sprint(order) != "[1 2 3]" {
+		t.Errorf("sorted by sender = %v, want [1 2 3]", order)
+	}
+
+	path := t.TempDir() + "/out.mbox"
+	m = typeCommand(m, "export mbox "+path)
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(Model)
+	if cmd == nil {
+		t.Fatal("export should start writing")
+	}
+	var msg tea.Msg
+	for _, c := range cmd().(tea.BatchMsg) {
+		if msg = c(); msg != nil {
+			if _, ok := msg.(Exported); ok {
+				break
+			}
+		}
+	}
+	updated, _ = m.Update(msg)
+	m = updated.(Model)
+	if m.status != "Exported 3 emails to "+path {
+		t.Errorf("status = %q, err = %v", m.status, m.err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || strings.Count(string(data), "\nFrom ") != 2 {
+		t.Errorf("mbox = %q, %v", data, err)
+	}
+}
+
+func TestModel_ExportFailedFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/2") {
+			http.Error(w, `{"error":"boom"}`, http.StatusInternalServerError)
+			return
+		}
+		id, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/emails/"))
+		_ = json.NewEncoder(w).Encode(api.EmailResponse{Email: api.Email{ID: id, RawEmail: "Subject: hi\r\n\r\nbody"}})
+	}))
+	defer server.Close()
+
+	m := NewModel(api.NewClientNoAuth(server.URL), Options{})
+	updated, _ := m.Update(EmailsFetched{Emails: pageOfEmails(3, 3), Total: 3})
+	m = updated.(Model)
+	path := t.TempDir() + "/out.mbox"
+	m = typeCommand(m, "export mbox "+path)
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(Model)
+	var msg tea.Msg
+	for _, c := range cmd().(tea.BatchMsg) {
+		if msg = c(); msg != nil {
+			if _, ok := msg.(ErrMsg); ok {
+				break
+			}
+		}
+	}
+	updated, _ = m.Update(msg)
+	m = updated.(Model)
+	if m.err == nil || !strings.Contains(m.err.Error(), "#2") || strings.HasPrefix(m.status, "Exported") {
+		t.Errorf("err = %v, status = %q", m.err, m.status)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("partial mbox written: %v", err)
+	}
+}
+
+func TestSortEmails(t *testing.T) {
+	emails := pageOfEmails(4, 4)
+	emails[1].IsRead = 0
+	emails[3].IsRead = 0
+	emails[2].IsStarred = 1
+	ids := func(emails []api.Email) string {
+		var out []int
+		for _, e := range emails {
+			out = append(out, e.ID)
+		}
+		return fmt.Sprint(out)
+	}
+	if got := ids(sortEmails(emails, "unread-first")); got != "[3 1 4 2]" {
+		t.Errorf("unread-first = %s", got)
+	}
+	if got := ids(sortEmails(emails, "starred-first")); got != "[2 4 3 1]" {
+		t.Errorf("starred-first = %s", got)
+	}
+	if got := ids(emails); got != "[4 3 2 1]" {
+		t.Errorf("sorting changed the input: %s", got)
+	}
+}
+
+func TestGroupEmails(t *testing.T) {
+	// Thursday afternoon, local time.
+	now := time.Date(2026, 10, 15, 15, 0, 0, 0, time.Local)
+	at := func(days int) string {
+		return now.AddDate(0, 0, -days).UTC().Format("2006-01-02 15:04:05")
+	}
+	emails := []api.Email{
+		{ID: 1, Sender: "a@x.org", ReceivedAt: at(0)},
+		{ID: 2, Sender: "Bob <b@Example.com>", ReceivedAt: at(1)},
+		{ID: 3, Sender: "c@x.org", ReceivedAt: at(3)},
+		{ID: 4, Sender: "d@example.com", ReceivedAt: at(4)},
+		{ID: 5, Sender: "e@x.org", ReceivedAt: at(0)},
+	}
+	describe := func(groups []emailGroup) string {
+		var parts []string
+		for _, g := range groups {
+			var ids []int
+			for _, e := range g.Emails {
+				ids = append(ids, e.ID)
+			}
+			parts = append(parts, fmt.Sprintf("%s%v", g.Label, ids))
+		}
+		return strings.Join(parts, " ")
+	}
+	if got := describe(groupEmails(emails, "date", now)); got != "Today[1 5] Yesterday[2] This week[3] Older[4]" {
+		t.Errorf("by date = %s", got)
+	}
+	if got := describe(groupEmails(emails, "domain", now)); got != "example.com[2 4] x.org[1 3 5]" {
+		t.Errorf("by domain = %s", got)
+	}
+}
+
+func TestListModel_GroupHeaders(t *testing.T) {
+	timeNow = func() time.Time { return time.Date(2026, 10, 15, 15, 0, 0, 0, time.UTC) }
+	defer func() { timeNow = time.Now }()
+
+	l := NewListModel(80, 40)
+	l.SetGroup("domain")
+	emails := pageOfEmails(3, 3)
+	emails[1].Sender = "bob@mercury.dev"
+	l.SetEmails(emails)
+	if l.Len() != 5 {
+		t.Fatalf("rows = %d, want 3 emails and 2 headers", l.Len())
+	}
+	if got := l.SelectedEmail(); got == nil || got.ID != 3 {
+		t.Fatalf("selected %v, want #3 below the first header", got)
+	}
+
+	down := tea.KeyMsg{Type: tea.KeyDown}
+	up := tea.KeyMsg{Type: tea.KeyUp}
+	l, _ = l.Update(down)
+	l, _ = l.Update(down)
+	if got := l.SelectedEmail(); got == nil || got.ID != 2 {
+		t.Errorf("after two downs selected %v, want #2 past the mercury.dev header", got)
+	}
+	l, _ = l.Update(up)
+	l, _ = l.Update(up)
+	l, _ = l.Update(up)
+	if got := l.SelectedEmail(); got == nil || got.ID != 3 || l.Index() != 1 {
+		t.Errorf("at the top selected %v at row %d, want #3 at row 1", got, l.Index())
+	}
+	l.MarkAll()
+	if l.MarkedCount() != 3 {
+		t.Errorf("marked %d, want every email and no headers", l.MarkedCount())
+	}
+}
+
+func TestModel_ViewPersistsPerFolder(t *testing.T) {
+	path := t.TempDir() + "/tui.json"
+	state, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+	m := NewModel(nil, Options{State: state})
+	m = typeCommand(m, "sort unread-first")
+	updated, save := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(Model)
+	if save == nil {
+		t.Fatal("changing the sort should save state")
+	}
+	if msg := save(); msg != nil {
+		t.Fatalf("save: %v", msg)
+	}
+
+	reloaded, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+	if got := reloaded.view("inbox").Sort; got != "unread-first" {
+		t.Errorf("saved inbox sort = %q", got)
+	}
+	if got := reloaded.view("archive").Sort; got != "date" {
+		t.Errorf("archive sort = %q, want the default", got)
+	}
+	m = NewModel(nil, Options{State: reloaded})
+	if m.list.sort != "unread-first" {
+		t.Errorf("restored sort = %q", m.list.sort)
+	}
+	updated, _ = m.Update(ProfileSwitched{})
+	m, _ = switchFolder(updated.(Model), "archive")
+	if m.list.sort != "date" {
+		t.Errorf("archive sort = %q", m.list.sort)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := LoadState(path); err == nil || s == nil {
+		t.Errorf("corrupt state: %v, %v", s, err)
+	}
+}
+
+func TestModel_Reader(t *testing.T) {
+	m := NewModel(nil, Options{ReaderWidth: 30})
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 120, Height: 12})
+	m = updated.(Model)
+	emails := pageOfEmails(4, 4)
+	emails[2].IsRead = 0
+	updated, _ = m.Update(EmailsFetched{Emails: emails, Total: 4})
+	m = updated.(Model)
+	open := emails[0]
+	open.RawEmail = "Subject: Long\r\n\r\n" + strings.Repeat("word ", 40) + "needle\n" + strings.Repeat("line\n", 30) + "last Needle"
+	updated, _ = m.Update(EmailFetched{Email: open})
+	m = updated.(Model)
+
+	press := func(keys string) {
+		t.Helper()
+		msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(keys)}
+		switch keys {
+		case " ":
+			msg = tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")}
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "esc":
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		}
+		updated, _ := m.Update(msg)
+		m = updated.(Model)
+	}
+
+	press("f")
+	if !m.reading {
+		t.Fatal("f should open the reader")
+	}
+	for _, line := range strings.Split(m.preview.viewport.View(), "\n") {
+		if w := lipgloss.Width(strings.TrimRight(line, " ")); w > 32 {
+			t.Fatalf("line %q is %d wide, want wrapped at 30", line, w)
+		}
+	}
+
+	press("/")
+	for _, r := range "needle" {
+		press(string(r))
+	}
+	press("enter")
+	if m.status != "Match 1 of 2" {
+		t.Errorf("after find: status = %q", m.status)
+	}
+	press("/")
+	press("enter")
+	if m.status != "Match 2 of 2" {
+		t.Errorf("after repeat: status = %q", m.status)
+	}
+	press("esc")
+	if m.preview.Finding() != "" || !m.reading {
+		t.Errorf("esc should clear the search first: finding=%q reading=%v", m.preview.Finding(), m.reading)
+	}
+
+	press("N")
+	if got := m.list.SelectedEmail().ID; got != 2 {
+		t.Errorf("N selected #%d, want unread #2", got)
+	}
+	press("p")
+	if got := m.list.SelectedEmail().ID; got != 3 {
+		t.Errorf("p selected #%d, want #3", got)
+	}
+	press("n")
+	press("n")
+	press("n")
+	if got := m.list.SelectedEmail().ID; got != 1 || m.status != "No more messages" {
+		t.Errorf("past the end: #%d, status %q", got, m.status)
+	}
+
+	// Space pages through a long message, then moves on.
+	m.list.SetIndex(0)
+	updated, _ = m.Update(EmailFetched{Email: open})
+	m = updated.(Model)
+	for i := 0; i < 20 && m.list.SelectedEmail().ID == 4; i++ {
+		press(" ")
+	}
+	if got := m.list.SelectedEmail().ID; got != 3 {
+		t.Errorf("space advanced to #%d, want #3", got)
+	}
+
+	press("esc")
+	if m.reading {
+		t.Error("esc should close the reader")
+	}
+}
+
+func TestModel_Layout(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		want          layoutMode
+		panels        int
+	}{
+		{"wide", 120, 30, layoutSideBySide, 2},
+		{"tall", 80, 40, layoutStacked, 2},
+		{"narrow", 60, 20, layoutSingle, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewModel(nil, Options{})
+			updated, _ := m.Update(tea.WindowSizeMsg{Width: tt.width, Height: tt.height})
+			m = updated.(Model)
+			updated, _ = m.Update(EmailsFetched{Emails: pageOfEmails(5, 5), Total: 5})
+			m = updated.(Model)
+
+			if mode, _, _ := m.panes(); mode != tt.want {
+				t.Errorf("layout = %v, want %v", mode, tt.want)
+			}
+			view := m.View()
+			if w, h := lipgloss.Size(view); w != tt.width || h != tt.height {
+				t.Errorf("view is %dx%d, want %dx%d", w, h, tt.width, tt.height)
+			}
+			if got := strings.Count(view, "╭") + strings.Count(view, "┏"); got != tt.panels {
+				t.Errorf("%d panels shown, want %d", got, tt.panels)
+			}
+		})
+	}
+}
+
+func TestModel_SinglePaneNavigation(t *testing.T) {
+	m := NewModel(nil, Options{})
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 60, Height: 20})
+	m = updated.(Model)
+	updated, _ = m.Update(EmailsFetched{Emails: pageOfEmails(3, 3), Total: 3})
+	m = updated.(Model)
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(Model)
+	if m.focus != focusPreview {
+		t.Fatal("enter should show the preview")
+	}
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = updated.(Model)
+	if m.focus != focusList {
+		t.Error("esc should go back to the list")
+	}
+}
+
+func TestModel_ResizeSplit(t *testing.T) {
+	path := t.TempDir() + "/tui.json"
+	state, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+	m := NewModel(nil, Options{State: state})
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 120, Height: 30})
+	m = updated.(Model)
+	_, before, _ := m.panes()
+
+	var save tea.Cmd
+	for i := 0; i < 20; i++ {
+		updated, save = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(">")})
+		m = updated.(Model)
+	}
+	_, after, _ := m.panes()
+	if after.width <= before.width || m.split() != maxSplit {
+		t.Errorf("list grew from %d to %d, split %v", before.width, after.width, m.split())
+	}
+	if w, _ := lipgloss.Size(m.View()); w != 120 {
+		t.Errorf("view is %d wide after resizing", w)
+	}
+	if msg := save(); msg != nil {
+		t.Fatalf("save: %v", msg)
+	}
+
+	reloaded, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+	if reloaded.Split != maxSplit {
+		t.Errorf("saved split = %v, want %v", reloaded.Split, maxSplit)
+	}
+}
+
+func TestModel_LinkPicker(t *testing.T) {
+	var clipboard bytes.Buffer
+	clipboardOutput = &clipboard
+	defer func() { clipboardOutput = os.Stderr }()
+	t.Setenv("TMUX", "")
+	t.Setenv("TERM", "xterm")
+
+	m := NewModel(nil, Options{Opener: []string{"true"}})
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 120, Height: 30})
+	m = updated.(Model)
+	email := api.Email{ID: 1, RawEmail: "Content-Type: text/html\r\n\r\n" +
+		`<a href="https://a.example/1">one</a> <a href="https://evil.example">https://bank.example</a>`}
+	updated, _ = m.Update(EmailFetched{Email: email})
+	m = updated.(Model)
+
+	press := func(keys string) tea.Cmd {
+		t.Helper()
+		msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(keys)}
+		if keys == "enter" {
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		}
+		updated, cmd := m.Update(msg)
+		m = updated.(Model)
+		return cmd
+	}
+
+	press("L")
+	if m.picker == nil || len(m.picker.links) != 2 {
+		t.Fatalf("picker = %+v", m.picker)
+	}
+	if view := m.View(); !strings.Contains(view, "leads to evil.example") {
+		t.Errorf("lookalike not flagged:\n%s", view)
+	}
+	press("j")
+	cmd := press("y")
+	if m.picker != nil || cmd == nil {
+		t.Fatal("y should copy and close the picker")
+	}
+	updated, _ = m.Update(cmd())
+	m = updated.(Model)
+	want := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte("https://evil.example")) + "\a"
+	if clipboard.String() != want || m.status != "Copied link to the clipboard" {
+		t.Errorf("clipboard %q, status %q", clipboard.String(), m.status)
+	}
+
+	press("L")
+	cmd = press("enter")
+	if msg, ok := cmd().(LinkOpened); !ok || msg.URL != "https://a.example/1" {
+		t.Errorf("open = %#v", msg)
+	}
+}
+
+func TestModel_Yank(t *testing.T) {
+	var clipboard bytes.Buffer
+	clipboardOutput = &clipboard
+	defer func() { clipboardOutput = os.Stderr }()
+	t.Setenv("TMUX", "")
+	t.Setenv("TERM", "xterm")
+
+	m := NewModel(nil, Options{})
+	email := api.Email{ID: 7, MessageID: "<abc@mail.example>", Sender: "Alice <alice@example.com>", RawEmail: "Subject: hi\r\n\r\nline one\nline two"}
+	updated, _ := m.Update(EmailsFetched{Emails: []api.Email{email}, Total: 1})
+	m = updated.(Model)
+	updated, _ = m.Update(EmailFetched{Email: email})
+	m = updated.(Model)
+
+	yank := func(keys string) string {
+		t.Helper()
+		clipboard.Reset()
+		updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(keys)})
+		m = updated.(Model)
+		if cmd == nil {
+			t.Fatalf("%s copied nothing", keys)
+		}
+		updated, _ = m.Update(cmd())
+		m = updated.(Model)
+		seq := strings.TrimSuffix(strings.TrimPrefix(clipboard.String(), "\x1b]52;c;"), "\a")
+		text, err := base64.StdEncoding.DecodeString(seq)
+		if err != nil {
+			t.Fatalf("%s wrote %q", keys, clipboard.String())
+		}
+		return string(text)
+	}
+
+	if got := yank("y"); got != "alice@example.com" || !strings.Contains(m.status, "Copied sender") {
+		t.Errorf("y copied %q, status %q", got, m.status)
+	}
+	if got := yank("i"); got != "abc@mail.example" {
+		t.Errorf("yi copied %q", got)
+	}
+	if got := yank("i"); got != "mercury read 7" {
+		t.Errorf("yii copied %q", got)
+	}
+	if got := yank("Y"); got != "line one\nline two" || m.status != "Copied body (2 lines) to the clipboard" {
+		t.Errorf("Y copied %q, status %q", got, m.status)
+	}
+
+	// i without a yank before it is not a yank.
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("i")})
+	m = updated.(Model)
+	if cmd != nil {
+		t.Error("a lone i should not copy")
+	}
+}
+
+func TestModel_PreviewModes(t *testing.T) {
+	m := NewModel(nil, Options{})
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 120, Height: 30})
+	m = updated.(Model)
+	email := api.Email{ID: 1, Subject: "Hi", RawEmail: "Received: from relay\r\nContent-Type: multipart/mixed; boundary=x\r\n\r\n" +
+		"--x\r\nContent-Type: text/plain\r\n\r\nhello there\r\n" +
+		"--x\r\nContent-Type: application/pdf; name=a.pdf\r\nContent-Transfer-Encoding: base64\r\n\r\nJVBERg==\r\n--x--\r\n"}
+	updated, _ = m.Update(EmailFetched{Email: email})
+	m = updated.(Model)
+
+	press := func(k string) string {
+		t.Helper()
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+		m = updated.(Model)
+		return m.preview.viewport.View()
+	}
+
+	if view := press("H"); !strings.Contains(view, "Received: from relay") || !strings.Contains(view, "hello there") {
+		t.Errorf("headers view:\n%s", view)
+	}
+	if view := press("O"); !strings.Contains(view, "--x") || strings.Contains(view, "Subject: Hi") {
+		t.Errorf("source view:\n%s", view)
+	}
+	if view := press("S"); !strings.Contains(view, `└── application/pdf  base64  8 B  "a.pdf"`) {
+		t.Errorf("structure view:\n%s", view)
+	}
+	if view := press("S"); !strings.Contains(view, "hello there") || m.status != "Showing the message" {
+		t.Errorf("back to the body: %q\n%s", m.status, view)
+	}
+}
+
+func TestModel_AuthBadges(t *testing.T) {
+	m := NewModel(nil, Options{})
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 120, Height: 30})
+	m = updated.(Model)
+	updated, _ = m.Update(EmailsFetched{Emails: pageOfEmails(2, 2), Total: 2})
+	m = updated.(Model)
+
+	spoofed := m.list.SelectedEmail()
+	full := *spoofed
+	full.RawEmail = "Authentication-Results: mx.cloudflare.net; spf=pass; dkim=none; dmarc=fail header.from=ceo.example\r\n" +
+		"Subject: Wire transfer\r\n\r\nPlease pay."
+	updated, _ = m.Update(EmailFetched{Email: full})
+	m = updated.(Model)
+
+	preview := m.preview.viewport.View()
+	for _, want := range []string{"✓ SPF", "– DKIM", "✗ DMARC (ceo.example) fail"} {
+		if !strings.Contains(preview, want) {
+			t.Errorf("preview lacks %q:\n%s", want, preview)
+		}
+	}
+	item, _ := m.list.SelectedItem()
+	if !strings.HasSuffix(item.Title(), "⚠") {
+		t.Errorf("list row %q lacks the DMARC badge", item.Title())
+	}
+
+	// The badge survives a refresh of the summaries.
+	updated, _ = m.Update(EmailsFetched{Emails: pageOfEmails(2, 2), Total: 2})
+	m = updated.(Model)
+	if item, _ := m.list.SelectedItem(); !strings.HasSuffix(item.Title(), "⚠") {
+		t.Errorf("after refresh: %q", item.Title())
+	}
+}
+
+func TestModel_Unsubscribe(t *testing.T) {
+	m := NewModel(nil, Options{Opener: []string{"true"}})
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 120, Height: 30})
+	m = updated.(Model)
+	emails := []api.Email{
+		{ID: 1, Sender: "News <news@list.example>", Subject: "Issue 2"},
+		{ID: 2, Sender: "Alice <alice@example.com>", Subject: "Lunch"},
+		{ID: 3, Sender: "news@list.example", Subject: "Issue 1"},
+	}
+	updated, _ = m.Update(EmailsFetched{Emails: emails, Total: 3})
+	m = updated.(Model)
+	press := func(keys string) tea.Cmd {
+		t.Helper()
+		updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(keys)})
+		m = updated.(Model)
+		return cmd
+	}
+
+	press("U")
+	if m.unsubscribe != nil || m.status != "Open a message to unsubscribe" {
+		t.Fatalf("status = %q", m.status)
+	}
+
+	email := emails[0]
+	email.RawEmail = "From: News <news@list.example>\r\nList-Unsubscribe: <http://list.example/leave>\r\n\r\nhi"
+	updated, _ = m.Update(EmailFetched{Email: email})
+	m = updated.(Model)
+	press("U")
+	if m.unsubscribe == nil || !strings.Contains(m.View(), "web page at list.example") {
+		t.Fatalf("prompt not shown:\n%s", m.View())
+	}
+	press("n")
+	if m.unsubscribe != nil || m.status != "Unsubscribe cancelled" {
+		t.Fatalf("status = %q", m.status)
+	}
+
+	press("U")
+	cmd := press("a")
+	if len(m.pending) != 1 || len(m.pending[0].Emails) != 2 || len(m.emails) != 1 {
+		t.Fatalf("pending = %+v", m.pending)
+	}
+	if cmd == nil {
+		t.Fatal("a should open the unsubscribe page")
+	}
+}
+
+func TestModel_EventCard(t *testing.T) {
+	m := NewModel(nil, Options{})
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 140, Height: 40})
+	m = updated.(Model)
+	email := api.Email{ID: 1, RawEmail: "Content-Type: multipart/mixed; boundary=b\r\n\r\n" +
+		"--b\r\nContent-Type: text/calendar; method=REQUEST\r\n\r\n" +
+		"BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:u1\r\nDTSTART;VALUE=DATE:20261224\r\n" +
+		"SUMMARY:Holiday party\r\nLOCATION:Rooftop\r\nORGANIZER;CN=Bob:mailto:bob@example.com\r\n" +
+		"END:VEVENT\r\nEND:VCALENDAR\r\n--b--\r\n"}
+	updated, _ = m.Update(EmailFetched{Email: email})
+	m = updated.(Model)
+
+	preview := m.preview.viewport.View()
+	for _, want := range []string{"Holiday party", "Thu 24 Dec 2026 (all day)", "Rooftop", "Bob <bob@example.com>"} {
+		if !strings.Contains(preview, want) {
+			t.Errorf("preview lacks %q:\n%s", want, preview)
+		}
+	}
+	if strings.Contains(preview, "(No content)") {
+		t.Errorf("an invitation is content:\n%s", preview)
+	}
+}
+
+func TestModel_PGP(t *testing.T) {
+	opened := &pgp.Result{
+		Encrypted: true,
+		Signature: &pgp.Verification{Status: pgp.Good, Signer: "Alice <alice@example.com>", Trust: pgp.TrustFull},
+		Body:      "The launch code is 1234",
+	}
+	m := NewModel(nil, Options{OpenPGP: func(string, *api.Email) (*pgp.Result, error) { return opened, nil }})
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 140, Height: 40})
+	m = updated.(Model)
+	email := api.Email{ID: 5, RawEmail: "Content-Type: multipart/encrypted; protocol=\"application/pgp-encrypted\"; boundary=b\r\n\r\n--b--\r\n"}
+	updated, cmd := m.Update(EmailFetched{Email: email})
+	m = updated.(Model)
+	if cmd == nil || !strings.Contains(m.preview.viewport.View(), "Decrypting and verifying") {
+		t.Fatalf("no pending badge:\n%s", m.preview.viewport.View())
+	}
+
+	updated, _ = m.Update(PGPOpened{ID: 4, Result: &pgp.Result{Body: "stale"}})
+	m = updated.(Model)
+	if strings.Contains(m.preview.viewport.View(), "stale") {
+		t.Error("result for another email was shown")
+	}
+
+	updated, _ = m.Update(cmd())
+	m = updated.(Model)
+	preview := m.preview.viewport.View()
+	for _, want := range []string{"✓ Encrypted · Good signature from Alice <alice@example.com> (full trust)", "The launch code is 1234"} {
+		if !strings.Contains(preview, want) {
+			t.Errorf("preview lacks %q:\n%s", want, preview)
+		}
+	}
+}
+
+func TestModel_SMIME(t *testing.T) {
+	verified := &api.SMIMEResult{
+		Verification: smime.Verification{Status: smime.Invalid, Reason: "content does not match the signed digest"},
+		Content:      &api.Email{RawEmail: "Content-Type: text/plain\r\n\r\nWire the funds"},
+	}
+	m := NewModel(nil, Options{VerifySMIME: func(string, *api.Email) (*api.SMIMEResult, error) { return verified, nil }})
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 140, Height: 40})
+	m = updated.(Model)
+	email := api.Email{ID: 5, RawEmail: "Content-Type: multipart/signed; protocol=\"application/pkcs7-signature\"; boundary=b\r\n\r\n--b--\r\n"}
+	updated, cmd := m.Update(EmailFetched{Email: email})
+	m = updated.(Model)
+	if cmd == nil || !strings.Contains(m.preview.viewport.View(), "S/MIME: Verifying") {
+		t.Fatalf("no pending badge:\n%s", m.preview.viewport.View())
+	}
+
+	updated, _ = m.Update(cmd())
+	m = updated.(Model)
+	preview := m.preview.viewport.View()
+	for _, want := range []string{"✗ INVALID signature: content does not match the signed digest", "Wire the funds"} {
+		if !strings.Contains(preview, want) {
+			t.Errorf("preview lacks %q:\n%s", want, preview)
+		}
+	}
+}
+
+func TestModel_ProfileSwitchPassesName(t *testing.T) {
+	books := map[string]*contacts.Book{}
+	for _, name := range []string{"work", "home"} {
+		book, err := contacts.Open(filepath.Join(t.TempDir(), contacts.FileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		book.Add(name, name+"@example.com")
+		books[name] = book
+	}
+	var verifiedAs string
+	m := NewModel(nil, Options{
+		Profile:  "work",
+		Contacts: func(profile string) *contacts.Book { return books[profile] },
+		VerifySMIME: func(profile string, _ *api.Email) (*api.SMIMEResult, error) {
+			verifiedAs = profile
+			return nil, nil
+		},
+	})
+	if got := m.contactBook().Addresses(); len(got) != 1 || got[0] != "work@example.com" {
+		t.Fatalf("work contacts = %v", got)
+	}
+
+	updated, _ := m.Update(ProfileSwitched{Name: "home"})
+	m = updated.(Model)
+	if got := m.contactBook().Addresses(); len(got) != 1 || got[0] != "home@example.com" {
+		t.Errorf("contacts after switching = %v", got)
+	}
+	if got := m.registry.Complete("compose ho"); len(got) != 1 || got[0] != "home@example.com" {
+		t.Errorf("completion after switching = %v", got)
+	}
+	verifySMIME(m.opts.VerifySMIME, m.profile, api.Email{ID: 1})()
+	if verifiedAs != "home" {
+		t.Errorf("S/MIME verified as %q", verifiedAs)
+	}
+}
+
+func TestModel_Contacts(t *testing.T) {
+	book, err := contacts.Open(filepath.Join(t.TempDir(), contacts.FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	book.Add("Alice Smith", "alice@example.com")
+	m := NewModel(nil, Options{Contacts: func(string) *contacts.Book { return book }})
+
+	// c asks for the recipient first, completing contacts
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'c'}})
+	m = updated.(Model)
+	if !m.commanding || m.commandInput.Value() != "compose " {
+		t.Fatalf("commanding=%v line=%q", m.commanding, m.commandInput.Value())
+	}
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("al")})
+	m = updated.(Model)
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m = updated.(Model)
+	if got := m.commandInput.Value(); got != "compose alice@example.com " {
+		t.Errorf("completed %q", got)
+	}
+	if to, _, err := m.expandRecipients("alice"); err != nil || strings.Join(to, ",") != "alice@example.com" {
+		t.Errorf("expandRecipients = %q, %v", to, err)
+	}
+	m.commanding = false
+
+	// the sender of unread mail is collected once, however often it is opened
+	carol := api.Email{ID: 7, Sender: "Carol <carol@example.com>", Folder: "inbox", ReceivedAt: "2026-10-17 09:00:00"}
+	for i := 0; i < 2; i++ {
+		updated, _ = m.Update(EmailFetched{Email: carol})
+		m = updated.(Model)
+	}
+	read := api.Email{ID: 8, Sender: "dan@example.com", Folder: "inbox", IsRead: 1}
+	updated, _ = m.Update(EmailFetched{Email: read})
+	m = updated.(Model)
+	updated, _ = m.Update(EmailSent{MessageID: "x", To: "erin@example.com"})
+	m = updated.(Model)
+
+	counts := map[string]int{}
+	for _, c := range book.List() {
+		counts[c.Email] = c.Count
+	}
+	if counts["carol@example.com"] != 1 || counts["erin@example.com"] != 1 {
+		t.Errorf("counts = %v", counts)
+	}
+	if _, ok := counts["dan@example.com"]; ok {
+		t.Error("already read mail was collected")
+	}
+}
+
+func TestModel_ComposeToGroup(t *testing.T) {
+	book, err := contacts.Open(filepath.Join(t.TempDir(), contacts.FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	book.Add("Alice Smith", "alice@example.com")
+	if _, err := book.AddToGroup("board", "alice", "bob@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	m := NewModel(nil, Options{Contacts: func(string) *contacts.Book { return book }})
+	m.width = 200
+
+	m, _ = composeTo(m, "@board")
+	if m.compose == nil {
+		t.Fatalf("compose not started: %v", m.err)
+	}
+	defer cleanupCompose(&m)
+	draft := "To: @board\nCc: carol@example.com\nSubject: Minutes\n\nAttached.\n"
+	if err := os.WriteFile(m.compose.TmpFile, []byte(draft), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// the expanded recipients are shown before anything is sent
+	updated, cmd := m.Update(EditorClosed{TmpFile: m.compose.TmpFile})
+	m = updated.(Model)
+	if m.sending == nil || cmd != nil {
+		t.Fatalf("sending=%v cmd=%v err=%v", m.sending, cmd != nil, m.err)
+	}
+	if req := m.sending.req; req.To != "alice@example.com, bob@example.com" || req.Cc != "carol@example.com" {
+		t.Errorf("request = %+v", req)
+	}
+	if view := m.sendPromptView(); !strings.Contains(view, "Send to 3 recipients?") || !strings.Contains(view, "bob@example.com") {
+		t.Errorf("prompt = %q", view)
+	}
+
+	// declining keeps the draft
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}})
+	m = updated.(Model)
+	if m.sending != nil || m.compose == nil {
+		t.Fatalf("decline: sending=%v compose=%v", m.sending, m.compose)
+	}
+
+	updated, _ = m.Update(EditorClosed{TmpFile: m.compose.TmpFile})
+	m = updated.(Model)
+	updated, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'y'}})
+	m = updated.(Model)
+	if m.sending != nil || m.compose != nil || cmd == nil {
+		t.Errorf("confirm: sending=%v compose=%v cmd=%v", m.sending, m.compose, cmd != nil)
+	}
+
+	m, _ = composeTo(m, "@nobody")
+	if m.err == nil || !strings.Contains(m.err.Error(), `no group "@nobody"`) {
+		t.Errorf("unknown group error = %v", m.err)
+	}
+}
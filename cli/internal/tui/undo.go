@@ -0,0 +1,319 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+)
+
+// undoWindow is how long a delete or archive can be undone before it is sent
+const undoWindow = 5 * time.Second
+
+// timeNow is replaced in tests
+var timeNow = time.Now
+
+// pendingAction is an optimistic delete or archive awaiting its undo window
+type pendingAction struct {
+	Kind     bulkKind // bulkDelete or bulkArchive
+	Emails   []api.Email
+	Deadline time.Time
+}
+
+// startAction hides the targeted emails and queues the action for commit
+func startAction(m Model, kind bulkKind) (Model, tea.Cmd) {
+	return queueAction(m, kind, m.targets())
+}
+
+// queueAction hides emails and queues the action for commit
+func queueAction(m Model, kind bulkKind, emails []api.Email) (Model, tea.Cmd) {
+	if len(emails) == 0 {
+		return m, nil
+	}
+	m.err = nil
+	m.list.ClearMarks()
+	m.pending = append(m.pending, pendingAction{Kind: kind, Emails: emails, Deadline: timeNow().Add(undoWindow)})
+	ids := make([]int, len(emails))
+	for i, email := range emails {
+		ids[i] = email.ID
+	}
+	m, cmd := removeEmails(m, ids)
+	if m.undoTicking {
+		return m, cmd
+	}
+	m.undoTicking = true
+	return m, tea.Batch(cmd, scheduleUndoTick())
+}
+
+// scheduleUndoTick refreshes the undo countdown once a second
+func scheduleUndoTick() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return UndoTick{}
+	})
+}
+
+// expirePending commits actions whose undo window has passed
+func expirePending(m Model) (Model, tea.Cmd) {
+	now := timeNow()
+	var expired []pendingAction
+	kept := m.pending[:0]
+	for _, p := range m.pending {
+		if now.Before(p.Deadline) {
+			kept = append(kept, p)
+		} else {
+			expired = append(expired, p)
+		}
+	}
+	m.pending = kept
+
+	var cmds []tea.Cmd
+	for _, p := range expired {
+		var cmd tea.Cmd
+		m, cmd = startBulk(m, p.Kind, "", p.Emails)
+		cmds = append(cmds, cmd)
+	}
+	if len(m.pending) == 0 {
+		m.undoTicking = false
+	} else {
+		cmds = append(cmds, scheduleUndoTick())
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// undoAction restores the most recently hidden emails
+func undoAction(m Model) (Model, tea.Cmd) {
+	if len(m.pending) == 0 {
+		return m, nil
+	}
+	p := m.pending[len(m.pending)-1]
+	m.pending = m.pending[:len(m.pending)-1]
+	m, cmd := restoreEmails(m, p.Emails)
+	m.status = "Restored " + plural(len(p.Emails), "email")
+	return m, cmd
+}
+
+// commitPending sends every pending action and waits for them, for a clean
+// quit or switch; failures come back in PendingCommitted
+func commitPending(m Model) (Model, tea.Cmd) {
+	if len(m.pending) == 0 {
+		return m, nil
+	}
+	pending := m.pending
+	m.pending = nil
+	client, gen := m.client, m.gen
+	return m, func() tea.Msg {
+		jobs := make([]*bulkJob, len(pending))
+		for i, p := range pending {
+			job := newBulkJob(client, p.Kind, "", p.Emails)
+			job.Gen = gen
+			forEach(p.Emails, job.apply, job.results)
+			for range p.Emails {
+				if r := <-job.results; r.Err != nil {
+					job.Failed = append(job.Failed, r)
+				}
+				job.Done++
+			}
+			jobs[i] = job
+		}
+		return PendingCommitted{Jobs: jobs}
+	}
+}
+
+// pendingCommitted reports actions sent by commitPending, putting failed
+// emails back if their listing is still shown, and finishes a quit unless
+// something failed
+func pendingCommitted(m Model, msg PendingCommitted) (Model, tea.Cmd) {
+	quitting := m.quitting
+	m.quitting = false
+	var failed []string
+	var cmds []tea.Cmd
+	shown := true
+	for _, job := range msg.Jobs {
+		if len(job.Failed) == 0 {
+			continue
+		}
+		failed = append(failed, errorSummary(job).Error())
+		if job.Gen != m.gen {
+			shown = false
+			continue
+		}
+		emails := make([]api.Email, len(job.Failed))
+		for i, r := range job.Failed {
+			emails[i] = r.Email
+		}
+		var cmd tea.Cmd
+		m, cmd = restoreEmails(m, emails)
+		cmds = append(cmds, cmd)
+	}
+	if len(failed) == 0 {
+		if quitting {
+			return m, tea.Quit
+		}
+		return m, nil
+	}
+	text := strings.Join(failed, "; ")
+	if quitting {
+		text += " — q to quit anyway"
+	}
+	if shown {
+		m.err = errors.New(text)
+	} else {
+		// The next listing's fetch clears errors; keep this one in view.
+		m.status = text
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// undoStatus describes the newest pending action and its remaining window
+func (m Model) undoStatus() string {
+	if len(m.pending) == 0 {
+		return ""
+	}
+	p := m.pending[len(m.pending)-1]
+	remaining := p.Deadline.Sub(timeNow()).Round(time.Second)
+	if remaining < time.Second {
+		remaining = time.Second
+	}
+	what := p.Kind.done()
+	if len(p.Emails) > 1 {
+		what += fmt.Sprintf(" %d", len(p.Emails))
+	}
+	text := fmt.Sprintf("%s — u to undo (%ds)", what, int(remaining/time.Second))
+	if len(m.pending) > 1 {
+		text += fmt.Sprintf(" · %d pending", len(m.pending))
+	}
+	return text
+}
+
+// withoutPending drops emails hidden by pending actions from a fresh listing
+func (m Model) withoutPending(emails []api.Email) ([]api.Email, int) {
+	if len(m.pending) == 0 {
+		return emails, 0
+	}
+	hidden := make(map[int]bool)
+	for _, p := range m.pending {
+		for _, email := range p.Emails {
+			hidden[email.ID] = true
+		}
+	}
+	kept := make([]api.Email, 0, len(emails))
+	for _, email := range emails {
+		if !hidden[email.ID] {
+			kept = append(kept, email)
+		}
+	}
+	return kept, len(emails) - len(kept)
+}
+
+// removeEmails drops emails from the list and selects the row after them
+func removeEmails(m Model, ids []int) (Model, tea.Cmd) {
+	remove := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		remove[id] = true
+	}
+	first := -1
+	kept := make([]api.Email, 0, len(m.emails))
+	for _, email := range m.emails {
+		if remove[email.ID] {
+			if i := m.list.indexOf(email.ID); first == -1 || (i >= 0 && i < first) {
+				first = i
+			}
+			continue
+		}
+		kept = append(kept, email)
+	}
+	if len(kept) == len(m.emails) {
+		return m, nil
+	}
+	removed := len(m.emails) - len(kept)
+	m.emails = kept
+	if m.searchQuery != "" {
+		m.inbox = removeByID(m.inbox, remove)
+	}
+	m.total -= removed
+	if m.total < 0 {
+		m.total = 0
+	}
+	if m.searchQuery == "" {
+		m.list.SetTitle(m.listTitle())
+	}
+
+	selectedID := m.selectedID()
+	if remove[selectedID] {
+		m.currentEmail = nil
+		m.preview.SetEmail(nil)
+	}
+	if m.threaded && len(m.emails) > 0 {
+		m.loading = true
+		return m, tea.Batch(m.buildThreads(), m.spinner.Tick)
+	}
+	m.list.SetEmails(m.emails)
+	if len(m.emails) == 0 {
+		return m, nil
+	}
+	if m.list.SelectID(selectedID) {
+		return m, nil
+	}
+	if first < 0 {
+		first = 0
+	}
+	if first >= m.list.Len() {
+		first = m.list.Len() - 1
+	}
+	m.list.SetIndex(first)
+	m.loading = true
+	return m, tea.Batch(fetchEmail(m.client, m.list.SelectedEmail().ID), m.spinner.Tick)
+}
+
+// restoreEmails puts emails back in date order and selects the newest
+func restoreEmails(m Model, emails []api.Email) (Model, tea.Cmd) {
+	if len(emails) == 0 {
+		return m, nil
+	}
+	for _, email := range emails {
+		m.emails = insertByDate(m.emails, email)
+		if m.searchQuery != "" {
+			m.inbox = insertByDate(m.inbox, email)
+		}
+	}
+	m.total += len(emails)
+	if m.searchQuery == "" {
+		m.list.SetTitle(m.listTitle())
+	}
+	m.loading = true
+	if m.threaded {
+		m.currentEmail = nil
+		m.preview.SetEmail(nil)
+		return m, tea.Batch(m.buildThreads(), m.spinner.Tick)
+	}
+	m.list.SetEmails(m.emails)
+	m.list.SelectID(emails[0].ID)
+	return m, tea.Batch(fetchEmail(m.client, emails[0].ID), m.spinner.Tick)
+}
+
+// insertByDate inserts email into a newest-first listing
+func insertByDate(emails []api.Email, email api.Email) []api.Email {
+	i := 0
+	for i < len(emails) && (emails[i].ReceivedAt > email.ReceivedAt ||
+		emails[i].ReceivedAt == email.ReceivedAt && emails[i].ID > email.ID) {
+		i++
+	}
+	out := make([]api.Email, 0, len(emails)+1)
+	out = append(out, emails[:i]...)
+	out = append(out, email)
+	return append(out, emails[i:]...)
+}
+
+func removeByID(emails []api.Email, remove map[int]bool) []api.Email {
+	out := make([]api.Email, 0, len(emails))
+	for _, email := range emails {
+		if !remove[email.ID] {
+			out = append(out, email)
+		}
+	}
+	return out
+}
@@ -0,0 +1,80 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+	"github.com/misty-step/mercury/cli/internal/unsubscribe"
+)
+
+// unsubscribePrompt is an unsubscribe awaiting confirmation
+type unsubscribePrompt struct {
+	email  *api.Email
+	action unsubscribe.Action
+}
+
+// startUnsubscribe plans leaving the previewed email's list and asks first
+func startUnsubscribe(m Model) (Model, tea.Cmd) {
+	email := m.currentEmail
+	if selected := m.list.SelectedEmail(); email == nil || (selected != nil && selected.ID != email.ID) {
+		m.status = "Open a message to unsubscribe"
+		return m, nil
+	}
+	action, err := unsubscribe.Plan(email)
+	if err != nil {
+		m.status = "Message has no List-Unsubscribe header"
+		return m, nil
+	}
+	m.unsubscribe = &unsubscribePrompt{email: email, action: action}
+	return m, nil
+}
+
+// updateUnsubscribe confirms the prompt: y unsubscribes, a also archives the
+// list's loaded mail, anything else cancels
+func updateUnsubscribe(m Model, msg tea.KeyMsg) (Model, tea.Cmd) {
+	p := m.unsubscribe
+	m.unsubscribe = nil
+	switch msg.String() {
+	case "y", "a":
+	default:
+		m.status = "Unsubscribe cancelled"
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	if p.action.Method == unsubscribe.Web {
+		cmd = m.openURL(p.action.URL)
+	} else {
+		cmd = runUnsubscribe(m.client, p)
+	}
+	if msg.String() == "y" {
+		return m, cmd
+	}
+	var same []api.Email
+	for i := range m.emails {
+		if unsubscribe.SameList(p.email, &m.emails[i]) {
+			same = append(same, m.emails[i])
+		}
+	}
+	m, archive := queueAction(m, bulkArchive, same)
+	return m, tea.Batch(cmd, archive)
+}
+
+// runUnsubscribe carries out the planned unsubscribe
+func runUnsubscribe(client *api.Client, p *unsubscribePrompt) tea.Cmd {
+	return func() tea.Msg {
+		if err := unsubscribe.Do(context.Background(), client, p.email, p.action); err != nil {
+			return ErrMsg{Err: fmt.Errorf("unsubscribe: %w", err)}
+		}
+		return Unsubscribed{Sender: p.email.Sender}
+	}
+}
+
+// unsubscribePromptView is the confirmation shown in the status line
+func (m Model) unsubscribePromptView() string {
+	p := m.unsubscribe
+	return fmt.Sprintf("Unsubscribe from %s by %s?  y yes · a yes and archive their mail · any other key cancels", p.email.Sender, p.action)
+}
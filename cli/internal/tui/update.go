@@ -1,10 +1,13 @@
 package tui
 
 import (
+	"fmt"
+
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
+
+	"github.com/misty-step/mercury/cli/internal/pgp"
 )
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -13,64 +16,166 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.status != "" {
 			m.status = ""
 		}
+		if m.searching {
+			return updateSearchInput(m, msg)
+		}
+		if m.moving {
+			return updateMoveInput(m, msg)
+		}
+		if m.commanding {
+			return updateCommandInput(m, msg)
+		}
+		if m.finding {
+			return updateFindInput(m, msg)
+		}
+		if m.picker != nil {
+			return updateLinkPicker(m, msg)
+		}
+		if m.unsubscribe != nil {
+			return updateUnsubscribe(m, msg)
+		}
+		if m.sending != nil {
+			return updateSendPrompt(m, msg)
+		}
+		if m.showHelp {
+			// Any key closes the help overlay.
+			m.showHelp = false
+			return m, nil
+		}
+		if m.yank != yankNone {
+			var cmd tea.Cmd
+			var handled bool
+			m, cmd, handled = updateYank(m, msg)
+			if handled {
+				return m, cmd
+			}
+		}
+		if m.reading {
+			if m, cmd, ok := updateReader(m, msg); ok {
+				return m, cmd
+			}
+		}
 		switch {
-		case key.Matches(msg, keys.Quit):
+		case key.Matches(msg, m.keys.Quit):
 			cleanupCompose(&m)
-			return m, tea.Quit
-		case key.Matches(msg, keys.Tab):
+			if len(m.pending) == 0 {
+				return m, tea.Quit
+			}
+			// Quit once the pending actions are sent, so a failure can
+			// be reported; q again quits without waiting.
+			m, commit := commitPending(m)
+			m.quitting = true
+			m.status = "Sending pending actions…"
+			return m, commit
+		case key.Matches(msg, m.keys.Reader):
+			return toggleReader(m)
+		case key.Matches(msg, m.keys.Help):
+			m.showHelp = true
+			return m, nil
+		case key.Matches(msg, m.keys.Tab):
 			if m.focus == focusList {
 				m.focus = focusPreview
 			} else {
 				m.focus = focusList
 			}
 			return m, nil
-		case key.Matches(msg, keys.Refresh):
-			m.loading = true
-			m.err = nil
-			return m, tea.Batch(fetchEmails(m.client, 50, 0), m.spinner.Tick)
-		case key.Matches(msg, keys.MarkRead):
-			if selected := m.list.SelectedEmail(); selected != nil && selected.IsRead == 0 {
-				m.loading = true
-				m.err = nil
-				return m, tea.Batch(markRead(m.client, selected.ID), m.spinner.Tick)
-			}
+		case key.Matches(msg, m.keys.Search):
+			return startSearch(m)
+		case key.Matches(msg, m.keys.Command):
+			return startCommand(m)
+		case key.Matches(msg, m.keys.Threads):
+			return toggleThreads(m)
+		case key.Matches(msg, m.keys.Fold):
+			return toggleFold(m)
+		case key.Matches(msg, m.keys.Yank):
+			return yankSenderAddress(m)
+		case key.Matches(msg, m.keys.YankBody):
+			return yankBody(m)
+		case key.Matches(msg, m.keys.Headers):
+			return togglePreviewMode(m, previewHeaders)
+		case key.Matches(msg, m.keys.Source):
+			return togglePreviewMode(m, previewRaw)
+		case key.Matches(msg, m.keys.Structure):
+			return togglePreviewMode(m, previewStructure)
+		case key.Matches(msg, m.keys.Links):
+			return startLinks(m)
+		case key.Matches(msg, m.keys.Unsubscribe):
+			return startUnsubscribe(m)
+		case key.Matches(msg, m.keys.Shrink):
+			return resizeSplit(m, -splitStep)
+		case key.Matches(msg, m.keys.Grow):
+			return resizeSplit(m, splitStep)
+		case key.Matches(msg, m.keys.Back) && m.focus == focusPreview && m.singlePane():
+			m.focus = focusList
 			return m, nil
-		case key.Matches(msg, keys.Delete):
-			if selected := m.list.SelectedEmail(); selected != nil {
-				m.loading = true
-				m.err = nil
-				return m, tea.Batch(deleteEmail(m.client, selected.ID), m.spinner.Tick)
-			}
+		case key.Matches(msg, m.keys.Back) && m.list.MarkedCount() > 0:
+			m.list.ClearMarks()
 			return m, nil
-		case key.Matches(msg, keys.Compose):
+		case key.Matches(msg, m.keys.Back) && m.searchQuery != "":
+			return clearSearch(m)
+		case key.Matches(msg, m.keys.Refresh):
+			var clear tea.Cmd
+			m, clear = clearNewBadge(m)
+			if m.searchQuery != "" {
+				m, cmd := clearSearch(m)
+				return m, tea.Batch(clear, cmd)
+			}
+			m.loading = true
+			m.err = nil
+			return m, tea.Batch(clear, refreshEmails(m), m.spinner.Tick)
+		case key.Matches(msg, m.keys.MarkRead):
+			return bulkMarkRead(m)
+		case key.Matches(msg, m.keys.Star):
+			return bulkToggleStar(m)
+		case key.Matches(msg, m.keys.Move):
+			return startMove(m)
+		case key.Matches(msg, m.keys.Delete):
+			return startAction(m, bulkDelete)
+		case key.Matches(msg, m.keys.Archive):
+			return startAction(m, bulkArchive)
+		case key.Matches(msg, m.keys.Undo):
+			return undoAction(m)
+		case key.Matches(msg, m.keys.Compose):
 			m.err = nil
-			return startCompose(m, "", "", nil)
-		case key.Matches(msg, keys.Reply):
+			return startNewCompose(m)
+		case key.Matches(msg, m.keys.Reply):
 			m.err = nil
 			return startReply(m, m.currentEmail)
 		}
 
-		if m.focus == focusList {
+		if m.focus == focusList && !m.reading {
 			switch {
-			case key.Matches(msg, keys.Up), key.Matches(msg, keys.Down):
+			case key.Matches(msg, m.keys.Enter):
+				m.focus = focusPreview
+				return m, nil
+			case key.Matches(msg, m.keys.Mark):
+				m.list.ToggleMark()
+				return m, nil
+			case key.Matches(msg, m.keys.MarkRange):
+				m.list.MarkRange()
+				return m, nil
+			case key.Matches(msg, m.keys.MarkAll):
+				m.list.MarkAll()
+				return m, nil
+			default:
+				// Cursor keys, plus the list's own paging keys.
+				index := m.list.Index()
 				var cmd tea.Cmd
 				m.list, cmd = m.list.Update(msg)
+				if m.list.Index() == index {
+					return m, cmd
+				}
+				var more, clear tea.Cmd
+				m, more = maybeLoadMore(m)
+				m, clear = clearNewBadge(m)
 				if selected := m.list.SelectedEmail(); selected != nil {
-					m.selected = m.list.Index()
 					if m.currentEmail == nil || m.currentEmail.ID != selected.ID {
 						m.loading = true
 						m.err = nil
-						return m, tea.Batch(cmd, fetchEmail(m.client, selected.ID), m.spinner.Tick)
+						return m, tea.Batch(cmd, more, clear, fetchEmail(m.client, selected.ID), m.spinner.Tick)
 					}
 				}
-				return m, cmd
-			case key.Matches(msg, keys.Enter):
-				m.focus = focusPreview
-				return m, nil
-			default:
-				var cmd tea.Cmd
-				m.list, cmd = m.list.Update(msg)
-				return m, cmd
+				return m, tea.Batch(cmd, more, clear)
 			}
 		}
 
@@ -82,38 +187,61 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.height = msg.Height
 		m.help.Width = m.width
-		statusHeight := lipgloss.Height(m.statusView())
-		contentHeight := m.height - statusHeight
-		if contentHeight < 0 {
-			contentHeight = 0
-		}
-		listWidth := m.width / 3
-		previewWidth := m.width - listWidth - 2
-		listHeight := contentHeight - 2
-		if listHeight < 0 {
-			listHeight = 0
-		}
-		m.list.SetSize(listWidth, listHeight)
-		m.preview.SetSize(previewWidth, listHeight)
-		return m, nil
+		return m.layout(), nil
 
 	case EmailsFetched:
+		if msg.Gen != m.gen {
+			// Fetched before a folder or profile switch.
+			return m, nil
+		}
+		if msg.Cached && len(msg.Emails) == 0 {
+			return m, nil
+		}
+		var hidden int
+		msg.Emails, hidden = m.withoutPending(msg.Emails)
+		msg.Total -= hidden
+		if msg.Offset > 0 {
+			if m.searchQuery != "" {
+				m.loadingMore = false
+				return m, nil
+			}
+			return appendPage(m, msg)
+		}
 		m.loading = false
 		m.err = nil
+		if m.searchQuery != "" {
+			m.inbox = msg.Emails
+			m.total = msg.Total
+			return m, nil
+		}
+		selectedID, index := m.selectedID(), m.list.Index()
 		m.emails = msg.Emails
+		m.total = msg.Total
+		m.list.SetTitle(m.listTitle())
+		if m.threaded {
+			m.loading = true
+			return m, tea.Batch(m.buildThreads(), m.spinner.Tick)
+		}
 		m.list.SetEmails(msg.Emails)
 		if len(m.emails) == 0 {
-			m.selected = 0
 			m.currentEmail = nil
 			m.preview.SetEmail(nil)
 			return m, nil
 		}
-		if m.selected < 0 || m.selected >= len(m.emails) {
-			m.selected = 0
+		// Follow the selected email if it is still listed; otherwise stay
+		// at the same position.
+		if !m.list.SelectID(selectedID) {
+			if index >= m.list.Len() {
+				index = m.list.Len() - 1
+			}
+			m.list.SetIndex(index)
+		}
+		selected := m.list.SelectedEmail()
+		if m.currentEmail != nil && m.currentEmail.ID == selected.ID {
+			return m, nil
 		}
-		m.list.SetIndex(m.selected)
 		m.loading = true
-		return m, tea.Batch(fetchEmail(m.client, m.emails[m.selected].ID), m.spinner.Tick)
+		return m, tea.Batch(fetchEmail(m.client, selected.ID), m.spinner.Tick)
 
 	case EmailFetched:
 		m.loading = false
@@ -124,57 +252,70 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		email := msg.Email
 		m.currentEmail = &email
 		m.preview.SetEmail(&email)
-		return m, nil
-
-	case EmailMarked:
-		m.loading = false
-		m.err = nil
-		for i := range m.emails {
-			if m.emails[i].ID == msg.ID {
-				m.emails[i].IsRead = 1
-				break
-			}
-		}
-		if m.currentEmail != nil && m.currentEmail.ID == msg.ID {
-			m.currentEmail.IsRead = 1
+		m.list.SetAuth(&email)
+		var cmds []tea.Cmd
+		if m.opts.OpenPGP != nil && pgp.Detect(&email) != pgp.None {
+			m.preview.SetPGP(&pgpState{pending: true})
+			cmds = append(cmds, openPGP(m.opts.OpenPGP, m.profile, email))
 		}
-		m.list.SetEmails(m.emails)
-		if m.selected >= 0 && m.selected < len(m.emails) {
-			m.list.SetIndex(m.selected)
+		if m.opts.VerifySMIME != nil && email.IsSMIME() {
+			m.preview.SetSMIME(&smimeState{pending: true})
+			cmds = append(cmds, verifySMIME(m.opts.VerifySMIME, m.profile, email))
 		}
-		return m, nil
+		m, save := observeSender(m, &email)
+		cmds = append(cmds, save)
+		return m, tea.Batch(cmds...)
+
+	case PGPOpened:
+		return pgpOpened(m, msg)
+
+	case SMIMEVerified:
+		return smimeVerified(m, msg)
+
+	case UndoTick:
+		return expirePending(m)
 
-	case EmailDeleted:
+	case BulkProgress:
+		return bulkProgress(m, msg)
+
+	case PendingCommitted:
+		return pendingCommitted(m, msg)
+
+	case SearchResults:
+		return showSearchResults(m, msg)
+
+	case PollTick:
+		return handlePollTick(m)
+
+	case NewEmails:
+		msg.Emails, _ = m.withoutPending(msg.Emails)
+		return mergeNewEmails(m, msg)
+
+	case PollFailed:
+		return pollFailed(m, msg)
+
+	case ProfileSwitched:
+		return profileSwitched(m, msg)
+
+	case Exported:
 		m.loading = false
-		m.err = nil
-		idx := -1
-		for i, email := range m.emails {
-			if email.ID == msg.ID {
-				idx = i
-				break
-			}
-		}
-		if idx == -1 {
-			return m, nil
-		}
-		m.emails = append(m.emails[:idx], m.emails[idx+1:]...)
-		if len(m.emails) == 0 {
-			m.selected = 0
-			m.currentEmail = nil
-			m.list.SetEmails(nil)
-			m.preview.SetEmail(nil)
-			return m, nil
-		}
-		if idx >= len(m.emails) {
-			idx = len(m.emails) - 1
-		}
-		m.selected = idx
-		m.list.SetEmails(m.emails)
-		m.list.SetIndex(m.selected)
-		m.currentEmail = nil
-		m.preview.SetEmail(nil)
-		m.loading = true
-		return m, tea.Batch(fetchEmail(m.client, m.emails[idx].ID), m.spinner.Tick)
+		m.status = fmt.Sprintf("Exported %s to %s", plural(msg.Count, "email"), msg.Path)
+		return m, nil
+
+	case LinkOpened:
+		m.status = "Opened " + msg.URL
+		return m, nil
+
+	case Copied:
+		m.status = "Copied " + msg.What + " to the clipboard"
+		return m, nil
+
+	case Unsubscribed:
+		m.status = "Unsubscribed from " + msg.Sender
+		return m, nil
+
+	case ThreadsBuilt:
+		return showThreads(m, msg)
 
 	case EditorClosed:
 		return handleEditorClose(m, msg.TmpFile, msg.Err)
@@ -183,16 +324,17 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.loading = true
 		m.err = nil
 		m.status = "Sent " + msg.MessageID
-		return m, tea.Batch(fetchEmails(m.client, 50, 0), m.spinner.Tick)
+		return m, tea.Batch(refreshEmails(m), m.spinner.Tick, observeRecipients(m, msg.To))
 
 	case ErrMsg:
 		m.loading = false
+		m.loadingMore = false
 		m.err = msg.Err
 		m.status = ""
 		return m, nil
 
 	case spinner.TickMsg:
-		if !m.loading {
+		if !m.loading && len(m.jobs) == 0 {
 			return m, nil
 		}
 		var cmd tea.Cmd
@@ -200,5 +342,25 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, cmd
 	}
 
+	if m.searching {
+		var cmd tea.Cmd
+		m.searchInput, cmd = m.searchInput.Update(msg)
+		return m, cmd
+	}
+	if m.moving {
+		var cmd tea.Cmd
+		m.moveInput, cmd = m.moveInput.Update(msg)
+		return m, cmd
+	}
+	if m.commanding {
+		var cmd tea.Cmd
+		m.commandInput, cmd = m.commandInput.Update(msg)
+		return m, cmd
+	}
+	if m.finding {
+		var cmd tea.Cmd
+		m.findInput, cmd = m.findInput.Update(msg)
+		return m, cmd
+	}
 	return m, nil
 }
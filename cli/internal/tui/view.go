@@ -1,15 +1,10 @@
 package tui
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"fmt"
 
-var (
-	focusedBorderColor = lipgloss.Color("69")
-	blurredBorderColor = lipgloss.Color("240")
-	focusedPanelStyle  = lipgloss.NewStyle().Border(lipgloss.ThickBorder()).BorderForeground(focusedBorderColor)
-	blurredPanelStyle  = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(blurredBorderColor)
-	statusTextStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("242"))
-	statusErrorStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Bold(true)
-	statusBarStyle     = lipgloss.NewStyle()
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/lipgloss"
 )
 
 func (m Model) statusView() string {
@@ -18,20 +13,56 @@ func (m Model) statusView() string {
 	}
 
 	switch {
+	case m.searching:
+		return m.styles.statusBar.Width(m.width).Render(m.searchInput.View())
+	case m.moving:
+		return m.styles.statusBar.Width(m.width).Render(m.moveInput.View())
+	case m.commanding:
+		return m.styles.statusBar.Width(m.width).Render(m.commandView())
+	case m.finding:
+		return m.styles.statusBar.Width(m.width).Render(m.findInput.View())
+	case m.unsubscribe != nil:
+		return m.styles.statusBar.Width(m.width).Render(m.styles.statusText.Render(m.unsubscribePromptView()))
+	case m.sending != nil:
+		return m.styles.statusBar.Width(m.width).Render(m.styles.statusText.Render(m.sendPromptView()))
 	case m.err != nil:
-		return statusBarStyle.Width(m.width).Render(statusErrorStyle.Render(m.err.Error()))
+		return m.styles.statusBar.Width(m.width).Render(m.styles.statusError.Render(m.err.Error()))
+	case len(m.jobs) > 0:
+		text := lipgloss.JoinHorizontal(
+			lipgloss.Left,
+			m.spinner.View(),
+			m.styles.statusText.Render(" "+m.bulkStatus()),
+		)
+		return m.styles.statusBar.Width(m.width).Render(text)
+	case len(m.pending) > 0 && m.status == "":
+		return m.styles.statusBar.Width(m.width).Render(m.withBadges(m.styles.statusText.Render(m.undoStatus())))
 	case m.loading:
 		text := lipgloss.JoinHorizontal(
 			lipgloss.Left,
 			m.spinner.View(),
-			statusTextStyle.Render(" Loading..."),
+			m.styles.statusText.Render(" Loading..."),
 		)
-		return statusBarStyle.Width(m.width).Render(text)
+		return m.styles.statusBar.Width(m.width).Render(text)
 	case m.status != "":
-		return statusBarStyle.Width(m.width).Render(statusTextStyle.Render(m.status))
+		return m.styles.statusBar.Width(m.width).Render(m.withBadges(m.styles.statusText.Render(m.status)))
 	default:
-		return statusBarStyle.Width(m.width).Render(m.help.View(keys))
+		return m.styles.statusBar.Width(m.width).Render(m.withBadges(m.help.View(m.helpKeys())))
+	}
+}
+
+// withBadges prefixes text with counts of newly arrived and marked mail
+func (m Model) withBadges(text string) string {
+	var badges []string
+	if m.newCount > 0 {
+		badges = append(badges, m.styles.badge.Render(fmt.Sprintf("● %d new", m.newCount)), "  ")
 	}
+	if n := m.list.MarkedCount(); n > 0 {
+		badges = append(badges, m.styles.badge.Render(fmt.Sprintf("✓ %d marked", n)), "  ")
+	}
+	if len(badges) == 0 {
+		return text
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Left, append(badges, text)...)
 }
 
 func (m Model) View() string {
@@ -46,31 +77,35 @@ func (m Model) View() string {
 		contentHeight = 0
 	}
 
-	listWidth := m.width / 3
-	previewWidth := m.width - listWidth - 2
-	if previewWidth < 0 {
-		previewWidth = 0
-	}
-
-	listStyle := blurredPanelStyle
-	previewStyle := blurredPanelStyle
-	if m.focus == focusList {
-		listStyle = focusedPanelStyle
+	if m.showHelp {
+		return lipgloss.JoinVertical(lipgloss.Left, m.helpView(contentHeight), statusView)
 	}
-	if m.focus == focusPreview {
-		previewStyle = focusedPanelStyle
+	if m.picker != nil {
+		return lipgloss.JoinVertical(lipgloss.Left, m.linkPickerView(contentHeight), statusView)
 	}
 
-	listView := listStyle.Copy().
-		Width(listWidth).
-		Height(contentHeight).
-		Render(m.list.View())
+	return lipgloss.JoinVertical(lipgloss.Left, m.panesView(), statusView)
+}
 
-	previewView := previewStyle.Copy().
-		Width(previewWidth).
-		Height(contentHeight).
-		Render(m.preview.View())
+// helpView renders every active binding in a centered box
+func (m Model) helpView(height int) string {
+	full := m.help
+	full.ShowAll = true
+	box := m.styles.helpOverlay.Render(lipgloss.JoinVertical(
+		lipgloss.Left,
+		m.styles.subject.Render("Keys"),
+		"",
+		full.FullHelpView(m.helpKeys().FullHelp()),
+		"",
+		m.styles.statusText.Render("Press any key to close"),
+	))
+	return lipgloss.Place(m.width, height, lipgloss.Center, lipgloss.Center, box)
+}
 
-	panes := lipgloss.JoinHorizontal(lipgloss.Top, listView, previewView)
-	return lipgloss.JoinVertical(lipgloss.Left, panes, statusView)
+// helpKeys returns the bindings to show for the current mode
+func (m Model) helpKeys() help.KeyMap {
+	if m.reading {
+		return readerKeys(m.keys)
+	}
+	return m.keys
 }
@@ -0,0 +1,82 @@
+package tui
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+)
+
+// yankStep is what the last yank copied, so a following yank_id key can
+// copy something else instead
+type yankStep int
+
+const (
+	yankNone yankStep = iota
+	yankSender
+	yankMessageID
+)
+
+// yankTarget is the previewed email, or the selected one before it loads
+func (m Model) yankTarget() *api.Email {
+	selected := m.list.SelectedEmail()
+	if m.currentEmail != nil && (selected == nil || selected.ID == m.currentEmail.ID) {
+		return m.currentEmail
+	}
+	return selected
+}
+
+// yankSenderAddress copies the sender's address
+func yankSenderAddress(m Model) (Model, tea.Cmd) {
+	email := m.yankTarget()
+	if email == nil {
+		return m, nil
+	}
+	address := email.Sender
+	if addr, err := mail.ParseAddress(email.Sender); err == nil {
+		address = addr.Address
+	}
+	m.yank = yankSender
+	return m, copyText(address, "sender "+address)
+}
+
+// yankBody copies the plain text body of the previewed email
+func yankBody(m Model) (Model, tea.Cmd) {
+	email := m.currentEmail
+	if email == nil {
+		m.status = "Open a message to copy its body"
+		return m, nil
+	}
+	body := email.Body()
+	if body == "" {
+		m.status = "Message has no text body"
+		return m, nil
+	}
+	return m, copyText(body, fmt.Sprintf("body (%s)", plural(strings.Count(body, "\n")+1, "line")))
+}
+
+// updateYank handles the key after a yank: yank_id copies the message ID,
+// and again, a command that reads the message
+func updateYank(m Model, msg tea.KeyMsg) (Model, tea.Cmd, bool) {
+	step := m.yank
+	m.yank = yankNone
+	email := m.yankTarget()
+	if !key.Matches(msg, m.keys.YankID) || email == nil {
+		return m, nil, false
+	}
+	if step == yankMessageID {
+		command := fmt.Sprintf("mercury read %d", email.ID)
+		return m, copyText(command, "command "+command), true
+	}
+	id := strings.Trim(email.MessageID, "<> ")
+	if id == "" {
+		m.status = "Message has no Message-ID"
+		return m, nil, true
+	}
+	m.yank = yankMessageID
+	return m, copyText(id, "message ID "+id), true
+}
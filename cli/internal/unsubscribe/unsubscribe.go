@@ -0,0 +1,234 @@
+// Package unsubscribe leaves mailing lists using the List-Unsubscribe
+// header (RFC 2369), preferring one-click unsubscribe (RFC 8058).
+package unsubscribe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+)
+
+// Method is how an unsubscribe request is made.
+type Method int
+
+const (
+	// OneClick POSTs to an HTTPS URL with no further interaction.
+	OneClick Method = iota
+	// Mailto sends an email to the list's unsubscribe address.
+	Mailto
+	// Web is a page the user has to open and follow themselves.
+	Web
+)
+
+// Action is a planned unsubscribe request.
+type Action struct {
+	Method Method
+	// URL is the one-click or web page URL.
+	URL string
+	// To, Subject and Body make up the mailto message.
+	To      string
+	Subject string
+	Body    string
+}
+
+// ErrNoHeader is returned by Plan for mail without List-Unsubscribe.
+var ErrNoHeader = errors.New("no List-Unsubscribe header")
+
+// HTTPClient makes one-click requests; tests replace it. Redirects are
+// not followed, so the POST only reaches the host the signed header names.
+var HTTPClient = &http.Client{
+	Timeout: 15 * time.Second,
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+var uriPattern = regexp.MustCompile(`<([^>]+)>`)
+
+// Plan picks the best unsubscribe method the email offers: one-click when
+// List-Unsubscribe-Post allows it and a verified DKIM signature covers
+// both headers, then mailto, then a web page.
+func Plan(email *api.Email) (Action, error) {
+	header := email.Header("List-Unsubscribe")
+	if strings.TrimSpace(header) == "" {
+		return Action{}, ErrNoHeader
+	}
+	oneClick := strings.Contains(strings.ToLower(email.Header("List-Unsubscribe-Post")), "list-unsubscribe=one-click") &&
+		signedListHeaders(email)
+
+	var mailto, https, web string
+	for _, m := range uriPattern.FindAllStringSubmatch(header, -1) {
+		uri := strings.TrimSpace(m[1])
+		lower := strings.ToLower(uri)
+		switch {
+		case strings.HasPrefix(lower, "mailto:") && mailto == "":
+			mailto = uri
+		case strings.HasPrefix(lower, "https://") && https == "":
+			https = uri
+		case strings.HasPrefix(lower, "http://") && web == "":
+			web = uri
+		}
+	}
+
+	switch {
+	case oneClick && https != "":
+		return Action{Method: OneClick, URL: https}, nil
+	case mailto != "":
+		return parseMailto(mailto)
+	case https != "":
+		return Action{Method: Web, URL: https}, nil
+	case web != "":
+		return Action{Method: Web, URL: web}, nil
+	}
+	return Action{}, fmt.Errorf("List-Unsubscribe has no usable address: %s", header)
+}
+
+// signedListHeaders reports whether a DKIM signature the receiving server
+// verified covers List-Unsubscribe and List-Unsubscribe-Post, which RFC 8058
+// requires before a one-click POST. Otherwise a forged message could make
+// the client POST to any host it names.
+func signedListHeaders(email *api.Email) bool {
+	dkim := email.Authentication().DKIM
+	if dkim.Result != api.AuthPass || dkim.Domain == "" {
+		return false
+	}
+	for _, f := range email.HeaderFields() {
+		if !strings.EqualFold(f.Name, "DKIM-Signature") {
+			continue
+		}
+		tags := dkimTags(f.Value)
+		if !strings.EqualFold(tags["d"], dkim.Domain) {
+			continue
+		}
+		signed := make(map[string]bool)
+		for _, name := range strings.Split(tags["h"], ":") {
+			signed[strings.ToLower(name)] = true
+		}
+		if signed["list-unsubscribe"] && signed["list-unsubscribe-post"] {
+			return true
+		}
+	}
+	return false
+}
+
+// dkimTags reads the tag=value list of a DKIM-Signature, dropping the
+// folding whitespace values may contain.
+func dkimTags(value string) map[string]string {
+	tags := make(map[string]string)
+	for _, part := range strings.Split(value, ";") {
+		if name, val, ok := strings.Cut(part, "="); ok {
+			tags[strings.TrimSpace(name)] = strings.Join(strings.Fields(val), "")
+		}
+	}
+	return tags
+}
+
+// parseMailto reads the address, subject and body of a mailto URI.
+func parseMailto(uri string) (Action, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return Action{}, fmt.Errorf("parse %s: %w", uri, err)
+	}
+	to, _, _ := strings.Cut(u.Opaque, ",")
+	to, err = url.PathUnescape(to)
+	if err != nil || to == "" {
+		return Action{}, fmt.Errorf("no address in %s", uri)
+	}
+	a := Action{Method: Mailto, To: to, Subject: "unsubscribe", Body: "unsubscribe"}
+	query, _ := url.ParseQuery(u.RawQuery)
+	for key, values := range query {
+		switch strings.ToLower(key) {
+		case "subject":
+			a.Subject = values[0]
+		case "body":
+			a.Body = values[0]
+		}
+	}
+	return a, nil
+}
+
+// String describes the action for a confirmation prompt.
+func (a Action) String() string {
+	switch a.Method {
+	case OneClick:
+		return "one-click request to " + host(a.URL)
+	case Mailto:
+		return "email to " + a.To
+	default:
+		return "web page at " + host(a.URL)
+	}
+}
+
+func host(raw string) string {
+	if u, err := url.Parse(raw); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return raw
+}
+
+// Do carries out a one-click or mailto action. The mailto message is sent
+// from the address the list mails, so the right subscription ends. Web
+// actions cannot be automated and return an error naming the page.
+func Do(ctx context.Context, client *api.Client, email *api.Email, a Action) error {
+	switch a.Method {
+	case OneClick:
+		return postOneClick(ctx, a.URL)
+	case Mailto:
+		from := email.Recipient
+		if addr, err := mail.ParseAddress(from); err == nil {
+			from = addr.Address
+		}
+		resp, err := client.SendEmail(&api.SendRequest{From: from, To: a.To, Subject: a.Subject, Text: a.Body})
+		if err != nil {
+			return fmt.Errorf("send unsubscribe email: %w", err)
+		}
+		if !resp.Success {
+			return fmt.Errorf("send unsubscribe email: %s", resp.Error)
+		}
+		return nil
+	default:
+		return fmt.Errorf("the list only offers a web page; open %s", a.URL)
+	}
+}
+
+// postOneClick makes the RFC 8058 request: a form POST without cookies or
+// credentials.
+func postOneClick(ctx context.Context, target string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader("List-Unsubscribe=One-Click"))
+	if err != nil {
+		return fmt.Errorf("one-click unsubscribe: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("one-click unsubscribe: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("one-click unsubscribe: %s returned %s", host(target), resp.Status)
+	}
+	return nil
+}
+
+// SameList reports whether other came from the same sender address as
+// email, which is how a list's earlier mail is found in summaries.
+func SameList(email, other *api.Email) bool {
+	return strings.EqualFold(address(email.Sender), address(other.Sender))
+}
+
+func address(sender string) string {
+	if addr, err := mail.ParseAddress(sender); err == nil {
+		return addr.Address
+	}
+	return strings.TrimSpace(sender)
+}
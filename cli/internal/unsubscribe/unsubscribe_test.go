@@ -0,0 +1,156 @@
+package unsubscribe
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/misty-step/mercury/cli/internal/api"
+)
+
+func newsletter(headers string) *api.Email {
+	return &api.Email{
+		ID:        1,
+		Sender:    "Shop News <news@shop.example>",
+		Recipient: "Me <me@example.com>",
+		RawEmail:  headers + "Subject: Deals\r\n\r\nbody",
+	}
+}
+
+// oneClick offers one-click unsubscribe under a DKIM signature from
+// shop.example that signs the listed headers and passed with result.
+func oneClick(result, signed string) string {
+	return "Authentication-Results: mx.cloudflare.net; dkim=" + result + " header.d=shop.example\r\n" +
+		"DKIM-Signature: v=1; a=rsa-sha256; d=shop.example; s=s1;\r\n h=from:subject:" + signed + "; bh=x; b=y\r\n" +
+		"List-Unsubscribe: <mailto:leave@shop.example>, <https://shop.example/u/42>\r\n" +
+		"List-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n"
+}
+
+func TestPlan(t *testing.T) {
+	mailto := Action{Method: Mailto, To: "leave@shop.example", Subject: "unsubscribe", Body: "unsubscribe"}
+	tests := []struct {
+		name    string
+		headers string
+		want    Action
+		wantErr bool
+	}{
+		{
+			name:    "one-click",
+			headers: oneClick("pass", "list-unsubscribe:\r\n list-unsubscribe-post"),
+			want:    Action{Method: OneClick, URL: "https://shop.example/u/42"},
+		},
+		{
+			name:    "one-click unsigned",
+			headers: "List-Unsubscribe: <mailto:leave@shop.example>, <https://shop.example/u/42>\r\nList-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n",
+			want:    mailto,
+		},
+		{name: "one-click with failed dkim", headers: oneClick("fail", "list-unsubscribe:list-unsubscribe-post"), want: mailto},
+		{name: "one-click post header not signed", headers: oneClick("pass", "list-unsubscribe"), want: mailto},
+		{
+			name:    "one-click from untrusted server",
+			headers: strings.Replace(oneClick("pass", "list-unsubscribe:list-unsubscribe-post"), "mx.cloudflare.net", "evil.example", 1),
+			want:    mailto,
+		},
+		{
+			name:    "mailto without post header",
+			headers: "List-Unsubscribe: <https://shop.example/u/42>,\r\n <mailto:leave@shop.example?subject=Remove%20me>\r\n",
+			want:    Action{Method: Mailto, To: "leave@shop.example", Subject: "Remove me", Body: "unsubscribe"},
+		},
+		{
+			name:    "web only",
+			headers: "List-Unsubscribe: <http://shop.example/u/42>\r\nList-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n",
+			want:    Action{Method: Web, URL: "http://shop.example/u/42"},
+		},
+		{name: "missing", headers: "", wantErr: true},
+		{name: "unusable", headers: "List-Unsubscribe: <ftp://shop.example>\r\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Plan(newsletter(tt.headers))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Plan() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Plan() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Plan() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDo_OneClick(t *testing.T) {
+	var body, contentType, cookie string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body, contentType, cookie = string(data), r.Header.Get("Content-Type"), r.Header.Get("Cookie")
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer server.Close()
+
+	if err := Do(context.Background(), nil, newsletter(""), Action{Method: OneClick, URL: server.URL}); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if body != "List-Unsubscribe=One-Click" || contentType != "application/x-www-form-urlencoded" || cookie != "" {
+		t.Errorf("request body %q, type %q, cookie %q", body, contentType, cookie)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer failing.Close()
+	if err := Do(context.Background(), nil, newsletter(""), Action{Method: OneClick, URL: failing.URL}); err == nil || !strings.Contains(err.Error(), "410") {
+		t.Errorf("Do() error = %v, want the status", err)
+	}
+
+	// A redirect elsewhere is reported, not followed.
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, server.URL+"/elsewhere", http.StatusTemporaryRedirect)
+	}))
+	defer redirect.Close()
+	body = ""
+	if err := Do(context.Background(), nil, newsletter(""), Action{Method: OneClick, URL: redirect.URL}); err == nil || body != "" {
+		t.Errorf("Do() error = %v, redirect followed with %q", err, body)
+	}
+}
+
+func TestDo_Mailto(t *testing.T) {
+	var sent api.SendRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		_, _ = w.Write([]byte(`{"success":true}`))
+	}))
+	defer server.Close()
+
+	client := api.NewClientWithSecret(server.URL, "secret")
+	action := Action{Method: Mailto, To: "leave@shop.example", Subject: "unsubscribe", Body: "unsubscribe"}
+	if err := Do(context.Background(), client, newsletter(""), action); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if sent.From != "me@example.com" || sent.To != "leave@shop.example" || sent.Subject != "unsubscribe" {
+		t.Errorf("sent %+v", sent)
+	}
+
+	if err := Do(context.Background(), client, newsletter(""), Action{Method: Web, URL: "https://shop.example"}); err == nil {
+		t.Error("a web page cannot be automated")
+	}
+}
+
+func TestSameList(t *testing.T) {
+	a := &api.Email{Sender: "Shop News <NEWS@shop.example>"}
+	b := &api.Email{Sender: "news@shop.example"}
+	c := &api.Email{Sender: "other@shop.example"}
+	if !SameList(a, b) || SameList(a, c) {
+		t.Error("SameList should compare sender addresses")
+	}
+}
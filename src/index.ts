@@ -8,13 +8,23 @@
 import { requireAuth, requireScope, type AuthContext } from './auth';
 import { requireEmailOwnership } from './authorization';
 import { handleCreateApiKey, handleListApiKeys, handleRevokeApiKey } from './api-keys';
-import { sendEmail } from './send/resend';
+import {
+  headerAddress,
+  rawHeader,
+  rawMessageProblem,
+  sendRawEmail,
+  type RawSendResult,
+  type SendEmailBinding,
+} from './send/raw';
+import { sendEmail, type ResendAttachment } from './send/resend';
 import { handleCreateUser, handleGetMe, handleGetUser, handleGetUsers } from './users';
 
 export interface Env {
   DB: D1Database;
   API_SECRET: string;
   RESEND_API_KEY: string;
+  /** Relays raw MIME messages; optional, needed for "raw" sends */
+  SEND_EMAIL?: SendEmailBinding;
 }
 
 interface EmailMessage {
@@ -119,6 +129,51 @@ function isRecord(value: unknown): value is Record<string, unknown> {
   return typeof value === 'object' && value !== null;
 }
 
+/** Validates the optional attachments of a send request; null if malformed */
+function parseAttachments(value: unknown): ResendAttachment[] | undefined | null {
+  if (value === undefined) return undefined;
+  if (!Array.isArray(value)) return null;
+  const attachments: ResendAttachment[] = [];
+  for (const item of value) {
+    if (!isRecord(item) || typeof item.filename !== 'string' || typeof item.content !== 'string') {
+      return null;
+    }
+    if (item.content_type !== undefined && typeof item.content_type !== 'string') {
+      return null;
+    }
+    attachments.push({
+      filename: item.filename,
+      content: item.content,
+      ...(item.content_type ? { content_type: item.content_type } : {}),
+    });
+  }
+  return attachments;
+}
+
+const EMAIL_REGEX = /^[^\s@]+@[^\s@]+\.[^\s@]+$/;
+
+/** Resend's limit on the recipients of one email, To, Cc and Bcc together */
+const MAX_RECIPIENTS = 50;
+
+/**
+ * Splits a recipient field, a comma-separated string or an array of
+ * addresses, into trimmed addresses; null if any address is malformed
+ */
+function parseRecipients(value: unknown): string[] | null {
+  if (value === undefined || value === null) return [];
+  const items = typeof value === 'string' ? value.split(',') : value;
+  if (!Array.isArray(items)) return null;
+  const recipients: string[] = [];
+  for (const item of items) {
+    if (typeof item !== 'string') return null;
+    const address = item.trim();
+    if (address.length === 0) continue;
+    if (!EMAIL_REGEX.test(address)) return null;
+    recipients.push(address);
+  }
+  return recipients;
+}
+
 /** Duck-type check for Response (instanceof fails across environments) */
 function isHttpResponse(value: unknown): value is Response {
   return (
@@ -138,7 +193,7 @@ async function listEmails(auth: AuthContext, env: Env, url: URL): Promise<Respon
   const offset = Number.isFinite(offsetParam) ? offsetParam : 0;
   const folder = url.searchParams.get('folder') || 'inbox';
   const unreadOnly = url.searchParams.get('unread') === 'true';
-  const since = url.searchParams.get('since'); // ISO timestamp
+  const since = url.searchParams.get('since'); // ISO timestamp, inclusive
   const unsynced = url.searchParams.get('unsynced') === 'true';
   const isAdmin = auth.user.role === 'admin';
   const recipientFilter = isAdmin ? url.searchParams.get('recipient') : null;
@@ -161,7 +216,9 @@ async function listEmails(auth: AuthContext, env: Env, url: URL): Promise<Respon
     conditions.push('is_read = 0');
   }
   if (since) {
-    conditions.push('received_at > ?');
+    // Inclusive: mail can share the second of the newest email a client
+    // already has, so clients de-duplicate by ID instead.
+    conditions.push('received_at >= ?');
     params.push(since);
   }
   if (unsynced) {
@@ -306,30 +363,52 @@ async function sendOutboundEmail(
     return jsonResponse({ error: 'Invalid request body' }, 400);
   }
 
-  const to = typeof payload.to === 'string' ? payload.to.trim() : '';
-  const subject = typeof payload.subject === 'string' ? payload.subject.trim() : '';
+  const to = parseRecipients(payload.to);
+  const cc = parseRecipients(payload.cc);
+  const bcc = parseRecipients(payload.bcc);
+  const raw = typeof payload.raw === 'string' && payload.raw.length > 0 ? payload.raw : undefined;
   const html = typeof payload.html === 'string' ? payload.html : undefined;
   const text = typeof payload.text === 'string' ? payload.text : undefined;
   const explicitFrom = typeof payload.from === 'string' ? payload.from.trim() : '';
   const from = explicitFrom.length > 0 ? explicitFrom : defaultFrom;
+  const attachments = parseAttachments(payload.attachments);
+  const subject = (
+    typeof payload.subject === 'string' ? payload.subject : raw ? (rawHeader(raw, 'subject') ?? '') : ''
+  ).trim();
+
+  if (to === null || cc === null || bcc === null) {
+    return jsonResponse({ error: 'Invalid email address format' }, 400);
+  }
 
   if (to.length === 0) {
     return jsonResponse({ error: 'Missing "to"' }, 400);
   }
 
-  const EMAIL_REGEX = /^[^\s@]+@[^\s@]+\.[^\s@]+$/;
-  if (!EMAIL_REGEX.test(to)) {
-    return jsonResponse({ error: 'Invalid email address format' }, 400);
+  if (to.length + cc.length + bcc.length > MAX_RECIPIENTS) {
+    return jsonResponse({ error: `Too many recipients (at most ${MAX_RECIPIENTS})` }, 400);
   }
 
   if (subject.length === 0) {
     return jsonResponse({ error: 'Missing "subject"' }, 400);
   }
 
-  if (!html && !text) {
+  if (!raw && !html && !text) {
     return jsonResponse({ error: 'Missing "html" or "text"' }, 400);
   }
 
+  if (attachments === null) {
+    return jsonResponse({ error: 'Invalid "attachments"' }, 400);
+  }
+
+  if (raw && headerAddress(rawHeader(raw, 'from')) !== headerAddress(from)) {
+    return jsonResponse({ error: 'Raw message From header does not match "from"' }, 400);
+  }
+
+  const rawProblem = raw ? rawMessageProblem(raw) : undefined;
+  if (rawProblem) {
+    return jsonResponse({ error: rawProblem }, 400);
+  }
+
   if (auth.user.role !== 'admin') {
     const aliasUserId = await resolveAliasUserId(env.DB, from);
     if (aliasUserId !== auth.user.id) {
@@ -337,13 +416,26 @@ async function sendOutboundEmail(
     }
   }
 
-  const sendResult = await sendEmail(env.RESEND_API_KEY, {
-    to,
-    subject,
-    from,
-    html,
-    text,
-  });
+  let sendResult: RawSendResult;
+  if (raw) {
+    if (!env.SEND_EMAIL) {
+      return jsonResponse({ error: 'Raw MIME sending is not configured (send_email binding SEND_EMAIL)' }, 501);
+    }
+    sendResult = await sendRawEmail(env.SEND_EMAIL, headerAddress(from), [...to, ...cc, ...bcc], raw);
+  } else {
+    sendResult = await sendEmail(env.RESEND_API_KEY, {
+      to,
+      ...(cc.length > 0 ? { cc } : {}),
+      ...(bcc.length > 0 ? { bcc } : {}),
+      subject,
+      from,
+      html,
+      text,
+      attachments,
+    });
+  }
+
+  const recipient = to.join(', ');
 
   if (sendResult.success && sendResult.messageId) {
     await env.DB.prepare(
@@ -352,29 +444,46 @@ async function sendOutboundEmail(
         VALUES (?, ?, ?, ?, ?, ?, 'sent', datetime('now'))
       `,
     )
-      .bind(sendResult.messageId, from, to, subject, html ?? null, text ?? null)
+      .bind(sendResult.messageId, from, recipient, subject, html ?? null, text ?? raw ?? null)
       .run();
 
     return jsonResponse({ success: true, messageId: sendResult.messageId });
   }
 
+  // Some recipients already have the message; say so rather than report a
+  // failure that invites sending it again to everyone.
+  const partial = (sendResult.delivered?.length ?? 0) > 0;
+
   await env.DB.prepare(
     `
       INSERT INTO sent_emails (message_id, sender, recipient, subject, html, text, status, error, sent_at)
-      VALUES (?, ?, ?, ?, ?, ?, 'error', ?, datetime('now'))
+      VALUES (?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))
     `,
   )
     .bind(
       sendResult.messageId ?? null,
       from,
-      to,
+      recipient,
       subject,
       html ?? null,
-      text ?? null,
+      text ?? raw ?? null,
+      partial ? 'partial' : 'error',
       sendResult.error ?? 'Unknown error',
     )
     .run();
 
+  if (partial) {
+    return jsonResponse(
+      {
+        error: sendResult.error,
+        partial: true,
+        messageId: sendResult.messageId,
+        delivered: sendResult.delivered,
+        undelivered: sendResult.undelivered,
+      },
+      502,
+    );
+  }
   return jsonResponse({ error: sendResult.error || 'Failed to send email' }, 502);
 }
 
@@ -0,0 +1,93 @@
+import { EmailMessage } from 'cloudflare:email';
+
+import type { ResendSendResult } from './resend';
+
+/** The Cloudflare send_email binding, which relays complete MIME messages */
+export interface SendEmailBinding {
+  send(message: EmailMessage): Promise<void>;
+}
+
+/**
+ * Returns the value of a header in the top-level header block of a raw
+ * message, unfolded; undefined if absent
+ */
+export function rawHeader(raw: string, name: string): string | undefined {
+  const end = raw.search(/\r?\n\r?\n/);
+  const block = (end < 0 ? raw : raw.slice(0, end)).replace(/\r?\n[ \t]+/g, ' ');
+  const prefix = name.toLowerCase() + ':';
+  for (const line of block.split(/\r?\n/)) {
+    if (line.toLowerCase().startsWith(prefix)) {
+      return line.slice(prefix.length).trim();
+    }
+  }
+  return undefined;
+}
+
+/** Extracts the bare address from a header value such as "Alice <a@b.c>" */
+export function headerAddress(value: string | undefined): string {
+  if (!value) return '';
+  const angle = value.match(/<([^>]+)>/);
+  return (angle ? angle[1] : value).trim().toLowerCase();
+}
+
+/** Headers a raw message must carry; the relay sends it as is */
+const REQUIRED_RAW_HEADERS = ['From', 'Date', 'Message-ID'];
+
+/**
+ * Returns why a raw message cannot be relayed, or undefined if it can; run
+ * before anything is sent, so a rejected message reaches nobody
+ */
+export function rawMessageProblem(raw: string): string | undefined {
+  for (const name of REQUIRED_RAW_HEADERS) {
+    if (!rawHeader(raw, name)) {
+      return `Raw message has no ${name} header`;
+    }
+  }
+  return undefined;
+}
+
+/** The outcome of a raw send, naming who got the message when some did not */
+export interface RawSendResult extends ResendSendResult {
+  delivered?: string[];
+  undelivered?: string[];
+}
+
+/**
+ * Sends a raw RFC 5322 message unchanged, once per envelope recipient, so
+ * signed and encrypted MIME structures arrive intact. The message is checked
+ * with rawMessageProblem first; a failure partway through reports which
+ * recipients were already sent it
+ */
+export async function sendRawEmail(
+  binding: SendEmailBinding,
+  from: string,
+  recipients: string[],
+  raw: string,
+): Promise<RawSendResult> {
+  const problem = rawMessageProblem(raw);
+  if (problem) {
+    return { success: false, error: problem };
+  }
+  const messageId = rawHeader(raw, 'message-id');
+  const delivered: string[] = [];
+  for (const [i, recipient] of recipients.entries()) {
+    try {
+      await binding.send(new EmailMessage(from, recipient, raw));
+    } catch (err) {
+      const reason = err instanceof Error ? err.message : String(err);
+      if (delivered.length === 0) {
+        return { success: false, messageId, error: reason };
+      }
+      const undelivered = recipients.slice(i);
+      return {
+        success: false,
+        messageId,
+        delivered,
+        undelivered,
+        error: `Partially sent: delivered to ${delivered.join(', ')}; not sent to ${undelivered.join(', ')} (${recipient}: ${reason})`,
+      };
+    }
+    delivered.push(recipient);
+  }
+  return { success: true, messageId, delivered };
+}
@@ -1,9 +1,19 @@
+export interface ResendAttachment {
+  filename: string;
+  /** Base64 encoded file content */
+  content: string;
+  content_type?: string;
+}
+
 export interface ResendSendRequest {
-  to: string;
+  to: string | string[];
+  cc?: string[];
+  bcc?: string[];
   subject: string;
   from: string;
   html?: string;
   text?: string;
+  attachments?: ResendAttachment[];
 }
 
 export interface ResendSendResult {
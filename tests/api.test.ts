@@ -145,6 +145,104 @@ describe('api', () => {
     expect(body.error).toBe('Invalid email address format');
   });
 
+  it('should reject an invalid address in a recipient list', async () => {
+    const response = await worker.fetch(
+      buildRequest('/send', {
+        method: 'POST',
+        headers: {
+          Authorization: 'Bearer secret',
+          'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({
+          to: 'someone@example.com, other@example.com',
+          cc: ['not-an-email'],
+          subject: 'Hi',
+          text: 'Hello',
+        }),
+      }),
+      env as never,
+      createExecutionContext(),
+    );
+
+    expect(response.status).toBe(400);
+    const body = await response.json();
+    expect(body.error).toBe('Invalid email address format');
+  });
+
+  it('should reject a raw message whose From header differs from "from"', async () => {
+    const response = await worker.fetch(
+      buildRequest('/send', {
+        method: 'POST',
+        headers: {
+          Authorization: 'Bearer secret',
+          'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({
+          from: 'me@example.com',
+          to: 'someone@example.com',
+          subject: 'Hi',
+          raw: 'From: ceo@example.com\r\nTo: someone@example.com\r\nSubject: Hi\r\n\r\nHello\r\n',
+        }),
+      }),
+      env as never,
+      createExecutionContext(),
+    );
+
+    expect(response.status).toBe(400);
+    const body = await response.json();
+    expect(body.error).toBe('Raw message From header does not match "from"');
+  });
+
+  it('should reject a raw message without a Message-ID before sending it', async () => {
+    const sent: unknown[] = [];
+    const response = await worker.fetch(
+      buildRequest('/send', {
+        method: 'POST',
+        headers: {
+          Authorization: 'Bearer secret',
+          'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({
+          from: 'me@example.com',
+          to: 'someone@example.com',
+          subject: 'Hi',
+          raw: 'From: me@example.com\r\nTo: someone@example.com\r\nDate: Thu, 15 Oct 2026 09:00:00 +0000\r\nSubject: Hi\r\n\r\nHello\r\n',
+        }),
+      }),
+      { ...env, SEND_EMAIL: { send: async (message: unknown) => void sent.push(message) } } as never,
+      createExecutionContext(),
+    );
+
+    expect(response.status).toBe(400);
+    const body = await response.json();
+    expect(body.error).toBe('Raw message has no Message-ID header');
+    expect(sent).toHaveLength(0);
+  });
+
+  it('should reject malformed attachments on send', async () => {
+    const response = await worker.fetch(
+      buildRequest('/send', {
+        method: 'POST',
+        headers: {
+          Authorization: 'Bearer secret',
+          'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({
+          to: 'someone@example.com',
+          subject: 'Hi',
+          text: 'Hello',
+          attachments: [{ filename: 'invite.ics' }],
+        }),
+      }),
+      env as never,
+      createExecutionContext(),
+    );
+
+    expect(response.status).toBe(400);
+    const body = await response.json();
+    expect(body.error).toBe('Invalid "attachments"');
+  });
+
   it('should return 404 for debug endpoint', async () => {
     const response = await worker.fetch(
       buildRequest('/debug', {
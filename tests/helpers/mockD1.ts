@@ -591,7 +591,7 @@ function parseEmailFilters(
   const folder = String(params[index++]);
   const userId = normalized.includes('USER_ID = ?') ? Number(params[index++]) : null;
   const recipient = normalized.includes('RECIPIENT = ?') ? String(params[index++]) : null;
-  const since = normalized.includes('RECEIVED_AT > ?') ? String(params[index++]) : null;
+  const since = normalized.includes('RECEIVED_AT >= ?') ? String(params[index++]) : null;
 
   const filters: EmailFilters = {
     folder,
@@ -629,7 +629,7 @@ function applyEmailFilters(emails: EmailRecord[], filters: EmailFilters): EmailR
   }
 
   if (filters.since !== null) {
-    results = results.filter((record) => record.received_at > filters.since);
+    results = results.filter((record) => record.received_at >= filters.since);
   }
 
   if (filters.unsyncedOnly) {
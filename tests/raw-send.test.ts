@@ -0,0 +1,60 @@
+import { describe, expect, it } from 'vitest';
+import { rawMessageProblem, sendRawEmail, type SendEmailBinding } from '../src/send/raw';
+
+const raw =
+  'From: me@example.com\r\nTo: a@example.com\r\nDate: Thu, 15 Oct 2026 09:00:00 +0000\r\n' +
+  'Message-ID: <1@example.com>\r\nSubject: Hi\r\n\r\nHello\r\n';
+
+/** A binding that records recipients and rejects the ones listed */
+function binding(failFor: string[] = []): SendEmailBinding & { sent: string[] } {
+  const sent: string[] = [];
+  return {
+    sent,
+    async send(message) {
+      if (failFor.includes(message.to)) {
+        throw new Error('relay refused');
+      }
+      sent.push(message.to);
+    },
+  };
+}
+
+describe('raw send', () => {
+  it('should name the first missing required header', () => {
+    expect(rawMessageProblem(raw)).toBeUndefined();
+    expect(rawMessageProblem(raw.replace('Date: Thu, 15 Oct 2026 09:00:00 +0000\r\n', ''))).toBe(
+      'Raw message has no Date header',
+    );
+  });
+
+  it('should send nothing when a required header is missing', async () => {
+    const relay = binding();
+    const result = await sendRawEmail(relay, 'me@example.com', ['a@example.com'], raw.replace(/Message-ID:.*\r\n/, ''));
+
+    expect(result.success).toBe(false);
+    expect(result.error).toBe('Raw message has no Message-ID header');
+    expect(relay.sent).toHaveLength(0);
+  });
+
+  it('should report partial delivery with who got the message', async () => {
+    const relay = binding(['b@example.com']);
+    const result = await sendRawEmail(
+      relay,
+      'me@example.com',
+      ['a@example.com', 'b@example.com', 'c@example.com'],
+      raw,
+    );
+
+    expect(result.success).toBe(false);
+    expect(result.messageId).toBe('<1@example.com>');
+    expect(result.delivered).toEqual(['a@example.com']);
+    expect(result.undelivered).toEqual(['b@example.com', 'c@example.com']);
+    expect(result.error).toContain('Partially sent');
+  });
+
+  it('should report a failure on the first recipient as a plain error', async () => {
+    const result = await sendRawEmail(binding(['a@example.com']), 'me@example.com', ['a@example.com'], raw);
+
+    expect(result).toEqual({ success: false, messageId: '<1@example.com>', error: 'relay refused' });
+  });
+});